@@ -0,0 +1,177 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Acknowledgment modes for captured messages.
+const (
+	AckFull   = "full"   // "ДОБАВИЛ СООБЩЕНИЕ В ..." reply
+	AckReact  = "react"  // 👍 reaction on the user's message
+	AckSilent = "silent" // nothing
+)
+
+func (s *sqlStore) AckMode(chatID int64) string {
+	v, _, _ := s.GetKV(chatKey(chatID, "ack"))
+	switch v {
+	case AckReact, AckSilent:
+		return v
+	}
+	return AckFull
+}
+
+func (a *App) react(chatID int64, messageID int, emoji string) error {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonZero("message_id", messageID)
+	params["reaction"] = `[{"type":"emoji","emoji":"` + emoji + `"}]`
+	_, err := a.Bot.MakeRequest("setMessageReaction", params)
+	return err
+}
+
+// ackLinkKeep is how many recent confirmations per chat can still be
+// replied to for an edit.
+const ackLinkKeep = 200
+
+func (s *sqlStore) LinkAck(chatID, messageID, itemID int64) error {
+	db := s.db(chatID)
+	if _, err := db.Exec(
+		`INSERT INTO ack_messages(chat_id, message_id, item_id) VALUES(?,?,?)
+		 ON CONFLICT(chat_id, message_id) DO UPDATE SET item_id=excluded.item_id`,
+		chatID, messageID, itemID,
+	); err != nil {
+		return err
+	}
+	_, err := db.Exec(
+		`DELETE FROM ack_messages WHERE chat_id=? AND message_id <= (SELECT message_id FROM ack_messages WHERE chat_id=? ORDER BY message_id DESC LIMIT 1 OFFSET ?)`,
+		chatID, chatID, ackLinkKeep,
+	)
+	return err
+}
+
+func (s *sqlStore) AckItem(chatID, messageID int64) (int64, bool, error) {
+	var itemID int64
+	err := s.db(chatID).QueryRow(`SELECT item_id FROM ack_messages WHERE chat_id=? AND message_id=?`, chatID, messageID).Scan(&itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return itemID, err == nil, err
+}
+
+func (s *sqlStore) EditItem(chatID, id int64, text string) error {
+	stored, norm, err := s.sealText(chatID, text)
+	if err != nil {
+		return err
+	}
+	_, err = s.db(chatID).Exec(`UPDATE items SET text=?, norm=? WHERE chat_id=? AND id=?`, stored, norm, chatID, id)
+	return err
+}
+
+// ackCapture confirms a stored message according to the chat's ack mode.
+// A failed reaction falls back to the text confirmation, which remembers
+// the item so that a reply to it edits the item (see editFromAck).
+func (a *App) ackCapture(m *tgbotapi.Message, topic string, itemID int64) {
+	chatID := m.Chat.ID
+	switch a.Store.AckMode(chatID) {
+	case AckSilent:
+		return
+	case AckReact:
+		err := a.react(chatID, m.MessageID, "👍")
+		if err == nil {
+			return
+		}
+		log.Printf("reaction error: %v", err)
+	}
+	msg := tgbotapi.NewMessage(chatID, a.tr(chatID, "added", topicLabel(a.Store.Lang(chatID), topic)))
+	if markup := a.replyMarkup(chatID); markup != nil {
+		msg.ReplyMarkup = markup
+	}
+	sent, err := a.Bot.Send(msg)
+	if err != nil {
+		return
+	}
+	if err := a.Store.LinkAck(chatID, int64(sent.MessageID), itemID); err != nil {
+		log.Printf("link ack error: %v", err)
+	}
+}
+
+// editFromAck treats a reply to a capture confirmation as the item's new
+// text. Only active items are edited; a secret one's text isn't echoed.
+func (a *App) editFromAck(m *tgbotapi.Message) bool {
+	if m.ReplyToMessage == nil || m.ReplyToMessage.From == nil || m.ReplyToMessage.From.ID != a.Bot.Self.ID {
+		return false
+	}
+	chatID := m.Chat.ID
+	itemID, ok, err := a.Store.AckItem(chatID, int64(m.ReplyToMessage.MessageID))
+	if err != nil || !ok {
+		return false
+	}
+	text := strings.TrimSpace(m.Text)
+	if text == "" {
+		return false
+	}
+	it, err := a.Store.GetItem(chatID, itemID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return true
+	}
+	if it == nil {
+		a.send(chatID, a.tr(chatID, "edit.gone", itemID))
+		return true
+	}
+	if it.Status != StatusActive {
+		a.send(chatID, a.tr(chatID, "edit.closed", itemID))
+		return true
+	}
+
+	var due time.Time
+	if it.Topic == TopicTasks {
+		text, due = a.applyDue(chatID, text)
+	}
+	err = a.Store.EditItem(chatID, itemID, text)
+	if errors.Is(err, errLocked) {
+		a.sendLocked(chatID)
+		return true
+	}
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return true
+	}
+	if !due.IsZero() {
+		if err := a.Store.SetDue(chatID, itemID, due); err != nil {
+			log.Printf("set due error: %v", err)
+			due = time.Time{}
+		}
+	}
+	it.Text = text
+	a.send(chatID, fmt.Sprintf("✏️ #%d: %s", itemID, shownText(*it)))
+	if !due.IsZero() {
+		a.confirmDue(chatID, due)
+	}
+	return true
+}
+
+func (a *App) handleAck(chatID int64, arg string) {
+	mode := strings.ToLower(strings.TrimSpace(arg))
+	switch mode {
+	case "":
+		a.send(chatID, a.tr(chatID, "ack.show", a.Store.AckMode(chatID)))
+		return
+	case AckFull, AckReact, AckSilent:
+	default:
+		a.send(chatID, a.tr(chatID, "ack.usage"))
+		return
+	}
+	if err := a.Store.SetKV(chatKey(chatID, "ack"), mode); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "ack.set", mode))
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// AttachEvent links an item to a calendar event; sql.ErrNoRows means the
+// chat has no such item.
+func (s *sqlStore) AttachEvent(chatID, itemID int64, eventID string) error {
+	res, err := s.db(chatID).Exec(`UPDATE items SET event_id=? WHERE chat_id=? AND id=?`, eventID, chatID, itemID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// TasksByEvent returns active items attached to calendar events, keyed by event id.
+func (s *sqlStore) TasksByEvent(chatID int64) (map[string][]Item, error) {
+	rows, err := s.readDB(chatID).Query(
+		`SELECT id, chat_id, topic, text, flagged, secret, created_at, event_id FROM items
+		 WHERE chat_id=? AND status=? AND event_id<>'' ORDER BY id`,
+		chatID, StatusActive,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string][]Item{}
+	for rows.Next() {
+		var it Item
+		var created, eventID string
+		if err := rows.Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &it.Flagged, &it.Secret, &created, &eventID); err != nil {
+			return nil, err
+		}
+		it.Text = s.openText(chatID, it.Text)
+		it.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		out[eventID] = append(out[eventID], it)
+	}
+	return out, rows.Err()
+}
+
+// wordStems cuts words to their first five letters, which is enough to
+// match "встрече с Иваном" against "Встреча с Иваном".
+func wordStems(text string) map[string]bool {
+	out := map[string]bool{}
+	for _, w := range strings.Fields(normalizeText(text)) {
+		if utf8.RuneCountInString(w) < 3 {
+			continue
+		}
+		r := []rune(w)
+		if len(r) > 5 {
+			r = r[:5]
+		}
+		out[string(r)] = true
+	}
+	return out
+}
+
+// matchEvent picks the event sharing most word stems with the query.
+func matchEvent(events []CalendarEvent, query string) (CalendarEvent, bool) {
+	q := wordStems(query)
+	best, bestScore := CalendarEvent{}, 0
+	for _, ev := range events {
+		score := 0
+		for st := range wordStems(ev.Summary) {
+			if q[st] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = ev, score
+		}
+	}
+	return best, bestScore > 0
+}
+
+// formatAgenda renders events with their attached tasks indented below.
+func formatAgenda(lang string, events []CalendarEvent, tasks map[string][]Item, tz *time.Location) string {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+
+	var b strings.Builder
+	for i, ev := range events {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if ev.AllDay {
+			b.WriteString(tr(lang, "agenda.allday"))
+		} else {
+			b.WriteString(ev.Start.In(tz).Format("15:04"))
+		}
+		b.WriteString(" " + ev.Summary)
+		if ev.Location != "" {
+			b.WriteString(" (" + ev.Location + ")")
+		}
+		for _, it := range tasks[ev.ID] {
+			fmt.Fprintf(&b, "\n    ☐ #%d %s", it.ID, shownText(it))
+		}
+	}
+	return b.String()
+}
+
+// todayAgenda is the calendar part of the morning digest. When the calendar
+// client cannot list events it falls back to the preformatted schedule.
+func todayAgenda(ctx context.Context, cal CalendarClient, store Store, chatID int64, now time.Time, tz *time.Location) (string, error) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, tz)
+	events, err := cal.ListEvents(ctx, day, day.AddDate(0, 0, 1))
+	if err != nil || len(events) == 0 {
+		return cal.GetTodaySchedule(ctx, store.Lang(chatID), now)
+	}
+	tasks, err := store.TasksByEvent(chatID)
+	if err != nil {
+		return "", err
+	}
+	return formatAgenda(store.Lang(chatID), events, tasks, tz), nil
+}
+
+// handleAttach handles "/attach <task> <event title>", e.g.
+// "/attach 12 к встрече с Иваном". Events of the next 7 days are searched.
+func (a *App) handleAttach(ctx context.Context, chatID int64, arg string) {
+	idStr, query, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	itemID, err := strconv.ParseInt(strings.TrimPrefix(idStr, "#"), 10, 64)
+	query = strings.TrimSpace(query)
+	if err != nil || query == "" {
+		a.send(chatID, a.tr(chatID, "attach.usage"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+
+	now := time.Now().In(a.tz(chatID))
+	events, err := a.Calendar.ListEvents(ctx, now.Add(-12*time.Hour), now.AddDate(0, 0, 7))
+	if err != nil {
+		a.send(chatID, tr(lang, "attach.nocalendar"))
+		return
+	}
+	ev, ok := matchEvent(events, strings.TrimPrefix(query, "к "))
+	if !ok {
+		a.send(chatID, tr(lang, "attach.noevent"))
+		return
+	}
+	if err := a.Store.AttachEvent(chatID, itemID, ev.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.send(chatID, tr(lang, "item.notfound", itemID))
+			return
+		}
+		a.send(chatID, tr(lang, "err.write"))
+		return
+	}
+	a.send(chatID, tr(lang, "attach.done", itemID, ev.Summary, ev.Start.In(a.tz(chatID)).Format("02.01 15:04")))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The "anniversary" digest section (off until enabled in /digest) brings
+// back notable items completed on this day in past years: «Год назад вы
+// закрыли: переезд». Old items are read from item_history once compacted,
+// so nothing is lost to COMPACT_AFTER_DAYS.
+
+const anniversaryYears = 10
+
+type anniversarySection struct {
+	store Store
+}
+
+func (s *anniversarySection) Name() string             { return "anniversary" }
+func (s *anniversarySection) Title(lang string) string { return "" }
+
+type anniversaryHit struct {
+	Years int
+	Text  string
+	Score int
+}
+
+// notableScore ranks a completed item; shopping lists and reminders are
+// never notable.
+func notableScore(topic, text string, flagged bool, created, completed time.Time, notes int) int {
+	if topic == TopicShopping || topic == TopicReminders {
+		return -1
+	}
+	score := parsePriority(text)*3 + notes
+	if !created.IsZero() && completed.Sub(created) >= 14*24*time.Hour {
+		score += 2
+	}
+	if flagged {
+		score += 3
+	}
+	return score
+}
+
+func yearsAgo(lang string, n int) string {
+	switch {
+	case n == 1:
+		return tr(lang, "anniversary.year")
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return tr(lang, "anniversary.years.few", n)
+	default:
+		return tr(lang, "anniversary.years", n)
+	}
+}
+
+func (s *anniversarySection) Render(ctx context.Context, chatID int64, now time.Time) (string, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var hits []anniversaryHit
+	for y := 1; y <= anniversaryYears; y++ {
+		from := today.AddDate(-y, 0, 0)
+		to := from.AddDate(0, 0, 1)
+
+		items, err := s.store.ListCompleted(chatID, from, to)
+		if err != nil {
+			return "", err
+		}
+		for _, it := range items {
+			notes, _ := s.store.ListNotes(chatID, it.ID)
+			if sc := notableScore(it.Topic, it.Text, it.Flagged, it.CreatedAt, it.CompletedAt, len(notes)); sc >= 0 {
+				hits = append(hits, anniversaryHit{Years: y, Text: shownText(it), Score: sc})
+			}
+		}
+
+		archived, err := s.store.HistoryItems(chatID, from.Format("2006-01"))
+		if err != nil {
+			return "", err
+		}
+		for topic, list := range archived {
+			for _, it := range list {
+				completed, _ := time.Parse(time.RFC3339, it.CompletedAt)
+				if completed.Before(from) || !completed.Before(to) {
+					continue
+				}
+				created, _ := time.Parse(time.RFC3339, it.CreatedAt)
+				if sc := notableScore(topic, it.Text, false, created, completed, len(it.Notes)); sc >= 0 {
+					hits = append(hits, anniversaryHit{Years: y, Text: it.shownText(), Score: sc})
+				}
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return "", nil
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > 3 {
+		hits = hits[:3]
+	}
+	lang := s.store.Lang(chatID)
+	lines := make([]string, 0, len(hits))
+	for _, h := range hits {
+		lines = append(lines, "📆 "+tr(lang, "anniversary.line", yearsAgo(lang, h.Years), h.Text))
+	}
+	return strings.Join(lines, "\n"), nil
+}
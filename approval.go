@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Bulk destructive commands (/clear) don't run straight away. In a group a
+// second member has to confirm them; in a private chat the owner confirms
+// with a button. One request waits per chat, for approvalTTL; a new one
+// replaces it. Callback data is "appr:ok:<id>" or "appr:no:<id>".
+
+const approvalTTL = time.Hour
+
+type pendingOp struct {
+	ID      int64     `json:"id"`
+	Kind    string    `json:"kind"` // "clear"
+	Arg     string    `json:"arg"`  // topic for "clear"
+	By      int64     `json:"by"`
+	ByName  string    `json:"by_name"`
+	Created time.Time `json:"created"`
+}
+
+func (a *App) loadPendingOp(chatID int64) (*pendingOp, error) {
+	raw, ok, err := a.Store.GetKV(chatKey(chatID, "approval"))
+	if err != nil || !ok {
+		return nil, err
+	}
+	var op pendingOp
+	if err := json.Unmarshal([]byte(raw), &op); err != nil {
+		return nil, err
+	}
+	if time.Since(op.Created) > approvalTTL {
+		return nil, nil
+	}
+	return &op, nil
+}
+
+// requestApproval parks op and asks for its confirmation.
+func (a *App) requestApproval(chatID int64, from *tgbotapi.User, op pendingOp, what string) {
+	op.ID = time.Now().UnixNano()
+	op.Created = time.Now()
+	if from != nil {
+		op.By, op.ByName = from.ID, userDisplayName(from)
+	}
+	b, _ := json.Marshal(op)
+	if err := a.Store.SetKV(chatKey(chatID, "approval"), string(b)); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+
+	lang := a.Store.Lang(chatID)
+	text := tr(lang, "approval.ask", what)
+	if isGroupChat(chatID) {
+		text = tr(lang, "approval.ask.group", op.ByName, what)
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ "+tr(lang, "approval.confirm"), fmt.Sprintf("appr:ok:%d", op.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("✖ "+tr(lang, "approval.cancel"), fmt.Sprintf("appr:no:%d", op.ID)),
+	))
+	_, _ = a.Bot.Send(msg)
+}
+
+// handleApprovalCallback handles "appr:ok|no:<id>".
+func (a *App) handleApprovalCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	msgID := cq.Message.MessageID
+	lang := a.Store.Lang(chatID)
+	verdict, idStr, _ := strings.Cut(data, ":")
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+
+	op, err := a.loadPendingOp(chatID)
+	if err != nil || op == nil || op.ID != id {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "approval.stale")))
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, msgID, tgbotapi.InlineKeyboardMarkup{}))
+		return
+	}
+	who := userDisplayName(cq.From)
+	if verdict == "no" {
+		_ = a.Store.DeleteKV(chatKey(chatID, "approval"))
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "approval.cancelled")))
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageText(chatID, msgID, cq.Message.Text+"\n✖ "+tr(lang, "approval.cancelled.by", who)))
+		return
+	}
+	if isGroupChat(chatID) && cq.From.ID == op.By {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "approval.other")))
+		return
+	}
+	if err := a.Store.DeleteKV(chatKey(chatID, "approval")); err != nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "err.write")))
+		return
+	}
+
+	result, err := a.runPendingOp(chatID, *op)
+	if err != nil {
+		log.Printf("approved %s error: %v", op.Kind, err)
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "err.write")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	mark := "\n✅ " + result
+	if isGroupChat(chatID) {
+		mark = "\n✅ " + tr(lang, "approval.confirmed.by", who, result)
+	}
+	_, _ = a.Bot.Send(tgbotapi.NewEditMessageText(chatID, msgID, cq.Message.Text+mark))
+}
+
+func (a *App) runPendingOp(chatID int64, op pendingOp) (string, error) {
+	switch op.Kind {
+	case "clear":
+		n, err := a.clearTopic(chatID, op.Arg)
+		return a.tr(chatID, "approval.cleared", n), err
+	}
+	return "", fmt.Errorf("unknown operation %q", op.Kind)
+}
+
+// clearTopic deletes the topic's active items one by one, so each shows
+// up in events and in /undo.
+func (a *App) clearTopic(chatID int64, topic string) (int, error) {
+	items, err := a.Store.ListActive(chatID, topic)
+	if err != nil {
+		return 0, err
+	}
+	err = a.Store.InTx(chatID, func(tx Store) error {
+		for _, it := range items {
+			if err := tx.DeleteItem(chatID, it.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+// handleClear handles "/clear <список>".
+func (a *App) handleClear(m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	topic, ok := a.topicFromButton(chatID, strings.TrimSpace(m.CommandArguments()))
+	if !ok {
+		a.send(chatID, a.tr(chatID, "clear.usage"))
+		return
+	}
+	items, err := a.Store.ListActive(chatID, topic)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if len(items) == 0 {
+		a.send(chatID, a.tr(chatID, "empty"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+	what := tr(lang, "clear.what", a.topicButton(chatID, lang, topic), len(items))
+	a.requestApproval(chatID, m.From, pendingOp{Kind: "clear", Arg: topic}, what)
+}
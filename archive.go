@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Nothing leaves items by itself any more: the night wipe archives
+// reminders (status archived, completed_at set) instead of deleting them.
+// /archive pages through done and archived items, newest first, and
+// "/retention 90" (default ARCHIVE_RETENTION_DAYS, 0 keeps everything)
+// purges them, and /history months, once they're older than that. The
+// purge runs at WIPE_TIME.
+
+// archiveEntry is a done or archived item.
+type archiveEntry struct {
+	Item
+	Archived bool // wiped rather than done
+}
+
+func (s *sqlStore) ListArchive(chatID int64, topic string) ([]archiveEntry, error) {
+	q := `SELECT id, chat_id, topic, text, flagged, secret, created_at, completed_at, status FROM items
+		 WHERE chat_id=? AND status IN (?, ?)`
+	args := []any{chatID, StatusDone, StatusArchived}
+	if topic != "" {
+		q += ` AND topic=?`
+		args = append(args, topic)
+	}
+	rows, err := s.readDB(chatID).Query(q+` ORDER BY completed_at DESC, id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []archiveEntry
+	for rows.Next() {
+		var e archiveEntry
+		var created, completed, status string
+		if err := rows.Scan(&e.ID, &e.ChatID, &e.Topic, &e.Text, &e.Flagged, &e.Secret, &created, &completed, &status); err != nil {
+			return nil, err
+		}
+		e.Text = s.openText(chatID, e.Text)
+		e.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		e.CompletedAt, _ = time.Parse(time.RFC3339, completed)
+		e.Archived = status == StatusArchived
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// PurgeArchive deletes done and archived items completed before `before`,
+// with their notes, and /history months before its month.
+func (s *sqlStore) PurgeArchive(chatID int64, before time.Time) (int, error) {
+	var n int
+	err := s.For(chatID).inTx(func(tx *sqlStore) error {
+		cutoff := before.UTC().Format(time.RFC3339)
+		rows, err := tx.DB.Query(
+			`SELECT id FROM items WHERE chat_id=? AND status IN (?, ?) AND completed_at<>'' AND completed_at<?`,
+			chatID, StatusDone, StatusArchived, cutoff,
+		)
+		if err != nil {
+			return err
+		}
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, id := range ids {
+			for _, q := range []string{
+				`DELETE FROM items WHERE id=?`,
+				`DELETE FROM item_notes WHERE item_id=?`,
+				`DELETE FROM item_threads WHERE item_id=?`,
+				`DELETE FROM goal_links WHERE item_id=?`,
+			} {
+				if _, err := tx.DB.Exec(q, id); err != nil {
+					return err
+				}
+			}
+		}
+		n = len(ids)
+		_, err = tx.DB.Exec(`DELETE FROM item_history WHERE chat_id=? AND month<?`, chatID, before.UTC().Format("2006-01"))
+		return err
+	})
+	return n, err
+}
+
+// retentionDays is the chat's /retention, else ARCHIVE_RETENTION_DAYS.
+func retentionDays(store Store, chatID int64) int {
+	raw, ok, err := store.GetKV(chatKey(chatID, "retention"))
+	if err != nil || !ok {
+		raw = envOr("ARCHIVE_RETENTION_DAYS", "0")
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func (s *Scheduler) purgeArchive(chatID int64, now time.Time) {
+	days := retentionDays(s.store, chatID)
+	if days == 0 {
+		return
+	}
+	n, err := s.store.PurgeArchive(chatID, now.AddDate(0, 0, -days))
+	if err != nil {
+		log.Printf("scheduler: purge archive error: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("scheduler: purged %d archived items in chat %d", n, chatID)
+	}
+}
+
+func (a *App) renderArchive(chatID int64, topic string, page int) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	q := topic
+	if topic == listAll {
+		q = ""
+	}
+	entries, err := a.Store.ListArchive(chatID, q)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+	lang := a.Store.Lang(chatID)
+	title := tr(lang, "archive.title")
+	if topic != listAll {
+		title += ": " + strings.ToUpper(a.topicButton(chatID, lang, topic))
+	}
+	if len(entries) == 0 {
+		return title + "\n" + tr(lang, "archive.empty"), tgbotapi.InlineKeyboardMarkup{}, nil
+	}
+
+	pages := max(1, (len(entries)+listPageSize-1)/listPageSize)
+	page = min(max(page, 0), pages-1)
+	shown := entries[page*listPageSize : min((page+1)*listPageSize, len(entries))]
+	tz := a.tz(chatID)
+	var b strings.Builder
+	b.WriteString(title)
+	if pages > 1 {
+		fmt.Fprintf(&b, " (%d/%d)", page+1, pages)
+	}
+	b.WriteString(":")
+	for _, e := range shown {
+		mark := "✅"
+		if e.Archived {
+			mark = "🗄"
+		}
+		fmt.Fprintf(&b, "\n%s %s · %s #%d", mark, e.CompletedAt.In(tz).Format("02.01"), shownText(e.Item), e.ID)
+		if topic == listAll {
+			b.WriteString(" · " + a.topicButton(chatID, lang, e.Topic))
+		}
+	}
+	if days := retentionDays(a.Store, chatID); days > 0 {
+		b.WriteString("\n\n" + tr(lang, "archive.kept", days))
+	}
+
+	var markup tgbotapi.InlineKeyboardMarkup
+	if pages > 1 {
+		var row []tgbotapi.InlineKeyboardButton
+		if page > 0 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️", fmt.Sprintf("arch:%s:%d", topic, page-1)))
+		}
+		if page < pages-1 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("▶️", fmt.Sprintf("arch:%s:%d", topic, page+1)))
+		}
+		markup = tgbotapi.NewInlineKeyboardMarkup(row)
+	}
+	return b.String(), markup, nil
+}
+
+// handleArchive handles "/archive [список]".
+func (a *App) handleArchive(chatID int64, arg string) {
+	if a.Store.Locked(chatID) {
+		a.sendLocked(chatID)
+		return
+	}
+	topic := listAll
+	if arg = strings.TrimSpace(arg); arg != "" {
+		t, ok := a.topicFromButton(chatID, arg)
+		if !ok {
+			a.send(chatID, a.tr(chatID, "topic.unknown")+" "+a.tr(chatID, "archive.usage"))
+			return
+		}
+		topic = t
+	}
+	text, markup, err := a.renderArchive(chatID, topic, 0)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	if len(markup.InlineKeyboard) > 0 {
+		msg.ReplyMarkup = markup
+	}
+	_, _ = a.Bot.Send(msg)
+}
+
+// handleArchiveCallback handles "arch:<topic>:<page>".
+func (a *App) handleArchiveCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	topic, pageStr, _ := strings.Cut(data, ":")
+	page, _ := strconv.Atoi(pageStr)
+	text, markup, err := a.renderArchive(chatID, topic, page)
+	if err != nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.read")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	if len(markup.InlineKeyboard) > 0 {
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageTextAndMarkup(chatID, cq.Message.MessageID, text, markup))
+	} else {
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, text))
+	}
+}
+
+// handleRetention handles "/retention [<дни> | off]".
+func (a *App) handleRetention(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	key := chatKey(chatID, "retention")
+	switch arg {
+	case "":
+		if days := retentionDays(a.Store, chatID); days > 0 {
+			a.send(chatID, a.tr(chatID, "retention.days", days))
+		} else {
+			a.send(chatID, a.tr(chatID, "retention.off")+" "+a.tr(chatID, "retention.hint"))
+		}
+		return
+	case "off":
+		arg = "0"
+	}
+	days, err := strconv.Atoi(arg)
+	if err != nil || days < 0 {
+		a.send(chatID, a.tr(chatID, "retention.usage"))
+		return
+	}
+	if err := a.Store.SetKV(key, strconv.Itoa(days)); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	if days == 0 {
+		a.send(chatID, a.tr(chatID, "retention.off"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "retention.set", days))
+}
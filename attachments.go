@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// A photo or file sent to the chat becomes an item in the active topic:
+// the caption is its text ("📷 фото" without one) and the file stays linked
+// by file_id (and stored, see MediaManager). The rest of an album joins the
+// first photo's item. /list marks such items with 📎 and a button that
+// sends the files again — "buy exactly this one" in the shop.
+
+// ItemsWithMedia lists the chat's items that have files.
+func (s *sqlStore) ItemsWithMedia(chatID int64) (map[int64]bool, error) {
+	rows, err := s.DB.Query(`SELECT DISTINCT item_id FROM item_media WHERE chat_id=?`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
+}
+
+func attachmentText(lang string, m *tgbotapi.Message, now time.Time) string {
+	if c := strings.TrimSpace(m.Caption); c != "" {
+		return c
+	}
+	if m.Document != nil && m.Document.FileName != "" {
+		return "📄 " + m.Document.FileName
+	}
+	return "📷 " + tr(lang, "attachment.photo", now.Format("02.01 15:04"))
+}
+
+// captureAttachment stores a message with photos or files as an item.
+func (a *App) captureAttachment(ctx context.Context, m *tgbotapi.Message) bool {
+	refs := mediaRefs(m)
+	if len(refs) == 0 || strings.HasPrefix(strings.TrimSpace(m.Caption), "/") {
+		return false
+	}
+	chatID := m.Chat.ID
+	albumKey := chatKey(chatID, "album")
+	if m.MediaGroupID != "" {
+		if raw, ok, _ := a.Store.GetKV(albumKey); ok {
+			group, idStr, _ := strings.Cut(raw, "|")
+			if id, err := strconv.ParseInt(idStr, 10, 64); err == nil && group == m.MediaGroupID {
+				a.attachRefs(ctx, chatID, id, refs)
+				return true
+			}
+		}
+	}
+
+	topic := a.touchState(chatID).Topic
+	text := attachmentText(a.Store.Lang(chatID), m, time.Now().In(a.tz(chatID)))
+	if t, rest, ok := a.hashtagTopic(chatID, text); ok {
+		topic, text = t, rest
+	}
+	id, res := a.storeCapture(chatID, provenanceOf(m), topic, text)
+	switch res {
+	case captureStored:
+		a.attachRefs(ctx, chatID, id, refs)
+		if m.MediaGroupID != "" {
+			_ = a.Store.SetKV(albumKey, m.MediaGroupID+"|"+strconv.FormatInt(id, 10))
+		}
+		a.ackCapture(m, topic, id)
+	case captureDuplicate:
+		a.attachRefs(ctx, chatID, id, refs)
+		a.send(chatID, a.tr(chatID, "dup", topicLabel(a.Store.Lang(chatID), topic), id))
+	case captureRejected:
+		a.send(chatID, a.tr(chatID, "filter.rejected"))
+	case captureLocked:
+		a.sendLocked(chatID)
+	default:
+		a.send(chatID, a.tr(chatID, "err.write"))
+	}
+	return true
+}
+
+func (a *App) attachRefs(ctx context.Context, chatID, itemID int64, refs []FileRef) {
+	for _, ref := range refs {
+		if err := a.Media.Attach(ctx, chatID, itemID, ref); err != nil {
+			log.Printf("attach %s to %d: %v", ref.Kind, itemID, err)
+		}
+	}
+}
+
+// sendAttachments re-sends an item's files by file_id.
+func (a *App) sendAttachments(chatID, itemID int64) error {
+	files, err := a.Store.ItemMedia(chatID, itemID)
+	if err != nil {
+		return err
+	}
+	caption := fmt.Sprintf("#%d", itemID)
+	if it, err := a.Store.GetItem(chatID, itemID); err == nil && it != nil {
+		caption += " " + shownText(*it)
+	}
+	for _, f := range files {
+		file := tgbotapi.FileID(f.FileID)
+		var msg tgbotapi.Chattable
+		switch f.Kind {
+		case "photo":
+			p := tgbotapi.NewPhoto(chatID, file)
+			p.Caption = caption
+			msg = p
+		case "voice":
+			v := tgbotapi.NewVoice(chatID, file)
+			v.Caption = caption
+			msg = v
+		case "audio":
+			au := tgbotapi.NewAudio(chatID, file)
+			au.Caption = caption
+			msg = au
+		case "video":
+			v := tgbotapi.NewVideo(chatID, file)
+			v.Caption = caption
+			msg = v
+		default:
+			d := tgbotapi.NewDocument(chatID, file)
+			d.Caption = caption
+			msg = d
+		}
+		if _, err := a.Bot.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleAttachmentCallback handles "att:<id>".
+func (a *App) handleAttachmentCallback(cq *tgbotapi.CallbackQuery, idStr string) {
+	chatID := cq.Message.Chat.ID
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return
+	}
+	if err := a.sendAttachments(chatID, id); err != nil {
+		log.Printf("send attachments of %d: %v", id, err)
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "file.send.failed")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Weekly availability: "/busy пн-пт 09-18 работа" marks a block that
+// repeats every week. The date picker leaves out time presets that fall
+// into a block and offers the block's end instead, /plan lays today's
+// estimated tasks into the free windows between PLAN_DAY_START and
+// PLAN_DAY_END, and the "plan" digest section shows the same.
+
+type BusyBlock struct {
+	Days     uint8 // bit i is weekday i, 0 = Monday (see parseWeekday)
+	From, To int   // minutes since midnight, From < To
+	Label    string
+}
+
+// dayBit is t's weekday in BusyBlock.Days.
+func dayBit(t time.Time) uint8 {
+	return 1 << ((int(t.Weekday()) + 6) % 7)
+}
+
+// parseWeekdays reads "пн-пт", "сб,вс", "будни", "выходные" or "ежедневно".
+func parseWeekdays(s string) (uint8, bool) {
+	switch s = strings.ToLower(s); s {
+	case "будни", "weekdays":
+		s = "пн-пт"
+	case "выходные", "weekend":
+		s = "сб-вс"
+	case "ежедневно", "daily":
+		s = "пн-вс"
+	}
+	var mask uint8
+	for _, part := range strings.Split(s, ",") {
+		a, b, isRange := strings.Cut(part, "-")
+		from, ok := parseWeekday(a)
+		if !ok {
+			return 0, false
+		}
+		to := from
+		if isRange {
+			if to, ok = parseWeekday(b); !ok {
+				return 0, false
+			}
+		}
+		for i := from; ; i = (i + 1) % 7 {
+			mask |= 1 << i
+			if i == to {
+				break
+			}
+		}
+	}
+	return mask, true
+}
+
+func formatWeekdays(lang string, mask uint8) string {
+	var on []int
+	for i := range 7 {
+		if mask&(1<<i) != 0 {
+			on = append(on, i)
+		}
+	}
+	if len(on) == 7 {
+		return tr(lang, "busy.daily")
+	}
+	if len(on) > 2 && on[len(on)-1]-on[0] == len(on)-1 {
+		return weekdayShort(lang, on[0]) + "-" + weekdayShort(lang, on[len(on)-1])
+	}
+	var names []string
+	for _, i := range on {
+		names = append(names, weekdayShort(lang, i))
+	}
+	return strings.Join(names, ",")
+}
+
+// parseClockRange reads "09-18", "9:30-13" or "09:00–18:00".
+func parseClockRange(s string) (from, to int, ok bool) {
+	a, b, found := strings.Cut(strings.ReplaceAll(s, "–", "-"), "-")
+	if !found {
+		return 0, 0, false
+	}
+	clock := func(v string) (int, bool) {
+		if !strings.Contains(v, ":") {
+			v += ":00"
+		}
+		if len(v) == 4 {
+			v = "0" + v
+		}
+		t, err := time.Parse("15:04", v)
+		if err != nil {
+			return 0, false
+		}
+		return t.Hour()*60 + t.Minute(), true
+	}
+	from, okA := clock(a)
+	to, okB := clock(b)
+	if b == "24" || b == "24:00" {
+		to, okB = 24*60, true
+	}
+	return from, to, okA && okB && from < to
+}
+
+// parseBusy reads "<дни> <время> [метка]".
+func parseBusy(s string) (BusyBlock, bool) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return BusyBlock{}, false
+	}
+	days, ok := parseWeekdays(fields[0])
+	if !ok {
+		return BusyBlock{}, false
+	}
+	from, to, ok := parseClockRange(fields[1])
+	if !ok {
+		return BusyBlock{}, false
+	}
+	return BusyBlock{Days: days, From: from, To: to, Label: strings.Join(fields[2:], " ")}, true
+}
+
+func clockString(m int) string {
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
+
+// String is the stored form of the block; Format is the shown one.
+func (b BusyBlock) String() string {
+	return b.Format(LangRU)
+}
+
+func (b BusyBlock) Format(lang string) string {
+	s := formatWeekdays(lang, b.Days) + " " + clockString(b.From) + "-" + clockString(b.To)
+	if b.Label != "" {
+		s += " " + b.Label
+	}
+	return s
+}
+
+// busyBlocks is the chat's weekly profile.
+func busyBlocks(store Store, chatID int64) []BusyBlock {
+	raw, _, _ := store.GetKV(chatKey(chatID, "busy"))
+	var out []BusyBlock
+	for _, line := range strings.Split(raw, "\n") {
+		if b, ok := parseBusy(line); ok {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+func setBusyBlocks(store Store, chatID int64, blocks []BusyBlock) error {
+	if len(blocks) == 0 {
+		return store.DeleteKV(chatKey(chatID, "busy"))
+	}
+	lines := make([]string, len(blocks))
+	for i, b := range blocks {
+		lines[i] = b.String()
+	}
+	return store.SetKV(chatKey(chatID, "busy"), strings.Join(lines, "\n"))
+}
+
+// busyAt is the block covering t, if any.
+func busyAt(blocks []BusyBlock, t time.Time) (BusyBlock, bool) {
+	m := t.Hour()*60 + t.Minute()
+	for _, b := range blocks {
+		if b.Days&dayBit(t) != 0 && b.From <= m && m < b.To {
+			return b, true
+		}
+	}
+	return BusyBlock{}, false
+}
+
+type timeWindow struct{ From, To time.Time }
+
+// freeWindows cuts the day's blocks out of [from, to).
+func freeWindows(blocks []BusyBlock, from, to time.Time) []timeWindow {
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	var busy []timeWindow
+	for _, b := range blocks {
+		if b.Days&dayBit(day) != 0 {
+			busy = append(busy, timeWindow{day.Add(time.Duration(b.From) * time.Minute), day.Add(time.Duration(b.To) * time.Minute)})
+		}
+	}
+	sort.Slice(busy, func(i, j int) bool { return busy[i].From.Before(busy[j].From) })
+	var out []timeWindow
+	cur := from
+	for _, b := range busy {
+		if b.From.After(cur) && cur.Before(to) {
+			out = append(out, timeWindow{cur, minTime(b.From, to)})
+		}
+		if b.To.After(cur) {
+			cur = b.To
+		}
+	}
+	if cur.Before(to) {
+		out = append(out, timeWindow{cur, to})
+	}
+	return out
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// freePresets drops presets that fall into a block on day and adds the end
+// of each of that day's blocks, so the picker never proposes a busy time.
+func freePresets(lang string, presets []TimePreset, blocks []BusyBlock, day time.Time) []TimePreset {
+	var out []TimePreset
+	seen := map[string]bool{}
+	for _, p := range presets {
+		t, err := time.Parse("15:04", p.Clock)
+		if err != nil {
+			continue
+		}
+		at := time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location())
+		if _, busy := busyAt(blocks, at); !busy {
+			out = append(out, p)
+			seen[p.Clock] = true
+		}
+	}
+	for _, b := range blocks {
+		end := clockString(b.To)
+		if b.Days&dayBit(day) == 0 || b.To >= 24*60 || seen[end] {
+			continue
+		}
+		if _, busy := busyAt(blocks, day.Add(time.Duration(b.To)*time.Minute)); busy {
+			continue
+		}
+		name := tr(lang, "busy.after")
+		if b.Label != "" {
+			name = tr(lang, "busy.after.label", b.Label)
+		}
+		out = append(out, TimePreset{Name: name, Clock: end})
+		seen[end] = true
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Clock < out[j].Clock })
+	return out
+}
+
+// planBounds is today's planning range: from now (or PLAN_DAY_START) to
+// PLAN_DAY_END.
+func planBounds(now time.Time) (time.Time, time.Time) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start, end := 8*60, 22*60
+	if f, t, ok := parseClockRange(envOr("PLAN_DAY_START", "08:00") + "-" + envOr("PLAN_DAY_END", "22:00")); ok {
+		start, end = f, t
+	}
+	from := day.Add(time.Duration(start) * time.Minute)
+	if now.After(from) {
+		from = now.Truncate(5 * time.Minute).Add(5 * time.Minute)
+	}
+	return from, day.Add(time.Duration(end) * time.Minute)
+}
+
+type plannedTask struct {
+	Item     Item
+	From, To time.Time
+}
+
+// planDay puts estimated tasks, best ranked first, into the first free
+// window long enough for them; the ones that fit nowhere are left over.
+func planDay(items []Item, windows []timeWindow, bias EstimateBias, now time.Time) ([]plannedTask, []Item) {
+	ranked := suggestNext(items, now, len(items))
+	var planned []plannedTask
+	var left []Item
+	for _, c := range ranked {
+		est, ok := parseEffort(c.Item.Text)
+		if !ok {
+			continue
+		}
+		d := time.Duration(bias.Adjust(c.Item, est)) * time.Minute
+		placed := false
+		for i, w := range windows {
+			if w.To.Sub(w.From) >= d {
+				planned = append(planned, plannedTask{Item: c.Item, From: w.From, To: w.From.Add(d)})
+				windows[i].From = w.From.Add(d)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			left = append(left, c.Item)
+		}
+	}
+	sort.SliceStable(planned, func(i, j int) bool { return planned[i].From.Before(planned[j].From) })
+	return planned, left
+}
+
+func formatWindows(ws []timeWindow) string {
+	var parts []string
+	for _, w := range ws {
+		if w.To.Sub(w.From) >= 15*time.Minute {
+			parts = append(parts, w.From.Format("15:04")+"–"+w.To.Format("15:04"))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// buildPlan renders today's plan for chatID; "" when there's nothing to plan.
+func buildPlan(store Store, chatID int64, now time.Time, bias EstimateBias) (string, error) {
+	items, err := store.ListActive(chatID, TopicTasks)
+	if err != nil {
+		return "", err
+	}
+	if f, ok := activeFocus(store, chatID, now); ok {
+		items = f.Filter(items)
+	}
+	from, to := planBounds(now)
+	windows := freeWindows(busyBlocks(store, chatID), from, to)
+	lang := store.Lang(chatID)
+	free := formatWindows(windows)
+	if free == "" {
+		return tr(lang, "plan.full"), nil
+	}
+	planned, left := planDay(items, append([]timeWindow(nil), windows...), bias, now)
+
+	var b strings.Builder
+	b.WriteString(tr(lang, "plan.free", free))
+	for _, p := range planned {
+		fmt.Fprintf(&b, "\n%s–%s #%d %s", p.From.Format("15:04"), p.To.Format("15:04"), p.Item.ID, shownText(p.Item))
+	}
+	if len(left) > 0 {
+		b.WriteString("\n" + tr(lang, "plan.left", len(left)))
+		for _, it := range left {
+			fmt.Fprintf(&b, " #%d", it.ID)
+		}
+	}
+	return b.String(), nil
+}
+
+// handlePlan handles "/plan".
+func (a *App) handlePlan(chatID int64) {
+	now := time.Now().In(a.tz(chatID))
+	bias, err := loadEstimateBias(a.Store, chatID, now)
+	if err != nil {
+		bias = EstimateBias{Ratio: 1}
+	}
+	text, err := buildPlan(a.Store, chatID, now, bias)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "plan.title")+":\n"+text+"\n\n"+a.tr(chatID, "plan.busy.hint"))
+}
+
+// handleBusy handles "/busy [<дни> <время> [метка] | del <n> | off]".
+func (a *App) handleBusy(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	lang := a.Store.Lang(chatID)
+	blocks := busyBlocks(a.Store, chatID)
+	first, rest, _ := strings.Cut(arg, " ")
+	switch first {
+	case "":
+		if len(blocks) == 0 {
+			a.send(chatID, tr(lang, "busy.none")+" "+tr(lang, "busy.usage"))
+			return
+		}
+		var b strings.Builder
+		b.WriteString(tr(lang, "busy.header"))
+		for i, bl := range blocks {
+			fmt.Fprintf(&b, "\n%d. %s", i+1, bl.Format(lang))
+		}
+		b.WriteString("\n\n" + tr(lang, "busy.help"))
+		a.send(chatID, b.String())
+		return
+	case "off":
+		blocks = nil
+	case "del":
+		n, err := strconv.Atoi(strings.TrimSpace(rest))
+		if err != nil || n < 1 || n > len(blocks) {
+			a.send(chatID, tr(lang, "busy.del.usage"))
+			return
+		}
+		blocks = append(blocks[:n-1], blocks[n:]...)
+	default:
+		bl, ok := parseBusy(arg)
+		if !ok {
+			a.send(chatID, tr(lang, "busy.usage"))
+			return
+		}
+		blocks = append(blocks, bl)
+	}
+	if err := setBusyBlocks(a.Store, chatID, blocks); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, tr(lang, "busy.saved", len(blocks)))
+}
+
+// planSection is today's plan in the morning digest.
+type planSection struct {
+	store Store
+}
+
+func (s *planSection) Name() string             { return "plan" }
+func (s *planSection) Title(lang string) string { return tr(lang, "plan.title") }
+
+func (s *planSection) Render(ctx context.Context, chatID int64, now time.Time) (string, error) {
+	bias, err := loadEstimateBias(s.store, chatID, now)
+	if err != nil {
+		return "", err
+	}
+	return buildPlan(s.store, chatID, now, bias)
+}
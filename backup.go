@@ -0,0 +1,276 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"modernc.org/sqlite"
+)
+
+// Nightly backup: at BACKUP_TIME the scheduler snapshots the SQLite file
+// (and each of DB_SHARDS) with SQLite's online backup API, gzips it and
+// sends it to BACKUP_CHAT_ID, else the owner chat. Only the last
+// BACKUP_KEEP backups stay in that chat; older messages are deleted.
+// /backup runs it right away. Postgres has pg_dump for this.
+
+const maxBackupBytes = 50 << 20 // Bot API upload limit
+
+// Snapshot copies the database, and each shard, into dir and returns the
+// files written.
+func (s *sqlStore) Snapshot(ctx context.Context, dir string) ([]string, error) {
+	if s.driver != driverSQLite {
+		return nil, errors.New("snapshots need DB_DRIVER=sqlite")
+	}
+	stores := []*sqlStore{s}
+	if s.router != nil {
+		stores = s.router.shards
+	}
+	names, _ := snapshotFiles()
+	var out []string
+	for i, st := range stores {
+		db, ok := unwrapConn(st.DB).(*sql.DB)
+		if !ok || i >= len(names) {
+			continue
+		}
+		dst := filepath.Join(dir, names[i])
+		if err := sqliteBackup(ctx, db, dst); err != nil {
+			return out, fmt.Errorf("%s: %w", names[i], err)
+		}
+		out = append(out, dst)
+	}
+	return out, nil
+}
+
+// snapshotFiles names the snapshot of each database file: DB_PATH's base
+// name, then "shard1-<name>"… for DB_SHARDS and the extra bots' files (see
+// botSnapshotFiles), with the paths they restore to.
+func snapshotFiles() (names, paths []string) {
+	main := envOr("DB_PATH", "gtd.db")
+	names, paths = []string{filepath.Base(main)}, []string{main}
+	for i, p := range shardPathsFromEnv() {
+		names = append(names, fmt.Sprintf("shard%d-%s", i+1, filepath.Base(p)))
+		paths = append(paths, p)
+	}
+	botNames, botPaths := botSnapshotFiles()
+	return append(names, botNames...), append(paths, botPaths...)
+}
+
+// sqliteBackup copies db into a new file at dst while it stays in use.
+func sqliteBackup(ctx context.Context, db *sql.DB, dst string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(dc any) error {
+		src, ok := dc.(interface {
+			NewBackup(string) (*sqlite.Backup, error)
+		})
+		if !ok {
+			return errors.New("driver has no backup API")
+		}
+		b, err := src.NewBackup(dst)
+		if err != nil {
+			return err
+		}
+		for {
+			more, err := b.Step(-1)
+			if err != nil {
+				_ = b.Finish()
+				return err
+			}
+			if !more {
+				return b.Finish()
+			}
+		}
+	})
+}
+
+// gzipFile writes path.gz and returns its path.
+func gzipFile(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return "", err
+	}
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(path)
+	if _, err := io.Copy(zw, in); err != nil {
+		_ = out.Close()
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		_ = out.Close()
+		return "", err
+	}
+	return out.Name(), out.Close()
+}
+
+func backupChatID() (int64, bool) {
+	if id, err := strconv.ParseInt(strings.TrimSpace(os.Getenv("BACKUP_CHAT_ID")), 10, 64); err == nil {
+		return id, true
+	}
+	return ownerChatID()
+}
+
+// compressedSnapshot snapshots the databases into dir and gzips them,
+// returning the .gz files.
+func compressedSnapshot(ctx context.Context, store Store, dir string) ([]string, error) {
+	files, err := store.Snapshot(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	bots, err := snapshotBots(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, bots...)
+	var out []string
+	for _, f := range files {
+		gz, err := gzipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, gz)
+	}
+	return out, nil
+}
+
+// snapshotBase is "gtd" for ".../gtd.db.gz".
+func snapshotBase(path string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".db")
+}
+
+// runBackup snapshots, compresses and sends the databases to chatID and
+// returns the sent message ids.
+func runBackup(ctx context.Context, bot *tgbotapi.BotAPI, store Store, chatID int64, now time.Time) ([]int, error) {
+	dir, err := os.MkdirTemp("", "gtd-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := compressedSnapshot(ctx, store, dir)
+	if err != nil {
+		return nil, err
+	}
+	var sent []int
+	for _, gz := range files {
+		b, err := os.ReadFile(gz)
+		if err != nil {
+			return sent, err
+		}
+		if len(b) > maxBackupBytes {
+			return sent, fmt.Errorf("%s is %d MB, over the %d MB upload limit", filepath.Base(gz), len(b)>>20, maxBackupBytes>>20)
+		}
+		name := snapshotBase(gz) + now.Format("-20060102-1504") + ".db.gz"
+		doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: b})
+		doc.Caption = "💾 " + tr(store.Lang(chatID), "backup.caption", now.Format("02.01.2006 15:04"))
+		doc.DisableNotification = true
+		m, err := bot.Send(doc)
+		if err != nil {
+			return sent, err
+		}
+		sent = append(sent, m.MessageID)
+	}
+	return sent, nil
+}
+
+// pruneBackups records msgIDs as the newest backups in chatID and deletes
+// the messages of those beyond BACKUP_KEEP.
+func pruneBackups(bot *tgbotapi.BotAPI, store Store, chatID int64, msgIDs []int) {
+	keep, err := strconv.Atoi(envOr("BACKUP_KEEP", "7"))
+	if err != nil || keep < 0 {
+		keep = 7
+	}
+	raw, _, _ := store.GetKV("backup_messages")
+	var runs []string // "chat:msg msg", newest first
+	run := strconv.FormatInt(chatID, 10) + ":"
+	for i, id := range msgIDs {
+		if i > 0 {
+			run += " "
+		}
+		run += strconv.Itoa(id)
+	}
+	runs = append(runs, run)
+	if raw != "" {
+		runs = append(runs, strings.Split(raw, ",")...)
+	}
+	if keep > 0 && len(runs) > keep {
+		for _, old := range runs[keep:] {
+			chat, ids, _ := strings.Cut(old, ":")
+			c, _ := strconv.ParseInt(chat, 10, 64)
+			for _, id := range strings.Fields(ids) {
+				n, _ := strconv.Atoi(id)
+				if _, err := bot.Request(tgbotapi.NewDeleteMessage(c, n)); err != nil {
+					log.Printf("backup: delete old message %d: %v", n, err)
+				}
+			}
+		}
+		runs = runs[:keep]
+	}
+	if err := store.SetKV("backup_messages", strings.Join(runs, ",")); err != nil {
+		log.Printf("backup: save message ids: %v", err)
+	}
+}
+
+// backupDatabase is the nightly job.
+func (s *Scheduler) backupDatabase(ctx context.Context, now time.Time) {
+	chatID, ok := backupChatID()
+	if !ok {
+		log.Printf("scheduler: backup skipped, set BACKUP_CHAT_ID or OWNER_ID")
+		return
+	}
+	sent, err := runBackup(ctx, s.bot, s.store, chatID, now)
+	if len(sent) > 0 {
+		pruneBackups(s.bot, s.store, chatID, sent)
+	}
+	if err != nil {
+		log.Printf("scheduler: backup error: %v", err)
+		_, _ = s.bot.Send(tgbotapi.NewMessage(chatID, "⚠️ "+tr(s.store.Lang(chatID), "backup.failed", err)))
+	}
+}
+
+// handleBackup handles "/backup": a backup now, to the backup chat. Owner
+// only.
+func (a *App) handleBackup(ctx context.Context, m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	if !isOwner(m) {
+		a.send(chatID, a.tr(chatID, "owner.only"))
+		return
+	}
+	if a.Name != "" {
+		a.send(chatID, a.tr(chatID, "backup.primary"))
+		return
+	}
+	target, ok := backupChatID()
+	if !ok {
+		target = chatID
+	}
+	sent, err := runBackup(ctx, a.Bot, a.Store, target, time.Now().In(a.TZ))
+	if len(sent) > 0 {
+		pruneBackups(a.Bot, a.Store, target, sent)
+	}
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "backup.failed", err))
+		return
+	}
+	if target != chatID {
+		a.send(chatID, a.tr(chatID, "backup.sent", len(sent)))
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Snapshots in S3/MinIO: with BACKUP_S3_TIME set, the database snapshots
+// also go to the S3_* bucket as <BACKUP_S3_PREFIX><yyyymmdd-hhmm>/<file>.gz,
+// keeping the last BACKUP_S3_KEEP runs. On a fresh host "/restore" pulls the
+// latest run (or "/restore <run>"), checks it and stages each file next to
+// its database as <path>.restore; at the next start the staged files replace
+// the databases, the old ones kept as <path>.bak.
+
+const backupStamp = "20060102-1504"
+
+func backupS3Prefix() string {
+	p := envOr("BACKUP_S3_PREFIX", "backups/")
+	if !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p
+}
+
+// backupRuns lists the runs in the bucket, oldest first, with their keys.
+func backupRuns(ctx context.Context, c *s3Client) ([]string, map[string][]string, error) {
+	keys, err := c.List(ctx, backupS3Prefix())
+	if err != nil {
+		return nil, nil, err
+	}
+	byRun := map[string][]string{}
+	for _, k := range keys {
+		run, _, ok := strings.Cut(strings.TrimPrefix(k, backupS3Prefix()), "/")
+		if _, err := time.Parse(backupStamp, run); ok && err == nil {
+			byRun[run] = append(byRun[run], k)
+		}
+	}
+	runs := make([]string, 0, len(byRun))
+	for r := range byRun {
+		runs = append(runs, r)
+	}
+	slices.Sort(runs)
+	return runs, byRun, nil
+}
+
+// uploadBackup puts a compressed snapshot into the bucket and drops runs
+// beyond BACKUP_S3_KEEP.
+func uploadBackup(ctx context.Context, c *s3Client, store Store, now time.Time) (string, error) {
+	dir, err := os.MkdirTemp("", "gtd-backup-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := compressedSnapshot(ctx, store, dir)
+	if err != nil {
+		return "", err
+	}
+	run := now.Format(backupStamp)
+	for _, gz := range files {
+		f, err := os.Open(gz)
+		if err != nil {
+			return "", err
+		}
+		st, err := f.Stat()
+		if err == nil {
+			err = c.Put(ctx, backupS3Prefix()+run+"/"+filepath.Base(gz), f, st.Size())
+		}
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	keep, err := strconv.Atoi(envOr("BACKUP_S3_KEEP", "14"))
+	if err != nil || keep <= 0 {
+		return run, nil
+	}
+	runs, byRun, err := backupRuns(ctx, c)
+	if err != nil {
+		return run, err
+	}
+	for _, old := range runs[:max(0, len(runs)-keep)] {
+		for _, k := range byRun[old] {
+			if err := c.Delete(ctx, k); err != nil {
+				return run, err
+			}
+		}
+	}
+	return run, nil
+}
+
+func (s *Scheduler) backupToS3(ctx context.Context, now time.Time) {
+	c, err := s3ClientFromEnv()
+	if err == nil {
+		_, err = uploadBackup(ctx, c, s.store, now)
+	}
+	if err != nil {
+		log.Printf("scheduler: s3 backup error: %v", err)
+		if chatID, ok := backupChatID(); ok {
+			_, _ = s.bot.Send(tgbotapi.NewMessage(chatID, tr(s.store.Lang(chatID), "backup.s3.failed", err)))
+		}
+	}
+}
+
+// stageRestore downloads the run's files and leaves each, checked, at
+// <path>.restore.
+func stageRestore(ctx context.Context, c *s3Client, keys []string) ([]string, error) {
+	names, paths := snapshotFiles()
+	var staged []string
+	for _, k := range keys {
+		name := strings.TrimSuffix(path.Base(k), ".gz")
+		i := slices.Index(names, name)
+		if i < 0 {
+			return staged, fmt.Errorf("%s: no such database in DB_PATH or DB_SHARDS", name)
+		}
+		dst := paths[i] + ".restore"
+		if err := downloadSnapshot(ctx, c, k, dst); err != nil {
+			os.Remove(dst)
+			return staged, fmt.Errorf("%s: %w", name, err)
+		}
+		staged = append(staged, dst)
+	}
+	return staged, nil
+}
+
+func downloadSnapshot(ctx context.Context, c *s3Client, key, dst string) error {
+	body, err := c.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, zr); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	db, err := openDB(driverSQLite, dst)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var check string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&check); err != nil {
+		return err
+	}
+	if check != "ok" {
+		return fmt.Errorf("integrity check: %s", check)
+	}
+	return nil
+}
+
+// applyStagedRestore swaps staged snapshots in before the databases are
+// opened.
+func applyStagedRestore() error {
+	_, paths := snapshotFiles()
+	for _, p := range paths {
+		staged := p + ".restore"
+		if _, err := os.Stat(staged); err != nil {
+			continue
+		}
+		if _, err := os.Stat(p); err == nil {
+			if err := os.Rename(p, p+".bak"); err != nil {
+				return err
+			}
+		}
+		if err := os.Rename(staged, p); err != nil {
+			return err
+		}
+		log.Printf("restore: %s replaced from snapshot, previous file kept as %s.bak", p, p)
+	}
+	return nil
+}
+
+// handleRestore handles "/restore [list | <run>]". Owner only.
+func (a *App) handleRestore(ctx context.Context, m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	lang := a.Store.Lang(chatID)
+	if !isOwner(m) {
+		a.send(chatID, tr(lang, "owner.only"))
+		return
+	}
+	if a.Name != "" {
+		a.send(chatID, tr(lang, "restore.main"))
+		return
+	}
+	if driver, _, _ := dbFromEnv(); driver != driverSQLite {
+		a.send(chatID, tr(lang, "restore.sqlite_only"))
+		return
+	}
+	c, err := s3ClientFromEnv()
+	if err != nil {
+		a.send(chatID, tr(lang, "restore.no_s3", err))
+		return
+	}
+	runs, byRun, err := backupRuns(ctx, c)
+	if err != nil {
+		a.send(chatID, tr(lang, "restore.list.failed", err))
+		return
+	}
+	if len(runs) == 0 {
+		a.send(chatID, tr(lang, "restore.none"))
+		return
+	}
+
+	arg := strings.TrimSpace(m.CommandArguments())
+	if arg == "list" {
+		var b strings.Builder
+		b.WriteString(tr(lang, "restore.list"))
+		for i := len(runs) - 1; i >= 0 && i >= len(runs)-10; i-- {
+			b.WriteString("\n" + tr(lang, "restore.list.run", runs[i], len(byRun[runs[i]])))
+		}
+		b.WriteString("\n\n" + tr(lang, "restore.list.hint"))
+		a.send(chatID, b.String())
+		return
+	}
+	run := runs[len(runs)-1]
+	if arg != "" {
+		if _, ok := byRun[arg]; !ok {
+			a.send(chatID, tr(lang, "restore.unknown"))
+			return
+		}
+		run = arg
+	}
+	staged, err := stageRestore(ctx, c, byRun[run])
+	if err != nil {
+		a.send(chatID, tr(lang, "restore.failed", err))
+		return
+	}
+	a.send(chatID, tr(lang, "restore.staged", run, len(staged)))
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Bills are monthly payments with an amount and a due day: "/bill add
+// интернет 650 15". Each morning the scheduler reminds about unpaid bills
+// due within BILL_REMIND_DAYS (and overdue ones) with a ✅ Оплачено button;
+// on the 1st it sums up the month before.
+
+type Bill struct {
+	ID     int64
+	ChatID int64
+	Title  string
+	Amount float64
+	DueDay int // 1..31, clamped to the month's last day
+}
+
+type BillStatus struct {
+	Bill
+	Due    time.Time
+	Paid   bool
+	PaidAt time.Time
+}
+
+func billRemindDays() int {
+	n, err := strconv.Atoi(envOr("BILL_REMIND_DAYS", "3"))
+	if err != nil || n < 0 {
+		return 3
+	}
+	return n
+}
+
+// billDue is the bill's due date in the month of period (YYYY-MM).
+func billDue(day int, period string, loc *time.Location) time.Time {
+	month, _ := time.ParseInLocation("2006-01", period, loc)
+	last := month.AddDate(0, 1, -1).Day()
+	return month.AddDate(0, 0, min(day, last)-1)
+}
+
+func (s *sqlStore) AddBill(chatID int64, b Bill) (int64, error) {
+	var id int64
+	err := s.db(chatID).QueryRow(
+		`INSERT INTO bills(chat_id, title, amount, due_day, created_at) VALUES(?,?,?,?,?) RETURNING id`,
+		chatID, b.Title, b.Amount, b.DueDay, time.Now().UTC().Format(time.RFC3339),
+	).Scan(&id)
+	return id, err
+}
+
+func (s *sqlStore) DeleteBill(chatID, id int64) error {
+	return s.For(chatID).inTx(func(tx *sqlStore) error {
+		if _, err := tx.DB.Exec(`DELETE FROM bill_payments WHERE bill_id IN (SELECT id FROM bills WHERE chat_id=? AND id=?)`, chatID, id); err != nil {
+			return err
+		}
+		_, err := tx.DB.Exec(`DELETE FROM bills WHERE chat_id=? AND id=?`, chatID, id)
+		return err
+	})
+}
+
+// BillsFor returns the chat's bills with their state for period, by due day.
+func (s *sqlStore) BillsFor(chatID int64, period string, loc *time.Location) ([]BillStatus, error) {
+	rows, err := s.readDB(chatID).Query(
+		`SELECT b.id, b.chat_id, b.title, b.amount, b.due_day, COALESCE(p.paid_at, '')
+		 FROM bills b LEFT JOIN bill_payments p ON p.bill_id = b.id AND p.period = ?
+		 WHERE b.chat_id=? ORDER BY b.due_day, b.id`,
+		period, chatID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []BillStatus
+	for rows.Next() {
+		var b BillStatus
+		var paid string
+		if err := rows.Scan(&b.ID, &b.ChatID, &b.Title, &b.Amount, &b.DueDay, &paid); err != nil {
+			return nil, err
+		}
+		b.Due = billDue(b.DueDay, period, loc)
+		b.PaidAt, _ = time.Parse(time.RFC3339, paid)
+		b.Paid = paid != ""
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// PayBill marks the bill paid for period; false if there is no such bill.
+func (s *sqlStore) PayBill(chatID, id int64, period string, now time.Time) (bool, error) {
+	var amount float64
+	if err := s.db(chatID).QueryRow(`SELECT amount FROM bills WHERE chat_id=? AND id=?`, chatID, id).Scan(&amount); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO bill_payments(bill_id, period, amount, paid_at) VALUES(?,?,?,?)
+		 ON CONFLICT(bill_id, period) DO NOTHING`,
+		id, period, amount, now.UTC().Format(time.RFC3339),
+	)
+	return err == nil, err
+}
+
+// parseBill reads "<название> <сумма> <день>".
+func parseBill(arg string) (Bill, bool) {
+	fields := strings.Fields(arg)
+	if len(fields) < 3 {
+		return Bill{}, false
+	}
+	day, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || day < 1 || day > 31 {
+		return Bill{}, false
+	}
+	amount, err := parseNumber(fields[len(fields)-2])
+	if err != nil || amount <= 0 {
+		return Bill{}, false
+	}
+	return Bill{Title: strings.Join(fields[:len(fields)-2], " "), Amount: amount, DueDay: day}, true
+}
+
+func billPayButton(lang string, id int64, period string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ "+tr(lang, "bill.paid"), fmt.Sprintf("bill:%d:%s", id, period)),
+	))
+}
+
+func formatBills(lang string, bills []BillStatus) string {
+	var b strings.Builder
+	var total, paid float64
+	for _, bs := range bills {
+		mark := "▫️"
+		if bs.Paid {
+			mark = "✅"
+			paid += bs.Amount
+		}
+		total += bs.Amount
+		b.WriteString("\n" + tr(lang, "bill.line", mark, bs.ID, bs.Title, formatAmount(bs.Amount), bs.Due.Format("02.01")))
+	}
+	b.WriteString("\n\n" + tr(lang, "bills.total", formatAmount(total), formatAmount(paid), formatAmount(total-paid)))
+	return b.String()
+}
+
+// handleBill handles "/bill [add <название> <сумма> <день> | paid <id> | del <id>]".
+func (a *App) handleBill(chatID int64, arg string) {
+	lang := a.Store.Lang(chatID)
+	now := time.Now().In(a.tz(chatID))
+	period := now.Format("2006-01")
+	sub, rest, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	switch sub {
+	case "":
+		bills, err := a.Store.BillsFor(chatID, period, a.tz(chatID))
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if len(bills) == 0 {
+			a.send(chatID, tr(lang, "bills.none"))
+			return
+		}
+		a.send(chatID, tr(lang, "bills.header", period)+formatBills(lang, bills))
+	case "add":
+		b, ok := parseBill(rest)
+		if !ok {
+			a.send(chatID, tr(lang, "bill.add.usage"))
+			return
+		}
+		id, err := a.Store.AddBill(chatID, b)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "bill.added", id, b.Title, formatAmount(b.Amount), b.DueDay))
+	case "paid", "del":
+		id, err := strconv.ParseInt(strings.TrimSpace(rest), 10, 64)
+		if err != nil {
+			a.send(chatID, tr(lang, "bill.id.usage", sub))
+			return
+		}
+		if sub == "del" {
+			if err := a.Store.DeleteBill(chatID, id); err != nil {
+				a.send(chatID, a.tr(chatID, "err.write"))
+				return
+			}
+			a.send(chatID, tr(lang, "bill.deleted", id))
+			return
+		}
+		ok, err := a.Store.PayBill(chatID, id, period, time.Now())
+		switch {
+		case err != nil:
+			a.send(chatID, a.tr(chatID, "err.write"))
+		case !ok:
+			a.send(chatID, tr(lang, "bill.notfound", id))
+		default:
+			a.send(chatID, tr(lang, "bill.paid.done", id, period))
+		}
+	default:
+		a.send(chatID, tr(lang, "bill.usage"))
+	}
+}
+
+// handleBillCallback handles "bill:<id>:<period>".
+func (a *App) handleBillCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	idStr, period, _ := strings.Cut(data, ":")
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	ok, err := a.Store.PayBill(chatID, id, period, time.Now())
+	if err != nil || !ok {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.write")))
+		return
+	}
+	paid := a.tr(chatID, "bill.paid")
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, paid))
+	_, _ = a.Bot.Send(tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, cq.Message.Text+"\n✅ "+paid))
+}
+
+// sendBillReminders reminds about this month's unpaid bills due within
+// billRemindDays, and the overdue ones.
+func (s *Scheduler) sendBillReminders(chatID int64, now time.Time) {
+	period := now.Format("2006-01")
+	bills, err := s.store.BillsFor(chatID, period, now.Location())
+	if err != nil {
+		log.Printf("scheduler: bills error: %v", err)
+		return
+	}
+	lang := s.store.Lang(chatID)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	horizon := today.AddDate(0, 0, billRemindDays())
+	for _, b := range bills {
+		if b.Paid || b.Due.After(horizon) {
+			continue
+		}
+		var when string
+		switch days := int(b.Due.Sub(today).Hours() / 24); {
+		case days < 0:
+			when = tr(lang, "bill.overdue", b.Due.Format("02.01"))
+		case days == 0:
+			when = tr(lang, "bill.due.today")
+		default:
+			when = tr(lang, "bill.due", b.Due.Format("02.01"))
+		}
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("💳 %s — %s, %s", b.Title, formatAmount(b.Amount), when))
+		msg.ReplyMarkup = billPayButton(lang, b.ID, period)
+		_ = s.deliver("bill", msg)
+	}
+}
+
+// sendBillsReport sums up last month's bills on the 1st.
+func (s *Scheduler) sendBillsReport(chatID int64, now time.Time) {
+	period := now.AddDate(0, -1, 0).Format("2006-01")
+	bills, err := s.store.BillsFor(chatID, period, now.Location())
+	if err != nil {
+		log.Printf("scheduler: bills report error: %v", err)
+		return
+	}
+	if len(bills) == 0 {
+		return
+	}
+	lang := s.store.Lang(chatID)
+	s.send("bills_report", tgbotapi.NewMessage(chatID, tr(lang, "bills.header", period)+formatBills(lang, bills)))
+}
@@ -3,12 +3,13 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -21,25 +22,41 @@ const (
 	TopicReminders = "reminders"
 	TopicShopping  = "shopping"
 	TopicBasket    = "basket"
+	TopicSomeday   = "someday"
 
-	StatusActive = "active"
+	StatusActive   = "active"
+	StatusDone     = "done"
+	StatusArchived = "archived" // wiped by the night wipe, kept for /archive
 )
 
 type ChatState struct {
 	Topic        string
 	LastActivity time.Time
+
+	// Batch capture session (/capture ... /stop)
+	CaptureTopic string
+	Captured     []string
 }
 
-type Store struct {
-	DB *sql.DB
+type sqlStore struct {
+	DB     dbConn
+	driver string       // driverSQLite or driverPostgres
+	read   dbConn       // read replica for lists and stats; nil reads from DB
+	router *shardRouter // nil unless DB_SHARDS is set
+	vault  *vault       // keys of unlocked encrypted chats
 }
 
 type Item struct {
-	ID        int64
-	ChatID    int64
-	Topic     string
-	Text      string
-	CreatedAt time.Time
+	ID          int64
+	ChatID      int64
+	Topic       string
+	Text        string
+	Status      string // set by GetItem
+	Flagged     bool
+	Secret      bool
+	CreatedAt   time.Time
+	CompletedAt time.Time
+	Due         time.Time // set by ListActive and OverdueTasks
 }
 
 func mustEnv(key string) string {
@@ -58,14 +75,13 @@ func envOr(key, def string) string {
 	return v
 }
 
-func openStore(dbPath string) (*Store, error) {
-	db, err := sql.Open("sqlite", dbPath)
+func openStore(driver, dsn string) (*sqlStore, error) {
+	db, err := openDB(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
-	db.SetMaxOpenConns(1)
 
-	s := &Store{DB: db}
+	s := &sqlStore{DB: wrapConn(driver, db), driver: driver, vault: newVault()}
 	if err := s.migrate(); err != nil {
 		_ = db.Close()
 		return nil, err
@@ -73,7 +89,7 @@ func openStore(dbPath string) (*Store, error) {
 	return s, nil
 }
 
-func (s *Store) migrate() error {
+func (s *sqlStore) migrate() error {
 	ddl := `
 CREATE TABLE IF NOT EXISTS items (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -89,32 +105,414 @@ CREATE TABLE IF NOT EXISTS kv (
   k TEXT PRIMARY KEY,
   v TEXT NOT NULL
 );
+
+CREATE TABLE IF NOT EXISTS entitlements (
+  chat_id INTEGER PRIMARY KEY,
+  plan TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  charge_id TEXT NOT NULL,
+  notified_at TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS goals (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  chat_id INTEGER NOT NULL,
+  title TEXT NOT NULL,
+  target REAL NOT NULL,
+  unit TEXT NOT NULL,
+  period TEXT NOT NULL,
+  progress REAL NOT NULL,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_goals_chat_period ON goals(chat_id, period);
+
+CREATE TABLE IF NOT EXISTS item_threads (
+  chat_id INTEGER NOT NULL,
+  thread_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  PRIMARY KEY (chat_id, thread_id)
+);
+
+CREATE TABLE IF NOT EXISTS bills (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  chat_id INTEGER NOT NULL,
+  title TEXT NOT NULL,
+  amount REAL NOT NULL,
+  due_day INTEGER NOT NULL,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_bills_chat ON bills(chat_id);
+
+CREATE TABLE IF NOT EXISTS bill_payments (
+  bill_id INTEGER NOT NULL,
+  period TEXT NOT NULL,
+  amount REAL NOT NULL,
+  paid_at TEXT NOT NULL,
+  PRIMARY KEY (bill_id, period)
+);
+
+CREATE TABLE IF NOT EXISTS cards (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  chat_id INTEGER NOT NULL,
+  store TEXT NOT NULL,
+  name TEXT NOT NULL,
+  code TEXT NOT NULL,
+  file_id TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  UNIQUE (chat_id, store)
+);
+
+CREATE TABLE IF NOT EXISTS recipes (
+  chat_id INTEGER NOT NULL,
+  recipe TEXT NOT NULL,
+  name TEXT NOT NULL,
+  ingredients TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (chat_id, recipe)
+);
+
+CREATE TABLE IF NOT EXISTS meal_plan (
+  chat_id INTEGER NOT NULL,
+  day INTEGER NOT NULL,
+  recipe TEXT NOT NULL,
+  PRIMARY KEY (chat_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS deferred_messages (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  kind TEXT NOT NULL,
+  chat_id INTEGER NOT NULL,
+  payload TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_deferred_messages_chat ON deferred_messages(chat_id, id);
+
+CREATE TABLE IF NOT EXISTS maintenance (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  chat_id INTEGER NOT NULL,
+  title TEXT NOT NULL,
+  every_months INTEGER NOT NULL,
+  every_km INTEGER NOT NULL,
+  last_done_at TEXT NOT NULL,
+  last_done_km INTEGER NOT NULL,
+  notified TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_maintenance_chat ON maintenance(chat_id);
+
+CREATE TABLE IF NOT EXISTS chat_templates (
+  chat_id INTEGER NOT NULL,
+  template TEXT NOT NULL,
+  name TEXT NOT NULL,
+  topic TEXT NOT NULL,
+  items TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (chat_id, template)
+);
+
+CREATE TABLE IF NOT EXISTS journal (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  chat_id INTEGER NOT NULL,
+  day TEXT NOT NULL,
+  text TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_journal_chat_day ON journal(chat_id, day);
+
+CREATE TABLE IF NOT EXISTS moods (
+  chat_id INTEGER NOT NULL,
+  day TEXT NOT NULL,
+  mood INTEGER NOT NULL,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (chat_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS time_entries (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  chat_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  minutes INTEGER NOT NULL,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_time_entries_chat ON time_entries(chat_id, item_id);
+
+CREATE TABLE IF NOT EXISTS topics (
+  chat_id INTEGER NOT NULL,
+  topic TEXT NOT NULL,
+  name TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (chat_id, topic)
+);
+
+CREATE TABLE IF NOT EXISTS ack_messages (
+  chat_id INTEGER NOT NULL,
+  message_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  PRIMARY KEY (chat_id, message_id)
+);
+
+CREATE TABLE IF NOT EXISTS item_notes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  chat_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  author TEXT NOT NULL,
+  text TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_item_notes_item ON item_notes(chat_id, item_id);
+
+CREATE TABLE IF NOT EXISTS chat_links (
+  chat_id INTEGER NOT NULL,
+  target_id INTEGER NOT NULL,
+  title TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (chat_id, target_id)
+);
+
+CREATE TABLE IF NOT EXISTS dead_letters (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  kind TEXT NOT NULL,
+  chat_id INTEGER NOT NULL,
+  payload TEXT NOT NULL,
+  error TEXT NOT NULL,
+  attempts INTEGER NOT NULL,
+  created_at TEXT NOT NULL,
+  last_attempt_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS media (
+  file_unique_id TEXT PRIMARY KEY,
+  file_id TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  size INTEGER NOT NULL,
+  mime TEXT NOT NULL,
+  name TEXT NOT NULL,
+  storage_key TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS item_media (
+  chat_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  file_unique_id TEXT NOT NULL,
+  PRIMARY KEY (chat_id, item_id, file_unique_id)
+);
+
+CREATE TABLE IF NOT EXISTS goal_links (
+  chat_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  goal_id INTEGER NOT NULL,
+  amount REAL NOT NULL,
+  PRIMARY KEY (chat_id, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS chat_state (
+  chat_id INTEGER PRIMARY KEY,
+  topic TEXT NOT NULL,
+  last_activity TEXT NOT NULL,
+  capture_topic TEXT NOT NULL,
+  captured TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS chats (
+  chat_id INTEGER PRIMARY KEY,
+  first_seen TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS chat_shards (
+  chat_id INTEGER PRIMARY KEY,
+  shard INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS item_actions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  chat_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  action TEXT NOT NULL,
+  payload TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_item_actions_chat ON item_actions(chat_id, id);
+
+CREATE TABLE IF NOT EXISTS leases (
+  name TEXT PRIMARY KEY,
+  holder TEXT NOT NULL,
+  expires_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS item_history (
+  chat_id INTEGER NOT NULL,
+  month TEXT NOT NULL,
+  topic TEXT NOT NULL,
+  count INTEGER NOT NULL,
+  items BLOB NOT NULL,
+  PRIMARY KEY (chat_id, month, topic)
+);
 `
-	_, err := s.DB.Exec(ddl)
+	if s.driver == driverPostgres {
+		ddl = pgTypes(ddl)
+	}
+	if _, err := s.DB.Exec(ddl); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "norm", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "flagged", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "completed_at", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "completed_by", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "completed_by_name", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "event_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "due_at", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "notify_chat", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "message_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "chat_type", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "forward_from", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "forward_link", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "forward_date", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "remind_at", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "reminded_at", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "snoozed_until", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("items", "secret", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.migrateGoalLinks(); err != nil {
+		return err
+	}
+	if err := s.backfillNorm(); err != nil {
+		return err
+	}
+	if err := s.ensureSearchIndex(); err != nil {
+		return err
+	}
+	return s.backfillChats()
+}
+
+// ensureColumn adds a column to an existing table if it is missing.
+func (s *sqlStore) ensureColumn(table, column, def string) error {
+	if s.driver == driverPostgres {
+		_, err := s.DB.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s`, table, column, pgTypes(def)))
+		return err
+	}
+	ok, err := s.hasColumn(table, column)
+	if err != nil || ok {
+		return err
+	}
+	_, err = s.DB.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, def))
 	return err
 }
 
-func (s *Store) AddItem(chatID int64, topic, text string) (int64, error) {
+func (s *sqlStore) hasColumn(table, column string) (bool, error) {
+	q := `SELECT name FROM pragma_table_info(?)`
+	if s.driver == driverPostgres {
+		q = `SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ?`
+	}
+	rows, err := s.DB.Query(q, table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+func (s *sqlStore) backfillNorm() error {
+	rows, err := s.DB.Query(`SELECT id, text FROM items WHERE norm=''`)
+	if err != nil {
+		return err
+	}
+	norms := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return err
+		}
+		norms[id] = normalizeText(text)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, n := range norms {
+		if _, err := s.DB.Exec(`UPDATE items SET norm=? WHERE id=?`, n, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlStore) AddItem(chatID int64, topic, text string) (int64, error) {
 	now := time.Now().UTC().Format(time.RFC3339)
-	res, err := s.DB.Exec(
-		`INSERT INTO items(chat_id, topic, text, status, created_at) VALUES(?,?,?,?,?)`,
-		chatID, topic, text, StatusActive, now,
-	)
+	var id int64
+	stored, norm, err := s.sealText(chatID, text)
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	err = s.db(chatID).QueryRow(
+		`INSERT INTO items(chat_id, topic, text, norm, status, created_at) VALUES(?,?,?,?,?,?) RETURNING id`,
+		chatID, topic, stored, norm, StatusActive, now,
+	).Scan(&id)
+	if err == nil {
+		s.logAction(chatID, id, actionCreated, "")
+	}
+	return id, err
 }
 
-func (s *Store) ListActive(chatID int64, topic string) ([]Item, error) {
-	q := `SELECT id, chat_id, topic, text, created_at FROM items WHERE chat_id=? AND status=?`
+func (s *sqlStore) ItemMessage(chatID, id int64) int {
+	var messageID int
+	_ = s.db(chatID).QueryRow(`SELECT message_id FROM items WHERE chat_id=? AND id=?`, chatID, id).Scan(&messageID)
+	return messageID
+}
+
+func (s *sqlStore) ListActive(chatID int64, topic string) ([]Item, error) {
+	q := `SELECT id, chat_id, topic, text, flagged, secret, created_at, due_at FROM items WHERE chat_id=? AND status=?`
 	args := []any{chatID, StatusActive}
 	if topic != "" {
 		q += ` AND topic=?`
 		args = append(args, topic)
 	}
 	q += ` ORDER BY id ASC`
-	rows, err := s.DB.Query(q, args...)
+	rows, err := s.readDB(chatID).Query(q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -123,47 +521,118 @@ func (s *Store) ListActive(chatID int64, topic string) ([]Item, error) {
 	var out []Item
 	for rows.Next() {
 		var it Item
-		var created string
-		if err := rows.Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &created); err != nil {
+		var created, due string
+		if err := rows.Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &it.Flagged, &it.Secret, &created, &due); err != nil {
 			return nil, err
 		}
+		it.Text = s.openText(chatID, it.Text)
 		t, _ := time.Parse(time.RFC3339, created)
 		it.CreatedAt = t
+		it.Due, _ = time.Parse(time.RFC3339, due)
 		out = append(out, it)
 	}
 	return out, rows.Err()
 }
 
-func (s *Store) DeleteItem(chatID, id int64) error {
-	_, err := s.DB.Exec(`DELETE FROM items WHERE chat_id=? AND id=?`, chatID, id)
+// FindDuplicate returns an active item in the topic whose normalized text
+// equals the normalized form of text, or nil. In an encrypted chat both are
+// keyed hashes (see crypt.go).
+func (s *sqlStore) FindDuplicate(chatID int64, topic, text string) (*Item, error) {
+	var it Item
+	var created string
+	err := s.db(chatID).QueryRow(
+		`SELECT id, chat_id, topic, text, created_at FROM items WHERE chat_id=? AND topic=? AND status=? AND norm=? ORDER BY id LIMIT 1`,
+		chatID, topic, StatusActive, s.normFor(chatID, text),
+	).Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	it.Text = s.openText(chatID, it.Text)
+	it.CreatedAt, _ = time.Parse(time.RFC3339, created)
+	return &it, nil
+}
+
+func (s *sqlStore) MoveItem(chatID, id int64, topic string) error {
+	var from string
+	if err := s.db(chatID).QueryRow(`SELECT topic FROM items WHERE chat_id=? AND id=?`, chatID, id).Scan(&from); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if _, err := s.db(chatID).Exec(`UPDATE items SET topic=? WHERE chat_id=? AND id=?`, topic, chatID, id); err != nil {
+		return err
+	}
+	if from != topic {
+		s.logAction(chatID, id, actionMoved, from)
+	}
+	return nil
+}
+
+func (s *sqlStore) FlagItem(chatID, id int64) error {
+	_, err := s.db(chatID).Exec(`UPDATE items SET flagged=1 WHERE chat_id=? AND id=?`, chatID, id)
 	return err
 }
 
-func topicLabel(topic string) string {
+// CompleteItem marks an item done, keeping it for reports.
+// by is the user who completed it, nil when unknown.
+func (s *sqlStore) CompleteItem(chatID, id int64, by *tgbotapi.User, now time.Time) error {
+	var userID int64
+	var name string
+	if by != nil {
+		userID, name = by.ID, userDisplayName(by)
+	}
+	res, err := s.db(chatID).Exec(
+		`UPDATE items SET status=?, completed_at=?, completed_by=?, completed_by_name=? WHERE chat_id=? AND id=? AND status=?`,
+		StatusDone, now.UTC().Format(time.RFC3339), userID, name, chatID, id, StatusActive,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		s.logAction(chatID, id, actionCompleted, "")
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteItem(chatID, id int64) error {
+	snapshot, err := s.itemSnapshot(chatID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db(chatID).Exec(`DELETE FROM items WHERE chat_id=? AND id=?`, chatID, id); err != nil {
+		return err
+	}
+	if snapshot != "" {
+		s.logAction(chatID, id, actionDeleted, snapshot)
+	}
+	return nil
+}
+
+func topicLabel(lang, topic string) string {
 	switch topic {
-	case TopicTasks:
-		return "ЗАДАЧИ"
-	case TopicReminders:
-		return "НАПОМИНАНИЯ"
-	case TopicShopping:
-		return "ПОКУПКИ"
-	case TopicBasket:
-		return "КОРЗИНУ"
+	case TopicTasks, TopicReminders, TopicShopping, TopicBasket, TopicSomeday:
+		return tr(lang, "label."+topic)
 	default:
 		return strings.ToUpper(topic)
 	}
 }
 
 func isTopicButtonText(t string) (string, bool) {
-	switch strings.TrimSpace(strings.ToLower(t)) {
-	case "задачи":
+	switch normalizeText(t) {
+	case "задачи", "tasks":
 		return TopicTasks, true
-	case "напоминания":
+	case "напоминания", "reminders":
 		return TopicReminders, true
-	case "покупки":
+	case "покупки", "shopping":
 		return TopicShopping, true
-	case "корзина":
+	case "корзина", "basket":
 		return TopicBasket, true
+	case "когда-нибудь", "someday":
+		return TopicSomeday, true
 	case "menu":
 		return TopicBasket, true
 	default:
@@ -171,75 +640,34 @@ func isTopicButtonText(t string) (string, bool) {
 	}
 }
 
-func mainMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
-	kb := tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Задачи"),
-			tgbotapi.NewKeyboardButton("Напоминания"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Покупки"),
-			tgbotapi.NewKeyboardButton("Корзина"),
-		),
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("menu"),
-		),
-	)
-	kb.ResizeKeyboard = true
-	return kb
-}
-
 type App struct {
-	Bot        *tgbotapi.BotAPI
-	Store      *Store
-	TZ         *time.Location
-	TTL        time.Duration
-	StateMu    sync.Mutex
-	ChatStates map[int64]*ChatState
-}
-
-func (a *App) getState(chatID int64) *ChatState {
-	a.StateMu.Lock()
-	defer a.StateMu.Unlock()
+	Name     string // BOTS entry; "" for BOT_TOKEN
+	Bot      *tgbotapi.BotAPI
+	Store    Store
+	TZ       *time.Location
+	TTL      time.Duration
+	Filters  FilterChain
+	Calendar CalendarClient
+	Digest   *Digest
+	States   *StateManager
+	HTTP     *http.Client // Bot API client, also for file downloads
+	Media    *MediaManager
+	Events   *EventBus
+	Scripts  *ScriptRunner
+	STT      Transcriber // nil: voice notes are kept as files
+	TTS      Speaker     // nil: no spoken answers
 
-	st := a.ChatStates[chatID]
-	if st == nil {
-		st = &ChatState{Topic: TopicBasket, LastActivity: time.Now().In(a.TZ)}
-		a.ChatStates[chatID] = st
-	}
-	return st
+	plugins        []Plugin
+	pluginCommands map[string]PluginCommand
+	topics         topicThreads
 }
 
-func (a *App) touchState(chatID int64) *ChatState {
-	a.StateMu.Lock()
-	defer a.StateMu.Unlock()
-
-	now := time.Now().In(a.TZ)
-	st := a.ChatStates[chatID]
-	if st == nil {
-		st = &ChatState{Topic: TopicBasket, LastActivity: now}
-		a.ChatStates[chatID] = st
-		return st
-	}
-	if now.Sub(st.LastActivity) > a.TTL {
-		st.Topic = TopicBasket
-	}
-	st.LastActivity = now
-	return st
+func (a *App) touchState(chatID int64) ChatState {
+	return a.States.Touch(chatID)
 }
 
 func (a *App) setTopic(chatID int64, topic string) {
-	a.StateMu.Lock()
-	defer a.StateMu.Unlock()
-
-	now := time.Now().In(a.TZ)
-	st := a.ChatStates[chatID]
-	if st == nil {
-		st = &ChatState{}
-		a.ChatStates[chatID] = st
-	}
-	st.Topic = topic
-	st.LastActivity = now
+	a.States.SetTopic(chatID, topic)
 }
 
 func (a *App) resetToMenu(chatID int64) {
@@ -248,51 +676,116 @@ func (a *App) resetToMenu(chatID int64) {
 
 func (a *App) send(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = mainMenuKeyboard()
+	if markup := a.replyMarkup(chatID); markup != nil {
+		msg.ReplyMarkup = markup
+	}
 	_, _ = a.Bot.Send(msg)
 }
 
+// run handles updates until ctx is cancelled, each chat on its own worker.
+// Updates already queued are finished: handlers get a context that
+// outlives the cancellation.
 func (a *App) run(ctx context.Context) error {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 30
-	updates := a.Bot.GetUpdatesChan(u)
+	updates, err := newTransportFromEnv(a.Bot, a.Store, a.Name, &a.topics).Start(ctx)
+	if err != nil {
+		return err
+	}
 
+	hctx := context.WithoutCancel(ctx)
+	d := newDispatcher(func(upd tgbotapi.Update) { a.handleUpdate(hctx, upd) })
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		case upd := <-updates:
-			if upd.Message != nil {
-				a.handleMessage(ctx, upd.Message)
-			}
-			if upd.CallbackQuery != nil {
-				a.handleCallback(ctx, upd.CallbackQuery)
+			if !d.Close(shutdownGrace) {
+				log.Printf("shutdown: chat workers still busy")
 			}
+			return nil
+		case upd := <-updates:
+			d.Dispatch(upd)
 		}
 	}
 }
 
+func (a *App) handleUpdate(ctx context.Context, upd tgbotapi.Update) {
+	if upd.Message != nil {
+		a.handleMessage(ctx, upd.Message)
+	}
+	if upd.CallbackQuery != nil {
+		a.handleCallback(ctx, upd.CallbackQuery)
+	}
+	if upd.PreCheckoutQuery != nil {
+		a.handlePreCheckout(upd.PreCheckoutQuery)
+	}
+}
+
 func (a *App) handleMessage(ctx context.Context, m *tgbotapi.Message) {
 	chatID := m.Chat.ID
+	a.registerChat(chatID)
+
+	if m.SuccessfulPayment != nil {
+		a.handleSuccessfulPayment(m)
+		return
+	}
+
+	if !a.permitMessage(m) {
+		return
+	}
 
 	if m.IsCommand() {
-		if m.Command() == "start" || m.Command() == "menu" {
-			a.resetToMenu(chatID)
-			a.send(chatID, "Меню открыто. Режим по умолчанию: КОРЗИНА.")
-		}
+		a.handleCommand(ctx, m)
+		return
+	}
+
+	if a.pluginMessage(ctx, m) {
+		return
+	}
+
+	if a.captureThreadNote(m) {
+		return
+	}
+
+	if a.editFromAck(m) {
+		return
+	}
+
+	if a.journalFromReply(m) {
+		return
+	}
+
+	if a.captureCardPhoto(m) {
+		return
+	}
+
+	if a.importFromCaption(ctx, m) {
+		return
+	}
+
+	if a.timezoneFromLocation(m) {
+		return
+	}
+
+	if a.captureVoice(ctx, m) {
+		return
+	}
+
+	if a.captureAttachment(ctx, m) {
 		return
 	}
 
 	if m.Text == "" {
-		a.send(chatID, "Понимаю только текст.")
+		a.send(chatID, a.tr(chatID, "only.text"))
 		return
 	}
 
-	if topic, ok := isTopicButtonText(m.Text); ok {
-		if strings.ToLower(m.Text) == "menu" {
+	if a.handleKeyboardAction(chatID, m.Text) {
+		return
+	}
+
+	if topic, ok := a.topicFromButton(chatID, m.Text); ok {
+		if normalizeText(m.Text) == "menu" {
 			a.resetToMenu(chatID)
 			items, _ := a.Store.ListActive(chatID, TopicBasket)
-			a.send(chatID, "Меню открыто. Режим: КОРЗИНА.")
+			a.send(chatID, a.tr(chatID, "menu.opened.basket"))
 			a.sendItemsOneByOne(chatID, TopicBasket, items)
 			return
 		}
@@ -300,8 +793,27 @@ func (a *App) handleMessage(ctx context.Context, m *tgbotapi.Message) {
 		a.setTopic(chatID, topic)
 		items, _ := a.Store.ListActive(chatID, topic)
 
-		a.send(chatID, fmt.Sprintf("Режим: %s.", topicLabel(topic)))
+		a.send(chatID, a.tr(chatID, "mode", topicLabel(a.Store.Lang(chatID), topic)))
 		a.sendItemsOneByOne(chatID, topic, items)
+		if topic == TopicShopping {
+			a.offerCards(chatID)
+		}
+		return
+	}
+
+	// "#покупки молоко" goes to that topic, whatever the chat is in
+	if topic, text, ok := a.hashtagTopic(chatID, m.Text); ok {
+		a.captureText(m, ruleOutcome{Topic: topic}, text)
+		return
+	}
+
+	// Batch capture session: everything goes silently to the chosen topic
+	if topic, ok := a.captureTopic(chatID); ok {
+		a.captureBatch(chatID, provenanceOf(m), topic, strings.TrimSpace(m.Text))
+		return
+	}
+
+	if a.intentFromText(ctx, m) {
 		return
 	}
 
@@ -312,60 +824,493 @@ func (a *App) handleMessage(ctx context.Context, m *tgbotapi.Message) {
 		return
 	}
 
-	_, err := a.Store.AddItem(chatID, st.Topic, text)
+	text = a.scriptCapture(chatID, st.Topic, text)
+	a.captureText(m, a.captureRules(chatID, st.Topic, text), text)
+}
+
+// captureText stores text in rule.Topic, with its reminder time or due
+// date, and acknowledges it.
+func (a *App) captureText(m *tgbotapi.Message, rule ruleOutcome, text string) {
+	chatID := m.Chat.ID
+	topic := rule.Topic
+
+	var remindAt, due time.Time
+	switch topic {
+	case TopicReminders:
+		text, remindAt = a.applyRemindAt(chatID, text)
+	case TopicTasks:
+		text, due = a.applyDue(chatID, text)
+	}
+
+	id, res := a.storeCapture(chatID, provenanceOf(m), topic, text)
+	if res == captureStored && rule.Flag {
+		if err := a.Store.FlagItem(chatID, id); err != nil {
+			log.Printf("rule flag error: %v", err)
+		}
+	}
+	if res == captureStored && !remindAt.IsZero() {
+		if err := a.Store.SetRemindAt(chatID, id, remindAt); err != nil {
+			log.Printf("set remind_at error: %v", err)
+			remindAt = time.Time{}
+		}
+	}
+	if res == captureStored && !due.IsZero() {
+		if err := a.Store.SetDue(chatID, id, due); err != nil {
+			log.Printf("set due error: %v", err)
+			due = time.Time{}
+		}
+	}
+	switch res {
+	case captureDuplicate:
+		a.send(chatID, a.tr(chatID, "dup", topicLabel(a.Store.Lang(chatID), topic), id))
+	case captureRejected:
+		a.send(chatID, a.tr(chatID, "filter.rejected"))
+	case captureFailed:
+		a.send(chatID, a.tr(chatID, "err.write"))
+	case captureLocked:
+		a.sendLocked(chatID)
+	default:
+		if rule.Silent {
+			return
+		}
+		a.ackCapture(m, topic, id)
+		if !remindAt.IsZero() {
+			a.confirmRemindAt(chatID, remindAt)
+		}
+		if !due.IsZero() {
+			a.confirmDue(chatID, due)
+		}
+	}
+}
+
+type captureResult int
+
+const (
+	captureStored captureResult = iota
+	captureDuplicate
+	captureRejected
+	captureFailed
+	captureLocked
+)
+
+// storeCapture runs duplicate detection and input filters, then stores the
+// text. For duplicates the returned id is the existing item.
+func (a *App) storeCapture(chatID int64, prov Provenance, topic, text string) (int64, captureResult) {
+	if dup, err := a.Store.FindDuplicate(chatID, topic, text); err == nil && dup != nil {
+		return dup.ID, captureDuplicate
+	}
+
+	verdict, reason := a.Filters.Check(text)
+	if verdict == FilterReject {
+		log.Printf("filter: rejected capture in chat %d (%q)", chatID, reason)
+		return 0, captureRejected
+	}
+
+	id, err := a.Store.CaptureItem(chatID, topic, text, verdict == FilterFlag, prov)
+	if errors.Is(err, errLocked) {
+		return 0, captureLocked
+	}
 	if err != nil {
-		a.send(chatID, "Ошибка записи.")
-		return
+		log.Printf("add item error: %v", err)
+		return 0, captureFailed
+	}
+	if verdict == FilterFlag {
+		log.Printf("filter: flagged item %d in chat %d (%q)", id, chatID, reason)
+	}
+	a.titleLink(chatID, id, text)
+	return id, captureStored
+}
+
+func (a *App) handleCommand(ctx context.Context, m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+
+	switch m.Command() {
+	case "start", "menu":
+		if m.Command() == "start" && m.From != nil {
+			a.detectLang(chatID, m.From.LanguageCode)
+		}
+		a.resetToMenu(chatID)
+		a.send(chatID, a.tr(chatID, "menu.opened"))
+	case "language":
+		a.handleLanguage(chatID, m.CommandArguments())
+	case "premium":
+		a.handlePremium(chatID)
+	case "next":
+		a.handleNext(chatID)
+	case "today":
+		a.handleToday(chatID)
+	case "capacity":
+		a.handleCapacity(chatID, m.CommandArguments())
+	case "contexts", "ctx":
+		a.handleContexts(chatID)
+	case "goal":
+		a.handleGoal(chatID, m.CommandArguments())
+	case "goals":
+		a.handleGoals(chatID)
+	case "delgoal":
+		a.handleDelGoal(chatID, m.CommandArguments())
+	case "checkin":
+		a.handleCheckin(chatID, m.CommandArguments())
+	case "linkgoal":
+		a.handleLinkGoal(chatID, m.CommandArguments())
+	case "capture":
+		a.handleCapture(chatID, m.CommandArguments())
+	case "stop":
+		a.handleStopCapture(chatID)
+	case "keyboard":
+		a.handleKeyboard(chatID, m.CommandArguments())
+	case "rename":
+		a.handleRename(chatID, m.CommandArguments())
+	case "compact":
+		a.handleCompact(chatID, m.CommandArguments())
+	case "ack":
+		a.handleAck(chatID, m.CommandArguments())
+	case "digest":
+		a.handleDigest(ctx, chatID, m.CommandArguments())
+	case "digestchannel":
+		a.handleDigestChannel(m)
+	case "digesttemplate":
+		a.handleDigestTemplate(chatID, m.CommandArguments())
+	case "travel":
+		a.handleTravel(chatID, m.CommandArguments())
+	case "timezone", "tz":
+		a.handleTimezone(chatID, m.CommandArguments())
+	case "linkchat":
+		a.handleLinkChat(m)
+	case "route":
+		a.handleRoute(chatID, m.CommandArguments())
+	case "deadletters":
+		a.handleDeadLetters(m)
+	case "backup":
+		a.handleBackup(ctx, m)
+	case "restore":
+		a.handleRestore(ctx, m)
+	case "times":
+		a.handleTimes(chatID, m.CommandArguments())
+	case "due":
+		a.handleDue(chatID, m.CommandArguments())
+	case "attach":
+		a.handleAttach(ctx, chatID, m.CommandArguments())
+	case "item":
+		a.handleItem(chatID, m.CommandArguments())
+	case "notes":
+		a.handleNotes(chatID, m.CommandArguments())
+	case "leaderboard":
+		a.handleLeaderboard(chatID, m.CommandArguments())
+	case "history":
+		a.handleHistory(chatID, m.CommandArguments())
+	case "archive":
+		a.handleArchive(chatID, m.CommandArguments())
+	case "retention":
+		a.handleRetention(chatID, m.CommandArguments())
+	case "gcalauth":
+		a.handleGCalAuth(ctx, m)
+	case "rules":
+		a.handleRules(chatID, m.CommandArguments())
+	case "script":
+		a.handleScript(chatID, m.CommandArguments())
+	case "view":
+		a.handleView(chatID, m.CommandArguments())
+	case "search":
+		a.handleSearch(chatID, m.CommandArguments())
+	case "watch":
+		a.handleWatch(m)
+	case "list":
+		a.handleList(chatID, m.CommandArguments())
+	case "tasks", "shopping", "reminders":
+		a.handleList(chatID, m.Command())
+	case "usage":
+		a.handleUsage(chatID)
+	case "export":
+		a.handleExport(chatID, m.CommandArguments())
+	case "import":
+		a.handleImport(ctx, m)
+	case "migrate":
+		a.handleMigrate(ctx, m)
+	case "undo":
+		a.handleUndo(chatID)
+	case "encrypt":
+		a.handleEncrypt(m)
+	case "decrypt":
+		a.handleDecrypt(m)
+	case "unlock":
+		a.handleUnlock(m)
+	case "lock":
+		a.handleLock(chatID)
+	case "secret":
+		a.handleSecret(chatID, m.CommandArguments())
+	case "review":
+		a.handleReview(chatID)
+	case "clear":
+		a.handleClear(m)
+	case "newlist":
+		a.handleNewList(chatID, m.CommandArguments())
+	case "dellist":
+		a.handleDelList(chatID, m.CommandArguments())
+	case "bill", "bills":
+		a.handleBill(chatID, m.CommandArguments())
+	case "card", "cards":
+		a.handleCard(chatID, m.CommandArguments())
+	case "recipe", "recipes":
+		a.handleRecipe(chatID, m.CommandArguments())
+	case "meal", "meals":
+		a.handleMeal(chatID, m.CommandArguments())
+	case "quiet", "dnd":
+		a.handleQuiet(chatID, m.CommandArguments())
+	case "trip":
+		a.handleTrip(chatID, m.CommandArguments())
+	case "maint", "maintenance":
+		a.handleMaintenance(chatID, m.CommandArguments())
+	case "odo":
+		a.handleOdometer(chatID, m.CommandArguments())
+	case "templates":
+		a.handleTemplates(chatID)
+	case "template":
+		a.handleTemplate(chatID, m.CommandArguments())
+	case "journal":
+		a.handleJournal(chatID, m.CommandArguments())
+	case "mood":
+		a.handleMood(chatID, m.CommandArguments())
+	case "focus":
+		a.handleFocus(chatID, m.CommandArguments())
+	case "timer":
+		a.handleTimer(chatID, m.CommandArguments())
+	case "spent":
+		a.handleSpent(chatID, m.CommandArguments())
+	case "estimates":
+		a.handleEstimates(chatID)
+	case "busy":
+		a.handleBusy(chatID, m.CommandArguments())
+	case "plan":
+		a.handlePlan(chatID)
+	case "intents":
+		a.handleIntents(chatID, m.CommandArguments())
+	case "speak":
+		a.handleSpeak(chatID, m.CommandArguments())
+	case "role":
+		a.handleRole(m)
+	default:
+		a.pluginCommand(ctx, m)
 	}
-	a.send(chatID, fmt.Sprintf("ДОБАВИЛ СООБЩЕНИЕ В %s.", topicLabel(st.Topic)))
 }
 
 func (a *App) handleCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
+	if !a.permitCallback(cq) {
+		return
+	}
+	if a.pluginCallback(ctx, cq) {
+		return
+	}
 	chatID := cq.Message.Chat.ID
 	data := strings.TrimSpace(cq.Data)
 
-	if strings.HasPrefix(data, "done:") {
-		idStr := strings.TrimPrefix(data, "done:")
+	if strings.HasPrefix(data, "done:") || strings.HasPrefix(data, "del:") {
+		action, idStr, _ := strings.Cut(data, ":")
 		id, _ := strconv.ParseInt(idStr, 10, 64)
-		_ = a.Store.DeleteItem(chatID, id)
+		var err error
+		key := "deleted"
+		if action == "done" {
+			err, key = a.Store.FinishItem(chatID, id, cq.From, time.Now()), "done"
+		} else {
+			err = a.Store.DeleteItem(chatID, id)
+		}
+		if err != nil {
+			_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+			if errors.Is(err, errLocked) {
+				a.sendLocked(chatID)
+			} else {
+				log.Printf("%s item error: %v", action, err)
+				a.send(chatID, a.tr(chatID, "err.write"))
+			}
+			return
+		}
 
-		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, "Удалено"))
-		edit := tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, "✅ Удалено")
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, key)))
+		edit := tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, a.tr(chatID, key+".mark"))
 		_, _ = a.Bot.Send(edit)
 		editMarkup := tgbotapi.NewEditMessageReplyMarkup(chatID, cq.Message.MessageID, tgbotapi.InlineKeyboardMarkup{})
 		_, _ = a.Bot.Send(editMarkup)
 	}
+
+	if strings.HasPrefix(data, "pg:") {
+		a.handleListCallback(cq, strings.TrimPrefix(data, "pg:"))
+	}
+
+	if strings.HasPrefix(data, "snz:") {
+		a.handleSnoozeCallback(cq, strings.TrimPrefix(data, "snz:"))
+	}
+
+	if strings.HasPrefix(data, "rdone:") {
+		a.handleRoutedDone(cq, strings.TrimPrefix(data, "rdone:"))
+	}
+
+	if strings.HasPrefix(data, "goto:") {
+		a.handleGotoCallback(cq, strings.TrimPrefix(data, "goto:"))
+	}
+
+	if strings.HasPrefix(data, "thread:") {
+		id, _ := strconv.ParseInt(strings.TrimPrefix(data, "thread:"), 10, 64)
+		a.openItemThread(cq, id)
+	}
+
+	if strings.HasPrefix(data, "due:") {
+		id, _ := strconv.ParseInt(strings.TrimPrefix(data, "due:"), 10, 64)
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+		a.openDatePicker(chatID, id)
+	}
+
+	if strings.HasPrefix(data, "dp:") {
+		a.handleDatePickerCallback(cq, strings.TrimPrefix(data, "dp:"))
+	}
+
+	if strings.HasPrefix(data, "dg:") {
+		a.handleDigestCallback(cq, strings.TrimPrefix(data, "dg:"))
+	}
+
+	if strings.HasPrefix(data, "ctx:") {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+		a.sendContextItems(chatID, strings.TrimPrefix(data, "ctx:"))
+	}
+
+	if data == "triage:start" {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+		a.startTriage(chatID)
+	}
+
+	if strings.HasPrefix(data, "mv:") {
+		a.handleMoveCallback(cq, strings.TrimPrefix(data, "mv:"))
+	}
+
+	if strings.HasPrefix(data, "move:") {
+		a.handleMovePicker(cq, strings.TrimPrefix(data, "move:"))
+	}
+
+	if strings.HasPrefix(data, "rv:") {
+		a.handleReviewCallback(cq, strings.TrimPrefix(data, "rv:"))
+	}
+
+	if strings.HasPrefix(data, "appr:") {
+		a.handleApprovalCallback(cq, strings.TrimPrefix(data, "appr:"))
+	}
+
+	if strings.HasPrefix(data, "bill:") {
+		a.handleBillCallback(cq, strings.TrimPrefix(data, "bill:"))
+	}
+
+	if strings.HasPrefix(data, "card:") {
+		a.handleCardCallback(cq, strings.TrimPrefix(data, "card:"))
+	}
+
+	if strings.HasPrefix(data, "maint:") {
+		a.handleMaintCallback(cq, strings.TrimPrefix(data, "maint:"))
+	}
+
+	if strings.HasPrefix(data, "tpl:") {
+		a.handleTemplateCallback(cq, strings.TrimPrefix(data, "tpl:"))
+	}
+
+	if strings.HasPrefix(data, "sr:") {
+		a.handleSearchCallback(cq, strings.TrimPrefix(data, "sr:"))
+	}
+
+	if strings.HasPrefix(data, "mood:") {
+		a.handleMoodCallback(cq, strings.TrimPrefix(data, "mood:"))
+	}
+
+	if strings.HasPrefix(data, "att:") {
+		a.handleAttachmentCallback(cq, strings.TrimPrefix(data, "att:"))
+	}
+
+	if strings.HasPrefix(data, "arch:") {
+		a.handleArchiveCallback(cq, strings.TrimPrefix(data, "arch:"))
+	}
+
+	if strings.HasPrefix(data, "reveal:") {
+		a.handleRevealCallback(cq, strings.TrimPrefix(data, "reveal:"))
+	}
+
+	if strings.HasPrefix(data, "movex:") {
+		a.handleMoveCancel(cq, strings.TrimPrefix(data, "movex:"))
+	}
+
+	if strings.HasPrefix(data, "sd:") {
+		a.handleSomedayCallback(cq, strings.TrimPrefix(data, "sd:"))
+	}
+
+	if strings.HasPrefix(data, "lang:") {
+		lang := strings.TrimPrefix(data, "lang:")
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+		a.handleLanguage(chatID, lang)
+	}
+}
+
+func (a *App) handleLanguage(chatID int64, arg string) {
+	lang := strings.ToLower(strings.TrimSpace(arg))
+	if lang == "" {
+		msg := tgbotapi.NewMessage(chatID, a.tr(chatID, "lang.choose"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Русский", "lang:"+LangRU),
+			tgbotapi.NewInlineKeyboardButtonData("English", "lang:"+LangEN),
+		))
+		_, _ = a.Bot.Send(msg)
+		return
+	}
+	if !supportedLang(lang) {
+		a.send(chatID, a.tr(chatID, "lang.unknown"))
+		return
+	}
+	if err := a.Store.SetLang(chatID, lang); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "lang.set"))
 }
 
 func (a *App) sendItemsOneByOne(chatID int64, topic string, items []Item) {
 	if len(items) == 0 {
-		a.send(chatID, "Пусто.")
+		a.send(chatID, a.tr(chatID, "empty"))
 		return
 	}
+	lang := a.Store.Lang(chatID)
+	now := time.Now()
 	for _, it := range items {
-		msg := tgbotapi.NewMessage(chatID, formatSingleItem(topic, it))
-		msg.ReplyMarkup = singleKeyboard(it.ID)
+		msg := tgbotapi.NewMessage(chatID, formatSingleItem(lang, topic, it)+dueMark(lang, it.Due, now, a.tz(chatID)))
+		msg.ReplyMarkup = itemKeyboard(lang, chatID, it)
 		_, _ = a.Bot.Send(msg)
 	}
 }
 
+// itemKeyboard is the buttons under a listed item: singleKeyboard, or
+// groupItemKeyboard in a group, and 👁 for a secret item.
+func itemKeyboard(lang string, chatID int64, it Item) tgbotapi.InlineKeyboardMarkup {
+	markup := singleKeyboard(it.ID)
+	if isGroupChat(chatID) {
+		markup = groupItemKeyboard(lang, it.ID)
+	}
+	if it.Secret {
+		markup.InlineKeyboard[0] = append(markup.InlineKeyboard[0], revealButton(it.ID))
+	}
+	return markup
+}
+
 func singleKeyboard(id int64) tgbotapi.InlineKeyboardMarkup {
-	btn := tgbotapi.NewInlineKeyboardButtonData("✅", fmt.Sprintf("done:%d", id))
-	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(btn))
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅", fmt.Sprintf("done:%d", id)),
+		tgbotapi.NewInlineKeyboardButtonData("📅", fmt.Sprintf("due:%d", id)),
+		tgbotapi.NewInlineKeyboardButtonData("↪️", fmt.Sprintf("move:%d", id)),
+	))
 }
 
-func formatSingleItem(topic string, it Item) string {
+func formatSingleItem(lang, topic string, it Item) string {
+	text := shownText(it)
+	if it.Flagged {
+		text = "🚩 " + text
+	}
 	switch topic {
-	case TopicTasks:
-		return fmt.Sprintf("ЗАДАЧА #%d: %s", it.ID, it.Text)
-	case TopicReminders:
-		return fmt.Sprintf("НАПОМИНАНИЕ #%d: %s", it.ID, it.Text)
-	case TopicShopping:
-		return fmt.Sprintf("ПОКУПКА #%d: %s", it.ID, it.Text)
-	case TopicBasket:
-		return fmt.Sprintf("КОРЗИНА #%d: %s", it.ID, it.Text)
+	case TopicTasks, TopicReminders, TopicShopping, TopicBasket, TopicSomeday:
+		return fmt.Sprintf("%s #%d: %s", tr(lang, "item."+topic), it.ID, text)
 	default:
-		return fmt.Sprintf("%s #%d: %s", strings.ToUpper(topic), it.ID, it.Text)
+		return fmt.Sprintf("%s #%d: %s", strings.ToUpper(topic), it.ID, text)
 	}
 }
 
@@ -376,14 +1321,34 @@ func initApp() (*App, error) {
 		return nil, err
 	}
 
-	dbPath := envOr("DB_PATH", "gtd.db")
-	store, err := openStore(dbPath)
+	driver, dsn, err := dbFromEnv()
 	if err != nil {
 		return nil, err
 	}
+	if driver == driverSQLite {
+		if err := applyStagedRestore(); err != nil {
+			return nil, err
+		}
+	}
+	store, err := openStore(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.attachReplica(os.Getenv("DB_READ_DSN")); err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	if err := store.attachShards(shardPathsFromEnv()); err != nil {
+		_ = store.Close()
+		return nil, err
+	}
 
+	client, err := botHTTPClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
 	token := mustEnv("BOT_TOKEN")
-	bot, err := tgbotapi.NewBotAPI(token)
+	bot, err := newBotAPIFromEnv(token, client)
 	if err != nil {
 		return nil, err
 	}
@@ -391,12 +1356,30 @@ func initApp() (*App, error) {
 	ttlMin, _ := strconv.Atoi(envOr("TTL_MINUTES", "10"))
 	ttl := time.Duration(ttlMin) * time.Minute
 
+	filters, err := newFilterChainFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	cal, err := NewGoogleCalendarClientFromEnv(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	events := NewEventBus()
+	eventLogFromEnv(events)
+
 	return &App{
-		Bot:        bot,
-		Store:      store,
-		TZ:         loc,
-		TTL:        ttl,
-		ChatStates: map[int64]*ChatState{},
+		Bot:      bot,
+		Store:    newEventStore(store, events),
+		TZ:       loc,
+		TTL:      ttl,
+		Filters:  filters,
+		Calendar: cal,
+		States:   NewStateManager(ttl, storeStateHooks(store)),
+		HTTP:     client,
+		Events:   events,
+		Scripts:  newScriptRunnerFromEnv(store),
 	}, nil
 }
 
@@ -406,12 +1389,70 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer app.Store.DB.Close()
+	ha, err := leaseFromEnv(app.Store)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, stop := signalContext()
+	defer stop()
 
-	ctx := context.Background()
+	weather, err := NewWeatherClientFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	routing, err := NewRoutingClientFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	app.Media, err = NewMediaManagerFromEnv(app.Bot, app.HTTP, app.Store)
+	if err != nil {
+		log.Fatal(err)
+	}
+	app.STT, err = NewTranscriberFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	app.TTS, err = NewSpeakerFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	app.Digest = NewDigest(app.Store, app.Calendar, weather, app.Scripts)
+	startMetricsServer(ctx)
+	app.startScriptEvents()
+	app.startWatches()
+	app.initPlugins()
 
 	log.Printf("bot started as @%s", app.Bot.Self.UserName)
-	if err := app.run(ctx); err != nil {
+	specs, err := extraBotsFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	apps := []*App{app}
+	for _, spec := range specs {
+		b, err := app.newBot(spec, weather)
+		if err != nil {
+			log.Fatal(err)
+		}
+		apps = append(apps, b)
+	}
+
+	var sched *Scheduler
+	err = ha.Run(ctx, func(ctx context.Context) error {
+		sched = NewScheduler(app.Bot, app.Store, app.Calendar, app.Digest, routing, app.Media, app.Events, app.TZ)
+		for _, b := range apps[1:] {
+			sched.Attach(NewScheduler(b.Bot, b.Store, b.Calendar, b.Digest, routing, b.Media, b.Events, b.TZ))
+		}
+		sched.Start(ctx)
+		for _, b := range apps {
+			b.runPluginSchedule(ctx)
+		}
+		return runBots(ctx, apps)
+	})
+	if err != nil {
+		for _, b := range apps {
+			_ = b.Store.Close()
+		}
 		log.Fatal(err)
 	}
+	app.shutdown(sched, apps[1:])
 }
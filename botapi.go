@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// The Bot API client can go through a proxy (TELEGRAM_PROXY, http:// or
+// socks5://) and talk to a self-hosted Bot API server (BOT_API_URL), which
+// lifts the 20 MB download limit.
+
+func botHTTPClientFromEnv() (*http.Client, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if raw := strings.TrimSpace(os.Getenv("TELEGRAM_PROXY")); raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("TELEGRAM_PROXY: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("TELEGRAM_PROXY: unsupported scheme %q", u.Scheme)
+		}
+		tr.Proxy = http.ProxyURL(u)
+	}
+	// Long polling holds requests for up to 30s
+	return &http.Client{Transport: tr, Timeout: 60 * time.Second}, nil
+}
+
+func botAPIBase() string {
+	return strings.TrimRight(envOr("BOT_API_URL", "https://api.telegram.org"), "/")
+}
+
+// localBotAPI reports whether BOT_API_URL points at a server started with
+// --local, whose getFile returns absolute paths on its own disk.
+func localBotAPI() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("BOT_API_LOCAL")), "true")
+}
+
+func newBotAPIFromEnv(token string, client *http.Client) (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithClient(token, botAPIBase()+"/bot%s/%s", client)
+}
+
+// fileURL is tgbotapi.File.Link for the configured server.
+func fileURL(token, filePath string) string {
+	return fmt.Sprintf("%s/file/bot%s/%s", botAPIBase(), token, strings.TrimPrefix(filePath, "/"))
+}
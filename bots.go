@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// More bots in one process: BOTS="family=<token>,work=<token>" runs each
+// token beside BOT_TOKEN. A user's chat id is the same with every bot, so
+// each extra bot keeps its data apart — in its own SQLite file (DB_PATH with
+// "-<name>" before the extension, or DB_PATH_<NAME>) or, on Postgres, at
+// DATABASE_URL_<NAME>. The rest is shared: one scheduler loop ticks every
+// bot's chats, the webhooks share LISTEN_ADDR (at WEBHOOK_URL/<name>), and
+// the nightly backup and /restore cover the extra databases too. Stored
+// media of an extra bot lives under media/bots/<name>/ and only that bot's
+// sweep looks there.
+
+var botNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+type BotSpec struct {
+	Name  string
+	Token string
+}
+
+func extraBotsFromEnv() ([]BotSpec, error) {
+	var out []BotSpec
+	seen := map[string]bool{}
+	for _, part := range strings.Split(os.Getenv("BOTS"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, token, ok := strings.Cut(part, "=")
+		name, token = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(token)
+		if !ok || token == "" || !botNameRe.MatchString(name) {
+			return nil, fmt.Errorf("BOTS: want name=token, got %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("BOTS: %s listed twice", name)
+		}
+		seen[name] = true
+		out = append(out, BotSpec{Name: name, Token: token})
+	}
+	return out, nil
+}
+
+// botDBPath is the SQLite file of an extra bot.
+func botDBPath(name string) string {
+	if p := strings.TrimSpace(os.Getenv("DB_PATH_" + strings.ToUpper(name))); p != "" {
+		return p
+	}
+	main := envOr("DB_PATH", "gtd.db")
+	ext := filepath.Ext(main)
+	return strings.TrimSuffix(main, ext) + "-" + name + ext
+}
+
+func botDB(name string) (driver, dsn string, err error) {
+	driver, _, err = dbFromEnv()
+	if err != nil || driver == driverSQLite {
+		return driver, botDBPath(name), err
+	}
+	key := "DATABASE_URL_" + strings.ToUpper(name)
+	if dsn = strings.TrimSpace(os.Getenv(key)); dsn == "" {
+		return "", "", fmt.Errorf("bot %s: DB_DRIVER=postgres requires %s", name, key)
+	}
+	return driver, dsn, nil
+}
+
+// botSnapshotFiles names the extra bots' SQLite files in snapshots,
+// "bot-<name>-<file>", with their paths.
+func botSnapshotFiles() (names, paths []string) {
+	specs, err := extraBotsFromEnv()
+	if err != nil {
+		return nil, nil
+	}
+	for _, b := range specs {
+		p := botDBPath(b.Name)
+		names = append(names, fmt.Sprintf("bot-%s-%s", b.Name, filepath.Base(p)))
+		paths = append(paths, p)
+	}
+	return names, paths
+}
+
+var (
+	botStoresMu sync.Mutex
+	botStores   []*sqlStore // extra bots' databases in BOTS order, for snapshots
+)
+
+// snapshotBots copies the extra bots' SQLite databases into dir.
+func snapshotBots(ctx context.Context, dir string) ([]string, error) {
+	botStoresMu.Lock()
+	stores := append([]*sqlStore(nil), botStores...)
+	botStoresMu.Unlock()
+	names, _ := botSnapshotFiles()
+	var out []string
+	for i, st := range stores {
+		db, ok := unwrapConn(st.DB).(*sql.DB)
+		if !ok || st.driver != driverSQLite || i >= len(names) {
+			continue
+		}
+		dst := filepath.Join(dir, names[i])
+		if err := sqliteBackup(ctx, db, dst); err != nil {
+			return out, fmt.Errorf("%s: %w", names[i], err)
+		}
+		out = append(out, dst)
+	}
+	return out, nil
+}
+
+// newBot builds the App of an extra bot. It shares the process-wide
+// clients with a and has its own database, state and event bus.
+func (a *App) newBot(spec BotSpec, weather WeatherClient) (*App, error) {
+	driver, dsn, err := botDB(spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	store, err := openStore(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("bot %s: %w", spec.Name, err)
+	}
+	bot, err := newBotAPIFromEnv(spec.Token, a.HTTP)
+	if err != nil {
+		_ = store.Close()
+		return nil, fmt.Errorf("bot %s: %w", spec.Name, err)
+	}
+
+	events := NewEventBus()
+	eventLogFromEnv(events)
+	b := &App{
+		Name:     spec.Name,
+		Bot:      bot,
+		Store:    newEventStore(store, events),
+		TZ:       a.TZ,
+		TTL:      a.TTL,
+		Filters:  a.Filters,
+		Calendar: a.Calendar,
+		States:   NewStateManager(a.TTL, storeStateHooks(store)),
+		HTTP:     a.HTTP,
+		Events:   events,
+		Scripts:  newScriptRunnerFromEnv(store),
+		STT:      a.STT,
+		TTS:      a.TTS,
+	}
+	b.Media, err = NewMediaManagerFromEnv(bot, a.HTTP, b.Store)
+	if err != nil {
+		_ = store.Close()
+		return nil, fmt.Errorf("bot %s: %w", spec.Name, err)
+	}
+	b.Media.prefix = "media/bots/" + spec.Name + "/"
+	b.Digest = NewDigest(b.Store, b.Calendar, weather, b.Scripts)
+	b.startScriptEvents()
+	b.startWatches()
+	b.initPlugins()
+
+	botStoresMu.Lock()
+	botStores = append(botStores, store)
+	botStoresMu.Unlock()
+	log.Printf("bot %s started as @%s", spec.Name, bot.Self.UserName)
+	return b, nil
+}
+
+// runBots runs every bot until ctx ends or one of them fails.
+func runBots(ctx context.Context, apps []*App) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(apps))
+	for _, app := range apps {
+		go func() {
+			err := app.run(ctx)
+			if err != nil && app.Name != "" {
+				err = fmt.Errorf("bot %s: %w", app.Name, err)
+			}
+			cancel()
+			errs <- err
+		}()
+	}
+	var first error
+	for range apps {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
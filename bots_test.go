@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtraBotsFromEnv(t *testing.T) {
+	tests := []struct {
+		env  string
+		want []BotSpec
+		err  bool
+	}{
+		{"", nil, false},
+		{" , ", nil, false},
+		{"family=123:abc", []BotSpec{{Name: "family", Token: "123:abc"}}, false},
+		{" Family = 1:a , work=2:b,", []BotSpec{{Name: "family", Token: "1:a"}, {Name: "work", Token: "2:b"}}, false},
+		{"family_2=1:a", []BotSpec{{Name: "family_2", Token: "1:a"}}, false},
+		{"family", nil, true},
+		{"family=", nil, true},
+		{"=1:a", nil, true},
+		{"my-bot=1:a", nil, true},
+		{"../x=1:a", nil, true},
+		{"семья=1:a", nil, true},
+		{"a=1:a,A=2:b", nil, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("BOTS", tt.env)
+		got, err := extraBotsFromEnv()
+		if tt.err {
+			if err == nil {
+				t.Errorf("BOTS=%q: got %v, want an error", tt.env, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("BOTS=%q: got %v, %v; want %v", tt.env, got, err, tt.want)
+		}
+	}
+}
+
+func TestBotDBPath(t *testing.T) {
+	tests := []struct {
+		dbPath, own, want string
+	}{
+		{"", "", "gtd-family.db"},
+		{"/data/gtd.db", "", "/data/gtd-family.db"},
+		{"/data/gtd", "", "/data/gtd-family"},
+		{"/data/gtd.db", "/srv/family.sqlite", "/srv/family.sqlite"},
+	}
+	for _, tt := range tests {
+		t.Setenv("DB_PATH", tt.dbPath)
+		t.Setenv("DB_PATH_FAMILY", tt.own)
+		if got := botDBPath("family"); got != tt.want {
+			t.Errorf("DB_PATH=%q DB_PATH_FAMILY=%q: got %q, want %q", tt.dbPath, tt.own, got, tt.want)
+		}
+	}
+}
@@ -2,57 +2,185 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 )
 
 // CalendarClient is a tiny interface the scheduler uses.
-// For MVP, we keep it minimal: get today's schedule as a preformatted text.
+// For MVP, we keep it minimal: get today's schedule as a preformatted text
+// and the raw events of a time range for features that react to events.
 type CalendarClient interface {
-	GetTodaySchedule(ctx context.Context, now time.Time) (string, error)
+	GetTodaySchedule(ctx context.Context, lang string, now time.Time) (string, error)
+	ListEvents(ctx context.Context, from, to time.Time) ([]CalendarEvent, error)
 }
 
-// googleCalendarClient is intentionally minimal here.
-// полноценный OAuth2 + Calendar API можно добавить позже.
+type CalendarEvent struct {
+	ID       string
+	Summary  string
+	Location string
+	Start    time.Time
+	End      time.Time
+	AllDay   bool
+}
+
+const gcalEventsURL = "https://www.googleapis.com/calendar/v3/calendars/%s/events"
+
+// googleCalendarClient reads events with the Calendar v3 API. Access comes
+// from an OAuth2 refresh token: GCAL_REFRESH_TOKEN, or the token file that
+// /gcalauth (device-code flow) writes.
 type googleCalendarClient struct {
-	enabled bool
+	enabled    bool
 	calendarID string
-	tz *time.Location
+	tz         *time.Location
+	oauth      *googleOAuth
+	http       *http.Client
 }
 
 func NewGoogleCalendarClientFromEnv(tz *time.Location) (CalendarClient, error) {
 	// If GCAL_DISABLED=true or missing config -> return disabled client
 	if strings.EqualFold(strings.TrimSpace(os.Getenv("GCAL_DISABLED")), "true") {
-		return &googleCalendarClient{enabled:false, tz: tz}, nil
+		return &googleCalendarClient{enabled: false, tz: tz}, nil
 	}
 	calID := strings.TrimSpace(os.Getenv("GCAL_CALENDAR_ID"))
-	// For real integration you'd also require OAuth credentials.
-	// In this MVP we treat missing calendar id as "disabled".
 	if calID == "" {
-		return &googleCalendarClient{enabled:false, tz: tz}, nil
+		return &googleCalendarClient{enabled: false, tz: tz}, nil
+	}
+	oauth, err := googleOAuthFromEnv()
+	if err != nil {
+		return nil, err
 	}
 	return &googleCalendarClient{
-		enabled: true,
+		enabled:    true,
 		calendarID: calID,
-		tz: tz,
+		tz:         tz,
+		oauth:      oauth,
+		http:       &http.Client{Timeout: 15 * time.Second},
 	}, nil
 }
 
-func (c *googleCalendarClient) GetTodaySchedule(ctx context.Context, now time.Time) (string, error) {
+func (c *googleCalendarClient) GetTodaySchedule(ctx context.Context, lang string, now time.Time) (string, error) {
+	if !c.enabled {
+		return tr(lang, "schedule.unset"), nil
+	}
+	now = now.In(c.tz)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, c.tz)
+	events, err := c.ListEvents(ctx, day, day.AddDate(0, 0, 1))
+	if errors.Is(err, ErrCalendarNotAuthorized) {
+		return tr(lang, "schedule.unauthorized"), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(events) == 0 {
+		return tr(lang, "schedule.empty", day.Format("02.01")), nil
+	}
+	return tr(lang, "schedule.title", day.Format("02.01")) + "\n" + formatAgenda(lang, events, nil, c.tz), nil
+}
+
+type gcalTime struct {
+	DateTime string `json:"dateTime"`
+	Date     string `json:"date"`
+}
+
+func (t gcalTime) parse(tz *time.Location) (time.Time, bool, error) {
+	if t.DateTime != "" {
+		v, err := time.Parse(time.RFC3339, t.DateTime)
+		return v, false, err
+	}
+	v, err := time.ParseInLocation("2006-01-02", t.Date, tz)
+	return v, true, err
+}
+
+func (c *googleCalendarClient) ListEvents(ctx context.Context, from, to time.Time) ([]CalendarEvent, error) {
 	if !c.enabled {
-		return "Расписание из Google Calendar не настроено (GCAL_CALENDAR_ID не задан).", nil
+		return nil, ErrCalendarNotConfigured
+	}
+	token, err := c.oauth.AccessToken(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// TODO: Реальная интеграция:
-	// 1) OAuth2 / service account
-	// 2) calendar/v3 Events.List with timeMin/timeMax for "today" in tz
-	// 3) форматирование событий
-	//
-	// Пока возвращаем заглушку, чтобы остальная система работала.
-	return fmt.Sprintf("Расписание на сегодня (%s):\n(заглушка) календарь=%s", now.In(c.tz).Format("2006-01-02"), c.calendarID), nil
+	var out []CalendarEvent
+	pageToken := ""
+	for {
+		q := url.Values{
+			"timeMin":      {from.UTC().Format(time.RFC3339)},
+			"timeMax":      {to.UTC().Format(time.RFC3339)},
+			"singleEvents": {"true"},
+			"orderBy":      {"startTime"},
+			"maxResults":   {"250"},
+			"timeZone":     {c.tz.String()},
+		}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		u := fmt.Sprintf(gcalEventsURL, url.PathEscape(c.calendarID)) + "?" + q.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var res struct {
+			Items []struct {
+				ID       string   `json:"id"`
+				Status   string   `json:"status"`
+				Summary  string   `json:"summary"`
+				Location string   `json:"location"`
+				Start    gcalTime `json:"start"`
+				End      gcalTime `json:"end"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("calendar events: %s", resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, it := range res.Items {
+			if it.Status == "cancelled" {
+				continue
+			}
+			start, allDay, err := it.Start.parse(c.tz)
+			if err != nil {
+				continue
+			}
+			end, _, _ := it.End.parse(c.tz)
+			summary := it.Summary
+			if summary == "" {
+				summary = "(без названия)"
+			}
+			out = append(out, CalendarEvent{
+				ID:       it.ID,
+				Summary:  summary,
+				Location: it.Location,
+				Start:    start,
+				End:      end,
+				AllDay:   allDay,
+			})
+		}
+		if res.NextPageToken == "" {
+			return out, nil
+		}
+		pageToken = res.NextPageToken
+	}
 }
 
-var ErrCalendarNotConfigured = errors.New("calendar not configured")
+var (
+	ErrCalendarNotConfigured = errors.New("calendar not configured")
+	ErrCalendarNotAuthorized = errors.New("calendar not authorized")
+)
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (a *App) captureTopic(chatID int64) (string, bool) {
+	st := a.States.Get(chatID)
+	return st.CaptureTopic, st.CaptureTopic != ""
+}
+
+func (a *App) startCapture(chatID int64, topic string) {
+	a.States.Update(chatID, func(st *ChatState) {
+		st.CaptureTopic = topic
+		st.Captured = nil
+	})
+}
+
+func (a *App) appendCapture(chatID int64, line string) {
+	a.States.Update(chatID, func(st *ChatState) {
+		if st.CaptureTopic != "" {
+			st.Captured = append(st.Captured, line)
+		}
+	})
+}
+
+// stopCapture ends the session and returns what was captured.
+func (a *App) stopCapture(chatID int64) (string, []string, bool) {
+	var topic string
+	var lines []string
+	a.States.Update(chatID, func(st *ChatState) {
+		topic, lines = st.CaptureTopic, st.Captured
+		st.CaptureTopic, st.Captured = "", nil
+	})
+	return topic, lines, topic != ""
+}
+
+func (a *App) handleCapture(chatID int64, arg string) {
+	topic := TopicBasket
+	if arg = strings.TrimSpace(arg); arg != "" {
+		t, ok := a.topicFromButton(chatID, arg)
+		if !ok {
+			a.send(chatID, a.tr(chatID, "capture.unknown"))
+			return
+		}
+		topic = t
+	}
+	if _, ok := a.captureTopic(chatID); ok {
+		a.send(chatID, a.tr(chatID, "capture.running"))
+		return
+	}
+	a.startCapture(chatID, topic)
+	a.send(chatID, a.tr(chatID, "capture.started", topicLabel(a.Store.Lang(chatID), topic)))
+}
+
+// captureBatch stores one message of a capture session without replying.
+func (a *App) captureBatch(chatID int64, prov Provenance, topic, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		id, res := a.storeCapture(chatID, prov, topic, line)
+		switch res {
+		case captureStored:
+			a.appendCapture(chatID, fmt.Sprintf("#%d %s", id, line))
+		case captureDuplicate:
+			a.appendCapture(chatID, a.tr(chatID, "capture.dup", id, line))
+		case captureRejected:
+			a.appendCapture(chatID, a.tr(chatID, "capture.rejected", line))
+		case captureFailed:
+			a.appendCapture(chatID, a.tr(chatID, "capture.failed", line))
+		case captureLocked:
+			a.appendCapture(chatID, a.tr(chatID, "capture.locked", line))
+		}
+	}
+}
+
+func (a *App) handleStopCapture(chatID int64) {
+	topic, lines, ok := a.stopCapture(chatID)
+	if !ok {
+		a.send(chatID, a.tr(chatID, "capture.none"))
+		return
+	}
+	if len(lines) == 0 {
+		a.send(chatID, a.tr(chatID, "capture.empty"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "capture.done", topicLabel(a.Store.Lang(chatID), topic), len(lines), strings.Join(lines, "\n")))
+}
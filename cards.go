@@ -0,0 +1,212 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Loyalty cards for shopping. A card is a photo of the card or its barcode
+// sent with the caption "/card <магазин>", or just the number:
+// "/card add <магазин> <номер>". "/card <магазин>" shows it at the till,
+// and opening the shopping list offers the chat's cards as buttons
+// ("card:<id>"). Photos are kept as Telegram file_ids.
+
+const maxCardNameRunes = 20
+
+type Card struct {
+	ID     int64
+	Name   string
+	Code   string
+	FileID string
+}
+
+func (s *sqlStore) PutCard(chatID int64, c Card) error {
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO cards(chat_id, store, name, code, file_id, created_at) VALUES(?,?,?,?,?,?)
+		 ON CONFLICT(chat_id, store) DO UPDATE SET name=excluded.name, code=excluded.code, file_id=excluded.file_id`,
+		chatID, normalizeText(c.Name), c.Name, c.Code, c.FileID, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (s *sqlStore) Cards(chatID int64) ([]Card, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT id, name, code, file_id FROM cards WHERE chat_id=? ORDER BY name`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Card
+	for rows.Next() {
+		var c Card
+		if err := rows.Scan(&c.ID, &c.Name, &c.Code, &c.FileID); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// FindCard looks a card up by store name, or by its id when name is "#<id>".
+func (s *sqlStore) FindCard(chatID int64, name string) (*Card, error) {
+	var c Card
+	q, arg := `SELECT id, name, code, file_id FROM cards WHERE chat_id=? AND store=?`, any(normalizeText(name))
+	if id, err := strconv.ParseInt(strings.TrimPrefix(name, "#"), 10, 64); err == nil && strings.HasPrefix(name, "#") {
+		q, arg = `SELECT id, name, code, file_id FROM cards WHERE chat_id=? AND id=?`, id
+	}
+	err := s.readDB(chatID).QueryRow(q, chatID, arg).Scan(&c.ID, &c.Name, &c.Code, &c.FileID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *sqlStore) DeleteCard(chatID int64, name string) (bool, error) {
+	res, err := s.db(chatID).Exec(`DELETE FROM cards WHERE chat_id=? AND store=?`, chatID, normalizeText(name))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// handleCard handles "/card [<магазин> | add <магазин> <номер> | del <магазин>]".
+func (a *App) handleCard(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	sub, rest, _ := strings.Cut(arg, " ")
+	rest = strings.TrimSpace(rest)
+	switch sub {
+	case "":
+		cards, err := a.Store.Cards(chatID)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if len(cards) == 0 {
+			a.send(chatID, a.tr(chatID, "cards.none"))
+			return
+		}
+		msg := tgbotapi.NewMessage(chatID, a.tr(chatID, "cards.header"))
+		msg.ReplyMarkup = cardKeyboard(cards)
+		_, _ = a.Bot.Send(msg)
+	case "add":
+		i := strings.LastIndex(rest, " ")
+		if i < 0 {
+			a.send(chatID, a.tr(chatID, "card.add.usage"))
+			return
+		}
+		name, code := strings.TrimSpace(rest[:i]), strings.TrimSpace(rest[i:])
+		a.saveCard(chatID, Card{Name: name, Code: code})
+	case "del":
+		ok, err := a.Store.DeleteCard(chatID, rest)
+		switch {
+		case err != nil:
+			a.send(chatID, a.tr(chatID, "err.write"))
+		case !ok:
+			a.send(chatID, a.tr(chatID, "card.notfound", rest))
+		default:
+			a.send(chatID, a.tr(chatID, "card.deleted", rest))
+		}
+	default:
+		c, err := a.Store.FindCard(chatID, arg)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if c == nil {
+			a.send(chatID, a.tr(chatID, "card.notfound", arg)+" "+a.tr(chatID, "card.hint"))
+			return
+		}
+		a.sendCard(chatID, *c)
+	}
+}
+
+func (a *App) saveCard(chatID int64, c Card) {
+	if c.Name == "" || utf8.RuneCountInString(c.Name) > maxCardNameRunes {
+		a.send(chatID, a.tr(chatID, "card.name.long", maxCardNameRunes))
+		return
+	}
+	if err := a.Store.PutCard(chatID, c); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "card.saved", c.Name, c.Name))
+}
+
+// captureCardPhoto saves a photo captioned "/card <магазин> [номер]".
+func (a *App) captureCardPhoto(m *tgbotapi.Message) bool {
+	if len(m.Photo) == 0 {
+		return false
+	}
+	cmd, rest, _ := strings.Cut(strings.TrimSpace(m.Caption), " ")
+	if cmd, _, _ = strings.Cut(cmd, "@"); cmd != "/card" {
+		return false
+	}
+	name, code := strings.TrimSpace(rest), ""
+	if i := strings.LastIndex(name, " "); i > 0 && strings.ContainsAny(name[i:], "0123456789") {
+		name, code = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i:])
+	}
+	a.saveCard(m.Chat.ID, Card{Name: name, Code: code, FileID: m.Photo[len(m.Photo)-1].FileID})
+	return true
+}
+
+func (a *App) sendCard(chatID int64, c Card) {
+	caption := "💳 " + c.Name
+	if c.Code != "" {
+		caption += "\n" + c.Code
+	}
+	if c.FileID == "" {
+		a.send(chatID, caption)
+		return
+	}
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileID(c.FileID))
+	photo.Caption = caption
+	_, _ = a.Bot.Send(photo)
+}
+
+func cardKeyboard(cards []Card) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, c := range cards {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("💳 "+c.Name, fmt.Sprintf("card:%d", c.ID)))
+		if len(row) == 3 {
+			rows, row = append(rows, row), nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// offerCards follows the shopping list with the chat's cards, if any.
+func (a *App) offerCards(chatID int64) {
+	cards, err := a.Store.Cards(chatID)
+	if err != nil || len(cards) == 0 {
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, a.tr(chatID, "cards.offer"))
+	msg.ReplyMarkup = cardKeyboard(cards)
+	_, _ = a.Bot.Send(msg)
+}
+
+// handleCardCallback handles "card:<id>".
+func (a *App) handleCardCallback(cq *tgbotapi.CallbackQuery, idStr string) {
+	chatID := cq.Message.Chat.ID
+	c, err := a.Store.FindCard(chatID, "#"+idStr)
+	if err != nil || c == nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "card.gone")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	a.sendCard(chatID, *c)
+}
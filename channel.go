@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// A chat can mirror its morning digest into a private channel, which then
+// works as a read-only archive of agendas.
+
+func (s *sqlStore) DigestChannel(chatID int64) (int64, bool) {
+	v, ok, _ := s.GetKV(chatKey(chatID, "digest_channel"))
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	return id, err == nil
+}
+
+// canPost reports whether a member may publish in a channel.
+func canPost(m tgbotapi.ChatMember) bool {
+	return m.IsCreator() || (m.IsAdministrator() && m.CanPostMessages)
+}
+
+func (a *App) resolveChannel(ref string) (tgbotapi.Chat, error) {
+	cfg := tgbotapi.ChatInfoConfig{}
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		cfg.ChatID = id
+	} else {
+		cfg.SuperGroupUsername = "@" + strings.TrimPrefix(ref, "@")
+	}
+	return a.Bot.GetChat(cfg)
+}
+
+func (a *App) channelMember(channelID, userID int64) (tgbotapi.ChatMember, error) {
+	return a.Bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: channelID, UserID: userID},
+	})
+}
+
+// handleDigestChannel handles "/digestchannel <id|@username>" and
+// "/digestchannel off". Both the bot and the user must be able to post in
+// the channel.
+func (a *App) handleDigestChannel(m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	ref := strings.TrimSpace(m.CommandArguments())
+	lang := a.Store.Lang(chatID)
+	switch ref {
+	case "":
+		if id, ok := a.Store.DigestChannel(chatID); ok {
+			a.send(chatID, tr(lang, "channel.status", id))
+			return
+		}
+		a.send(chatID, tr(lang, "channel.usage"))
+		return
+	case "off":
+		if err := a.Store.DeleteKV(chatKey(chatID, "digest_channel")); err != nil {
+			a.send(chatID, tr(lang, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "channel.off"))
+		return
+	}
+
+	ch, err := a.resolveChannel(ref)
+	if err != nil || ch.Type != "channel" {
+		a.send(chatID, tr(lang, "channel.notfound"))
+		return
+	}
+	if bot, err := a.channelMember(ch.ID, a.Bot.Self.ID); err != nil || !canPost(bot) {
+		a.send(chatID, tr(lang, "channel.cantpost"))
+		return
+	}
+	if m.From == nil {
+		return
+	}
+	if user, err := a.channelMember(ch.ID, m.From.ID); err != nil || !(user.IsCreator() || user.IsAdministrator()) {
+		a.send(chatID, tr(lang, "channel.notadmin"))
+		return
+	}
+	if err := a.Store.SetKV(chatKey(chatID, "digest_channel"), strconv.FormatInt(ch.ID, 10)); err != nil {
+		a.send(chatID, tr(lang, "err.write"))
+		return
+	}
+	a.send(chatID, tr(lang, "channel.set", ch.Title))
+}
+
+// sendDigestToChannel mirrors an already composed digest.
+func (s *Scheduler) sendDigestToChannel(chatID int64, text string) {
+	channelID, ok := s.store.DigestChannel(chatID)
+	if !ok {
+		return
+	}
+	_ = s.deliver("digest_channel", tgbotapi.NewMessage(channelID, text))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every chat that talks to the bot is recorded in the chats table. The
+// scheduler serves each of them that has active items, plus CHAT_ID, so
+// several people can each get their own digest and reminder broadcasts.
+// Calendar jobs stay with CHAT_ID: the calendar belongs to the owner.
+
+func (s *sqlStore) RegisterChat(chatID int64, now time.Time) error {
+	_, err := s.DB.Exec(
+		`INSERT INTO chats(chat_id, first_seen) VALUES(?,?) ON CONFLICT(chat_id) DO NOTHING`,
+		chatID, now.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// backfillChats registers chats that had items before the chats table.
+func (s *sqlStore) backfillChats() error {
+	_, err := s.DB.Exec(
+		`INSERT INTO chats(chat_id, first_seen)
+		 SELECT chat_id, MIN(created_at) FROM items WHERE 1=1 GROUP BY chat_id
+		 ON CONFLICT(chat_id) DO NOTHING`,
+	)
+	return err
+}
+
+// ScheduledChats returns registered chats that have active items.
+func (s *sqlStore) ScheduledChats() ([]int64, error) {
+	active := map[int64]bool{}
+	for _, sh := range s.Shards() {
+		rows, err := sh.DB.Query(`SELECT DISTINCT chat_id FROM items WHERE status=?`, StatusActive)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			active[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := s.DB.Query(`SELECT chat_id FROM chats ORDER BY chat_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if active[id] {
+			out = append(out, id)
+		}
+	}
+	return out, rows.Err()
+}
+
+var registeredChats sync.Map
+
+// registerChat records the chat once per process.
+func (a *App) registerChat(chatID int64) {
+	if _, seen := registeredChats.LoadOrStore(chatID, true); seen {
+		return
+	}
+	if err := a.Store.RegisterChat(chatID, time.Now()); err != nil {
+		registeredChats.Delete(chatID)
+		log.Printf("register chat %d error: %v", chatID, err)
+	}
+}
+
+// homeChatID is CHAT_ID, the owner's chat.
+func homeChatID() (int64, bool) {
+	id, err := strconv.ParseInt(strings.TrimSpace(os.Getenv("CHAT_ID")), 10, 64)
+	return id, err == nil
+}
+
+// chats lists the chats to serve on this tick: CHAT_ID first, then every
+// registered chat with active items.
+func (s *Scheduler) chats() []int64 {
+	var out []int64
+	home, hasHome := homeChatID()
+	if hasHome {
+		out = append(out, home)
+	}
+	ids, err := s.store.ScheduledChats()
+	if err != nil {
+		log.Printf("scheduler: chats error: %v", err)
+		return out
+	}
+	for _, id := range ids {
+		if !hasHome || id != home {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Compact mode hides the persistent reply keyboard; everything is reachable
+// through commands and inline buttons.
+
+func (s *sqlStore) Compact(chatID int64) bool {
+	v, _, _ := s.GetKV(chatKey(chatID, "compact"))
+	return v == "on"
+}
+
+// replyMarkup returns the reply keyboard for a message, or nil in compact
+// mode. The first message after compact mode is enabled carries a
+// ReplyKeyboardRemove so the old keyboard disappears from the client.
+func (a *App) replyMarkup(chatID int64) any {
+	if !a.Store.Compact(chatID) {
+		return a.defaultKeyboard(chatID)
+	}
+	key := chatKey(chatID, "keyboard_removed")
+	if _, done, _ := a.Store.GetKV(key); done {
+		return nil
+	}
+	if err := a.Store.SetKV(key, "1"); err != nil {
+		log.Printf("compact migration error: %v", err)
+	}
+	return tgbotapi.NewRemoveKeyboard(true)
+}
+
+func (a *App) handleCompact(chatID int64, arg string) {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "on":
+		_ = a.Store.DeleteKV(chatKey(chatID, "keyboard_removed"))
+		if err := a.Store.SetKV(chatKey(chatID, "compact"), "on"); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "compact.on"))
+	case "off":
+		if err := a.Store.SetKV(chatKey(chatID, "compact"), "off"); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "compact.off"))
+	default:
+		key := "compact.status.off"
+		if a.Store.Compact(chatID) {
+			key = "compact.status.on"
+		}
+		a.send(chatID, a.tr(chatID, key)+" /compact on | off")
+	}
+}
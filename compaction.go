@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Completed items older than COMPACT_AFTER_DAYS are rolled into one
+// item_history row per chat, month and topic: a count plus the original
+// items as gzipped JSON.
+
+type ArchivedItem struct {
+	ID          int64    `json:"id"`
+	Text        string   `json:"text"`
+	CreatedAt   string   `json:"created_at"`
+	CompletedAt string   `json:"completed_at"`
+	CompletedBy string   `json:"completed_by,omitempty"`
+	Notes       []string `json:"notes,omitempty"`
+	Secret      bool     `json:"secret,omitempty"`
+}
+
+// shownText is the archived text as listings may show it.
+func (it ArchivedItem) shownText() string {
+	if it.Secret {
+		return secretMask
+	}
+	return it.Text
+}
+
+type HistoryRow struct {
+	Month string
+	Topic string
+	Count int
+}
+
+func compactAfter() time.Duration {
+	n, err := strconv.Atoi(envOr("COMPACT_AFTER_DAYS", "365"))
+	if err != nil || n <= 0 {
+		n = 365
+	}
+	return time.Duration(n) * 24 * time.Hour
+}
+
+func packArchive(items []ArchivedItem) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(items); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unpackArchive(blob []byte) ([]ArchivedItem, error) {
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(blob))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var items []ArchivedItem
+	err = json.Unmarshal(raw, &items)
+	return items, err
+}
+
+type compactKey struct {
+	chatID int64
+	month  string
+	topic  string
+}
+
+// CompactHistory moves done items completed before `before` into
+// item_history and returns how many items were rolled up.
+func (s *sqlStore) CompactHistory(before time.Time) (int, error) {
+	total := 0
+	for _, sh := range s.Shards() {
+		n, err := sh.compactHistory(before)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (s *sqlStore) compactHistory(before time.Time) (int, error) {
+	rows, err := s.DB.Query(
+		`SELECT id, chat_id, topic, text, created_at, completed_at, completed_by_name, secret FROM items
+		 WHERE status IN (?, ?) AND completed_at<>'' AND completed_at<? ORDER BY id`,
+		StatusDone, StatusArchived, before.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	groups := map[compactKey][]ArchivedItem{}
+	var ids []int64
+	for rows.Next() {
+		var a ArchivedItem
+		var k compactKey
+		if err := rows.Scan(&a.ID, &k.chatID, &k.topic, &a.Text, &a.CreatedAt, &a.CompletedAt, &a.CompletedBy, &a.Secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		k.month = a.CompletedAt[:7]
+		groups[k] = append(groups[k], a)
+		ids = append(ids, a.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	err = s.inTx(func(tx *sqlStore) error {
+		for k, items := range groups {
+			for i := range items {
+				notes, err := tx.ListNotes(k.chatID, items[i].ID)
+				if err != nil {
+					return err
+				}
+				for _, n := range notes {
+					items[i].Notes = append(items[i].Notes, n.Author+": "+n.Text)
+				}
+			}
+
+			var blob []byte
+			err := tx.DB.QueryRow(`SELECT items FROM item_history WHERE chat_id=? AND month=? AND topic=?`, k.chatID, k.month, k.topic).Scan(&blob)
+			if err == nil {
+				prev, err := unpackArchive(blob)
+				if err != nil {
+					return fmt.Errorf("history %d %s %s: %w", k.chatID, k.month, k.topic, err)
+				}
+				items = append(prev, items...)
+			}
+			packed, err := packArchive(items)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.DB.Exec(
+				`INSERT INTO item_history(chat_id, month, topic, count, items) VALUES(?,?,?,?,?)
+				 ON CONFLICT(chat_id, month, topic) DO UPDATE SET count=excluded.count, items=excluded.items`,
+				k.chatID, k.month, k.topic, len(items), packed,
+			); err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			for _, q := range []string{
+				`DELETE FROM items WHERE id=?`,
+				`DELETE FROM item_notes WHERE item_id=?`,
+				`DELETE FROM item_threads WHERE item_id=?`,
+				`DELETE FROM goal_links WHERE item_id=?`,
+			} {
+				if _, err := tx.DB.Exec(q, id); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+func (s *sqlStore) History(chatID int64) ([]HistoryRow, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT month, topic, count FROM item_history WHERE chat_id=? ORDER BY month DESC, topic`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []HistoryRow
+	for rows.Next() {
+		var h HistoryRow
+		if err := rows.Scan(&h.Month, &h.Topic, &h.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) HistoryItems(chatID int64, month string) (map[string][]ArchivedItem, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT topic, items FROM item_history WHERE chat_id=? AND month=? ORDER BY topic`, chatID, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string][]ArchivedItem{}
+	for rows.Next() {
+		var topic string
+		var blob []byte
+		if err := rows.Scan(&topic, &blob); err != nil {
+			return nil, err
+		}
+		items, err := unpackArchive(blob)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			items[i].Text = s.openText(chatID, items[i].Text)
+		}
+		out[topic] = items
+	}
+	return out, rows.Err()
+}
+
+func (s *Scheduler) compactHistory(now time.Time) {
+	n, err := s.store.CompactHistory(now.Add(-compactAfter()))
+	if err != nil {
+		log.Printf("scheduler: compact history error: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("scheduler: compacted %d old items", n)
+	}
+}
+
+// handleHistory handles "/history" (months with counts) and
+// "/history 2024-03" (that month's archived items).
+func (a *App) handleHistory(chatID int64, arg string) {
+	lang := a.Store.Lang(chatID)
+	month := strings.TrimSpace(arg)
+	if month == "" {
+		rows, err := a.Store.History(chatID)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if len(rows) == 0 {
+			a.send(chatID, tr(lang, "history.empty"))
+			return
+		}
+		var b strings.Builder
+		b.WriteString(tr(lang, "archive.title") + ":\n")
+		last := ""
+		for _, h := range rows {
+			if h.Month != last {
+				fmt.Fprintf(&b, "\n%s:", h.Month)
+				last = h.Month
+			}
+			fmt.Fprintf(&b, " %s %d", topicLabel(lang, h.Topic), h.Count)
+		}
+		b.WriteString("\n\n" + tr(lang, "history.hint"))
+		a.send(chatID, b.String())
+		return
+	}
+
+	if _, err := time.Parse("2006-01", month); err != nil {
+		a.send(chatID, tr(lang, "history.usage"))
+		return
+	}
+	groups, err := a.Store.HistoryItems(chatID, month)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if len(groups) == 0 {
+		a.send(chatID, a.tr(chatID, "empty"))
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s:", tr(lang, "archive.title"), month)
+	topics := make([]string, 0, len(groups))
+	for t := range groups {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	for _, topic := range topics {
+		fmt.Fprintf(&b, "\n\n%s:", topicLabel(lang, topic))
+		for _, it := range groups[topic] {
+			fmt.Fprintf(&b, "\n#%d %s", it.ID, it.shownText())
+		}
+	}
+	a.send(chatID, b.String())
+}
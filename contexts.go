@@ -0,0 +1,117 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// contextRe matches GTD context tags such as @дом, @компьютер, @звонки.
+// A tag must start a word, so e-mail addresses are not picked up.
+var contextRe = regexp.MustCompile(`(?:^|\s)(@[\p{L}\p{N}_]+)`)
+
+func parseContexts(text string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, m := range contextRe.FindAllStringSubmatch(text, -1) {
+		tag := normalizeText(m[1])
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+func hasContext(it Item, tag string) bool {
+	for _, t := range parseContexts(it.Text) {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func contextsKeyboard(tags []string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, t := range tags {
+		data := "ctx:" + t
+		if len(data) > 64 {
+			continue
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(t, data))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func (a *App) handleContexts(chatID int64) {
+	items, err := a.Store.ListActive(chatID, "")
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+
+	counts := map[string]int{}
+	for _, it := range items {
+		for _, t := range parseContexts(it.Text) {
+			counts[t]++
+		}
+	}
+	if len(counts) == 0 {
+		a.send(chatID, a.tr(chatID, "contexts.none"))
+		return
+	}
+
+	tags := make([]string, 0, len(counts))
+	for t := range counts {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	msg := tgbotapi.NewMessage(chatID, a.tr(chatID, "contexts.pick"))
+	msg.ReplyMarkup = contextsKeyboard(tags)
+	_, _ = a.Bot.Send(msg)
+}
+
+func (a *App) sendContextItems(chatID int64, tag string) {
+	items, err := a.Store.ListActive(chatID, "")
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+
+	byTopic := map[string][]Item{}
+	var topics []string
+	for _, it := range items {
+		if !hasContext(it, tag) {
+			continue
+		}
+		if _, ok := byTopic[it.Topic]; !ok {
+			topics = append(topics, it.Topic)
+		}
+		byTopic[it.Topic] = append(byTopic[it.Topic], it)
+	}
+
+	a.send(chatID, a.tr(chatID, "contexts.header", tag))
+	if len(topics) == 0 {
+		a.send(chatID, a.tr(chatID, "empty"))
+		return
+	}
+	for _, t := range topics {
+		a.sendItemsOneByOne(chatID, t, byTopic[t])
+	}
+}
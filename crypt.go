@@ -0,0 +1,490 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// /encrypt <пароль> turns on encryption of item text for the chat. The key
+// is derived from the passphrase and never stored: the database keeps only
+// the salt and a check value, and the key lives in memory for unlockTTL
+// after /unlock. While the chat is locked nothing can be captured, and
+// listings show 🔒 in place of encrypted text.
+//
+// Duplicate detection keeps working through a keyed hash in norm; search
+// decrypts in Go. Notes and the compacted history are not re-encrypted:
+// archived items keep whatever text they had.
+
+const (
+	sealedPrefix  = "enc1:"
+	sealedPlacard = "🔒"
+	unlockTTL     = 30 * time.Minute
+	kdfIterations = 600_000
+)
+
+var errLocked = errors.New("chat is locked")
+
+type cryptConfig struct {
+	Salt  []byte `json:"salt"`
+	Check string `json:"check"`
+}
+
+type chatKeys struct {
+	aead  cipher.AEAD
+	mac   []byte
+	until time.Time
+}
+
+func deriveKeys(pass string, salt []byte) (*chatKeys, error) {
+	raw, err := pbkdf2.Key(sha256.New, pass, salt, kdfIterations, 64)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(raw[:32])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &chatKeys{aead: aead, mac: raw[32:]}, nil
+}
+
+func (k *chatKeys) hash(s string) string {
+	h := hmac.New(sha256.New, k.mac)
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (k *chatKeys) check() string { return k.hash("gtdbot-check") }
+
+func (k *chatKeys) norm(text string) string { return "h:" + k.hash(normalizeText(text)) }
+
+func (k *chatKeys) seal(text string) string {
+	nonce := make([]byte, k.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(k.aead.Seal(nonce, nonce, []byte(text), nil))
+}
+
+func (k *chatKeys) open(text string) (string, bool) {
+	raw, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(text, sealedPrefix))
+	if err != nil || len(raw) < k.aead.NonceSize() {
+		return "", false
+	}
+	n := k.aead.NonceSize()
+	plain, err := k.aead.Open(nil, raw[:n], raw[n:], nil)
+	return string(plain), err == nil
+}
+
+// vault holds the keys of unlocked chats. It is shared by the store, its
+// shards and transactions.
+type vault struct {
+	mu   sync.Mutex
+	keys map[int64]*chatKeys
+}
+
+func newVault() *vault {
+	return &vault{keys: map[int64]*chatKeys{}}
+}
+
+func (v *vault) get(chatID int64) *chatKeys {
+	if v == nil {
+		return nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	k := v.keys[chatID]
+	if k != nil && time.Now().After(k.until) {
+		delete(v.keys, chatID)
+		return nil
+	}
+	return k
+}
+
+func (v *vault) put(chatID int64, k *chatKeys) {
+	k.until = time.Now().Add(unlockTTL)
+	v.mu.Lock()
+	v.keys[chatID] = k
+	v.mu.Unlock()
+}
+
+func (v *vault) drop(chatID int64) {
+	v.mu.Lock()
+	delete(v.keys, chatID)
+	v.mu.Unlock()
+}
+
+func (s *sqlStore) cryptConfig(chatID int64) (*cryptConfig, error) {
+	raw, ok, err := s.GetKV(chatKey(chatID, "crypt"))
+	if err != nil || !ok {
+		return nil, err
+	}
+	var cfg cryptConfig
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, fmt.Errorf("crypt config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// sealText is the stored text and norm for an item of chatID.
+func (s *sqlStore) sealText(chatID int64, text string) (string, string, error) {
+	cfg, err := s.cryptConfig(chatID)
+	if err != nil {
+		return "", "", err
+	}
+	if cfg == nil {
+		return text, normalizeText(text), nil
+	}
+	k := s.vault.get(chatID)
+	if k == nil {
+		return "", "", errLocked
+	}
+	return k.seal(text), k.norm(text), nil
+}
+
+// normFor is what sealText would store as norm for text.
+func (s *sqlStore) normFor(chatID int64, text string) string {
+	if k := s.vault.get(chatID); k != nil {
+		return k.norm(text)
+	}
+	return normalizeText(text)
+}
+
+// openText decrypts stored item text; 🔒 while the chat is locked.
+func (s *sqlStore) openText(chatID int64, text string) string {
+	if !strings.HasPrefix(text, sealedPrefix) {
+		return text
+	}
+	if k := s.vault.get(chatID); k != nil {
+		if plain, ok := k.open(text); ok {
+			return plain
+		}
+	}
+	return sealedPlacard
+}
+
+func (s *sqlStore) Locked(chatID int64) bool {
+	cfg, err := s.cryptConfig(chatID)
+	if err != nil {
+		log.Printf("crypt config error: %v", err)
+		return true
+	}
+	return cfg != nil && s.vault.get(chatID) == nil
+}
+
+func (s *sqlStore) Encrypted(chatID int64) bool {
+	cfg, _ := s.cryptConfig(chatID)
+	return cfg != nil
+}
+
+// unlockKeys derives and verifies the chat's keys; nil for a wrong passphrase.
+func (s *sqlStore) unlockKeys(chatID int64, pass string) (*chatKeys, error) {
+	cfg, err := s.cryptConfig(chatID)
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+	k, err := deriveKeys(pass, cfg.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(k.check()), []byte(cfg.Check)) {
+		return nil, nil
+	}
+	return k, nil
+}
+
+func (s *sqlStore) Unlock(chatID int64, pass string) (bool, error) {
+	k, err := s.unlockKeys(chatID, pass)
+	if err != nil || k == nil {
+		return false, err
+	}
+	s.vault.put(chatID, k)
+	return true, nil
+}
+
+func (s *sqlStore) Lock(chatID int64) {
+	s.vault.drop(chatID)
+}
+
+// EnableCrypt sets the passphrase and encrypts the chat's items, active
+// and done, and unlocks the chat.
+func (s *sqlStore) EnableCrypt(chatID int64, pass string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	k, err := deriveKeys(pass, salt)
+	if err != nil {
+		return err
+	}
+	cfg, _ := json.Marshal(cryptConfig{Salt: salt, Check: k.check()})
+	err = s.For(chatID).inTx(func(tx *sqlStore) error {
+		if err := tx.SetKV(chatKey(chatID, "crypt"), string(cfg)); err != nil {
+			return err
+		}
+		seal := func(text string) (string, string) {
+			if strings.HasPrefix(text, sealedPrefix) {
+				return "", ""
+			}
+			return k.seal(text), k.norm(text)
+		}
+		if err := tx.rewriteTexts(chatID, seal); err != nil {
+			return err
+		}
+		return tx.rewriteUndoSnapshots(chatID, seal)
+	})
+	if err == nil {
+		s.vault.put(chatID, k)
+	}
+	return err
+}
+
+// DisableCrypt decrypts the chat's items and forgets the passphrase; false
+// for a wrong passphrase.
+func (s *sqlStore) DisableCrypt(chatID int64, pass string) (bool, error) {
+	k, err := s.unlockKeys(chatID, pass)
+	if err != nil || k == nil {
+		return false, err
+	}
+	err = s.For(chatID).inTx(func(tx *sqlStore) error {
+		open := func(text string) (string, string) {
+			plain, ok := k.open(text)
+			if !ok {
+				return "", ""
+			}
+			return plain, normalizeText(plain)
+		}
+		if err := tx.rewriteTexts(chatID, open); err != nil {
+			return err
+		}
+		if err := tx.rewriteUndoSnapshots(chatID, open); err != nil {
+			return err
+		}
+		return tx.DeleteKV(chatKey(chatID, "crypt"))
+	})
+	if err == nil {
+		s.vault.drop(chatID)
+	}
+	return err == nil, err
+}
+
+// rewriteTexts replaces each item's text and norm with fn's result; an
+// empty text leaves the item as it is.
+func (s *sqlStore) rewriteTexts(chatID int64, fn func(text string) (string, string)) error {
+	rows, err := s.DB.Query(`SELECT id, text FROM items WHERE chat_id=?`, chatID)
+	if err != nil {
+		return err
+	}
+	texts := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return err
+		}
+		texts[id] = text
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, text := range texts {
+		text, norm := fn(text)
+		if text == "" {
+			continue
+		}
+		if _, err := s.DB.Exec(`UPDATE items SET text=?, norm=? WHERE chat_id=? AND id=?`, text, norm, chatID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteUndoSnapshots applies fn to the item rows kept for undoing
+// deletes (see itemSnapshot), so /undo brings an item back the way the
+// chat now stores text.
+func (s *sqlStore) rewriteUndoSnapshots(chatID int64, fn func(text string) (string, string)) error {
+	rows, err := s.DB.Query(`SELECT id, payload FROM item_actions WHERE chat_id=? AND action=?`, chatID, actionDeleted)
+	if err != nil {
+		return err
+	}
+	payloads := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var payload string
+		if err := rows.Scan(&id, &payload); err != nil {
+			rows.Close()
+			return err
+		}
+		payloads[id] = payload
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, payload := range payloads {
+		dec := json.NewDecoder(strings.NewReader(payload))
+		dec.UseNumber()
+		var row map[string]any
+		if err := dec.Decode(&row); err != nil {
+			return fmt.Errorf("undo log %d: %w", id, err)
+		}
+		old, _ := row["text"].(string)
+		text, norm := fn(old)
+		if text == "" {
+			continue
+		}
+		row["text"], row["norm"] = text, norm
+		b, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if _, err := s.DB.Exec(`UPDATE item_actions SET payload=? WHERE id=?`, string(b), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchSealed is SearchItems for an encrypted chat: every item is
+// decrypted and matched here.
+func (s *sqlStore) searchSealed(chatID int64, query string, limit int) ([]Item, error) {
+	if s.vault.get(chatID) == nil {
+		return nil, errLocked
+	}
+	rows, err := s.readDB(chatID).Query(
+		`SELECT id, chat_id, topic, text, flagged, secret, created_at, completed_at FROM items WHERE chat_id=? ORDER BY id DESC`,
+		chatID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	norm := normalizeText(query)
+	var out []Item
+	for rows.Next() && len(out) < limit {
+		var it Item
+		var created, completed string
+		if err := rows.Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &it.Flagged, &it.Secret, &created, &completed); err != nil {
+			return nil, err
+		}
+		it.Text = s.openText(chatID, it.Text)
+		if !strings.Contains(normalizeText(it.Text), norm) {
+			continue
+		}
+		it.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		it.CompletedAt, _ = time.Parse(time.RFC3339, completed)
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+// deletePassphrase removes the message carrying a passphrase from the chat.
+func (a *App) deletePassphrase(m *tgbotapi.Message) {
+	if _, err := a.Bot.Request(tgbotapi.NewDeleteMessage(m.Chat.ID, m.MessageID)); err != nil {
+		a.send(m.Chat.ID, a.tr(m.Chat.ID, "crypt.delete.failed"))
+	}
+}
+
+// handleEncrypt handles "/encrypt <пароль>".
+func (a *App) handleEncrypt(m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	pass := strings.TrimSpace(m.CommandArguments())
+	if pass == "" {
+		a.send(chatID, a.tr(chatID, "crypt.encrypt.usage"))
+		return
+	}
+	a.deletePassphrase(m)
+	if a.Store.Encrypted(chatID) {
+		a.send(chatID, a.tr(chatID, "crypt.already"))
+		return
+	}
+	if len([]rune(pass)) < 8 {
+		a.send(chatID, a.tr(chatID, "crypt.short"))
+		return
+	}
+	if err := a.Store.EnableCrypt(chatID, pass); err != nil {
+		log.Printf("enable crypt error: %v", err)
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "crypt.enabled", int(unlockTTL.Minutes())))
+}
+
+// handleDecrypt handles "/decrypt <пароль>".
+func (a *App) handleDecrypt(m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	pass := strings.TrimSpace(m.CommandArguments())
+	if pass == "" {
+		a.send(chatID, a.tr(chatID, "crypt.decrypt.usage"))
+		return
+	}
+	a.deletePassphrase(m)
+	ok, err := a.Store.DisableCrypt(chatID, pass)
+	switch {
+	case err != nil:
+		log.Printf("disable crypt error: %v", err)
+		a.send(chatID, a.tr(chatID, "err.write"))
+	case !ok && !a.Store.Encrypted(chatID):
+		a.send(chatID, a.tr(chatID, "crypt.off"))
+	case !ok:
+		a.send(chatID, a.tr(chatID, "crypt.wrong"))
+	default:
+		a.send(chatID, a.tr(chatID, "crypt.disabled"))
+	}
+}
+
+// handleUnlock handles "/unlock <пароль>".
+func (a *App) handleUnlock(m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	pass := strings.TrimSpace(m.CommandArguments())
+	if !a.Store.Encrypted(chatID) {
+		a.send(chatID, a.tr(chatID, "crypt.off")+" "+a.tr(chatID, "crypt.hint"))
+		return
+	}
+	if pass == "" {
+		a.send(chatID, a.tr(chatID, "crypt.unlock.usage"))
+		return
+	}
+	a.deletePassphrase(m)
+	ok, err := a.Store.Unlock(chatID, pass)
+	switch {
+	case err != nil:
+		a.send(chatID, a.tr(chatID, "err.read"))
+	case !ok:
+		a.send(chatID, a.tr(chatID, "crypt.wrong"))
+	default:
+		a.send(chatID, a.tr(chatID, "crypt.unlocked", int(unlockTTL.Minutes())))
+	}
+}
+
+// handleLock handles "/lock".
+func (a *App) handleLock(chatID int64) {
+	if !a.Store.Encrypted(chatID) {
+		a.send(chatID, a.tr(chatID, "crypt.off")+" "+a.tr(chatID, "crypt.hint"))
+		return
+	}
+	a.Store.Lock(chatID)
+	a.send(chatID, a.tr(chatID, "crypt.locked"))
+}
+
+func (a *App) sendLocked(chatID int64) {
+	a.send(chatID, a.tr(chatID, "crypt.closed"))
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	k, err := deriveKeys("пароль", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := deriveKeys("другой пароль", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, text := range []string{
+		"",
+		"buy milk",
+		"позвонить маме 📞 ~15м",
+		strings.Repeat("длинный текст ", 500),
+	} {
+		sealed := k.seal(text)
+		if !strings.HasPrefix(sealed, sealedPrefix) || strings.Contains(sealed, "маме") {
+			t.Errorf("seal(%.20q) = %.40q, want %s and no plaintext", text, sealed, sealedPrefix)
+		}
+		if got, ok := k.open(sealed); !ok || got != text {
+			t.Errorf("open(seal(%.20q)) = %.20q, %v", text, got, ok)
+		}
+		if k.seal(text) == sealed {
+			t.Errorf("seal(%.20q) twice gave the same ciphertext", text)
+		}
+		if _, ok := other.open(sealed); ok {
+			t.Errorf("seal(%.20q) opened with another passphrase", text)
+		}
+		tampered := sealed[:len(sealed)-2] + "AA"
+		if tampered != sealed {
+			if _, ok := k.open(tampered); ok {
+				t.Errorf("tampered seal(%.20q) opened", text)
+			}
+		}
+	}
+
+	for _, bad := range []string{sealedPrefix, sealedPrefix + "!!!", "plain text"} {
+		if _, ok := k.open(bad); ok {
+			t.Errorf("open(%q) succeeded", bad)
+		}
+	}
+
+	if k.norm("Купить Молоко") != k.norm("купить молоко") {
+		t.Error("norm differs for texts that normalize the same")
+	}
+	if k.norm("молоко") == other.norm("молоко") {
+		t.Error("norm is the same under different passphrases")
+	}
+	again, _ := deriveKeys("пароль", salt)
+	if again.check() != k.check() {
+		t.Error("check differs for the same passphrase and salt")
+	}
+	if other.check() == k.check() {
+		t.Error("check is the same for another passphrase")
+	}
+}
+
+func TestCryptStoreRoundTrip(t *testing.T) {
+	s, err := openStore(driverSQLite, filepath.Join(t.TempDir(), "crypt.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	const chatID = 42
+	id, err := s.AddItem(chatID, TopicTasks, "секретный план")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := func() string {
+		var text string
+		if err := s.DB.QueryRow(`SELECT text FROM items WHERE id=?`, id).Scan(&text); err != nil {
+			t.Fatal(err)
+		}
+		return text
+	}
+
+	if err := s.EnableCrypt(chatID, "пароль"); err != nil {
+		t.Fatal(err)
+	}
+	if text := stored(); !strings.HasPrefix(text, sealedPrefix) {
+		t.Fatalf("stored text after /encrypt = %q, want it sealed", text)
+	}
+	if it, err := s.GetItem(chatID, id); err != nil || it.Text != "секретный план" {
+		t.Fatalf("GetItem while unlocked = %+v, %v", it, err)
+	}
+
+	s.Lock(chatID)
+	if !s.Locked(chatID) {
+		t.Fatal("chat not locked after Lock")
+	}
+	if it, _ := s.GetItem(chatID, id); it.Text != sealedPlacard {
+		t.Errorf("GetItem while locked = %q, want %s", it.Text, sealedPlacard)
+	}
+	if _, err := s.AddItem(chatID, TopicTasks, "ещё"); err == nil {
+		t.Error("AddItem worked while locked")
+	}
+	if ok, err := s.Unlock(chatID, "не тот"); ok || err != nil {
+		t.Errorf("Unlock with a wrong passphrase = %v, %v", ok, err)
+	}
+	if ok, err := s.Unlock(chatID, "пароль"); !ok || err != nil {
+		t.Fatalf("Unlock = %v, %v", ok, err)
+	}
+
+	if ok, err := s.DisableCrypt(chatID, "пароль"); !ok || err != nil {
+		t.Fatalf("DisableCrypt = %v, %v", ok, err)
+	}
+	if text := stored(); text != "секретный план" {
+		t.Errorf("stored text after /decrypt = %q", text)
+	}
+	if s.Encrypted(chatID) {
+		t.Error("chat still encrypted after DisableCrypt")
+	}
+}
+
+func TestCryptSealsUndoSnapshots(t *testing.T) {
+	s, err := openStore(driverSQLite, filepath.Join(t.TempDir(), "crypt.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	const chatID = 42
+	id, err := s.AddItem(chatID, TopicTasks, "секретный план")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteItem(chatID, id); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := func() string {
+		var p string
+		if err := s.DB.QueryRow(`SELECT payload FROM item_actions WHERE chat_id=? AND action=?`, chatID, actionDeleted).Scan(&p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	if err := s.EnableCrypt(chatID, "пароль"); err != nil {
+		t.Fatal(err)
+	}
+	if p := snapshot(); strings.Contains(p, "секретный") || !strings.Contains(p, sealedPrefix) {
+		t.Errorf("undo snapshot after /encrypt = %s, want the text sealed", p)
+	}
+	if ok, err := s.DisableCrypt(chatID, "пароль"); !ok || err != nil {
+		t.Fatalf("DisableCrypt = %v, %v", ok, err)
+	}
+	if p := snapshot(); !strings.Contains(p, "секретный план") {
+		t.Errorf("undo snapshot after /decrypt = %s, want the plain text", p)
+	}
+	u, err := s.Undo(chatID)
+	if err != nil || u == nil || u.Item.Text != "секретный план" {
+		t.Errorf("Undo = %+v, %v", u, err)
+	}
+}
+
+func TestCryptMigrateRoundTrip(t *testing.T) {
+	s, err := openStore(driverSQLite, filepath.Join(t.TempDir(), "crypt.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	const source, target = 42, 43
+	if _, err := s.AddItem(source, TopicTasks, "секретный план"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.EnableCrypt(source, "пароль"); err != nil {
+		t.Fatal(err)
+	}
+	s.Lock(source)
+	if _, err := s.ExportChat(source); !errors.Is(err, errLocked) {
+		t.Errorf("ExportChat while locked = %v, want errLocked", err)
+	}
+	if ok, err := s.Unlock(source, "пароль"); !ok || err != nil {
+		t.Fatalf("Unlock = %v, %v", ok, err)
+	}
+	doc, err := s.ExportChat(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Items) != 1 || doc.Items[0]["text"] != "секретный план" || doc.Items[0]["norm"] != normalizeText("секретный план") {
+		t.Fatalf("exported items = %v, want the plain text", doc.Items)
+	}
+
+	// through JSON, as /migrate import reads it
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc, err = decodeMigration(LangRU, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.EnableCrypt(target, "другой"); err != nil {
+		t.Fatal(err)
+	}
+	s.Lock(target)
+	if _, err := s.ImportChat(target, doc); !errors.Is(err, errLocked) {
+		t.Errorf("ImportChat while locked = %v, want errLocked", err)
+	}
+	if ok, err := s.Unlock(target, "другой"); !ok || err != nil {
+		t.Fatalf("Unlock = %v, %v", ok, err)
+	}
+	if doc, err = decodeMigration(LangRU, b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ImportChat(target, doc); err != nil {
+		t.Fatal(err)
+	}
+	items, err := s.ListActive(target, "")
+	if err != nil || len(items) != 1 || items[0].Text != "секретный план" {
+		t.Fatalf("imported items = %+v, %v", items, err)
+	}
+	var text string
+	if err := s.DB.QueryRow(`SELECT text FROM items WHERE chat_id=?`, target).Scan(&text); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text, sealedPrefix) {
+		t.Errorf("stored text in the encrypted target = %q, want it sealed", text)
+	}
+}
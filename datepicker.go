@@ -0,0 +1,217 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Due dates are picked with an inline keyboard: a month grid, then a row of
+// time presets. Callback data is "dp:<id>:<step>":
+//
+//	m:2006-01            show that month
+//	d:2006-01-02         day chosen, ask for the time
+//	t:2006-01-02T15:04   set the due date
+//	e:2006-01-02         set the date without a time
+//	x                    clear the due date
+//	n                    no-op (labels, padding)
+
+var monthNames = map[string][]string{
+	LangRU: {"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь", "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"},
+	LangEN: {"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+}
+
+var pickerWeekdays = map[string][]string{
+	LangRU: {"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"},
+	LangEN: {"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"},
+}
+
+// A due date without a time is stored as the end of that day.
+const dueAllDay = "23:59"
+
+func (s *sqlStore) SetDue(chatID, id int64, due time.Time) error {
+	v := ""
+	if !due.IsZero() {
+		v = due.UTC().Format(time.RFC3339)
+	}
+	_, err := s.db(chatID).Exec(`UPDATE items SET due_at=? WHERE chat_id=? AND id=?`, v, chatID, id)
+	return err
+}
+
+func (s *sqlStore) Due(chatID, id int64) (time.Time, error) {
+	var v string
+	err := s.db(chatID).QueryRow(`SELECT due_at FROM items WHERE chat_id=? AND id=?`, chatID, id).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) || v == "" {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func formatDue(due time.Time, tz *time.Location) string {
+	due = due.In(tz)
+	if due.Format("15:04") == dueAllDay {
+		return due.Format("02.01.2006")
+	}
+	return due.Format("02.01.2006 15:04")
+}
+
+func pickerData(id int64, step string) string {
+	return fmt.Sprintf("dp:%d:%s", id, step)
+}
+
+// monthPicker renders the grid for the month containing `month`. Days
+// before today are shown but not clickable.
+func monthPicker(lang string, id int64, month, today time.Time) tgbotapi.InlineKeyboardMarkup {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	noop := pickerData(id, "n")
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("‹", pickerData(id, "m:"+first.AddDate(0, -1, 0).Format("2006-01"))),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %d", monthNames[lang][first.Month()-1], first.Year()), noop),
+			tgbotapi.NewInlineKeyboardButtonData("›", pickerData(id, "m:"+first.AddDate(0, 1, 0).Format("2006-01"))),
+		),
+	}
+	var head []tgbotapi.InlineKeyboardButton
+	for _, wd := range pickerWeekdays[lang] {
+		head = append(head, tgbotapi.NewInlineKeyboardButtonData(wd, noop))
+	}
+	rows = append(rows, head)
+
+	todayKey := today.Format("2006-01-02")
+	week := make([]tgbotapi.InlineKeyboardButton, 0, 7)
+	for i := 0; i < (int(first.Weekday())+6)%7; i++ {
+		week = append(week, tgbotapi.NewInlineKeyboardButtonData(" ", noop))
+	}
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		key := d.Format("2006-01-02")
+		switch {
+		case key < todayKey:
+			week = append(week, tgbotapi.NewInlineKeyboardButtonData("·", noop))
+		case key == todayKey:
+			week = append(week, tgbotapi.NewInlineKeyboardButtonData("["+strconv.Itoa(d.Day())+"]", pickerData(id, "d:"+key)))
+		default:
+			week = append(week, tgbotapi.NewInlineKeyboardButtonData(strconv.Itoa(d.Day()), pickerData(id, "d:"+key)))
+		}
+		if len(week) == 7 {
+			rows = append(rows, week)
+			week = make([]tgbotapi.InlineKeyboardButton, 0, 7)
+		}
+	}
+	if len(week) > 0 {
+		for len(week) < 7 {
+			week = append(week, tgbotapi.NewInlineKeyboardButtonData(" ", noop))
+		}
+		rows = append(rows, week)
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "picker.today"), pickerData(id, "d:"+todayKey)),
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "picker.tomorrow"), pickerData(id, "d:"+today.AddDate(0, 0, 1).Format("2006-01-02"))),
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "picker.none"), pickerData(id, "x")),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// timePicker offers time-of-day presets for the chosen day (see freePresets).
+func timePicker(lang string, id int64, day string, presets []TimePreset) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, p := range presets {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(p.Name+" "+p.Clock, pickerData(id, "t:"+day+"T"+p.Clock)))
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr(lang, "picker.allday"), pickerData(id, "e:"+day)),
+			tgbotapi.NewInlineKeyboardButtonData("‹ "+tr(lang, "picker.back"), pickerData(id, "m:"+day[:7])),
+		),
+	)...)
+}
+
+// openDatePicker sends the picker for an item as a new message.
+func (a *App) openDatePicker(chatID, id int64) {
+	lang := a.Store.Lang(chatID)
+	it, err := a.Store.GetItem(chatID, id)
+	if err != nil || it == nil {
+		a.send(chatID, tr(lang, "item.missing"))
+		return
+	}
+	text := tr(lang, "picker.title", it.ID, shownText(*it))
+	if due, err := a.Store.Due(chatID, id); err == nil && !due.IsZero() {
+		text += "\n" + tr(lang, "picker.current", formatDue(due, a.tz(chatID)))
+	}
+	now := time.Now().In(a.tz(chatID))
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = monthPicker(lang, id, now, now)
+	_, _ = a.Bot.Send(msg)
+}
+
+// handleDue handles "/due <id>".
+func (a *App) handleDue(chatID int64, arg string) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(arg), "#"), 10, 64)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "due.usage"))
+		return
+	}
+	a.openDatePicker(chatID, id)
+}
+
+// handleDatePickerCallback handles "dp:<id>:<step>".
+func (a *App) handleDatePickerCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	msgID := cq.Message.MessageID
+	idStr, step, _ := strings.Cut(data, ":")
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	kind, value, _ := strings.Cut(step, ":")
+	lang := a.Store.Lang(chatID)
+	now := time.Now().In(a.tz(chatID))
+
+	var due time.Time
+	switch kind {
+	case "m":
+		month, err := time.ParseInLocation("2006-01", value, a.tz(chatID))
+		if err != nil {
+			break
+		}
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, msgID, monthPicker(lang, id, month, now)))
+	case "d":
+		day, err := time.ParseInLocation("2006-01-02", value, a.tz(chatID))
+		if err != nil {
+			break
+		}
+		presets := freePresets(lang, a.Store.TimePresets(chatID), busyBlocks(a.Store, chatID), day)
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, msgID, timePicker(lang, id, value, presets)))
+	case "t", "e", "x":
+		if kind == "e" {
+			value += "T" + dueAllDay
+		}
+		if kind != "x" {
+			t, err := time.ParseInLocation("2006-01-02T15:04", value, a.tz(chatID))
+			if err != nil {
+				break
+			}
+			due = t
+		}
+		if err := a.Store.SetDue(chatID, id, due); err != nil {
+			_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.write")))
+			return
+		}
+		text := tr(lang, "picker.cleared", id)
+		if !due.IsZero() {
+			text = tr(lang, "picker.set", id, formatDue(due, a.tz(chatID)))
+		}
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageText(chatID, msgID, text))
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+}
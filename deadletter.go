@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Scheduled sends that keep failing are parked in dead_letters instead of
+// being lost in the log. The owner lists and retries them with /deadletters.
+
+const deliverAttempts = 3
+
+type DeadLetter struct {
+	ID        int64
+	Kind      string
+	ChatID    int64
+	Payload   string
+	Error     string
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// deadMessage is the stored form of a MessageConfig.
+type deadMessage struct {
+	ChatID      int64  `json:"chat_id"`
+	Text        string `json:"text"`
+	ReplyTo     int    `json:"reply_to,omitempty"`
+	ReplyMarkup any    `json:"reply_markup,omitempty"`
+}
+
+func (s *sqlStore) AddDeadLetter(kind string, chatID int64, payload string, attempts int, cause error) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := s.DB.Exec(
+		`INSERT INTO dead_letters(kind, chat_id, payload, error, attempts, created_at, last_attempt_at) VALUES(?,?,?,?,?,?,?)`,
+		kind, chatID, payload, cause.Error(), attempts, now, now,
+	)
+	return err
+}
+
+func (s *sqlStore) ListDeadLetters(limit int) ([]DeadLetter, error) {
+	rows, err := s.DB.Query(
+		`SELECT id, kind, chat_id, payload, error, attempts, created_at FROM dead_letters ORDER BY id DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []DeadLetter
+	for rows.Next() {
+		var d DeadLetter
+		var created string
+		if err := rows.Scan(&d.ID, &d.Kind, &d.ChatID, &d.Payload, &d.Error, &d.Attempts, &created); err != nil {
+			return nil, err
+		}
+		d.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) GetDeadLetter(id int64) (*DeadLetter, error) {
+	var d DeadLetter
+	var created string
+	err := s.DB.QueryRow(
+		`SELECT id, kind, chat_id, payload, error, attempts, created_at FROM dead_letters WHERE id=?`, id,
+	).Scan(&d.ID, &d.Kind, &d.ChatID, &d.Payload, &d.Error, &d.Attempts, &created)
+	if err != nil {
+		return nil, err
+	}
+	d.CreatedAt, _ = time.Parse(time.RFC3339, created)
+	return &d, nil
+}
+
+func (s *sqlStore) TouchDeadLetter(id int64, cause error) error {
+	_, err := s.DB.Exec(
+		`UPDATE dead_letters SET attempts=attempts+1, error=?, last_attempt_at=? WHERE id=?`,
+		cause.Error(), time.Now().UTC().Format(time.RFC3339), id,
+	)
+	return err
+}
+
+func (s *sqlStore) DeleteDeadLetter(id int64) error {
+	_, err := s.DB.Exec(`DELETE FROM dead_letters WHERE id=?`, id)
+	return err
+}
+
+// permanentSendError reports Telegram errors retrying won't fix (bad
+// request, bot blocked or kicked).
+func permanentSendError(err error) bool {
+	var tgErr *tgbotapi.Error
+	return errors.As(err, &tgErr) && (tgErr.Code == 400 || tgErr.Code == 403)
+}
+
+// deliver sends a message with a few retries and dead-letters it if all of
+// them fail.
+func deliver(bot *tgbotapi.BotAPI, store Store, kind string, msg tgbotapi.MessageConfig) error {
+	var err error
+	attempts := 0
+	for attempts < deliverAttempts {
+		attempts++
+		if _, err = bot.Send(msg); err == nil {
+			return nil
+		}
+		if permanentSendError(err) {
+			break
+		}
+		time.Sleep(time.Duration(attempts) * time.Second)
+	}
+
+	log.Printf("deliver %s to %d failed after %d attempt(s): %v", kind, msg.ChatID, attempts, err)
+	payload, _ := json.Marshal(deadMessage{ChatID: msg.ChatID, Text: msg.Text, ReplyTo: msg.ReplyToMessageID, ReplyMarkup: msg.ReplyMarkup})
+	if dlErr := store.AddDeadLetter(kind, msg.ChatID, string(payload), attempts, err); dlErr != nil {
+		log.Printf("dead letter error: %v", dlErr)
+	}
+	return err
+}
+
+func (s *Scheduler) deliver(kind string, msg tgbotapi.MessageConfig) error {
+	if s.hold(kind, msg) {
+		return nil
+	}
+	return deliver(s.bot, s.store, kind, msg)
+}
+
+// isOwner reports whether a message comes from the bot owner: OWNER_ID if
+// set, otherwise anyone in the CHAT_ID chat.
+func isOwner(m *tgbotapi.Message) bool {
+	if raw := strings.TrimSpace(os.Getenv("OWNER_ID")); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		return err == nil && m.From != nil && m.From.ID == id
+	}
+	raw := strings.TrimSpace(os.Getenv("CHAT_ID"))
+	return raw != "" && raw == strconv.FormatInt(m.Chat.ID, 10)
+}
+
+// isOwnerUser reports whether user is OWNER_ID. Unlike isOwner there is no
+// CHAT_ID fallback: in a group that would make every member the owner.
+func isOwnerUser(user *tgbotapi.User) bool {
+	id, err := strconv.ParseInt(strings.TrimSpace(os.Getenv("OWNER_ID")), 10, 64)
+	return err == nil && user != nil && user.ID == id
+}
+
+// ownerChatID is where owner alerts go: the OWNER_ID private chat if set,
+// otherwise CHAT_ID.
+func ownerChatID() (int64, bool) {
+	for _, name := range []string{"OWNER_ID", "CHAT_ID"} {
+		if id, err := strconv.ParseInt(strings.TrimSpace(os.Getenv(name)), 10, 64); err == nil {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func (a *App) retryDeadLetter(id int64) error {
+	d, err := a.Store.GetDeadLetter(id)
+	if err != nil {
+		return err
+	}
+	var dm deadMessage
+	if err := json.Unmarshal([]byte(d.Payload), &dm); err != nil {
+		return err
+	}
+	msg := tgbotapi.NewMessage(dm.ChatID, dm.Text)
+	msg.ReplyToMessageID = dm.ReplyTo
+	msg.AllowSendingWithoutReply = dm.ReplyTo != 0
+	msg.ReplyMarkup = dm.ReplyMarkup
+	if _, err := a.Bot.Send(msg); err != nil {
+		_ = a.Store.TouchDeadLetter(id, err)
+		return err
+	}
+	return a.Store.DeleteDeadLetter(id)
+}
+
+// handleDeadLetters handles "/deadletters", "/deadletters retry <id|all>"
+// and "/deadletters drop <id>". Owner only.
+func (a *App) handleDeadLetters(m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	if !isOwner(m) {
+		a.send(chatID, a.tr(chatID, "owner.only"))
+		return
+	}
+	fields := strings.Fields(m.CommandArguments())
+	if len(fields) == 0 {
+		list, err := a.Store.ListDeadLetters(20)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if len(list) == 0 {
+			a.send(chatID, a.tr(chatID, "deadletters.empty"))
+			return
+		}
+		var b strings.Builder
+		b.WriteString(a.tr(chatID, "deadletters.header") + "\n")
+		for _, d := range list {
+			b.WriteString(a.tr(chatID, "deadletters.line", d.ID, d.Kind, d.ChatID, d.CreatedAt.In(a.TZ).Format("02.01 15:04"), d.Attempts, d.Error) + "\n")
+		}
+		b.WriteString("\n/deadletters retry <id|all>, /deadletters drop <id>")
+		a.send(chatID, b.String())
+		return
+	}
+	if len(fields) != 2 {
+		a.send(chatID, a.tr(chatID, "deadletters.usage"))
+		return
+	}
+
+	switch fields[0] {
+	case "retry":
+		if fields[1] == "all" {
+			list, err := a.Store.ListDeadLetters(100)
+			if err != nil {
+				a.send(chatID, a.tr(chatID, "err.read"))
+				return
+			}
+			ok := 0
+			for _, d := range list {
+				if a.retryDeadLetter(d.ID) == nil {
+					ok++
+				}
+			}
+			a.send(chatID, a.tr(chatID, "deadletters.retried", ok, len(list)))
+			return
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(fields[1], "#"), 10, 64)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "deadletters.usage"))
+			return
+		}
+		if err := a.retryDeadLetter(id); err != nil {
+			a.send(chatID, a.tr(chatID, "deadletters.failed", err))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "deadletters.delivered"))
+	case "drop":
+		id, err := strconv.ParseInt(strings.TrimPrefix(fields[1], "#"), 10, 64)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "deadletters.drop.usage"))
+			return
+		}
+		if err := a.Store.DeleteDeadLetter(id); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "deadletters.dropped"))
+	default:
+		a.send(chatID, a.tr(chatID, "deadletters.usage"))
+	}
+}
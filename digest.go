@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// DigestSection is one optional block of the morning digest.
+// Render returns "" when the section has nothing to say today.
+type DigestSection interface {
+	Name() string
+	Title(lang string) string
+	Render(ctx context.Context, chatID int64, now time.Time) (string, error)
+}
+
+var defaultDigestSections = []string{"weather", "calendar"}
+
+// Digest composes the morning digest from sections enabled per chat.
+type Digest struct {
+	store    Store
+	sections []DigestSection
+}
+
+func NewDigest(store Store, cal CalendarClient, weather WeatherClient, scripts *ScriptRunner) *Digest {
+	return &Digest{
+		store: store,
+		sections: []DigestSection{
+			&weatherSection{weather: weather, store: store},
+			&calendarSection{cal: cal, store: store},
+			&quoteSection{store: store},
+			&streakSection{store: store},
+			&staleSection{store: store},
+			&scriptSection{store: store, scripts: scripts},
+			&anniversarySection{store: store},
+			&planSection{store: store},
+		},
+	}
+}
+
+func (d *Digest) section(name string) DigestSection {
+	for _, s := range d.sections {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Enabled returns the chat's section names in display order.
+func (d *Digest) Enabled(chatID int64) []string {
+	v, ok, _ := d.store.GetKV(chatKey(chatID, "digest_sections"))
+	if !ok {
+		return defaultDigestSections
+	}
+	var out []string
+	for _, n := range strings.Split(v, ",") {
+		if d.section(n) != nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func (d *Digest) SetEnabled(chatID int64, names []string) error {
+	return d.store.SetKV(chatKey(chatID, "digest_sections"), strings.Join(names, ","))
+}
+
+// Toggle switches a section on (appended last) or off.
+func (d *Digest) Toggle(chatID int64, name string) error {
+	if d.section(name) == nil {
+		return fmt.Errorf("unknown section %q", name)
+	}
+	cur := d.Enabled(chatID)
+	out := make([]string, 0, len(cur)+1)
+	found := false
+	for _, n := range cur {
+		if n == name {
+			found = true
+			continue
+		}
+		out = append(out, n)
+	}
+	if !found {
+		out = append(out, name)
+	}
+	return d.SetEnabled(chatID, out)
+}
+
+// DigestPart is a rendered section as seen by digest templates.
+type DigestPart struct {
+	Name  string
+	Title string
+	Body  string
+	Empty bool
+}
+
+func (d *Digest) renderParts(ctx context.Context, chatID int64, now time.Time) []DigestPart {
+	var parts []DigestPart
+	lang := d.store.Lang(chatID)
+	for _, name := range d.Enabled(chatID) {
+		sec := d.section(name)
+		body, err := sec.Render(ctx, chatID, now)
+		if err != nil {
+			log.Printf("digest: section %s: %v", name, err)
+			body = ""
+		}
+		parts = append(parts, DigestPart{Name: name, Title: sec.Title(lang), Body: body, Empty: body == ""})
+	}
+	return parts
+}
+
+func (d *Digest) Compose(ctx context.Context, chatID int64, now time.Time) string {
+	parts := d.renderParts(ctx, chatID, now)
+	src, ok, _ := d.store.GetKV(chatKey(chatID, "digest_template"))
+	if !ok {
+		src = defaultDigestTemplate
+	}
+	lang := d.store.Lang(chatID)
+	out, err := renderDigestTemplate(lang, src, now, parts)
+	if err != nil {
+		log.Printf("digest: chat %d template: %v; using default", chatID, err)
+		out, _ = renderDigestTemplate(lang, defaultDigestTemplate, now, parts)
+	}
+	if f, ok := activeFocus(d.store, chatID, now); ok && out != "" {
+		out = f.Banner(lang, now.Location()) + "\n\n" + out
+	}
+	return out
+}
+
+type weatherSection struct {
+	weather WeatherClient
+	store   Store
+}
+
+func (s *weatherSection) Name() string             { return "weather" }
+func (s *weatherSection) Title(lang string) string { return "" }
+
+func (s *weatherSection) Render(ctx context.Context, chatID int64, now time.Time) (string, error) {
+	return s.weather.TodayForecast(ctx, s.store.Lang(chatID), now)
+}
+
+type calendarSection struct {
+	cal   CalendarClient
+	store Store
+}
+
+func (s *calendarSection) Name() string             { return "calendar" }
+func (s *calendarSection) Title(lang string) string { return tr(lang, "calendar.title") }
+
+func (s *calendarSection) Render(ctx context.Context, chatID int64, now time.Time) (string, error) {
+	// The calendar is the owner's; other chats don't see it
+	if home, ok := homeChatID(); !ok || chatID != home {
+		return "", nil
+	}
+	if !s.store.HasFeature(chatID, FeatureCalendarSync, now) {
+		return tr(s.store.Lang(chatID), "calendar.premium"), nil
+	}
+	text, err := todayAgenda(ctx, s.cal, s.store, chatID, now, now.Location())
+	if err != nil {
+		return tr(s.store.Lang(chatID), "calendar.failed", err), nil
+	}
+	return text, nil
+}
+
+type quoteSection struct {
+	store Store
+}
+
+var digestQuotes = map[string][]string{
+	LangRU: {
+		"«Голова нужна, чтобы придумывать идеи, а не чтобы их хранить.» — Дэвид Аллен",
+		"«Нельзя сделать проект — можно сделать только следующее действие.» — Дэвид Аллен",
+		"«Если это займёт меньше двух минут — сделай сразу.»",
+		"«Всё, что не записано, будет отвлекать.»",
+		"«Обзор раз в неделю возвращает контроль.»",
+		"«Хорошо сделанное лучше хорошо сказанного.» — Бенджамин Франклин",
+		"«Начни с малого, но начни сегодня.»",
+	},
+	LangEN: {
+		"“Your mind is for having ideas, not holding them.” — David Allen",
+		"“You can't do a project — you can only do the next action.” — David Allen",
+		"“If it takes less than two minutes, do it now.”",
+		"“Whatever isn't written down will keep distracting you.”",
+		"“A weekly review gives you back control.”",
+		"“Well done is better than well said.” — Benjamin Franklin",
+		"“Start small, but start today.”",
+	},
+}
+
+func (s *quoteSection) Name() string             { return "quote" }
+func (s *quoteSection) Title(lang string) string { return "" }
+
+func (s *quoteSection) Render(ctx context.Context, chatID int64, now time.Time) (string, error) {
+	quotes := digestQuotes[s.store.Lang(chatID)]
+	return "💬 " + quotes[now.YearDay()%len(quotes)], nil
+}
+
+type streakSection struct {
+	store Store
+}
+
+func (s *streakSection) Name() string             { return "streaks" }
+func (s *streakSection) Title(lang string) string { return "" }
+
+// Render reports the run of days, ending yesterday, with at least one
+// completed item.
+func (s *streakSection) Render(ctx context.Context, chatID int64, now time.Time) (string, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	items, err := s.store.ListCompleted(chatID, today.AddDate(0, 0, -90), today)
+	if err != nil {
+		return "", err
+	}
+	days := map[string]bool{}
+	for _, it := range items {
+		days[it.CompletedAt.In(now.Location()).Format("2006-01-02")] = true
+	}
+	n := 0
+	for days[today.AddDate(0, 0, -n-1).Format("2006-01-02")] {
+		n++
+	}
+	if n == 0 {
+		return "", nil
+	}
+	return tr(s.store.Lang(chatID), "digest.streak", n), nil
+}
+
+type staleSection struct {
+	store Store
+}
+
+func (s *staleSection) Name() string             { return "stale" }
+func (s *staleSection) Title(lang string) string { return "" }
+
+func (s *staleSection) Render(ctx context.Context, chatID int64, now time.Time) (string, error) {
+	days, err := strconv.Atoi(envOr("STALE_TASK_DAYS", "14"))
+	if err != nil || days <= 0 {
+		days = 14
+	}
+	items, err := s.store.ListStale(chatID, TopicTasks, now.AddDate(0, 0, -days))
+	if err != nil {
+		return "", err
+	}
+	if f, ok := activeFocus(s.store, chatID, now); ok {
+		items = f.Filter(items)
+	}
+	if len(items) == 0 {
+		return "", nil
+	}
+	oldest := items[0]
+	return tr(s.store.Lang(chatID), "digest.stale", len(items), days, oldest.ID, shownText(oldest)), nil
+}
+
+func (d *Digest) settingsKeyboard(chatID int64) tgbotapi.InlineKeyboardMarkup {
+	lang := d.store.Lang(chatID)
+	on := map[string]bool{}
+	for _, n := range d.Enabled(chatID) {
+		on[n] = true
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, sec := range d.sections {
+		mark := "▫️"
+		if on[sec.Name()] {
+			mark = "✅"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(mark+" "+tr(lang, "digest.section."+sec.Name()), "dg:"+sec.Name()),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleDigest handles "/digest" (section settings) and "/digest now".
+func (a *App) handleDigest(ctx context.Context, chatID int64, arg string) {
+	if strings.TrimSpace(arg) == "now" {
+		text := a.Digest.Compose(ctx, chatID, time.Now().In(a.tz(chatID)))
+		if text == "" {
+			text = a.tr(chatID, "empty")
+		}
+		a.send(chatID, text)
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, a.tr(chatID, "digest.sections"))
+	msg.ReplyMarkup = a.Digest.settingsKeyboard(chatID)
+	_, _ = a.Bot.Send(msg)
+}
+
+func (a *App) handleDigestCallback(cq *tgbotapi.CallbackQuery, name string) {
+	chatID := cq.Message.Chat.ID
+	if err := a.Digest.Toggle(chatID, name); err != nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.write")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, cq.Message.MessageID, a.Digest.settingsKeyboard(chatID))
+	_, _ = a.Bot.Send(edit)
+}
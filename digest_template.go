@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	maxDigestTemplateLen = 2000
+	maxDigestLen         = 4000
+)
+
+// defaultDigestTemplate shows enabled sections in order and hides empty ones.
+const defaultDigestTemplate = `{{range .Sections}}{{if not .Empty}}{{if .Title}}{{.Title}}:
+{{end}}{{.Body}}
+
+{{end}}{{end}}`
+
+// digestTemplateData is what a digest template can see:
+// .Date, .Weekday, .Sections (list) and .S (sections by name).
+type digestTemplateData struct {
+	Date     string
+	Weekday  string
+	Sections []DigestPart
+	S        map[string]DigestPart
+}
+
+var digestWeekdays = map[string][]string{
+	LangRU: {"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+	LangEN: {"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+}
+
+var digestFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+var errDigestTooLong = errors.New("digest template output too long")
+
+// limitedBuffer stops template execution once the output exceeds the limit,
+// so a user template cannot blow up memory.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errDigestTooLong
+	}
+	return b.Buffer.Write(p)
+}
+
+func parseDigestTemplate(src string) (*template.Template, error) {
+	if len(src) > maxDigestTemplateLen {
+		return nil, fmt.Errorf("template longer than %d bytes", maxDigestTemplateLen)
+	}
+	return template.New("digest").Funcs(digestFuncs).Option("missingkey=zero").Parse(src)
+}
+
+func renderDigestTemplate(lang, src string, now time.Time, parts []DigestPart) (string, error) {
+	t, err := parseDigestTemplate(src)
+	if err != nil {
+		return "", err
+	}
+	data := digestTemplateData{
+		Date:     now.Format("02.01.2006"),
+		Weekday:  digestWeekdays[lang][now.Weekday()],
+		Sections: parts,
+		S:        map[string]DigestPart{},
+	}
+	for _, p := range parts {
+		data.S[p.Name] = p
+	}
+	buf := &limitedBuffer{limit: maxDigestLen}
+	if err := t.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// handleDigestTemplate handles "/digesttemplate" (show), "/digesttemplate reset"
+// and "/digesttemplate <template>".
+func (a *App) handleDigestTemplate(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	key := chatKey(chatID, "digest_template")
+	lang := a.Store.Lang(chatID)
+	switch arg {
+	case "":
+		cur, ok, _ := a.Store.GetKV(key)
+		if !ok {
+			cur = defaultDigestTemplate
+		}
+		a.send(chatID, tr(lang, "digesttpl.current")+"\n\n"+cur+"\n\n"+tr(lang, "digesttpl.help"))
+		return
+	case "reset":
+		_ = a.Store.DeleteKV(key)
+		a.send(chatID, tr(lang, "digesttpl.reset"))
+		return
+	}
+
+	if _, err := renderDigestTemplate(lang, arg, time.Now().In(a.tz(chatID)), nil); err != nil {
+		a.send(chatID, tr(lang, "digesttpl.error", err))
+		return
+	}
+	if err := a.Store.SetKV(key, arg); err != nil {
+		a.send(chatID, tr(lang, "err.write"))
+		return
+	}
+	a.send(chatID, tr(lang, "digesttpl.saved"))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// The dispatcher hands each chat's updates to a worker goroutine of its
+// own, so a handler waiting on bot.Send in one chat doesn't hold up the
+// rest. Within a chat updates are still handled one at a time, in order. A
+// worker lives while its chat has queued updates and exits when it drains.
+
+type dispatcher struct {
+	handle func(tgbotapi.Update)
+
+	mu     sync.Mutex
+	queues map[int64][]tgbotapi.Update // present while the chat's worker runs
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newDispatcher(handle func(tgbotapi.Update)) *dispatcher {
+	return &dispatcher{handle: handle, queues: map[int64][]tgbotapi.Update{}}
+}
+
+// updateChat is the chat an update belongs to, for ordering.
+func updateChat(upd tgbotapi.Update) int64 {
+	switch {
+	case upd.Message != nil:
+		return upd.Message.Chat.ID
+	case upd.CallbackQuery != nil && upd.CallbackQuery.Message != nil:
+		return upd.CallbackQuery.Message.Chat.ID
+	case upd.CallbackQuery != nil:
+		return upd.CallbackQuery.From.ID
+	case upd.PreCheckoutQuery != nil:
+		return upd.PreCheckoutQuery.From.ID
+	}
+	return 0
+}
+
+// Dispatch queues upd for its chat, starting the chat's worker if needed.
+func (d *dispatcher) Dispatch(upd tgbotapi.Update) {
+	chatID := updateChat(upd)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	q, running := d.queues[chatID]
+	d.queues[chatID] = append(q, upd)
+	if !running {
+		d.wg.Add(1)
+		go d.work(chatID)
+	}
+}
+
+func (d *dispatcher) work(chatID int64) {
+	defer d.wg.Done()
+	for {
+		d.mu.Lock()
+		q := d.queues[chatID]
+		if len(q) == 0 {
+			delete(d.queues, chatID)
+			d.mu.Unlock()
+			return
+		}
+		upd := q[0]
+		d.queues[chatID] = q[1:]
+		d.mu.Unlock()
+
+		d.run(chatID, upd)
+	}
+}
+
+func (d *dispatcher) run(chatID int64, upd tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("dispatch: chat %d: panic: %v", chatID, r)
+		}
+	}()
+	d.handle(upd)
+}
+
+// Close stops taking updates and waits up to timeout for the workers to
+// finish what they have queued. It reports whether they all did.
+func (d *dispatcher) Close(timeout time.Duration) bool {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func chatUpdate(id int, chatID int64) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: id, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chatID}}}
+}
+
+func TestUpdateChat(t *testing.T) {
+	from := &tgbotapi.User{ID: 7}
+	tests := []struct {
+		name string
+		upd  tgbotapi.Update
+		want int64
+	}{
+		{"message", chatUpdate(1, -100), -100},
+		{"button", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: from, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 5}}}}, 5},
+		{"inline button", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: from}}, 7},
+		{"pre-checkout", tgbotapi.Update{PreCheckoutQuery: &tgbotapi.PreCheckoutQuery{From: from}}, 7},
+		{"other", tgbotapi.Update{}, 0},
+	}
+	for _, tt := range tests {
+		if got := updateChat(tt.upd); got != tt.want {
+			t.Errorf("%s: updateChat = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDispatcherKeepsChatOrder(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[int64][]int{}
+	d := newDispatcher(func(upd tgbotapi.Update) {
+		time.Sleep(time.Duration(upd.UpdateID%3) * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		chatID := upd.Message.Chat.ID
+		seen[chatID] = append(seen[chatID], upd.UpdateID)
+	})
+	chats := []int64{1, 2, -100}
+	for i := range 60 {
+		d.Dispatch(chatUpdate(i, chats[i%len(chats)]))
+	}
+	if !d.Close(5 * time.Second) {
+		t.Fatal("workers did not finish")
+	}
+	for _, chatID := range chats {
+		ids := seen[chatID]
+		if len(ids) != 20 {
+			t.Errorf("chat %d: handled %d updates, want 20", chatID, len(ids))
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Errorf("chat %d: handled out of order: %v", chatID, ids)
+				break
+			}
+		}
+	}
+}
+
+func TestDispatcherChatsDontWaitForEachOther(t *testing.T) {
+	release := make(chan struct{})
+	otherDone := make(chan struct{})
+	d := newDispatcher(func(upd tgbotapi.Update) {
+		if upd.Message.Chat.ID == 1 {
+			<-release
+			return
+		}
+		close(otherDone)
+	})
+	d.Dispatch(chatUpdate(1, 1))
+	d.Dispatch(chatUpdate(2, 2))
+	select {
+	case <-otherDone:
+	case <-time.After(2 * time.Second):
+		t.Error("chat 2 waited for chat 1's handler")
+	}
+	close(release)
+	if !d.Close(2 * time.Second) {
+		t.Fatal("workers did not finish")
+	}
+}
+
+func TestDispatcherSurvivesPanics(t *testing.T) {
+	var mu sync.Mutex
+	var handled []int
+	d := newDispatcher(func(upd tgbotapi.Update) {
+		if upd.UpdateID == 1 {
+			panic("boom")
+		}
+		mu.Lock()
+		handled = append(handled, upd.UpdateID)
+		mu.Unlock()
+	})
+	d.Dispatch(chatUpdate(1, 1))
+	d.Dispatch(chatUpdate(2, 1))
+	if !d.Close(2 * time.Second) {
+		t.Fatal("workers did not finish")
+	}
+	if len(handled) != 1 || handled[0] != 2 {
+		t.Errorf("handled %v after a panic, want [2]", handled)
+	}
+}
+
+func TestDispatcherClose(t *testing.T) {
+	release := make(chan struct{})
+	calls := 0
+	d := newDispatcher(func(tgbotapi.Update) {
+		<-release
+		calls++
+	})
+	d.Dispatch(chatUpdate(1, 1))
+	if d.Close(20 * time.Millisecond) {
+		t.Error("Close reported done while a handler was still running")
+	}
+	d.Dispatch(chatUpdate(2, 1)) // after Close: dropped
+	close(release)
+	if !d.Close(2 * time.Second) {
+		t.Fatal("workers did not finish")
+	}
+	if calls != 1 {
+		t.Errorf("handled %d updates, want 1", calls)
+	}
+}
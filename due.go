@@ -0,0 +1,153 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Besides the 📅 picker, a task takes its due date from the end of its
+// text: "сдать отчёт до пятницы", "продлить полис due 2024-07-01". Listings
+// show the date, and every reminder broadcast opens with ПРОСРОЧЕНО for
+// tasks past theirs.
+
+var weekdayWords = map[string]time.Weekday{
+	"понедельник": time.Monday, "понедельника": time.Monday, "monday": time.Monday,
+	"вторник": time.Tuesday, "вторника": time.Tuesday, "tuesday": time.Tuesday,
+	"среда": time.Wednesday, "среды": time.Wednesday, "среду": time.Wednesday, "wednesday": time.Wednesday,
+	"четверг": time.Thursday, "четверга": time.Thursday, "thursday": time.Thursday,
+	"пятница": time.Friday, "пятницы": time.Friday, "пятницу": time.Friday, "friday": time.Friday,
+	"суббота": time.Saturday, "субботы": time.Saturday, "субботу": time.Saturday, "saturday": time.Saturday,
+	"воскресенье": time.Sunday, "воскресенья": time.Sunday, "sunday": time.Sunday,
+}
+
+// parseDueWord reads a day: "завтра", a weekday (the next one, never
+// today), "15.10", "15.10.2024" or "2024-07-01". The due time is the end of
+// that day.
+func parseDueWord(w string, now time.Time) (time.Time, bool) {
+	w = strings.ToLower(strings.TrimRight(w, ".,!"))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day := time.Time{}
+	if n, ok := dayWords[w]; ok {
+		day = today.AddDate(0, 0, n)
+	} else if wd, ok := weekdayWords[w]; ok {
+		n := (int(wd) - int(today.Weekday()) + 7) % 7
+		if n == 0 {
+			n = 7
+		}
+		day = today.AddDate(0, 0, n)
+	} else if t, err := time.ParseInLocation("2006-01-02", w, now.Location()); err == nil {
+		day = t
+	} else if t, err := time.ParseInLocation("02.01.2006", w, now.Location()); err == nil {
+		day = t
+	} else if m := dayDateRe.FindStringSubmatch(w); m != nil {
+		d, _ := strconv.Atoi(m[1])
+		mon, _ := strconv.Atoi(m[2])
+		t := time.Date(now.Year(), time.Month(mon), d, 0, 0, 0, 0, now.Location())
+		if t.Day() != d || t.Month() != time.Month(mon) {
+			return time.Time{}, false
+		}
+		if t.Before(today) {
+			t = t.AddDate(1, 0, 0)
+		}
+		day = t
+	} else {
+		return time.Time{}, false
+	}
+	due, err := time.ParseInLocation("2006-01-02T15:04", day.Format("2006-01-02")+"T"+dueAllDay, now.Location())
+	return due, err == nil
+}
+
+// parseDue takes "до <день>" or "due <день>" off the end of text.
+func parseDue(text string, now time.Time) (time.Time, string, bool) {
+	words := strings.Fields(text)
+	if len(words) < 3 {
+		return time.Time{}, text, false
+	}
+	if kw := strings.ToLower(words[len(words)-2]); kw != "до" && kw != "due" {
+		return time.Time{}, text, false
+	}
+	due, ok := parseDueWord(words[len(words)-1], now)
+	if !ok {
+		return time.Time{}, text, false
+	}
+	rest := strings.TrimSpace(strings.TrimRight(strings.Join(words[:len(words)-2], " "), ",—- "))
+	return due, rest, rest != ""
+}
+
+func (a *App) applyDue(chatID int64, text string) (string, time.Time) {
+	due, rest, ok := parseDue(text, time.Now().In(a.tz(chatID)))
+	if !ok {
+		return text, time.Time{}
+	}
+	return rest, due
+}
+
+func (a *App) confirmDue(chatID int64, due time.Time) {
+	if a.Store.AckMode(chatID) != AckFull {
+		return
+	}
+	a.send(chatID, a.tr(chatID, "due.set", formatDue(due, a.tz(chatID))))
+}
+
+// OverdueTasks returns active tasks whose due date has passed, oldest first.
+func (s *sqlStore) OverdueTasks(chatID int64, now time.Time) ([]Item, error) {
+	rows, err := s.readDB(chatID).Query(
+		`SELECT id, chat_id, topic, text, flagged, secret, created_at, due_at FROM items
+		 WHERE chat_id=? AND topic=? AND status=? AND due_at<>'' AND due_at<? ORDER BY due_at`,
+		chatID, TopicTasks, StatusActive, now.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Item
+	for rows.Next() {
+		var it Item
+		var created, due string
+		if err := rows.Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &it.Flagged, &it.Secret, &created, &due); err != nil {
+			return nil, err
+		}
+		it.Text = s.openText(chatID, it.Text)
+		it.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		it.Due, _ = time.Parse(time.RFC3339, due)
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+// dueMark is the suffix listings put after an item with a due date.
+func dueMark(lang string, due, now time.Time, tz *time.Location) string {
+	if due.IsZero() {
+		return ""
+	}
+	if due.Before(now) {
+		return " · ⚠️ " + tr(lang, "due.by", formatDue(due, tz))
+	}
+	return " · " + tr(lang, "due.by", formatDue(due, tz))
+}
+
+// sendOverdue opens a reminder broadcast with the chat's overdue tasks.
+func (s *Scheduler) sendOverdue(chatID int64, now time.Time) {
+	items, err := s.store.OverdueTasks(chatID, now)
+	if err != nil {
+		log.Printf("scheduler: overdue tasks error: %v", err)
+		return
+	}
+	if f, ok := activeFocus(s.store, chatID, now); ok {
+		items = f.Filter(items)
+	}
+	if len(items) == 0 {
+		return
+	}
+	lang := s.store.Lang(chatID)
+	var b strings.Builder
+	b.WriteString(tr(lang, "overdue.header"))
+	for _, it := range items {
+		b.WriteString("\n" + tr(lang, "overdue.line", it.ID, shownText(it), formatDue(it.Due, now.Location())))
+	}
+	_ = s.deliver("overdue", tgbotapi.NewMessage(chatID, b.String()))
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Domain events. Integrations (webhooks, MQTT, stats, audit) subscribe to
+// the bus instead of hooking into handlers; item events come from
+// eventStore, so every code path that changes an item emits them.
+
+type EventKind string
+
+const (
+	EventItemCreated   EventKind = "item.created"
+	EventItemCompleted EventKind = "item.completed"
+	EventItemDeleted   EventKind = "item.deleted"
+	EventItemMoved     EventKind = "item.moved"
+	EventDigestSent    EventKind = "digest.sent"
+	EventReminderSent  EventKind = "reminder.sent"
+)
+
+type Event struct {
+	Kind   EventKind
+	ChatID int64
+	ItemID int64
+	Topic  string
+	Text   string
+	UserID int64 // who completed the item, if known
+	At     time.Time
+}
+
+const eventQueue = 256
+
+type subscriber struct {
+	kinds map[EventKind]bool // empty = all
+	ch    chan Event
+}
+
+// EventBus fans events out to subscribers. Each subscriber runs in its own
+// goroutine with a bounded queue; a subscriber that falls behind loses
+// events rather than stalling the bot.
+type EventBus struct {
+	mu     sync.RWMutex
+	subs   []*subscriber
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe calls fn for events of the given kinds (all if none).
+func (b *EventBus) Subscribe(fn func(Event), kinds ...EventKind) {
+	sub := &subscriber{kinds: map[EventKind]bool{}, ch: make(chan Event, eventQueue)}
+	for _, k := range kinds {
+		sub.kinds[k] = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.subs = append(b.subs, sub)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for ev := range sub.ch {
+			fn(ev)
+		}
+	}()
+}
+
+// Close stops accepting events and waits up to timeout for subscribers to
+// work through their queues. It reports whether they all finished.
+func (b *EventBus) Close(timeout time.Duration) bool {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, sub := range b.subs {
+			close(sub.ch)
+		}
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (b *EventBus) wants(kind EventKind) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subs {
+		if len(sub.kinds) == 0 || sub.kinds[kind] {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *EventBus) Publish(ev Event) {
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subs {
+		if len(sub.kinds) > 0 && !sub.kinds[ev.Kind] {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			log.Printf("events: subscriber queue full, dropping %s", ev.Kind)
+		}
+	}
+}
+
+// eventStore publishes item events after each successful write. Inside a
+// transaction the events wait for the commit.
+type eventStore struct {
+	Store
+	bus     *EventBus
+	pending *[]Event // non-nil inside InTx
+}
+
+func newEventStore(store Store, bus *EventBus) *eventStore {
+	return &eventStore{Store: store, bus: bus}
+}
+
+func (s *eventStore) emit(ev Event) {
+	if s.pending != nil {
+		*s.pending = append(*s.pending, ev)
+		return
+	}
+	s.bus.Publish(ev)
+}
+
+// itemEvent fills in the item's topic and text, skipping the lookup when
+// nobody listens.
+func (s *eventStore) itemEvent(kind EventKind, chatID, id int64) (Event, bool) {
+	if !s.bus.wants(kind) {
+		return Event{}, false
+	}
+	ev := Event{Kind: kind, ChatID: chatID, ItemID: id}
+	if it, err := s.Store.GetItem(chatID, id); err == nil && it != nil {
+		ev.Topic, ev.Text = it.Topic, it.Text
+	}
+	return ev, true
+}
+
+func (s *eventStore) InTx(chatID int64, fn func(tx Store) error) error {
+	if s.pending != nil {
+		return fn(s)
+	}
+	var pending []Event
+	err := s.Store.InTx(chatID, func(tx Store) error {
+		return fn(&eventStore{Store: tx, bus: s.bus, pending: &pending})
+	})
+	if err != nil {
+		return err
+	}
+	for _, ev := range pending {
+		s.bus.Publish(ev)
+	}
+	return nil
+}
+
+func (s *eventStore) AddItem(chatID int64, topic, text string) (int64, error) {
+	id, err := s.Store.AddItem(chatID, topic, text)
+	if err == nil {
+		s.emit(Event{Kind: EventItemCreated, ChatID: chatID, ItemID: id, Topic: topic, Text: text})
+	}
+	return id, err
+}
+
+func (s *eventStore) CaptureItem(chatID int64, topic, text string, flagged bool, prov Provenance) (int64, error) {
+	id, err := s.Store.CaptureItem(chatID, topic, text, flagged, prov)
+	if err == nil {
+		s.emit(Event{Kind: EventItemCreated, ChatID: chatID, ItemID: id, Topic: topic, Text: text})
+	}
+	return id, err
+}
+
+func (s *eventStore) CompleteItem(chatID, id int64, by *tgbotapi.User, now time.Time) error {
+	return s.completed(chatID, id, by, now, s.Store.CompleteItem)
+}
+
+func (s *eventStore) FinishItem(chatID, id int64, by *tgbotapi.User, now time.Time) error {
+	return s.completed(chatID, id, by, now, s.Store.FinishItem)
+}
+
+func (s *eventStore) completed(chatID, id int64, by *tgbotapi.User, now time.Time, do func(int64, int64, *tgbotapi.User, time.Time) error) error {
+	if err := do(chatID, id, by, now); err != nil {
+		return err
+	}
+	if ev, ok := s.itemEvent(EventItemCompleted, chatID, id); ok {
+		ev.At = now
+		if by != nil {
+			ev.UserID = by.ID
+		}
+		s.emit(ev)
+	}
+	return nil
+}
+
+func (s *eventStore) DeleteItem(chatID, id int64) error {
+	ev, ok := s.itemEvent(EventItemDeleted, chatID, id)
+	if err := s.Store.DeleteItem(chatID, id); err != nil {
+		return err
+	}
+	if ok {
+		s.emit(ev)
+	}
+	return nil
+}
+
+func (s *eventStore) MoveItem(chatID, id int64, topic string) error {
+	if err := s.Store.MoveItem(chatID, id, topic); err != nil {
+		return err
+	}
+	if ev, ok := s.itemEvent(EventItemMoved, chatID, id); ok {
+		s.emit(ev)
+	}
+	return nil
+}
+
+// eventLogFromEnv subscribes a logger when EVENT_LOG is set: "all" or a
+// comma-separated list of kinds.
+func eventLogFromEnv(bus *EventBus) {
+	raw := strings.TrimSpace(os.Getenv("EVENT_LOG"))
+	if raw == "" {
+		return
+	}
+	var kinds []EventKind
+	if raw != "all" {
+		for _, k := range strings.Split(raw, ",") {
+			kinds = append(kinds, EventKind(strings.TrimSpace(k)))
+		}
+	}
+	bus.Subscribe(func(ev Event) {
+		log.Printf("event %s chat=%d item=%d topic=%s user=%d", ev.Kind, ev.ChatID, ev.ItemID, ev.Topic, ev.UserID)
+	}, kinds...)
+}
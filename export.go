@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// "/export csv" (or json) sends every item of the chat as a file: all
+// topics, active, done and archived, plus what compaction rolled into
+// /history (status "compacted"). It's for reading elsewhere; moving a chat
+// to another bot is /migrate.
+
+const statusCompacted = "compacted"
+
+var exportColumns = []string{"id", "topic", "status", "text", "flagged", "created_at", "completed_at", "completed_by", "due_at", "remind_at"}
+
+type ExportRow struct {
+	ID          int64  `json:"id"`
+	Topic       string `json:"topic"`
+	Status      string `json:"status"`
+	Text        string `json:"text"`
+	Flagged     bool   `json:"flagged"`
+	CreatedAt   string `json:"created_at"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	CompletedBy string `json:"completed_by,omitempty"`
+	DueAt       string `json:"due_at,omitempty"`
+	RemindAt    string `json:"remind_at,omitempty"`
+}
+
+func (r ExportRow) record() []string {
+	return []string{
+		strconv.FormatInt(r.ID, 10), r.Topic, r.Status, r.Text, strconv.FormatBool(r.Flagged),
+		r.CreatedAt, r.CompletedAt, r.CompletedBy, r.DueAt, r.RemindAt,
+	}
+}
+
+// ExportItems lists the chat's items, then its compacted history.
+func (s *sqlStore) ExportItems(chatID int64) ([]ExportRow, error) {
+	db := s.readDB(chatID)
+	rows, err := db.Query(
+		`SELECT id, topic, status, text, flagged, created_at, completed_at, completed_by_name, due_at, remind_at
+		 FROM items WHERE chat_id=? ORDER BY id`, chatID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ExportRow
+	for rows.Next() {
+		var r ExportRow
+		if err := rows.Scan(&r.ID, &r.Topic, &r.Status, &r.Text, &r.Flagged, &r.CreatedAt, &r.CompletedAt, &r.CompletedBy, &r.DueAt, &r.RemindAt); err != nil {
+			return nil, err
+		}
+		r.Text = s.openText(chatID, r.Text)
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hist, err := db.Query(`SELECT month, topic, items FROM item_history WHERE chat_id=? ORDER BY month, topic`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer hist.Close()
+	for hist.Next() {
+		var month, topic string
+		var blob []byte
+		if err := hist.Scan(&month, &topic, &blob); err != nil {
+			return nil, err
+		}
+		items, err := unpackArchive(blob)
+		if err != nil {
+			return nil, fmt.Errorf("history %d %s %s: %w", chatID, month, topic, err)
+		}
+		for _, it := range items {
+			out = append(out, ExportRow{
+				ID: it.ID, Topic: topic, Status: statusCompacted, Text: s.openText(chatID, it.Text),
+				CreatedAt: it.CreatedAt, CompletedAt: it.CompletedAt, CompletedBy: it.CompletedBy,
+			})
+		}
+	}
+	return out, hist.Err()
+}
+
+func exportCSV(rows []ExportRow) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff") // so Excel reads it as UTF-8
+	w := csv.NewWriter(&buf)
+	if err := w.Write(exportColumns); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		if err := w.Write(r.record()); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// exportItems sends the chat's items as a csv or json document.
+func (a *App) exportItems(chatID int64, format string) {
+	if a.Store.Locked(chatID) {
+		a.sendLocked(chatID)
+		return
+	}
+	rows, err := a.Store.ExportItems(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	var b []byte
+	if format == "json" {
+		b, err = json.MarshalIndent(rows, "", "  ")
+	} else {
+		b, err = exportCSV(rows)
+	}
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	name := fmt.Sprintf("gtd-items-%s.%s", time.Now().In(a.tz(chatID)).Format("20060102"), format)
+	msg := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: b})
+	msg.Caption = a.tr(chatID, "export.caption", len(rows))
+	if _, err := a.Bot.Send(msg); err != nil {
+		a.send(chatID, a.tr(chatID, "file.send.failed"))
+	}
+}
+
+// handleExport handles "/export [csv | json | settings]".
+func (a *App) handleExport(chatID int64, arg string) {
+	switch arg = strings.ToLower(strings.TrimSpace(arg)); arg {
+	case "", "csv", "json":
+		if arg == "" {
+			arg = "csv"
+		}
+		a.exportItems(chatID, arg)
+	case "settings":
+		a.sendSettingsExport(chatID)
+	default:
+		a.send(chatID, a.tr(chatID, "export.usage"))
+	}
+}
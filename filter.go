@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type FilterAction int
+
+const (
+	FilterAllow FilterAction = iota
+	FilterFlag
+	FilterReject
+)
+
+func parseFilterAction(s string) (FilterAction, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "reject":
+		return FilterReject, nil
+	case "flag":
+		return FilterFlag, nil
+	default:
+		return FilterAllow, fmt.Errorf("unknown filter action %q", s)
+	}
+}
+
+// InputFilter inspects captured text before it is stored.
+type InputFilter interface {
+	Check(text string) (FilterAction, string)
+}
+
+// FilterChain runs every filter and returns the strictest verdict.
+type FilterChain []InputFilter
+
+func (c FilterChain) Check(text string) (FilterAction, string) {
+	verdict, reason := FilterAllow, ""
+	for _, f := range c {
+		a, r := f.Check(text)
+		if a > verdict {
+			verdict, reason = a, r
+		}
+	}
+	return verdict, reason
+}
+
+type wordlistFilter struct {
+	words  []string // normalized
+	action FilterAction
+}
+
+func (f *wordlistFilter) Check(text string) (FilterAction, string) {
+	norm := " " + normalizeText(text) + " "
+	for _, w := range f.words {
+		if strings.Contains(norm, " "+w+" ") {
+			return f.action, w
+		}
+	}
+	return FilterAllow, ""
+}
+
+type regexFilter struct {
+	re     *regexp.Regexp
+	action FilterAction
+}
+
+func (f *regexFilter) Check(text string) (FilterAction, string) {
+	if m := f.re.FindString(text); m != "" {
+		return f.action, m
+	}
+	return FilterAllow, ""
+}
+
+// newFilterChainFromEnv builds filters from
+// FILTER_WORDS (comma separated), FILTER_WORDS_FILE (one word per line),
+// FILTER_REGEX and FILTER_ACTION (reject|flag).
+func newFilterChainFromEnv() (FilterChain, error) {
+	action, err := parseFilterAction(os.Getenv("FILTER_ACTION"))
+	if err != nil {
+		return nil, err
+	}
+
+	words := strings.Split(os.Getenv("FILTER_WORDS"), ",")
+	if path := strings.TrimSpace(os.Getenv("FILTER_WORDS_FILE")); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, strings.Split(string(b), "\n")...)
+	}
+
+	var chain FilterChain
+	wf := &wordlistFilter{action: action}
+	for _, w := range words {
+		if n := normalizeText(w); n != "" {
+			wf.words = append(wf.words, n)
+		}
+	}
+	if len(wf.words) > 0 {
+		chain = append(chain, wf)
+	}
+
+	if expr := strings.TrimSpace(os.Getenv("FILTER_REGEX")); expr != "" {
+		re, err := regexp.Compile("(?i)" + expr)
+		if err != nil {
+			return nil, fmt.Errorf("FILTER_REGEX: %w", err)
+		}
+		chain = append(chain, &regexFilter{re: re, action: action})
+	}
+	return chain, nil
+}
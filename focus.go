@@ -0,0 +1,154 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Focus mode: "/focus project Ремонт for 2h" (or "/focus Ремонт 2ч").
+// A project is whatever mentions it, as #ремонт or by name. Until the
+// window ends, /list, /today and the digest show only the project's items
+// and reminders about anything else wait: timed ones fire when focus ends,
+// the others skip their turn. "/focus off" ends it early.
+
+const maxFocus = 12 * time.Hour
+
+type Focus struct {
+	Project string
+	Until   time.Time
+}
+
+// activeFocus is the chat's focus, if it is still on at now.
+func activeFocus(store Store, chatID int64, now time.Time) (Focus, bool) {
+	raw, ok, err := store.GetKV(chatKey(chatID, "focus"))
+	if err != nil || !ok {
+		return Focus{}, false
+	}
+	project, until, _ := strings.Cut(raw, "|")
+	t, err := time.Parse(time.RFC3339, until)
+	if err != nil || !now.Before(t) {
+		return Focus{}, false
+	}
+	return Focus{Project: project, Until: t}, true
+}
+
+// Matches reports whether text belongs to the project.
+func (f Focus) Matches(text string) bool {
+	name := normalizeText(f.Project)
+	tag := "#" + strings.ReplaceAll(name, " ", "_")
+	for _, t := range itemTags(text) {
+		if t == tag {
+			return true
+		}
+	}
+	return strings.Contains(normalizeText(text), name)
+}
+
+func (f Focus) Filter(items []Item) []Item {
+	var out []Item
+	for _, it := range items {
+		if f.Matches(it.Text) {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+func (f Focus) Banner(lang string, tz *time.Location) string {
+	return tr(lang, "focus.banner", f.Project, f.Until.In(tz).Format("15:04"))
+}
+
+// focusFor is the focus for filtering chatID's lists right now, if any.
+func (a *App) focusFor(chatID int64) (Focus, bool) {
+	return activeFocus(a.Store, chatID, time.Now())
+}
+
+// parseFocusDuration reads "2h", "90m", "1h30m", "2ч", "30мин".
+func parseFocusDuration(s string) (time.Duration, bool) {
+	s = strings.NewReplacer("часа", "h", "часов", "h", "час", "h", "ч", "h", "минут", "m", "мин", "m", "м", "m").Replace(normalizeText(s))
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// parseFocus reads "[project] <название> [for|на] <длительность>".
+func parseFocus(arg string) (string, time.Duration, bool) {
+	fields := strings.Fields(arg)
+	if len(fields) > 0 && (fields[0] == "project" || normalizeText(fields[0]) == "проект") {
+		fields = fields[1:]
+	}
+	if len(fields) < 2 {
+		return "", 0, false
+	}
+	d, ok := parseFocusDuration(fields[len(fields)-1])
+	if !ok {
+		return "", 0, false
+	}
+	fields = fields[:len(fields)-1]
+	if n := len(fields); n > 1 && (fields[n-1] == "for" || fields[n-1] == "на") {
+		fields = fields[:n-1]
+	}
+	return strings.Join(fields, " "), min(d, maxFocus), true
+}
+
+// endFocus clears a focus whose window is over and says so.
+func (s *Scheduler) endFocus(chatID int64, now time.Time) {
+	raw, ok, err := s.store.GetKV(chatKey(chatID, "focus"))
+	if err != nil || !ok {
+		return
+	}
+	if _, on := activeFocus(s.store, chatID, now); on {
+		return
+	}
+	if err := s.store.DeleteKV(chatKey(chatID, "focus")); err != nil {
+		log.Printf("scheduler: end focus error: %v", err)
+		return
+	}
+	project, _, _ := strings.Cut(raw, "|")
+	s.send("focus", tgbotapi.NewMessage(chatID, tr(s.store.Lang(chatID), "focus.ended", project)))
+}
+
+// handleFocus handles "/focus [project <название> for <длительность> | off]".
+func (a *App) handleFocus(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	key := chatKey(chatID, "focus")
+	lang := a.Store.Lang(chatID)
+	switch arg {
+	case "":
+		if f, ok := a.focusFor(chatID); ok {
+			a.send(chatID, f.Banner(lang, a.tz(chatID))+". "+tr(lang, "focus.stop"))
+		} else {
+			a.send(chatID, tr(lang, "focus.none")+" "+tr(lang, "focus.usage"))
+		}
+		return
+	case "off":
+		if err := a.Store.DeleteKV(key); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "focus.off"))
+		return
+	}
+
+	project, d, ok := parseFocus(arg)
+	if !ok {
+		a.send(chatID, tr(lang, "focus.usage"))
+		return
+	}
+	f := Focus{Project: project, Until: time.Now().Add(d).Truncate(time.Minute)}
+	if err := a.Store.SetKV(key, f.Project+"|"+f.Until.UTC().Format(time.RFC3339)); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	items, err := a.Store.ListActive(chatID, "")
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	a.send(chatID, f.Banner(lang, a.tz(chatID))+". "+tr(lang, "focus.set", len(f.Filter(items))))
+}
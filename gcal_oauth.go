@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	googleTokenURL  = "https://oauth2.googleapis.com/token"
+	googleDeviceURL = "https://oauth2.googleapis.com/device/code"
+	gcalScope       = "https://www.googleapis.com/auth/calendar.readonly"
+)
+
+type oauthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// googleOAuth keeps a refreshed access token for the Calendar API. The
+// refresh token comes from GCAL_REFRESH_TOKEN or GCAL_TOKEN_FILE; the file
+// is rewritten whenever the device flow issues a new one.
+type googleOAuth struct {
+	clientID     string
+	clientSecret string
+	tokenFile    string
+	http         *http.Client
+
+	mu    sync.Mutex
+	token oauthToken
+}
+
+func googleOAuthFromEnv() (*googleOAuth, error) {
+	o := &googleOAuth{
+		clientID:     strings.TrimSpace(os.Getenv("GCAL_CLIENT_ID")),
+		clientSecret: strings.TrimSpace(os.Getenv("GCAL_CLIENT_SECRET")),
+		tokenFile:    envOr("GCAL_TOKEN_FILE", "gcal_token.json"),
+		http:         &http.Client{Timeout: 15 * time.Second},
+	}
+	if o.clientID == "" || o.clientSecret == "" {
+		return nil, fmt.Errorf("GCAL_CALENDAR_ID is set but GCAL_CLIENT_ID/GCAL_CLIENT_SECRET are missing")
+	}
+	if raw, err := os.ReadFile(o.tokenFile); err == nil {
+		if err := json.Unmarshal(raw, &o.token); err != nil {
+			return nil, fmt.Errorf("%s: %w", o.tokenFile, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if rt := strings.TrimSpace(os.Getenv("GCAL_REFRESH_TOKEN")); rt != "" {
+		o.token.RefreshToken = rt
+	}
+	if o.token.RefreshToken == "" {
+		log.Printf("calendar: no refresh token; run /gcalauth to connect Google Calendar")
+	}
+	return o, nil
+}
+
+func (o *googleOAuth) post(ctx context.Context, endpoint string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(raw, &body) == nil && body.Error != "" {
+			return oauthError(body.Error)
+		}
+		return fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// oauthError is the "error" code of a failed token request, e.g.
+// authorization_pending or invalid_grant.
+type oauthError string
+
+func (e oauthError) Error() string { return "oauth: " + string(e) }
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// apply stores a token response; caller holds mu.
+func (o *googleOAuth) apply(tr tokenResponse) error {
+	o.token.AccessToken = tr.AccessToken
+	o.token.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	if tr.RefreshToken == "" {
+		return nil
+	}
+	o.token.RefreshToken = tr.RefreshToken
+	raw, err := json.MarshalIndent(o.token, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(o.tokenFile, raw, 0o600)
+}
+
+// AccessToken returns a valid access token, refreshing it a minute before
+// it expires.
+func (o *googleOAuth) AccessToken(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.token.RefreshToken == "" {
+		return "", ErrCalendarNotAuthorized
+	}
+	if o.token.AccessToken != "" && time.Until(o.token.Expiry) > time.Minute {
+		return o.token.AccessToken, nil
+	}
+	var tr tokenResponse
+	err := o.post(ctx, googleTokenURL, url.Values{
+		"client_id":     {o.clientID},
+		"client_secret": {o.clientSecret},
+		"refresh_token": {o.token.RefreshToken},
+		"grant_type":    {"refresh_token"},
+	}, &tr)
+	if errors.Is(err, oauthError("invalid_grant")) {
+		o.token = oauthToken{}
+		return "", ErrCalendarNotAuthorized
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := o.apply(tr); err != nil {
+		return "", err
+	}
+	return o.token.AccessToken, nil
+}
+
+type deviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+func (o *googleOAuth) startDeviceFlow(ctx context.Context) (deviceCode, error) {
+	var dc deviceCode
+	err := o.post(ctx, googleDeviceURL, url.Values{
+		"client_id": {o.clientID},
+		"scope":     {gcalScope},
+	}, &dc)
+	if dc.Interval <= 0 {
+		dc.Interval = 5
+	}
+	return dc, err
+}
+
+// pollDevice waits until the user approves the code, then stores the
+// token.
+func (o *googleOAuth) pollDevice(ctx context.Context, dc deviceCode) error {
+	interval := time.Duration(dc.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(dc.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		var tr tokenResponse
+		err := o.post(ctx, googleTokenURL, url.Values{
+			"client_id":     {o.clientID},
+			"client_secret": {o.clientSecret},
+			"device_code":   {dc.DeviceCode},
+			"grant_type":    {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, &tr)
+		switch {
+		case errors.Is(err, oauthError("authorization_pending")):
+			continue
+		case errors.Is(err, oauthError("slow_down")):
+			interval += 5 * time.Second
+			continue
+		case err != nil:
+			return err
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		return o.apply(tr)
+	}
+	return oauthError("expired_token")
+}
+
+// handleGCalAuth handles "/gcalauth": the device-code flow. The owner opens
+// the link, enters the code, and the bot saves the refresh token.
+func (a *App) handleGCalAuth(ctx context.Context, m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	if !isOwner(m) {
+		a.send(chatID, a.tr(chatID, "owner.only"))
+		return
+	}
+	gc, ok := a.Calendar.(*googleCalendarClient)
+	if !ok || !gc.enabled {
+		a.send(chatID, a.tr(chatID, "gcal.unset"))
+		return
+	}
+	dc, err := gc.oauth.startDeviceFlow(ctx)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "gcal.start.failed", err))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "gcal.code", dc.VerificationURL, dc.UserCode, dc.ExpiresIn/60))
+	go func() {
+		if err := gc.oauth.pollDevice(ctx, dc); err != nil {
+			a.send(chatID, a.tr(chatID, "gcal.poll.failed", err))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "gcal.done"))
+	}()
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type Goal struct {
+	ID       int64
+	ChatID   int64
+	Title    string
+	Target   float64
+	Unit     string
+	Period   string // YYYY-MM
+	Progress float64
+}
+
+var (
+	goalAmountRe = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*([\p{L}]*)`)
+	monthStems   = []string{"январ", "феврал", "март", "апрел", "ма", "июн", "июл", "август", "сентябр", "октябр", "ноябр", "декабр"}
+	// monthSuffixes lists nominative, genitive and prepositional endings per month.
+	monthSuffixes = []map[string]bool{
+		{"ь": true, "я": true, "е": true},
+		{"ь": true, "я": true, "е": true},
+		{"": true, "а": true, "е": true},
+		{"ь": true, "я": true, "е": true},
+		{"й": true, "я": true, "е": true},
+		{"ь": true, "я": true, "е": true},
+		{"ь": true, "я": true, "е": true},
+		{"": true, "а": true, "е": true},
+		{"ь": true, "я": true, "е": true},
+		{"ь": true, "я": true, "е": true},
+		{"ь": true, "я": true, "е": true},
+		{"ь": true, "я": true, "е": true},
+	}
+)
+
+func parseNumber(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(strings.TrimSpace(s), ",", ".", 1), 64)
+}
+
+// parseGoalPeriod finds "в марте"/"март" style month names; without one the
+// goal is for the current month. A month already past means next year.
+func parseGoalPeriod(text string, now time.Time) string {
+	for _, w := range strings.Fields(normalizeText(text)) {
+		for i, stem := range monthStems {
+			rest, ok := strings.CutPrefix(w, stem)
+			if !ok || !monthSuffixes[i][rest] {
+				continue
+			}
+			m := time.Month(i + 1)
+			y := now.Year()
+			if m < now.Month() {
+				y++
+			}
+			return fmt.Sprintf("%04d-%02d", y, m)
+		}
+	}
+	return now.Format("2006-01")
+}
+
+// parseGoal reads "пробежать 100 км в марте": the first number is the
+// target and the word right after it is the unit.
+func parseGoal(text string, now time.Time) (Goal, error) {
+	text = strings.TrimSpace(text)
+	m := goalAmountRe.FindStringSubmatch(text)
+	if m == nil {
+		return Goal{}, errors.New("no target")
+	}
+	target, err := parseNumber(m[1])
+	if err != nil || target <= 0 {
+		return Goal{}, errors.New("bad target")
+	}
+	return Goal{Title: text, Target: target, Unit: m[2], Period: parseGoalPeriod(text, now)}, nil
+}
+
+func (s *sqlStore) AddGoal(chatID int64, g Goal) (int64, error) {
+	var id int64
+	err := s.db(chatID).QueryRow(
+		`INSERT INTO goals(chat_id, title, target, unit, period, progress, created_at) VALUES(?,?,?,?,?,0,?) RETURNING id`,
+		chatID, g.Title, g.Target, g.Unit, g.Period, time.Now().UTC().Format(time.RFC3339),
+	).Scan(&id)
+	return id, err
+}
+
+func (s *sqlStore) ListGoals(chatID int64, period string) ([]Goal, error) {
+	rows, err := s.readDB(chatID).Query(
+		`SELECT id, chat_id, title, target, unit, period, progress FROM goals WHERE chat_id=? AND period>=? ORDER BY period, id`,
+		chatID, period,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Goal
+	for rows.Next() {
+		var g Goal
+		if err := rows.Scan(&g.ID, &g.ChatID, &g.Title, &g.Target, &g.Unit, &g.Period, &g.Progress); err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) GoalsForPeriod(chatID int64, period string) ([]Goal, error) {
+	all, err := s.ListGoals(chatID, period)
+	if err != nil {
+		return nil, err
+	}
+	var out []Goal
+	for _, g := range all {
+		if g.Period == period {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
+
+func (s *sqlStore) AddGoalProgress(chatID, goalID int64, amount float64) error {
+	res, err := s.db(chatID).Exec(`UPDATE goals SET progress=progress+? WHERE chat_id=? AND id=?`, amount, chatID, goalID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteGoal(chatID, goalID int64) error {
+	_, err := s.db(chatID).Exec(`DELETE FROM goals WHERE chat_id=? AND id=?`, chatID, goalID)
+	return err
+}
+
+// migrateGoalLinks rebuilds a goal_links table from before links were
+// keyed by chat, giving each link the chat of its goal.
+func (s *sqlStore) migrateGoalLinks() error {
+	ok, err := s.hasColumn("goal_links", "chat_id")
+	if err != nil || ok {
+		return err
+	}
+	ddl := `CREATE TABLE goal_links_new (
+  chat_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  goal_id INTEGER NOT NULL,
+  amount REAL NOT NULL,
+  PRIMARY KEY (chat_id, item_id)
+)`
+	if s.driver == driverPostgres {
+		ddl = pgTypes(ddl)
+	}
+	return s.inTx(func(tx *sqlStore) error {
+		for _, q := range []string{
+			ddl,
+			`INSERT INTO goal_links_new(chat_id, item_id, goal_id, amount)
+			 SELECT g.chat_id, l.item_id, l.goal_id, l.amount FROM goal_links l JOIN goals g ON g.id = l.goal_id`,
+			`DROP TABLE goal_links`,
+			`ALTER TABLE goal_links_new RENAME TO goal_links`,
+		} {
+			if _, err := tx.DB.Exec(q); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LinkGoal links an item to a goal of the same chat; sql.ErrNoRows means
+// the chat has no such item or goal.
+func (s *sqlStore) LinkGoal(chatID, itemID, goalID int64, amount float64) error {
+	res, err := s.db(chatID).Exec(
+		`INSERT INTO goal_links(chat_id, item_id, goal_id, amount)
+		 SELECT ?,?,?,? WHERE EXISTS (SELECT 1 FROM items WHERE chat_id=? AND id=?)
+		   AND EXISTS (SELECT 1 FROM goals WHERE chat_id=? AND id=?)
+		 ON CONFLICT(chat_id, item_id) DO UPDATE SET goal_id=excluded.goal_id, amount=excluded.amount`,
+		chatID, itemID, goalID, amount, chatID, itemID, chatID, goalID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// TakeGoalLink returns and removes the goal link of an item.
+func (s *sqlStore) TakeGoalLink(chatID, itemID int64) (goalID int64, amount float64, ok bool, err error) {
+	err = s.db(chatID).QueryRow(`SELECT goal_id, amount FROM goal_links WHERE chat_id=? AND item_id=?`, chatID, itemID).Scan(&goalID, &amount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, err
+	}
+	_, err = s.db(chatID).Exec(`DELETE FROM goal_links WHERE chat_id=? AND item_id=?`, chatID, itemID)
+	return goalID, amount, true, err
+}
+
+func formatAmount(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func progressBar(done, total float64) string {
+	const width = 10
+	n := 0
+	if total > 0 {
+		n = int(done / total * width)
+	}
+	n = max(0, min(n, width))
+	return strings.Repeat("▓", n) + strings.Repeat("░", width-n)
+}
+
+func formatGoal(lang string, g Goal) string {
+	pct := 0.0
+	if g.Target > 0 {
+		pct = g.Progress / g.Target * 100
+	}
+	return tr(lang, "goal.line",
+		g.ID, g.Period, g.Title, progressBar(g.Progress, g.Target),
+		formatAmount(g.Progress), formatAmount(g.Target), g.Unit, pct)
+}
+
+func (a *App) handleGoal(chatID int64, arg string) {
+	if strings.TrimSpace(arg) == "" {
+		a.send(chatID, a.tr(chatID, "goal.usage"))
+		return
+	}
+	g, err := parseGoal(arg, time.Now().In(a.tz(chatID)))
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "goal.nonumber"))
+		return
+	}
+	id, err := a.Store.AddGoal(chatID, g)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	g.ID = id
+	a.send(chatID, formatGoal(a.Store.Lang(chatID), g)+"\n\n"+a.tr(chatID, "goal.added", id, id))
+}
+
+func (a *App) handleGoals(chatID int64) {
+	goals, err := a.Store.ListGoals(chatID, time.Now().In(a.tz(chatID)).Format("2006-01"))
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if len(goals) == 0 {
+		a.send(chatID, a.tr(chatID, "goals.none"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+	parts := make([]string, 0, len(goals))
+	for _, g := range goals {
+		parts = append(parts, formatGoal(lang, g))
+	}
+	a.send(chatID, strings.Join(parts, "\n\n"))
+}
+
+// handleCheckin handles "/checkin <goal> <amount>".
+func (a *App) handleCheckin(chatID int64, arg string) {
+	f := strings.Fields(arg)
+	if len(f) != 2 {
+		a.send(chatID, a.tr(chatID, "checkin.usage"))
+		return
+	}
+	goalID, err1 := strconv.ParseInt(f[0], 10, 64)
+	amount, err2 := parseNumber(f[1])
+	if err1 != nil || err2 != nil {
+		a.send(chatID, a.tr(chatID, "checkin.usage"))
+		return
+	}
+	if err := a.Store.AddGoalProgress(chatID, goalID, amount); err != nil {
+		a.send(chatID, a.tr(chatID, "goal.notfound"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "checkin.done"))
+}
+
+func (a *App) handleDelGoal(chatID int64, arg string) {
+	goalID, err := strconv.ParseInt(strings.TrimSpace(arg), 10, 64)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "delgoal.usage"))
+		return
+	}
+	if err := a.Store.DeleteGoal(chatID, goalID); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "goal.deleted"))
+}
+
+// handleLinkGoal handles "/linkgoal <item> <goal> [amount]".
+func (a *App) handleLinkGoal(chatID int64, arg string) {
+	f := strings.Fields(arg)
+	if len(f) < 2 || len(f) > 3 {
+		a.send(chatID, a.tr(chatID, "linkgoal.usage"))
+		return
+	}
+	itemID, err1 := strconv.ParseInt(strings.TrimPrefix(f[0], "#"), 10, 64)
+	goalID, err2 := strconv.ParseInt(f[1], 10, 64)
+	amount := 1.0
+	var err3 error
+	if len(f) == 3 {
+		amount, err3 = parseNumber(f[2])
+	}
+	if err1 != nil || err2 != nil || err3 != nil {
+		a.send(chatID, a.tr(chatID, "linkgoal.usage"))
+		return
+	}
+	if err := a.Store.LinkGoal(chatID, itemID, goalID, amount); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.send(chatID, a.tr(chatID, "linkgoal.none"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "linkgoal.done", itemID, formatAmount(amount), goalID))
+}
+
+// sendGoalsReport summarises last month's goals on the 1st.
+func (s *Scheduler) sendGoalsReport(chatID int64, now time.Time) {
+	period := now.AddDate(0, -1, 0).Format("2006-01")
+	goals, err := s.store.GoalsForPeriod(chatID, period)
+	if err != nil {
+		log.Printf("scheduler: goals report error: %v", err)
+		return
+	}
+	if len(goals) == 0 {
+		return
+	}
+
+	lang := s.store.Lang(chatID)
+	parts := []string{tr(lang, "goals.report", period)}
+	for _, g := range goals {
+		mark := "❌"
+		if g.Progress >= g.Target {
+			mark = "🏆"
+		}
+		parts = append(parts, mark+" "+formatGoal(lang, g))
+	}
+	s.send("goals_report", tgbotapi.NewMessage(chatID, strings.Join(parts, "\n\n")))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseGoal(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		text   string
+		target float64
+		unit   string
+		period string
+		err    bool
+	}{
+		{"пробежать 100 км в марте", 100, "км", "2027-03", false},
+		{"прочитать 5 книг", 5, "книг", "2026-10", false},
+		{"сбросить 2,5 кг в декабре", 2.5, "кг", "2026-12", false},
+		{"закрыть 20 задач в октябре", 20, "задач", "2026-10", false},
+		{"съездить в 3 города в мае", 3, "города", "2027-05", false},
+		{"выучить испанский", 0, "", "", true},
+		{"0 задач", 0, "", "", true},
+	}
+	for _, tt := range tests {
+		g, err := parseGoal(tt.text, now)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseGoal(%q) = %+v, want an error", tt.text, g)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseGoal(%q): %v", tt.text, err)
+			continue
+		}
+		if g.Target != tt.target || g.Unit != tt.unit || g.Period != tt.period || g.Title != tt.text {
+			t.Errorf("parseGoal(%q) = %+v; want target %v, unit %q, period %s", tt.text, g, tt.target, tt.unit, tt.period)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Active/standby: with HA_INSTANCE set, several instances share one
+// database and only the holder of the "bot" lease receives updates and
+// runs the scheduler. The others stand by, retrying the lease, and take
+// over within HA_LEASE_SECONDS when the active one stops renewing it.
+//
+// The database carries the state between them, so this is Postgres only
+// (with its own replication for the database tier): a SQLite file belongs
+// to one host, and a replica of it is read-only for a standby, so
+// HA_INSTANCE with DB_DRIVER=sqlite is refused at startup.
+
+const leaseName = "bot"
+
+func (s *sqlStore) AcquireLease(name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	res, err := s.DB.Exec(
+		`INSERT INTO leases(name, holder, expires_at) VALUES(?,?,?)
+		 ON CONFLICT(name) DO UPDATE SET holder=excluded.holder, expires_at=excluded.expires_at
+		 WHERE leases.holder=excluded.holder OR leases.expires_at<?`,
+		name, holder, now.Add(ttl).UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *sqlStore) ReleaseLease(name, holder string) error {
+	_, err := s.DB.Exec(`DELETE FROM leases WHERE name=? AND holder=?`, name, holder)
+	return err
+}
+
+type lease struct {
+	store  Store
+	holder string // empty: HA off, always active
+	ttl    time.Duration
+}
+
+func leaseFromEnv(store Store) (*lease, error) {
+	secs, err := strconv.Atoi(envOr("HA_LEASE_SECONDS", "30"))
+	if err != nil || secs < 3 {
+		secs = 30
+	}
+	holder := strings.TrimSpace(os.Getenv("HA_INSTANCE"))
+	if driver, _, _ := dbFromEnv(); holder != "" && driver != driverPostgres {
+		return nil, fmt.Errorf("HA_INSTANCE requires DB_DRIVER=postgres")
+	}
+	return &lease{store: store, holder: holder, ttl: time.Duration(secs) * time.Second}, nil
+}
+
+// Run calls active for every term in which this instance holds the lease,
+// with a context cancelled when the lease is lost, until ctx is done.
+// Without HA it just calls active(ctx).
+func (l *lease) Run(ctx context.Context, active func(ctx context.Context) error) error {
+	if l.holder == "" {
+		return active(ctx)
+	}
+	for {
+		log.Printf("ha: %s standing by", l.holder)
+		if !l.wait(ctx) {
+			return nil
+		}
+		log.Printf("ha: %s is active", l.holder)
+
+		term, lost := context.WithCancel(ctx)
+		go l.renew(term, lost)
+		err := active(term)
+		lost()
+		if ctx.Err() != nil {
+			if err := l.store.ReleaseLease(leaseName, l.holder); err != nil {
+				log.Printf("ha: release: %v", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// wait blocks until the lease is ours; false if ctx ended first.
+func (l *lease) wait(ctx context.Context) bool {
+	for {
+		ok, err := l.store.AcquireLease(leaseName, l.holder, time.Now(), l.ttl)
+		if err != nil {
+			log.Printf("ha: acquire: %v", err)
+		}
+		if ok {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(l.ttl / 3):
+		}
+	}
+}
+
+// renew extends the lease every ttl/3 and calls lost once another
+// instance holds it, or renewals have failed for long enough that one might.
+func (l *lease) renew(ctx context.Context, lost context.CancelFunc) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ok, err := l.store.AcquireLease(leaseName, l.holder, time.Now(), l.ttl)
+		switch {
+		case ok:
+			renewed = time.Now()
+			continue
+		case err == nil:
+			log.Printf("ha: lease taken over by another instance")
+		case time.Since(renewed) < l.ttl*2/3:
+			log.Printf("ha: renew: %v", err)
+			continue
+		default:
+			log.Printf("ha: renew failing (%v), stepping down", err)
+		}
+		lost()
+		return
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import "strings"
+
+// A message starting with a topic tag is captured straight into that
+// topic: "#покупки молоко", "#tasks fix bike", "#книги Дюна". The tag is
+// any name the topic answers to on the keyboard (renamed, built-in in
+// either language, or a /newlist list, with '_' standing for a space).
+// The current topic and its TTL are left alone.
+
+func (a *App) hashtagTopic(chatID int64, text string) (topic, rest string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "#") {
+		return "", "", false
+	}
+	tag, rest := text[1:], ""
+	if i := strings.IndexAny(tag, " \t\n"); i >= 0 {
+		tag, rest = tag[:i], strings.TrimSpace(tag[i:])
+	}
+	tag = strings.ReplaceAll(tag, "_", " ")
+	if tag == "" || rest == "" || normalizeText(tag) == "menu" {
+		return "", "", false
+	}
+	topic, ok = a.topicFromButton(chatID, tag)
+	return topic, rest, ok
+}
@@ -0,0 +1,1505 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	LangRU = "ru"
+	LangEN = "en"
+
+	DefaultLang = LangRU
+)
+
+// messages is the UI catalog. Missing keys fall back to DefaultLang,
+// then to the key itself.
+var messages = map[string]map[string]string{
+	LangRU: {
+		"menu.opened":        "Меню открыто. Режим по умолчанию: КОРЗИНА.",
+		"menu.opened.basket": "Меню открыто. Режим: КОРЗИНА.",
+		"mode":               "Режим: %s.",
+		"added":              "ДОБАВИЛ СООБЩЕНИЕ В %s.",
+		"dup":                "Уже есть в %s: #%d.",
+		"err.write":          "Ошибка записи.",
+		"filter.rejected":    "Это сообщение не сохранено.",
+		"only.text":          "Понимаю только текст.",
+		"empty":              "Пусто.",
+		"deleted":            "Удалено",
+		"deleted.mark":       "✅ Удалено",
+		"done":               "Выполнено",
+		"done.mark":          "✅ Выполнено",
+		"lang.choose":        "Выберите язык:",
+		"lang.set":           "Язык: русский.",
+		"lang.unknown":       "Неизвестный язык. Доступно: ru, en.",
+
+		"btn.tasks":     "Задачи",
+		"btn.reminders": "Напоминания",
+		"btn.shopping":  "Покупки",
+		"btn.basket":    "Корзина",
+		"btn.someday":   "Когда-нибудь",
+		"btn.today":     "Сегодня",
+		"btn.review":    "Обзор",
+
+		"label.tasks":     "ЗАДАЧИ",
+		"label.reminders": "НАПОМИНАНИЯ",
+		"label.shopping":  "ПОКУПКИ",
+		"label.basket":    "КОРЗИНУ",
+		"label.someday":   "КОГДА-НИБУДЬ",
+
+		"item.tasks":     "ЗАДАЧА",
+		"item.reminders": "НАПОМИНАНИЕ",
+		"item.shopping":  "ПОКУПКА",
+		"item.basket":    "КОРЗИНА",
+		"item.someday":   "КОГДА-НИБУДЬ",
+
+		"err.read":   "Ошибка чтения.",
+		"owner.only": "Команда доступна только владельцу бота.",
+
+		"wipe.done":        "НАПОМИНАНИЯ ОЧИЩЕНЫ (ночной вайп). Они в /archive.",
+		"premium.expiring": "ПРЕМИУМ заканчивается %s. Продлить: /premium",
+		"premium.expired":  "ПРЕМИУМ закончился. Продлить: /premium",
+
+		"intents.help":    "Можно писать обычными словами:\n• покажи задачи / покажи покупки / покажи список\n• что у меня сегодня\n• что дальше\n• сделал 5 — отметить запись #5\n• удали пункт 3 — удалить запись #3 (вернуть: /undo)\n• найди молоко\nВсё остальное я сохраняю как новую запись.",
+		"intents.no_item": "Записи #%d нет.",
+		"intents.done":    "✅ #%d %s",
+		"intents.deleted": "🗑 #%d %s удалено. Вернуть: /undo",
+		"intents.on":      "Понимаю команды обычными словами.",
+		"intents.off":     "Теперь любой текст — новая запись. Включить снова: /intents on",
+		"intents.hint":    "Выключить: /intents off",
+
+		"role.owner":         "владелец",
+		"role.editor":        "редактор",
+		"role.viewer":        "читатель",
+		"role.denied":        "Недостаточно прав: нужна роль «%s». Роли: /role",
+		"role.denied.button": "Нужна роль «%s»",
+		"role.private":       "Роли нужны только в группах: в личном чате всё решаете вы.",
+		"role.reset":         "Роли сброшены: все участники снова могут всё.",
+		"role.default.usage": "Роль по умолчанию: editor или viewer.",
+		"role.default.set":   "Остальные участники — %s.",
+		"role.usage":         "Ответьте на сообщение участника: /role editor, или укажите id: /role 123456 viewer",
+		"role.unknown":       "Роли: owner, editor, viewer (или off).",
+		"roles.none":         "РОЛИ: не заданы, все участники могут всё.\n\nНазначить: ответьте на сообщение участника /role viewer",
+		"roles.default":      "РОЛИ: участники — %s, администраторы группы — владельцы.",
+		"roles.header":       "РОЛИ:",
+		"roles.rest":         "Остальные — %s, администраторы группы — владельцы.",
+
+		"backup.s3.failed":    "⚠️ Резервная копия в S3 не удалась: %v",
+		"restore.main":        "Восстановление всех ботов запускает основной бот.",
+		"restore.sqlite_only": "Восстановление из снимка только для SQLite.",
+		"restore.no_s3":       "S3 не настроен: %v",
+		"restore.list.failed": "Не удалось прочитать бакет: %v",
+		"restore.none":        "В бакете нет снимков.",
+		"restore.list":        "СНИМКИ В S3:",
+		"restore.list.run":    "%s (файлов: %d)",
+		"restore.list.hint":   "Восстановить: /restore <снимок>",
+		"restore.unknown":     "Нет такого снимка. Список: /restore list",
+		"restore.failed":      "Восстановление не удалось: %v",
+		"restore.staged":      "Снимок %s скачан и проверен (файлов: %d). Перезапустите бота: он начнёт с этих данных, а текущие сохранит рядом как .bak.",
+
+		"undo.nothing":   "Нечего отменять.",
+		"undo.created":   "↩️ Убрал добавленное: #%d %s",
+		"undo.completed": "↩️ Снова активно: #%d %s (%s)",
+		"undo.deleted":   "↩️ Восстановил: #%d %s (%s)",
+		"undo.moved":     "↩️ Вернул в «%s»: #%d %s",
+
+		"premium.free":           "Все функции доступны бесплатно.",
+		"premium.active":         "ПРЕМИУМ активен до %s.",
+		"premium.title":          "GTD Премиум",
+		"premium.description":    "Понимание просьб через LLM и синхронизация календаря на %d дн.",
+		"premium.label":          "Премиум",
+		"premium.invoice.failed": "Не удалось выставить счёт.",
+		"premium.invoice.stale":  "Счёт устарел, запросите новый через /premium.",
+		"premium.save.failed":    "Оплата получена, но подписку не удалось сохранить. Напишите владельцу бота.",
+		"premium.thanks":         "Спасибо! ПРЕМИУМ активен до %s.",
+
+		"ack.show":    "Подтверждения: %s. Варианты: /ack full | react | silent",
+		"ack.usage":   "Варианты: /ack full | react | silent",
+		"ack.set":     "Подтверждения: %s.",
+		"edit.gone":   "Запись #%d уже удалена.",
+		"edit.closed": "Запись #%d уже закрыта, не меняю.",
+
+		"capture.unknown":  "Не знаю такой список. Пример: /capture задачи",
+		"capture.running":  "Сессия записи уже идёт. Завершить: /stop",
+		"capture.started":  "Пишите всё подряд — сохраню молча в %s. Завершить: /stop",
+		"capture.dup":      "(уже было #%d) %s",
+		"capture.rejected": "(не сохранено) %s",
+		"capture.failed":   "(ошибка записи) %s",
+		"capture.locked":   "(чат закрыт) %s",
+		"capture.none":     "Сессия записи не запущена. Начать: /capture задачи",
+		"capture.empty":    "Сессия завершена, ничего не записано.",
+		"capture.done":     "ЗАПИСАНО В %s (%d):\n%s",
+
+		"file.send.failed":     "Не удалось отправить файл.",
+		"file.download.failed": "Не удалось скачать файл.",
+		"import.failed":        "Не загрузил: %v",
+		"import.version":       "неизвестная версия %d",
+		"import.usage":         "Загрузить записи: ответьте на файл (CSV или JSON из /export, или текст по строке на запись под заголовками «# Покупки») командой /import. Настройки: /import settings",
+
+		"settings.caption":      "Настроек: %d. Загрузить: ответьте на этот файл командой /import settings",
+		"settings.reply":        "Ответьте командой на файл с настройками.",
+		"settings.notdoc":       "Это не файл настроек: %v",
+		"settings.done":         "Загружено настроек: %d.",
+		"settings.unknown":      "неизвестная настройка %q",
+		"settings.bad":          "%s: не %s",
+		"settings.bad.json":     "JSON",
+		"settings.bad.timezone": "часовой пояс",
+		"settings.bad.quiet":    "ЧЧ:ММ-ЧЧ:ММ",
+		"settings.bad.busy":     "расписание занятости",
+		"settings.bad.days":     "число дней",
+		"settings.bad.chat":     "номер чата",
+		"settings.bad.role":     "роль",
+		"settings.bad.roles":    "список ролей",
+		"settings.bad.template": "неверный шаблон %q",
+		"settings.bad.topic":    "неверный список %q",
+		"settings.topics.max":   "своих списков больше %d",
+
+		"goal.line":      "Цель %d (%s): %s\n%s %s/%s %s (%.0f%%)",
+		"goal.usage":     "Пример: /goal пробежать 100 км в марте",
+		"goal.nonumber":  "Не нашёл число цели. Пример: /goal пробежать 100 км в марте",
+		"goal.added":     "Отметить прогресс: /checkin %d 5\nПривязать задачу: /linkgoal <задача> %d [сколько]",
+		"goals.none":     "Целей нет. Добавить: /goal пробежать 100 км в марте",
+		"goal.notfound":  "Цель не найдена.",
+		"goal.deleted":   "Цель удалена.",
+		"goals.report":   "ЦЕЛИ ЗА %s:",
+		"checkin.usage":  "Пример: /checkin 1 5",
+		"checkin.done":   "Записал.",
+		"delgoal.usage":  "Пример: /delgoal 1",
+		"linkgoal.usage": "Пример: /linkgoal 12 1 5",
+		"linkgoal.none":  "Нет такой задачи/цели.",
+		"linkgoal.done":  "Задача #%d при выполнении добавит %s к цели %d.",
+
+		"maint.months":      "%d мес.",
+		"maint.km":          "%d км",
+		"maint.or":          " или ",
+		"maint.line":        "%d. %s — каждые %s, следующее: %s",
+		"maint.done.button": "✅ Сделано",
+		"maint.done.answer": "Сделано",
+		"maint.none":        "Регламентов нет. Пример: /maint add замена масла каждые 6 месяцев или 10000 км",
+		"maint.header":      "ОБСЛУЖИВАНИЕ:",
+		"maint.add.usage":   "Пример: /maint add замена масла каждые 6 месяцев или 10000 км",
+		"maint.km.start":    "Отсчёт по км пойдёт от первого /odo <км>.",
+		"maint.id.usage":    "Пример: /maint %s 2",
+		"maint.notfound":    "Регламента %d нет.",
+		"maint.deleted":     "Регламент %d удалён.",
+		"maint.done":        "✅ Отмечено. Следующий срок — в /maint.",
+		"maint.usage":       "Обслуживание: /maint, /maint add <что> каждые 6 месяцев или 10000 км, /maint done <id> [км], /maint del <id>, пробег: /odo <км>",
+		"maint.due":         "🔧 Пора: %s",
+		"maint.soon":        "🔧 Скоро: %s",
+		"odo.usage":         "Пример: /odo 48200",
+		"odo.show":          "Пробег: %d км. Обновить: /odo <км>",
+		"odo.show.at":       "Пробег: %d км (%s). Обновить: /odo <км>",
+		"odo.set":           "Пробег: %d км.",
+		"odo.ask":           "🚗 Какой сейчас пробег? /odo <км>",
+
+		"view.err.topic":    "не знаю такой список: %s",
+		"view.err.due":      "due: <3d, >1w, overdue, any или none",
+		"view.err.duration": "не понял срок: %s",
+		"view.err.older":    "не понял older: %s",
+		"view.err.sort":     "sort: priority, due, age или text",
+		"view.err.limit":    "не понял limit: %s",
+		"view.err.is":       "не понял is:%s",
+		"view.err":          "Ошибка в запросе: %v",
+		"views.none":        "Сохранённых видов нет.",
+		"views.header":      "ВИДЫ:",
+		"views.at":          " (в %s)",
+		"view.help":         "Запрос: topic:задачи tag:@работа due:<3d sort:priority\nУсловия: topic:, tag:@…, due:<3d|>1w|overdue|any|none, older:7d, flagged, слова из текста; sort:priority|due|age|text, limit:N.\n/view <запрос или имя> — показать\n/view save <имя> <запрос> — сохранить\n/view at <имя> ЧЧ:ММ — присылать каждый день (- отключить)\n/view del <имя> — удалить",
+		"view.save.usage":   "Пример: /view save работа topic:задачи tag:@работа sort:priority",
+		"view.saved":        "Вид сохранён: /view %s",
+		"view.at.usage":     "Пример: /view at работа 09:00",
+		"view.notfound":     "Нет такого вида. Список: /view",
+		"view.at.off":       "Рассылка вида отключена.",
+		"view.at.on":        "Буду присылать «%s» каждый день в %s.",
+		"view.del.usage":    "Пример: /view del работа",
+		"view.deleted":      "Вид удалён.",
+		"view.search":       "ПОИСК",
+
+		"watches.none":   "Отслеживаемых запросов нет.",
+		"watches.header": "ОТСЛЕЖИВАЮ:",
+		"watch.help":     "Добавить: /watch паспорт (синтаксис как в /view), удалить: /watch del <номер>",
+		"watch.notfound": "Нет запроса с таким номером. Список: /watch",
+		"watch.deleted":  "Больше не отслеживаю.",
+		"watch.max":      "Не больше %d запросов.",
+		"watch.added":    "Сообщу, как только появится запись по запросу «%s».",
+		"watch.dm":       "Уведомления придут в личку — если ещё не писали боту, нажмите /start у него.",
+
+		"rule.err.arrow":     "нужна стрелка: «если … → …»",
+		"rule.err.empty":     "пустой текст в «%s»",
+		"rule.err.time":      "не понял время в «%s»",
+		"rule.err.older":     "не понял «%s»",
+		"rule.err.topic":     "не знаю такой список: «%s»",
+		"rule.err.cond":      "не понял условие «%s»",
+		"rule.err.action":    "не понял действие «%s»",
+		"rule.err.noaction":  "нет действия",
+		"rule.err.scheduled": "по расписанию можно только перенести или пометить",
+		"rule.err":           "Не понял правило: %v",
+		"rules.help":         "Примеры:\n/rules add если текст содержит 'купить' → список=покупки\n/rules add если добавлено после 22:00 → молча\n/rules add если список корзина и старше 7 дней → список=когда-нибудь\nУсловия: текст содержит '…', добавлено после/до ЧЧ:ММ, список …, старше N дней.\nДействия: список=…, молча, пометить.\nУдалить: /rules del <номер>",
+		"rules.none":         "Правил нет.",
+		"rules.header":       "ПРАВИЛА:",
+		"rules.max":          "Не больше %d правил.",
+		"rule.added":         "Правило %d добавлено.",
+		"rule.notfound":      "Нет правила с таким номером. Список: /rules",
+		"rule.deleted":       "Правило удалено.",
+
+		"template.shopped":        "🛒 %s: в покупки %d, уже были %d.",
+		"templates.header":        "ШАБЛОНЫ:",
+		"template.where.task":     "задача",
+		"template.where.shopping": "покупки",
+		"templates.help":          "Свой: /template set Название: пункт; пункт. Вернуть встроенный: /template del Название",
+		"template.set.usage":      "Пример: /template set Переезд: коробки; скотч; грузчики",
+		"template.saved":          "Шаблон «%s» (%d пунктов) сохранён. Применить: /template %s",
+		"template.own.notfound":   "Своего шаблона с таким названием нет.",
+		"template.deleted":        "Шаблон удалён.",
+		"template.notfound":       "Нет такого шаблона. Все шаблоны: /templates",
+		"template.gone":           "Шаблона больше нет.",
+
+		"recipe.notfound": "Рецепта «%s» нет.",
+		"recipe.deleted":  "Рецепт «%s» удалён.",
+		"recipes.none":    "Рецептов нет.",
+		"recipe.usage":    "Пример: /recipe Борщ: свёкла 2 шт, капуста 0,5 кг, сметана",
+		"recipes.header":  "РЕЦЕПТЫ:",
+		"recipe.saved":    "Рецепт «%s» (%d ингредиентов) сохранён. В план: /meal пн %s",
+		"meals.header":    "МЕНЮ НА НЕДЕЛЮ:",
+		"meals.shop.hint": "В покупки: /meal shop",
+		"meal.usage":      "Пример: /meal пн борщ, /meal пн - (очистить), /meal shop",
+		"meal.norecipe":   "Рецепта «%s» нет. Добавить: /recipe %s: …",
+		"meal.cleared":    "%s: блюдо снято.",
+		"meal.shop.empty": "Нечего добавлять: в плане нет блюд. Пример: /meal пн борщ",
+		"meal.shop.done":  "🛒 В покупки: новых %d, дополнено %d.",
+
+		"calendar.title":   "РАСПИСАНИЕ НА СЕГОДНЯ",
+		"busy.daily":       "ежедневно",
+		"busy.after":       "после",
+		"busy.after.label": "после «%s»",
+		"plan.full":        "Свободного времени сегодня не осталось.",
+		"plan.free":        "Свободно: %s",
+		"plan.left":        "Не помещается: %d",
+		"plan.title":       "ПЛАН НА СЕГОДНЯ",
+		"plan.busy.hint":   "Занятое время: /busy",
+		"busy.none":        "Занятых блоков нет.",
+		"busy.usage":       "Пример: /busy пн-пт 09-18 работа",
+		"busy.header":      "ЗАНЯТО КАЖДУЮ НЕДЕЛЮ:",
+		"busy.help":        "Удалить: /busy del <номер>, все: /busy off",
+		"busy.del.usage":   "Пример: /busy del 1",
+		"busy.saved":       "Занятых блоков: %d. Список: /busy, план дня: /plan",
+
+		"minutes.m":         "%dм",
+		"minutes.h":         "%dч",
+		"minutes.hm":        "%dч%02dм",
+		"today.none":        "На сегодня задач нет.",
+		"today.header":      "СЕГОДНЯ:",
+		"today.scheduled":   "На сегодня по срокам и напоминаниям: %d, оценка %s из %s",
+		"today.unestimated": " (без оценки: %d)",
+		"today.adjusted":    "С поправкой на факт: %s",
+		"today.overload":    "⚠️ Перегруз на %s. Предлагаю отложить:",
+		"capacity.show":     "Ёмкость дня: %s.",
+		"capacity.hint":     "Изменить: /capacity 300 (минуты) или /capacity ~5ч",
+		"capacity.usage":    "Не понял. Пример: /capacity 300 или /capacity ~5ч",
+		"estimate.under":    "вы обычно занижаете оценки на %d%%",
+		"estimate.over":     "вы обычно завышаете оценки на %d%%",
+		"timer.idle":        "Таймер не идёт.",
+		"timer.hint":        "Запустить: /timer <номер задачи>",
+		"timer.running":     "⏱ #%s: %s. Остановить: /timer stop",
+		"timer.logged":      "⏱ #%d: записал %s.",
+		"timer.usage":       "Пример: /timer 12",
+		"timer.started":     "⏱ Пошёл таймер: #%d %s",
+		"timer.previous":    " (#%d: записал %s)",
+		"spent.usage":       "Пример: /spent 12 45м",
+		"estimates.none":    "Пока не с чем сравнивать: нужны выполненные задачи с оценкой (~30м) и временем (/timer или /spent).",
+		"estimates.header":  "ОЦЕНКИ И ФАКТ (задач: %d):\nВ среднем факт = оценка ×%.2f",
+		"item.notfound":     "Записи #%d нет.",
+		"estimates.tag":     "%s: ×%.2f (задач: %d)",
+		"estimates.few":     "/today начнёт учитывать поправку после %d задач.",
+
+		"calendar.premium":           "Синхронизация календаря доступна в премиуме: /premium",
+		"calendar.failed":            "Ошибка чтения календаря: %v",
+		"digest.streak":              "🔥 Серия: %d дн. подряд с выполненными делами",
+		"digest.stale":               "🕸 %d задач(и) старше %d дн. Самая старая: #%d %s",
+		"digest.section.weather":     "Погода",
+		"digest.section.calendar":    "Календарь",
+		"digest.section.quote":       "Цитата",
+		"digest.section.streaks":     "Серии",
+		"digest.section.stale":       "Залежавшиеся",
+		"digest.section.script":      "Скрипт",
+		"digest.section.anniversary": "Год назад",
+		"digest.section.plan":        "План дня",
+		"digest.sections":            "Разделы утреннего дайджеста:",
+
+		"bill.paid":      "Оплачено",
+		"bill.line":      "%s %d. %s — %s, до %s",
+		"bills.total":    "Итого: %s, оплачено %s, осталось %s.",
+		"bills.none":     "Счетов нет. Пример: /bill add интернет 650 15",
+		"bills.header":   "СЧЕТА ЗА %s:",
+		"bill.add.usage": "Пример: /bill add интернет 650 15 — название, сумма, число месяца",
+		"bill.added":     "Счёт %d: %s — %s каждый месяц до %d числа.",
+		"bill.id.usage":  "Пример: /bill %s 3",
+		"bill.deleted":   "Счёт %d удалён.",
+		"bill.notfound":  "Счёта %d нет.",
+		"bill.paid.done": "✅ Счёт %d оплачен за %s.",
+		"bill.usage":     "Счета: /bill, /bill add <название> <сумма> <день>, /bill paid <id>, /bill del <id>",
+		"bill.overdue":   "просрочен с %s",
+		"bill.due.today": "срок сегодня",
+		"bill.due":       "срок %s",
+
+		"journal.prompt":       "📓 Как прошёл день?",
+		"journal.prompt.reply": "Ответьте на это сообщение.",
+		"journal.added":        "📓 Записал в дневник за %s.",
+		"journal.header":       "ДНЕВНИК:",
+		"journal.title":        "Дневник",
+		"journal.status.off":   "Вечерний вопрос выключен, включить: /journal on 21:30",
+		"journal.status.on":    "Вечерний вопрос в %s, выключить: /journal off",
+		"journal.help":         "📓 Запись: /journal <текст> или ответ на вечерний вопрос. Неделя: /journal week, файлом: /journal md",
+		"journal.on.usage":     "Пример: /journal on 21:30",
+		"journal.on":           "📓 Спрошу, как прошёл день, в %s.",
+		"journal.off":          "Вечерний вопрос выключен.",
+		"journal.md.usage":     "Пример: /journal md month",
+		"journal.empty":        "В дневнике за это время пусто.",
+		"journal.caption":      "Дневник: %d записей.",
+
+		"link.code":          "Отправьте в чате для уведомлений (бот должен быть там участником):\n/linkchat %s\nКод действует %d мин.",
+		"link.notfound":      "Код не найден.",
+		"link.invalid":       "Код недействителен.",
+		"link.same":          "Код нужно отправить в другом чате.",
+		"link.done":          "Чат подключён для уведомлений.",
+		"link.linked":        "Подключён чат «%s». Настроить: /route",
+		"link.gone":          "Чат больше не подключён.",
+		"route.header":       "Чаты для уведомлений:",
+		"route.none":         "— нет. Подключить: /linkchat",
+		"route.help":         "/route <список|#id> <номер чата> — направить, /route <список|#id> - — вернуть сюда, /route unlink <номер> — отключить чат",
+		"route.notfound":     "Нет чата с таким номером. Список: /route",
+		"route.unlink.usage": "Пример: /route unlink 1",
+		"route.item.usage":   "Пример: /route #12 1",
+		"topic.unknown":      "Не знаю такой список.",
+		"route.done":         "Готово.",
+
+		"mood.question":    "Как настроение и энергия сегодня?",
+		"mood.header":      "НАСТРОЕНИЕ И ЗАДАЧИ %s:",
+		"mood.none":        "Отметок настроения не было.",
+		"mood.average":     "Задач в среднем: %s",
+		"mood.r.none":      "связи почти нет",
+		"mood.r.good":      "в хорошие дни делаете больше",
+		"mood.r.bad":       "в плохие дни делаете больше",
+		"mood.r":           "Связь: %.2f — %s.",
+		"mood.on.usage":    "Пример: /mood on 20:00",
+		"mood.on":          "Спрошу о настроении в %s. График: /mood month",
+		"mood.off":         "Больше не спрашиваю о настроении.",
+		"mood.month.usage": "Пример: /mood month 09.2026",
+		"mood.usage":       "Отметить: /mood, каждый день: /mood on 20:00, график: /mood month",
+		"mood.set":         "Настроение за %s: %s. Поменять — другой кнопкой.",
+
+		"crypt.delete.failed": "Не смог удалить сообщение с паролем — удалите его сами.",
+		"crypt.encrypt.usage": "Пример: /encrypt длинная фраза-пароль",
+		"crypt.already":       "Шифрование уже включено. Снять: /decrypt <пароль>",
+		"crypt.short":         "Пароль короче 8 символов, возьмите длиннее.",
+		"crypt.enabled":       "🔒 Шифрование включено, чат открыт на %d мин. Пароль нигде не хранится: забытый пароль — потерянные записи.\n/lock — закрыть, /unlock <пароль> — открыть.",
+		"crypt.decrypt.usage": "Пример: /decrypt <пароль>",
+		"crypt.off":           "Шифрование не включено.",
+		"crypt.hint":          "Включить: /encrypt <пароль>",
+		"crypt.wrong":         "Неверный пароль.",
+		"crypt.disabled":      "🔓 Шифрование снято, записи хранятся открыто.",
+		"crypt.unlock.usage":  "Пример: /unlock <пароль>",
+		"crypt.unlocked":      "🔓 Открыто на %d мин. Закрыть раньше: /lock",
+		"crypt.locked":        "🔒 Закрыто.",
+		"crypt.closed":        "🔒 Чат закрыт. Открыть: /unlock <пароль>",
+
+		"search.usage":        "Пример: /search паспорт, с архивом: /search архив паспорт",
+		"search.none":         "Ничего не нашёл.",
+		"search.header":       "ПОИСК «%s»:",
+		"search.active":       "активные",
+		"search.done":         "выполнено",
+		"archive.title":       "АРХИВ",
+		"search.archive.hint": "С архивом: /search архив %s",
+		"search.limit":        "Показаны первые %d совпадений.",
+
+		"archive.empty":   "Пусто. Старые месяцы: /history",
+		"archive.kept":    "Хранится %d дн.",
+		"archive.usage":   "Пример: /archive задачи",
+		"retention.days":  "Архив хранится %d дн. Изменить: /retention <дни>, хранить всё: /retention off",
+		"retention.off":   "Архив хранится без срока.",
+		"retention.hint":  "Ограничить: /retention 90",
+		"retention.usage": "Пример: /retention 90",
+		"retention.set":   "Выполненное и архив старше %d дн. будут удаляться каждую ночь.",
+
+		"migrate.notours":         "это не файл /migrate export",
+		"migrate.usage":           "Перенос чата в другой бот:\n/migrate export — выгрузить всё\n/migrate import — ответом на файл в новом боте",
+		"migrate.toobig":          "Выгрузка больше %s — Telegram не даст её скачать.",
+		"migrate.caption":         "Записей: %d, месяцев архива: %d. В новом боте ответьте на этот файл командой /migrate import",
+		"migrate.reply":           "Ответьте командой на файл из /migrate export.",
+		"migrate.notempty":        "В этом чате уже есть записи. Добавить к ним: /migrate import force",
+		"migrate.done":            "Перенесено: записей %d (номера сохранены у %d), заметок %d, целей %d, месяцев архива %d.",
+		"migrate.settings.failed": "Настройки не загрузил: %v",
+		"migrate.settings":        "Настроек: %d.",
+
+		"trip.task":       "Собрать вещи: %s, %s",
+		"trip.socks":      "бельё и носки ×%d",
+		"trip.usage":      "Пример: /trip пляж 15.07 22.07 — тип: %s",
+		"trip.reminder":   "Начать собираться: %s, %s (задача #%d)",
+		"trip.remind":     "⏰ Начать собираться: %s.",
+		"remind.today":    "сегодня в %s",
+		"remind.tomorrow": "завтра в %s",
+		"remind.on":       "%s в %s",
+		"remind.set":      "⏰ Напомню %s.",
+
+		"snooze.tomorrow": "Завтра",
+		"snooze.done":     "Отложено %s",
+
+		"review.now":     "Сделать сейчас",
+		"review.delete":  "Удалить",
+		"review.notime":  "Без времени",
+		"review.header":  "РАЗБОР КОРЗИНЫ (%d из %d)",
+		"review.empty":   "Корзина разобрана 🎉",
+		"review.over":    "Разбор окончен, в корзине осталось %d. Ещё круг: /review",
+		"review.gone":    "Уже разобрано",
+		"review.when":    "Когда напомнить?",
+		"review.done":    "сделано",
+		"review.deleted": "удалено",
+		"review.skipped": "осталось в корзине",
+
+		"cards.none":     "Карт нет. Пришлите фото карты с подписью /card <магазин> или: /card add <магазин> <номер>",
+		"cards.header":   "💳 Карты:",
+		"card.add.usage": "Пример: /card add Лента 778812345678 — или фото карты с подписью /card Лента",
+		"card.notfound":  "Карты «%s» нет.",
+		"card.deleted":   "Карта «%s» удалена.",
+		"card.hint":      "Все карты: /card",
+		"card.name.long": "Название магазина — до %d символов.",
+		"card.saved":     "💳 Карта «%s» сохранена. Показать: /card %s",
+		"cards.offer":    "💳 Карты магазинов:",
+		"card.gone":      "Карта удалена",
+
+		"item.goto":           "Перейти к сообщению",
+		"item.original":       "Оригинал",
+		"item.usage":          "Пример: /item 12",
+		"item.missing":        "Не нашёл такую запись.",
+		"item.created":        "Создано: %s",
+		"item.due":            "Срок: %s",
+		"item.completed":      "Выполнено: %s",
+		"item.forwarded":      "Переслано от: %s",
+		"item.source.none":    "Исходное сообщение не сохранено.",
+		"item.source":         "#%d — исходное сообщение ↑",
+		"item.source.deleted": "Исходное сообщение удалено.",
+
+		"picker.today":    "Сегодня",
+		"picker.tomorrow": "Завтра",
+		"picker.none":     "Без срока",
+		"picker.allday":   "Весь день",
+		"picker.back":     "Назад",
+		"picker.title":    "📅 Срок для #%d: %s",
+		"picker.current":  "Сейчас: %s",
+		"picker.cleared":  "📅 #%d: срок снят",
+		"picker.set":      "📅 #%d: срок %s",
+		"due.usage":       "Пример: /due 12",
+		"due.set":         "📅 Срок: %s.",
+		"due.by":          "до %s",
+		"overdue.header":  "ПРОСРОЧЕНО:",
+		"overdue.line":    "#%d %s (срок %s)",
+
+		"thread.discuss":         "Обсудить",
+		"thread.exists":          "Обсуждение уже есть (тема %d)",
+		"thread.unavailable":     "Темы в этом чате недоступны",
+		"thread.failed":          "Не удалось создать тему",
+		"thread.created":         "Тема создана",
+		"thread.notes":           "Ответы в этой теме сохраняются как заметки.",
+		"notes.usage":            "Пример: /notes 12",
+		"notes.none":             "Заметок нет.",
+		"notes.header":           "ЗАМЕТКИ #%d:",
+		"focus.banner":           "🎯 Фокус: %s до %s",
+		"focus.ended":            "Фокус на «%s» закончился. Отложенные напоминания придут сейчас.",
+		"focus.stop":             "Закончить: /focus off",
+		"focus.none":             "Фокуса нет.",
+		"focus.usage":            "Пример: /focus project Ремонт for 2h",
+		"focus.off":              "Фокус снят.",
+		"focus.set":              "Записей по проекту: %d, остальное подождёт. Список: /list",
+		"approval.ask":           "⚠️ Вы хотите %s. Подтвердите.",
+		"approval.ask.group":     "⚠️ %s хочет: %s.\nНужно подтверждение другого участника.",
+		"approval.confirm":       "Подтверждаю",
+		"approval.cancel":        "Отмена",
+		"approval.stale":         "Запрос устарел",
+		"approval.cancelled":     "Отменено",
+		"approval.cancelled.by":  "Отменил %s",
+		"approval.other":         "Подтвердить должен другой участник",
+		"approval.confirmed.by":  "Подтвердил %s. %s",
+		"approval.cleared":       "Удалено записей: %d.",
+		"clear.usage":            "Пример: /clear покупки",
+		"clear.what":             "очистить «%s» (%d записей)",
+		"deadletters.empty":      "Очередь пуста.",
+		"deadletters.header":     "НЕДОСТАВЛЕННОЕ:",
+		"deadletters.line":       "#%d %s → %d, %s, попыток %d: %s",
+		"deadletters.usage":      "Пример: /deadletters retry 3",
+		"deadletters.drop.usage": "Пример: /deadletters drop 3",
+		"deadletters.retried":    "Доставлено %d из %d.",
+		"deadletters.failed":     "Не удалось: %v",
+		"deadletters.delivered":  "Доставлено.",
+		"deadletters.dropped":    "Удалено.",
+
+		"next.priority":    "приоритет %s",
+		"next.overdue":     "просрочено",
+		"next.due.day":     "срок в ближайшие сутки",
+		"next.due.days":    "срок через %d дн.",
+		"next.age":         "%d дн.",
+		"next.effort":      "~%dм",
+		"next.none":        "Задач нет — можно разобрать корзину.",
+		"next.header":      "С ЧЕГО НАЧАТЬ:",
+		"speak.busy.block": "с %s до %s",
+		"speak.busy":       "Сегодня занято %s.",
+		"speak.none":       "Задач на сегодня нет.",
+		"speak.count":      "Задач на сегодня: %d.",
+		"speak.first":      "Первая: %s.",
+		"speak.rest":       "Дальше: %s.",
+		"speak.more":       "И ещё %d.",
+		"speak.on":         "Спросите голосом «что у меня сегодня» — отвечу голосом.",
+		"speak.off":        "Голосовые ответы выключены. Включить: /speak on",
+		"speak.unset":      "Синтез речи не настроен (TTS_BACKEND).",
+		"speak.help":       "Спросите голосом «что у меня сегодня» или «что дальше» — отвечу голосом. Выключить: /speak off",
+		"times.header":     "Время суток:",
+		"times.hint":       "Изменить: /times вечером 21:00, удалить: /times вечером -, сбросить: /times reset",
+		"times.reset":      "Время суток сброшено.",
+		"times.usage":      "Пример: /times утром 08:30",
+		"times.badname":    "Имя не должно содержать «=» и «,».",
+		"times.badclock":   "Время в формате ЧЧ:ММ, например 08:30.",
+		"times.deleted":    "Удалено: %s",
+		"newlist.usage":    "Пример: /newlist 📚 Книги",
+		"newlist.long":     "Название длиннее %d символов.",
+		"newlist.taken":    "Это название уже занято.",
+		"newlist.limit":    "Больше %d своих списков не бывает. Удалить: /dellist <название>",
+		"newlist.added":    "Список «%s» добавлен на клавиатуру.",
+		"dellist.usage":    "Пример: /dellist книги",
+		"dellist.builtin":  "Встроенные списки не удаляются, только свои из /newlist.",
+		"dellist.done":     "Список «%s» удалён.",
+		"dellist.moved":    "Записи (%d) перенесены в корзину.",
+
+		"list.all":         "ВСЕ ЗАПИСИ",
+		"list.usage":       "Пример: /list задачи",
+		"keyboard.header":  "Дополнительные кнопки:",
+		"keyboard.hint":    "Переключить: /keyboard today on | off",
+		"keyboard.unknown": "Не знаю такой кнопки. Доступно: %s",
+		"keyboard.updated": "Клавиатура обновлена.",
+		"rename.usage":     "Пример: /rename покупки 🛒 Магазин",
+		"weather.precip":   "осадки %.0f%%",
+		"weather.clear":    "Ясно",
+		"weather.partly":   "Переменная облачность",
+		"weather.overcast": "Пасмурно",
+		"weather.fog":      "Туман",
+		"weather.drizzle":  "Морось",
+		"weather.rain":     "Дождь",
+		"weather.snow":     "Снег",
+		"weather.storm":    "Гроза",
+		"script.help":      "Скрипт на Starlark (диалект Python). Можно определить:\ndef on_capture(text, topic): вернуть новый текст записи\ndef digest(items): строка для раздела «Скрипт» в /digest\ndef on_event(event): ответ на создание/выполнение/удаление/перенос\nСохранить: /script <код>, проверить: /script test <текст>, удалить: /script reset",
+		"script.none":      "Скрипта нет.",
+		"script.current":   "Скрипт:",
+		"script.reset":     "Скрипт удалён.",
+		"script.error":     "Ошибка: %s",
+		"script.nocapture": "on_capture не определена.",
+		"script.toobig":    "Скрипт больше %d КБ.",
+		"script.invalid":   "Ошибка в скрипте: %s",
+		"script.saved":     "Скрипт сохранён. Проверить: /script test <текст>",
+		"bytes.mb":         "%.1f МБ",
+		"bytes.kb":         "%d КБ",
+		"bytes.b":          "%d Б",
+		"usage.header":     "ДАННЫЕ ЧАТА:",
+		"usage.topic":      "%s: активные %d, выполнено %d",
+		"usage.empty":      "Записей нет.",
+		"usage.oldest":     "Самая старая запись: %s",
+		"usage.archived":   "В архиве: %d (с %s), /history",
+		"usage.files":      "Вложения: %d, хранится %s",
+		"usage.text":       "Текст в базе: ≈%s",
+		"usage.recent":     "За 30 дней: +%d записей (≈%s), за год ≈%s.",
+		"usage.compact":    "Выполненное уходит в архив через %d дн. (COMPACT_AFTER_DAYS).",
+
+		"agenda.allday":         "весь день",
+		"attach.usage":          "Пример: /attach 12 к встрече с Иваном",
+		"attach.nocalendar":     "Календарь недоступен.",
+		"attach.noevent":        "Не нашёл такое событие в ближайшие 7 дней.",
+		"attach.done":           "#%d привязано к «%s» (%s).",
+		"schedule.unset":        "Расписание из Google Calendar не настроено (GCAL_CALENDAR_ID не задан).",
+		"schedule.unauthorized": "Google Calendar не подключён: владелец бота может выполнить /gcalauth.",
+		"schedule.empty":        "Расписание на сегодня (%s): событий нет.",
+		"schedule.title":        "Расписание на сегодня (%s):",
+		"travel.off":            "Режим путешествия выключен.",
+		"travel.usage":          "Пример: /travel Asia/Tokyo until 2025-06-10",
+		"travel.status":         "В пути: %s до %s.",
+		"travel.bad":            "Не понял.",
+		"travel.past":           "Дата окончания уже прошла.",
+		"travel.set":            "Режим путешествия: напоминания по времени %s до %s включительно.",
+		"travel.ended":          "Режим путешествия закончился, время снова %s.",
+		"leaderboard.header":    "ЛИДЕРЫ НЕДЕЛИ:",
+		"leaderboard.fire":      "Неделя в огне!",
+		"leaderboard.off":       "Еженедельный рейтинг выключен.",
+		"leaderboard.on":        "Еженедельный рейтинг включён.",
+		"leaderboard.empty":     "За неделю пока никто ничего не закрыл.",
+		"import.empty.file":     "файл пустой",
+		"import.unparsed":       "Не разобрал файл: %v",
+		"import.none":           "В файле нет записей.",
+		"import.done":           "Загружено записей: %d.",
+		"secret.usage":          "Пример: /secret 12 — скрыть запись #12 (повторно — показать)",
+		"secret.off":            "#%d больше не секрет.",
+		"secret.on":             "#%d скрыта: в списках и дайджестах — %s, текст по кнопке 👁.",
+		"secret.ttl":            "исчезнет через %d с",
+		"retro.new":             "новое",
+		"retro.done":            "Выполнено: %d (%s к прошлому месяцу)",
+		"retro.tags":            "Топ тегов: %s",
+		"retro.longest":         "Дольше всего ждало (%d дн.): %s",
+		"retro.streak":          "Серия: %d дн. подряд (в прошлом месяце %d)",
+		"retro.title":           "ИТОГИ %s:",
+		"quiet.off":             "Тихие часы выключены.",
+		"quiet.enable":          "Включить: /quiet 23:30-07:30",
+		"quiet.status":          "Тихие часы: %s. Выключить: /quiet off",
+		"quiet.usage":           "Пример: /quiet 23:30-07:30",
+		"quiet.set":             "Тихие часы: %s. Уведомления за это время придут в %s.",
+
+		"history.empty":     "Архив пуст.",
+		"history.hint":      "Подробно: /history ГГГГ-ММ",
+		"history.usage":     "Пример: /history 2024-03",
+		"channel.status":    "Дайджест дублируется в канал %d. Отключить: /digestchannel off",
+		"channel.usage":     "Добавьте бота администратором канала и отправьте: /digestchannel @канал или /digestchannel -100…",
+		"channel.off":       "Канал для дайджеста отключён.",
+		"channel.notfound":  "Канал не найден. Бот должен быть его администратором.",
+		"channel.cantpost":  "У бота нет права публиковать сообщения в канале.",
+		"channel.notadmin":  "Подключить канал может только его администратор.",
+		"channel.set":       "Утренний дайджест будет дублироваться в «%s».",
+		"tz.current":        "Часовой пояс: %s, сейчас %s.",
+		"tz.hint":           "Сменить: /timezone Europe/Moscow или пришлите геопозицию.",
+		"tz.approx":         "Это примерно, по долготе и без перехода на летнее время; точнее: /timezone Europe/Moscow",
+		"tz.default":        "Часовой пояс по умолчанию: %s.",
+		"tz.unknown":        "Не знаю такой зоны. Пример: /timezone Europe/Moscow или /timezone +3",
+		"someday.activate":  "Активировать",
+		"someday.keep":      "Оставить",
+		"someday.delete":    "Удалить",
+		"someday.review":    "КОГДА-НИБУДЬ: ещё актуально?",
+		"someday.activated": "Активировано",
+		"someday.moved":     "Перенесено в %s",
+		"someday.kept":      "Оставлено",
+		"someday.stays":     "Осталось в %s",
+		"gcal.unset":        "Google Calendar не настроен: задайте GCAL_CALENDAR_ID, GCAL_CLIENT_ID и GCAL_CLIENT_SECRET.",
+		"gcal.start.failed": "Не удалось начать авторизацию: %v",
+		"gcal.code":         "Откройте %s и введите код %s (действует %d мин).",
+		"gcal.poll.failed":  "Авторизация не завершена: %v",
+		"gcal.done":         "Google Calendar подключён.",
+		"backup.caption":    "Резервная копия %s",
+		"backup.failed":     "Резервная копия не удалась: %v",
+		"backup.primary":    "Резервные копии всех ботов делает основной бот.",
+		"backup.sent":       "Резервная копия отправлена (файлов: %d).",
+
+		"export.caption":        "Записей: %d.",
+		"export.usage":          "Выгрузить записи: /export csv или /export json, настройки: /export settings",
+		"digesttpl.current":     "Шаблон дайджеста:",
+		"digesttpl.help":        "Доступно: .Date, .Weekday, .Sections (Name, Title, Body, Empty), .S.weather и т.п., функции upper/lower.\nСбросить: /digesttemplate reset",
+		"digesttpl.reset":       "Шаблон дайджеста сброшен.",
+		"digesttpl.error":       "Ошибка в шаблоне: %v",
+		"digesttpl.saved":       "Шаблон сохранён. Проверить: /digest now",
+		"contexts.none":         "Контекстов нет. Добавьте тег к записи, например: «позвонить маме @звонки».",
+		"contexts.pick":         "Выберите контекст:",
+		"contexts.header":       "Контекст %s:",
+		"compact.on":            "Компактный режим: клавиатура скрыта. Команды: /menu, /today, /next, /capture. Вернуть: /compact off",
+		"compact.off":           "Клавиатура возвращена.",
+		"compact.status.on":     "Компактный режим включён.",
+		"compact.status.off":    "Компактный режим выключен.",
+		"attachment.photo":      "фото от %s",
+		"anniversary.year":      "Год назад",
+		"anniversary.years.few": "%d года назад",
+		"anniversary.years":     "%d лет назад",
+		"anniversary.line":      "%s вы закрыли: %s",
+		"event.soon":            "СКОРО: %s в %s",
+		"event.leave":           "дорога ~%d мин — выходить к %s",
+		"prep.title":            "Подготовка: %s (%s)",
+
+		"webhook.stalled":    "Вебхук не получает обновления (%s). Бот переключился на long polling до перезапуска.",
+		"voice.note":         "голосовое %d:%02d от %s",
+		"voice.unrecognized": "Не удалось распознать речь, сохранил запись #%d с голосовым.",
+
+		"triage.nudge": "РАЗБЕРИ КОРЗИНУ: %d шт. лежат дольше %d дн.",
+		"triage.start": "Разобрать",
+		"triage.done":  "Корзина разобрана.",
+		"triage.empty": "Корзина пуста.",
+	},
+	LangEN: {
+		"menu.opened":        "Menu opened. Default mode: BASKET.",
+		"menu.opened.basket": "Menu opened. Mode: BASKET.",
+		"mode":               "Mode: %s.",
+		"added":              "ADDED TO %s.",
+		"dup":                "Already in %s: #%d.",
+		"err.write":          "Write error.",
+		"filter.rejected":    "This message was not saved.",
+		"only.text":          "I only understand text.",
+		"empty":              "Empty.",
+		"deleted":            "Deleted",
+		"deleted.mark":       "✅ Deleted",
+		"done":               "Done",
+		"done.mark":          "✅ Done",
+		"lang.choose":        "Choose a language:",
+		"lang.set":           "Language: English.",
+		"lang.unknown":       "Unknown language. Available: ru, en.",
+
+		"btn.tasks":     "Tasks",
+		"btn.reminders": "Reminders",
+		"btn.shopping":  "Shopping",
+		"btn.basket":    "Basket",
+		"btn.someday":   "Someday",
+		"btn.today":     "Today",
+		"btn.review":    "Review",
+
+		"label.tasks":     "TASKS",
+		"label.reminders": "REMINDERS",
+		"label.shopping":  "SHOPPING",
+		"label.basket":    "BASKET",
+		"label.someday":   "SOMEDAY",
+
+		"item.tasks":     "TASK",
+		"item.reminders": "REMINDER",
+		"item.shopping":  "BUY",
+		"item.basket":    "BASKET",
+		"item.someday":   "SOMEDAY",
+
+		"err.read":   "Read error.",
+		"owner.only": "Only the bot's owner may run this command.",
+
+		"wipe.done":        "REMINDERS CLEARED (nightly wipe). They are in /archive.",
+		"premium.expiring": "PREMIUM ends on %s. Renew: /premium",
+		"premium.expired":  "PREMIUM has ended. Renew: /premium",
+
+		"intents.help":    "You can write in plain words:\n• show tasks / show shopping / show list\n• what's today\n• what next\n• done 5 — mark item #5 done\n• delete 3 — delete item #3 (bring back: /undo)\n• find milk\nEverything else I save as a new item.",
+		"intents.no_item": "There is no item #%d.",
+		"intents.done":    "✅ #%d %s",
+		"intents.deleted": "🗑 #%d %s deleted. Bring back: /undo",
+		"intents.on":      "I understand commands in plain words.",
+		"intents.off":     "Now any text is a new item. Turn back on: /intents on",
+		"intents.hint":    "Turn off: /intents off",
+
+		"role.owner":         "owner",
+		"role.editor":        "editor",
+		"role.viewer":        "viewer",
+		"role.denied":        "Not allowed: this needs the %s role. Roles: /role",
+		"role.denied.button": "Needs the %s role",
+		"role.private":       "Roles are for groups only: in a private chat you decide everything.",
+		"role.reset":         "Roles reset: every member may do everything again.",
+		"role.default.usage": "Default role: editor or viewer.",
+		"role.default.set":   "Everyone else is %s.",
+		"role.usage":         "Reply to a member's message: /role editor, or give an id: /role 123456 viewer",
+		"role.unknown":       "Roles: owner, editor, viewer (or off).",
+		"roles.none":         "ROLES: none set, every member may do everything.\n\nTo set one, reply to a member's message with /role viewer",
+		"roles.default":      "ROLES: members are %s, group admins are owners.",
+		"roles.header":       "ROLES:",
+		"roles.rest":         "Everyone else is %s, group admins are owners.",
+
+		"backup.s3.failed":    "⚠️ S3 backup failed: %v",
+		"restore.main":        "Only the main bot restores, for all bots.",
+		"restore.sqlite_only": "Restoring from a snapshot works with SQLite only.",
+		"restore.no_s3":       "S3 is not configured: %v",
+		"restore.list.failed": "Could not read the bucket: %v",
+		"restore.none":        "The bucket has no snapshots.",
+		"restore.list":        "S3 SNAPSHOTS:",
+		"restore.list.run":    "%s (%d files)",
+		"restore.list.hint":   "Restore: /restore <snapshot>",
+		"restore.unknown":     "No such snapshot. List: /restore list",
+		"restore.failed":      "Restore failed: %v",
+		"restore.staged":      "Snapshot %s is downloaded and checked (%d files). Restart the bot: it will start from this data and keep the current files next to them as .bak.",
+
+		"undo.nothing":   "Nothing to undo.",
+		"undo.created":   "↩️ Removed what was added: #%d %s",
+		"undo.completed": "↩️ Active again: #%d %s (%s)",
+		"undo.deleted":   "↩️ Restored: #%d %s (%s)",
+		"undo.moved":     "↩️ Moved back to %s: #%d %s",
+
+		"premium.free":           "Everything is free here.",
+		"premium.active":         "PREMIUM is active until %s.",
+		"premium.title":          "GTD Premium",
+		"premium.description":    "Requests understood by an LLM and calendar sync for %d days.",
+		"premium.label":          "Premium",
+		"premium.invoice.failed": "Could not send the invoice.",
+		"premium.invoice.stale":  "This invoice is out of date, ask for a new one with /premium.",
+		"premium.save.failed":    "Payment received, but the subscription could not be saved. Please contact the bot's owner.",
+		"premium.thanks":         "Thank you! PREMIUM is active until %s.",
+
+		"ack.show":    "Confirmations: %s. Options: /ack full | react | silent",
+		"ack.usage":   "Options: /ack full | react | silent",
+		"ack.set":     "Confirmations: %s.",
+		"edit.gone":   "Item #%d has been deleted.",
+		"edit.closed": "Item #%d is already closed, leaving it as it is.",
+
+		"capture.unknown":  "No such list. Example: /capture tasks",
+		"capture.running":  "A capture session is already running. Finish: /stop",
+		"capture.started":  "Write away — I'll quietly save everything to %s. Finish: /stop",
+		"capture.dup":      "(already #%d) %s",
+		"capture.rejected": "(not saved) %s",
+		"capture.failed":   "(write error) %s",
+		"capture.locked":   "(chat locked) %s",
+		"capture.none":     "No capture session is running. Start: /capture tasks",
+		"capture.empty":    "Session finished, nothing was saved.",
+		"capture.done":     "SAVED TO %s (%d):\n%s",
+
+		"file.send.failed":     "Could not send the file.",
+		"file.download.failed": "Could not download the file.",
+		"import.failed":        "Not loaded: %v",
+		"import.version":       "unknown version %d",
+		"import.usage":         "To load items, reply to a file (CSV or JSON from /export, or text with one item per line under headers like “# Shopping”) with /import. Settings: /import settings",
+
+		"settings.caption":      "Settings: %d. To load them, reply to this file with /import settings",
+		"settings.reply":        "Reply with the command to a settings file.",
+		"settings.notdoc":       "This is not a settings file: %v",
+		"settings.done":         "Settings loaded: %d.",
+		"settings.unknown":      "unknown setting %q",
+		"settings.bad":          "%s: not %s",
+		"settings.bad.json":     "JSON",
+		"settings.bad.timezone": "a time zone",
+		"settings.bad.quiet":    "HH:MM-HH:MM",
+		"settings.bad.busy":     "a busy schedule",
+		"settings.bad.days":     "a number of days",
+		"settings.bad.chat":     "a chat id",
+		"settings.bad.role":     "a role",
+		"settings.bad.roles":    "a list of roles",
+		"settings.bad.template": "bad template %q",
+		"settings.bad.topic":    "bad list %q",
+		"settings.topics.max":   "more than %d custom lists",
+
+		"goal.line":      "Goal %d (%s): %s\n%s %s/%s %s (%.0f%%)",
+		"goal.usage":     "Example: /goal run 100 km",
+		"goal.nonumber":  "The goal needs a number. Example: /goal run 100 km",
+		"goal.added":     "Log progress: /checkin %d 5\nLink a task: /linkgoal <task> %d [amount]",
+		"goals.none":     "No goals. Add one: /goal run 100 km",
+		"goal.notfound":  "Goal not found.",
+		"goal.deleted":   "Goal deleted.",
+		"goals.report":   "GOALS FOR %s:",
+		"checkin.usage":  "Example: /checkin 1 5",
+		"checkin.done":   "Logged.",
+		"delgoal.usage":  "Example: /delgoal 1",
+		"linkgoal.usage": "Example: /linkgoal 12 1 5",
+		"linkgoal.none":  "No such task or goal.",
+		"linkgoal.done":  "Task #%d will add %s to goal %d when done.",
+
+		"maint.months":      "%d months",
+		"maint.km":          "%d km",
+		"maint.or":          " or ",
+		"maint.line":        "%d. %s — every %s, next: %s",
+		"maint.done.button": "✅ Done",
+		"maint.done.answer": "Done",
+		"maint.none":        "No schedules. Example: /maint add oil change every 6 months or 10000 km",
+		"maint.header":      "MAINTENANCE:",
+		"maint.add.usage":   "Example: /maint add oil change every 6 months or 10000 km",
+		"maint.km.start":    "Distance counts from the first /odo <km>.",
+		"maint.id.usage":    "Example: /maint %s 2",
+		"maint.notfound":    "There is no schedule %d.",
+		"maint.deleted":     "Schedule %d deleted.",
+		"maint.done":        "✅ Marked. The next date is in /maint.",
+		"maint.usage":       "Maintenance: /maint, /maint add <what> every 6 months or 10000 km, /maint done <id> [km], /maint del <id>, mileage: /odo <km>",
+		"maint.due":         "🔧 Due: %s",
+		"maint.soon":        "🔧 Coming up: %s",
+		"odo.usage":         "Example: /odo 48200",
+		"odo.show":          "Mileage: %d km. Update: /odo <km>",
+		"odo.show.at":       "Mileage: %d km (%s). Update: /odo <km>",
+		"odo.set":           "Mileage: %d km.",
+		"odo.ask":           "🚗 What's the mileage now? /odo <km>",
+
+		"view.err.topic":    "unknown list: %s",
+		"view.err.due":      "due: <3d, >1w, overdue, any or none",
+		"view.err.duration": "can't parse the period: %s",
+		"view.err.older":    "can't parse older: %s",
+		"view.err.sort":     "sort: priority, due, age or text",
+		"view.err.limit":    "can't parse limit: %s",
+		"view.err.is":       "can't parse is:%s",
+		"view.err":          "Query error: %v",
+		"views.none":        "No saved views.",
+		"views.header":      "VIEWS:",
+		"views.at":          " (at %s)",
+		"view.help":         "Query: topic:tasks tag:@work due:<3d sort:priority\nConditions: topic:, tag:@…, due:<3d|>1w|overdue|any|none, older:7d, flagged, words from the text; sort:priority|due|age|text, limit:N.\n/view <query or name> — show\n/view save <name> <query> — save\n/view at <name> HH:MM — send every day (- to turn off)\n/view del <name> — delete",
+		"view.save.usage":   "Example: /view save work topic:tasks tag:@work sort:priority",
+		"view.saved":        "View saved: /view %s",
+		"view.at.usage":     "Example: /view at work 09:00",
+		"view.notfound":     "No such view. List: /view",
+		"view.at.off":       "View delivery turned off.",
+		"view.at.on":        "I'll send “%s” every day at %s.",
+		"view.del.usage":    "Example: /view del work",
+		"view.deleted":      "View deleted.",
+		"view.search":       "SEARCH",
+
+		"watches.none":   "No watched queries.",
+		"watches.header": "WATCHING:",
+		"watch.help":     "Add: /watch passport (same syntax as /view), remove: /watch del <number>",
+		"watch.notfound": "No query with that number. List: /watch",
+		"watch.deleted":  "No longer watching.",
+		"watch.max":      "No more than %d queries.",
+		"watch.added":    "I'll let you know as soon as something matches “%s”.",
+		"watch.dm":       "Notifications go to your DMs — if you haven't messaged the bot yet, press /start there.",
+
+		"rule.err.arrow":     "an arrow is needed: “if … → …”",
+		"rule.err.empty":     "empty text in “%s”",
+		"rule.err.time":      "can't parse the time in “%s”",
+		"rule.err.older":     "can't parse “%s”",
+		"rule.err.topic":     "unknown list: “%s”",
+		"rule.err.cond":      "can't parse the condition “%s”",
+		"rule.err.action":    "can't parse the action “%s”",
+		"rule.err.noaction":  "no action",
+		"rule.err.scheduled": "scheduled rules can only move or flag",
+		"rule.err":           "Can't parse the rule: %v",
+		"rules.help":         "Examples:\n/rules add if text contains 'buy' → topic=shopping\n/rules add if added after 22:00 → silent\n/rules add if topic basket and older than 7 days → topic=someday\nConditions: text contains '…', added after/before HH:MM, topic …, older than N days.\nActions: topic=…, silent, flag.\nRemove: /rules del <number>",
+		"rules.none":         "No rules.",
+		"rules.header":       "RULES:",
+		"rules.max":          "No more than %d rules.",
+		"rule.added":         "Rule %d added.",
+		"rule.notfound":      "No rule with that number. List: /rules",
+		"rule.deleted":       "Rule deleted.",
+
+		"template.shopped":        "🛒 %s: %d added to shopping, %d already there.",
+		"templates.header":        "TEMPLATES:",
+		"template.where.task":     "task",
+		"template.where.shopping": "shopping",
+		"templates.help":          "Your own: /template set Name: item; item. Restore a built-in one: /template del Name",
+		"template.set.usage":      "Example: /template set Moving: boxes; tape; movers",
+		"template.saved":          "Template “%s” (%d items) saved. Apply: /template %s",
+		"template.own.notfound":   "You have no template with that name.",
+		"template.deleted":        "Template deleted.",
+		"template.notfound":       "No such template. All templates: /templates",
+		"template.gone":           "The template is gone.",
+
+		"recipe.notfound": "No recipe “%s”.",
+		"recipe.deleted":  "Recipe “%s” deleted.",
+		"recipes.none":    "No recipes.",
+		"recipe.usage":    "Example: /recipe Pancakes: flour 200 g, milk 0.5 l, eggs 2 pcs",
+		"recipes.header":  "RECIPES:",
+		"recipe.saved":    "Recipe “%s” (%d ingredients) saved. Plan it: /meal mon %s",
+		"meals.header":    "MEALS THIS WEEK:",
+		"meals.shop.hint": "To shopping: /meal shop",
+		"meal.usage":      "Example: /meal mon pancakes, /meal mon - (clear), /meal shop",
+		"meal.norecipe":   "No recipe “%s”. Add it: /recipe %s: …",
+		"meal.cleared":    "%s: dish cleared.",
+		"meal.shop.empty": "Nothing to add: the plan has no dishes. Example: /meal mon pancakes",
+		"meal.shop.done":  "🛒 To shopping: %d new, %d topped up.",
+
+		"calendar.title":   "TODAY'S SCHEDULE",
+		"busy.daily":       "daily",
+		"busy.after":       "after",
+		"busy.after.label": "after “%s”",
+		"plan.full":        "No free time left today.",
+		"plan.free":        "Free: %s",
+		"plan.left":        "Doesn't fit: %d",
+		"plan.title":       "TODAY'S PLAN",
+		"plan.busy.hint":   "Busy time: /busy",
+		"busy.none":        "No busy blocks.",
+		"busy.usage":       "Example: /busy mon-fri 09-18 work",
+		"busy.header":      "BUSY EVERY WEEK:",
+		"busy.help":        "Remove: /busy del <number>, all: /busy off",
+		"busy.del.usage":   "Example: /busy del 1",
+		"busy.saved":       "Busy blocks: %d. List: /busy, today's plan: /plan",
+
+		"minutes.m":         "%dm",
+		"minutes.h":         "%dh",
+		"minutes.hm":        "%dh%02dm",
+		"today.none":        "No tasks for today.",
+		"today.header":      "TODAY:",
+		"today.scheduled":   "Due or reminded today: %d, estimated %s of %s",
+		"today.unestimated": " (no estimate: %d)",
+		"today.adjusted":    "Adjusted for actuals: %s",
+		"today.overload":    "⚠️ Overloaded by %s. I suggest postponing:",
+		"capacity.show":     "Daily capacity: %s.",
+		"capacity.hint":     "Change it: /capacity 300 (minutes) or /capacity ~5h",
+		"capacity.usage":    "Didn't get that. Example: /capacity 300 or /capacity ~5h",
+		"estimate.under":    "you usually underestimate by %d%%",
+		"estimate.over":     "you usually overestimate by %d%%",
+		"timer.idle":        "No timer running.",
+		"timer.hint":        "Start one: /timer <task number>",
+		"timer.running":     "⏱ #%s: %s. Stop: /timer stop",
+		"timer.logged":      "⏱ #%d: logged %s.",
+		"timer.usage":       "Example: /timer 12",
+		"timer.started":     "⏱ Timer started: #%d %s",
+		"timer.previous":    " (#%d: logged %s)",
+		"spent.usage":       "Example: /spent 12 45m",
+		"estimates.none":    "Nothing to compare yet: this needs completed tasks with an estimate (~30m) and tracked time (/timer or /spent).",
+		"estimates.header":  "ESTIMATES VS ACTUALS (tasks: %d):\nOn average actual = estimate ×%.2f",
+		"item.notfound":     "No item #%d.",
+		"estimates.tag":     "%s: ×%.2f (tasks: %d)",
+		"estimates.few":     "/today will start applying the correction after %d tasks.",
+
+		"calendar.premium":           "Calendar sync is a premium feature: /premium",
+		"calendar.failed":            "Couldn't read the calendar: %v",
+		"digest.streak":              "🔥 Streak: %d days in a row with something done",
+		"digest.stale":               "🕸 %d task(s) older than %d days. The oldest: #%d %s",
+		"digest.section.weather":     "Weather",
+		"digest.section.calendar":    "Calendar",
+		"digest.section.quote":       "Quote",
+		"digest.section.streaks":     "Streaks",
+		"digest.section.stale":       "Stale",
+		"digest.section.script":      "Script",
+		"digest.section.anniversary": "A year ago",
+		"digest.section.plan":        "Today's plan",
+		"digest.sections":            "Morning digest sections:",
+
+		"bill.paid":      "Paid",
+		"bill.line":      "%s %d. %s — %s, by %s",
+		"bills.total":    "Total: %s, paid %s, left %s.",
+		"bills.none":     "No bills. Example: /bill add internet 650 15",
+		"bills.header":   "BILLS FOR %s:",
+		"bill.add.usage": "Example: /bill add internet 650 15 — name, amount, day of the month",
+		"bill.added":     "Bill %d: %s — %s every month by day %d.",
+		"bill.id.usage":  "Example: /bill %s 3",
+		"bill.deleted":   "Bill %d deleted.",
+		"bill.notfound":  "No bill %d.",
+		"bill.paid.done": "✅ Bill %d paid for %s.",
+		"bill.usage":     "Bills: /bill, /bill add <name> <amount> <day>, /bill paid <id>, /bill del <id>",
+		"bill.overdue":   "overdue since %s",
+		"bill.due.today": "due today",
+		"bill.due":       "due %s",
+
+		"journal.prompt":       "📓 How was your day?",
+		"journal.prompt.reply": "Reply to this message.",
+		"journal.added":        "📓 Added to the journal for %s.",
+		"journal.header":       "JOURNAL:",
+		"journal.title":        "Journal",
+		"journal.status.off":   "The evening question is off, turn it on: /journal on 21:30",
+		"journal.status.on":    "The evening question comes at %s, turn it off: /journal off",
+		"journal.help":         "📓 Write: /journal <text> or reply to the evening question. The week: /journal week, as a file: /journal md",
+		"journal.on.usage":     "Example: /journal on 21:30",
+		"journal.on":           "📓 I'll ask how your day went at %s.",
+		"journal.off":          "The evening question is off.",
+		"journal.md.usage":     "Example: /journal md month",
+		"journal.empty":        "The journal is empty for that period.",
+		"journal.caption":      "Journal: %d entries.",
+
+		"link.code":          "Send this in the chat for notifications (the bot must be a member there):\n/linkchat %s\nThe code is valid for %d min.",
+		"link.notfound":      "Code not found.",
+		"link.invalid":       "The code is not valid.",
+		"link.same":          "The code has to be sent in another chat.",
+		"link.done":          "The chat is linked for notifications.",
+		"link.linked":        "Linked the chat “%s”. Set it up: /route",
+		"link.gone":          "The chat is no longer linked.",
+		"route.header":       "Notification chats:",
+		"route.none":         "— none. Link one: /linkchat",
+		"route.help":         "/route <list|#id> <chat number> — route, /route <list|#id> - — bring back here, /route unlink <number> — unlink a chat",
+		"route.notfound":     "No chat with that number. List: /route",
+		"route.unlink.usage": "Example: /route unlink 1",
+		"route.item.usage":   "Example: /route #12 1",
+		"topic.unknown":      "Unknown list.",
+		"route.done":         "Done.",
+
+		"mood.question":    "How are your mood and energy today?",
+		"mood.header":      "MOOD AND TASKS %s:",
+		"mood.none":        "No mood check-ins.",
+		"mood.average":     "Tasks on average: %s",
+		"mood.r.none":      "hardly any link",
+		"mood.r.good":      "you get more done on good days",
+		"mood.r.bad":       "you get more done on bad days",
+		"mood.r":           "Correlation: %.2f — %s.",
+		"mood.on.usage":    "Example: /mood on 20:00",
+		"mood.on":          "I'll ask about your mood at %s. Chart: /mood month",
+		"mood.off":         "No more mood questions.",
+		"mood.month.usage": "Example: /mood month 09.2026",
+		"mood.usage":       "Check in: /mood, every day: /mood on 20:00, chart: /mood month",
+		"mood.set":         "Mood for %s: %s. Change it with another button.",
+
+		"crypt.delete.failed": "Couldn't delete the message with the passphrase — please delete it yourself.",
+		"crypt.encrypt.usage": "Example: /encrypt a-long-passphrase",
+		"crypt.already":       "Encryption is already on. Turn it off: /decrypt <passphrase>",
+		"crypt.short":         "The passphrase is shorter than 8 characters, pick a longer one.",
+		"crypt.enabled":       "🔒 Encryption is on, the chat is unlocked for %d min. The passphrase is stored nowhere: a forgotten passphrase means lost items.\n/lock — lock, /unlock <passphrase> — unlock.",
+		"crypt.decrypt.usage": "Example: /decrypt <passphrase>",
+		"crypt.off":           "Encryption is not on.",
+		"crypt.hint":          "Turn it on: /encrypt <passphrase>",
+		"crypt.wrong":         "Wrong passphrase.",
+		"crypt.disabled":      "🔓 Encryption is off, items are stored in the clear.",
+		"crypt.unlock.usage":  "Example: /unlock <passphrase>",
+		"crypt.unlocked":      "🔓 Unlocked for %d min. Lock earlier: /lock",
+		"crypt.locked":        "🔒 Locked.",
+		"crypt.closed":        "🔒 The chat is locked. Unlock: /unlock <passphrase>",
+
+		"search.usage":        "Example: /search passport, with the archive: /search archive passport",
+		"search.none":         "Nothing found.",
+		"search.header":       "SEARCH “%s”:",
+		"search.active":       "active",
+		"search.done":         "done",
+		"archive.title":       "ARCHIVE",
+		"search.archive.hint": "With the archive: /search archive %s",
+		"search.limit":        "Showing the first %d matches.",
+
+		"archive.empty":   "Empty. Older months: /history",
+		"archive.kept":    "Kept for %d days.",
+		"archive.usage":   "Example: /archive tasks",
+		"retention.days":  "The archive is kept for %d days. Change it: /retention <days>, keep everything: /retention off",
+		"retention.off":   "The archive is kept forever.",
+		"retention.hint":  "Limit it: /retention 90",
+		"retention.usage": "Example: /retention 90",
+		"retention.set":   "Done items and archive older than %d days will be deleted every night.",
+
+		"migrate.notours":         "this is not a /migrate export file",
+		"migrate.usage":           "Moving the chat to another bot:\n/migrate export — export everything\n/migrate import — as a reply to the file in the new bot",
+		"migrate.toobig":          "The export is over %s — Telegram won't let you download it.",
+		"migrate.caption":         "Items: %d, archive months: %d. In the new bot, reply to this file with /migrate import",
+		"migrate.reply":           "Send the command as a reply to the file from /migrate export.",
+		"migrate.notempty":        "This chat already has items. Add to them: /migrate import force",
+		"migrate.done":            "Moved: %d items (%d kept their numbers), %d notes, %d goals, %d archive months.",
+		"migrate.settings.failed": "Settings not loaded: %v",
+		"migrate.settings":        "Settings: %d.",
+
+		"trip.task":       "Pack: %s, %s",
+		"trip.socks":      "underwear and socks ×%d",
+		"trip.usage":      "Example: /trip beach 15.07 22.07 — type: %s",
+		"trip.reminder":   "Start packing: %s, %s (task #%d)",
+		"trip.remind":     "⏰ Start packing: %s.",
+		"remind.today":    "today at %s",
+		"remind.tomorrow": "tomorrow at %s",
+		"remind.on":       "%s at %s",
+		"remind.set":      "⏰ I'll remind you %s.",
+
+		"snooze.tomorrow": "Tomorrow",
+		"snooze.done":     "Snoozed until %s",
+
+		"review.now":     "Do it now",
+		"review.delete":  "Delete",
+		"review.notime":  "No time",
+		"review.header":  "BASKET REVIEW (%d of %d)",
+		"review.empty":   "The basket is empty 🎉",
+		"review.over":    "Review finished, %d left in the basket. Another round: /review",
+		"review.gone":    "Already sorted",
+		"review.when":    "When should I remind you?",
+		"review.done":    "done",
+		"review.deleted": "deleted",
+		"review.skipped": "left in the basket",
+
+		"cards.none":     "No cards. Send a photo of a card captioned /card <store> or: /card add <store> <number>",
+		"cards.header":   "💳 Cards:",
+		"card.add.usage": "Example: /card add Tesco 778812345678 — or a photo of the card captioned /card Tesco",
+		"card.notfound":  "No card “%s”.",
+		"card.deleted":   "Card “%s” deleted.",
+		"card.hint":      "All cards: /card",
+		"card.name.long": "The store name can be up to %d characters.",
+		"card.saved":     "💳 Card “%s” saved. Show it: /card %s",
+		"cards.offer":    "💳 Store cards:",
+		"card.gone":      "The card was deleted",
+
+		"item.goto":           "Go to message",
+		"item.original":       "Original",
+		"item.usage":          "Example: /item 12",
+		"item.missing":        "No such item.",
+		"item.created":        "Created: %s",
+		"item.due":            "Due: %s",
+		"item.completed":      "Done: %s",
+		"item.forwarded":      "Forwarded from: %s",
+		"item.source.none":    "The original message wasn't saved.",
+		"item.source":         "#%d — the original message ↑",
+		"item.source.deleted": "The original message was deleted.",
+
+		"picker.today":    "Today",
+		"picker.tomorrow": "Tomorrow",
+		"picker.none":     "No due date",
+		"picker.allday":   "All day",
+		"picker.back":     "Back",
+		"picker.title":    "📅 Due date for #%d: %s",
+		"picker.current":  "Now: %s",
+		"picker.cleared":  "📅 #%d: due date cleared",
+		"picker.set":      "📅 #%d: due %s",
+		"due.usage":       "Example: /due 12",
+		"due.set":         "📅 Due: %s.",
+		"due.by":          "by %s",
+		"overdue.header":  "OVERDUE:",
+		"overdue.line":    "#%d %s (due %s)",
+
+		"thread.discuss":         "Discuss",
+		"thread.exists":          "Discussion already exists (topic %d)",
+		"thread.unavailable":     "Topics are not available in this chat",
+		"thread.failed":          "Could not create the topic",
+		"thread.created":         "Topic created",
+		"thread.notes":           "Replies in this topic are saved as notes.",
+		"notes.usage":            "Example: /notes 12",
+		"notes.none":             "No notes.",
+		"notes.header":           "NOTES #%d:",
+		"focus.banner":           "🎯 Focus: %s until %s",
+		"focus.ended":            "Focus on “%s” is over. Held reminders are coming now.",
+		"focus.stop":             "Stop: /focus off",
+		"focus.none":             "No focus.",
+		"focus.usage":            "Example: /focus project Renovation for 2h",
+		"focus.off":              "Focus cleared.",
+		"focus.set":              "Items in the project: %d, the rest can wait. List: /list",
+		"approval.ask":           "⚠️ You want to %s. Please confirm.",
+		"approval.ask.group":     "⚠️ %s wants to %s.\nAnother member has to confirm.",
+		"approval.confirm":       "Confirm",
+		"approval.cancel":        "Cancel",
+		"approval.stale":         "The request has expired",
+		"approval.cancelled":     "Cancelled",
+		"approval.cancelled.by":  "Cancelled by %s",
+		"approval.other":         "Another member has to confirm",
+		"approval.confirmed.by":  "Confirmed by %s. %s",
+		"approval.cleared":       "Items deleted: %d.",
+		"clear.usage":            "Example: /clear shopping",
+		"clear.what":             "clear “%s” (%d items)",
+		"deadletters.empty":      "The queue is empty.",
+		"deadletters.header":     "UNDELIVERED:",
+		"deadletters.line":       "#%d %s → %d, %s, attempts %d: %s",
+		"deadletters.usage":      "Example: /deadletters retry 3",
+		"deadletters.drop.usage": "Example: /deadletters drop 3",
+		"deadletters.retried":    "Delivered %d of %d.",
+		"deadletters.failed":     "Failed: %v",
+		"deadletters.delivered":  "Delivered.",
+		"deadletters.dropped":    "Deleted.",
+
+		"next.priority":    "priority %s",
+		"next.overdue":     "overdue",
+		"next.due.day":     "due within a day",
+		"next.due.days":    "due in %d d",
+		"next.age":         "%d d old",
+		"next.effort":      "~%dm",
+		"next.none":        "No tasks — a good time to sort the inbox.",
+		"next.header":      "WHERE TO START:",
+		"speak.busy.block": "from %s to %s",
+		"speak.busy":       "Busy today %s.",
+		"speak.none":       "No tasks for today.",
+		"speak.count":      "Tasks for today: %d.",
+		"speak.first":      "First: %s.",
+		"speak.rest":       "Then: %s.",
+		"speak.more":       "And %d more.",
+		"speak.on":         "Ask by voice “what's on today” and I'll answer by voice.",
+		"speak.off":        "Voice answers are off. Turn on: /speak on",
+		"speak.unset":      "Speech synthesis is not configured (TTS_BACKEND).",
+		"speak.help":       "Ask by voice “what's on today” or “what next” and I'll answer by voice. Turn off: /speak off",
+		"times.header":     "Times of day:",
+		"times.hint":       "Change: /times вечером 21:00, delete: /times вечером -, reset: /times reset",
+		"times.reset":      "Times of day reset.",
+		"times.usage":      "Example: /times утром 08:30",
+		"times.badname":    "The name must not contain “=” or “,”.",
+		"times.badclock":   "Time as HH:MM, e.g. 08:30.",
+		"times.deleted":    "Deleted: %s",
+		"newlist.usage":    "Example: /newlist 📚 Books",
+		"newlist.long":     "The name is longer than %d characters.",
+		"newlist.taken":    "That name is already taken.",
+		"newlist.limit":    "You can't have more than %d lists of your own. Delete one: /dellist <name>",
+		"newlist.added":    "List “%s” added to the keyboard.",
+		"dellist.usage":    "Example: /dellist books",
+		"dellist.builtin":  "Built-in lists can't be deleted, only your own from /newlist.",
+		"dellist.done":     "List “%s” deleted.",
+		"dellist.moved":    "Its items (%d) were moved to the inbox.",
+
+		"list.all":         "ALL ITEMS",
+		"list.usage":       "Example: /list tasks",
+		"keyboard.header":  "Extra buttons:",
+		"keyboard.hint":    "Toggle: /keyboard today on | off",
+		"keyboard.unknown": "I don't know that button. Available: %s",
+		"keyboard.updated": "Keyboard updated.",
+		"rename.usage":     "Example: /rename shopping 🛒 Groceries",
+		"weather.precip":   "precipitation %.0f%%",
+		"weather.clear":    "Clear",
+		"weather.partly":   "Partly cloudy",
+		"weather.overcast": "Overcast",
+		"weather.fog":      "Fog",
+		"weather.drizzle":  "Drizzle",
+		"weather.rain":     "Rain",
+		"weather.snow":     "Snow",
+		"weather.storm":    "Thunderstorm",
+		"script.help":      "A script in Starlark (a Python dialect). You can define:\ndef on_capture(text, topic): return the item's new text\ndef digest(items): a string for the “Script” section of /digest\ndef on_event(event): a reply to an item being created/done/deleted/moved\nSave: /script <code>, test: /script test <text>, delete: /script reset",
+		"script.none":      "No script.",
+		"script.current":   "Script:",
+		"script.reset":     "Script deleted.",
+		"script.error":     "Error: %s",
+		"script.nocapture": "on_capture is not defined.",
+		"script.toobig":    "The script is over %d KB.",
+		"script.invalid":   "Script error: %s",
+		"script.saved":     "Script saved. Test: /script test <text>",
+		"bytes.mb":         "%.1f MB",
+		"bytes.kb":         "%d KB",
+		"bytes.b":          "%d B",
+		"usage.header":     "CHAT DATA:",
+		"usage.topic":      "%s: active %d, done %d",
+		"usage.empty":      "No items.",
+		"usage.oldest":     "Oldest item: %s",
+		"usage.archived":   "Archived: %d (since %s), /history",
+		"usage.files":      "Attachments: %d, stored %s",
+		"usage.text":       "Text in the database: ≈%s",
+		"usage.recent":     "Last 30 days: +%d items (≈%s), ≈%s a year.",
+		"usage.compact":    "Done items go to the archive after %d days (COMPACT_AFTER_DAYS).",
+
+		"agenda.allday":         "all day",
+		"attach.usage":          "Example: /attach 12 to the meeting with Ivan",
+		"attach.nocalendar":     "The calendar is unavailable.",
+		"attach.noevent":        "No such event in the next 7 days.",
+		"attach.done":           "#%d attached to “%s” (%s).",
+		"schedule.unset":        "The Google Calendar schedule is not configured (GCAL_CALENDAR_ID is not set).",
+		"schedule.unauthorized": "Google Calendar is not connected: the bot owner can run /gcalauth.",
+		"schedule.empty":        "Today's schedule (%s): no events.",
+		"schedule.title":        "Today's schedule (%s):",
+		"travel.off":            "Travel mode is off.",
+		"travel.usage":          "Example: /travel Asia/Tokyo until 2025-06-10",
+		"travel.status":         "Travelling: %s until %s.",
+		"travel.bad":            "I didn't get that.",
+		"travel.past":           "The end date has already passed.",
+		"travel.set":            "Travel mode: reminders follow %s time through %s.",
+		"travel.ended":          "Travel mode is over, the time is %s again.",
+		"leaderboard.header":    "LEADERS OF THE WEEK:",
+		"leaderboard.fire":      "The week is on fire!",
+		"leaderboard.off":       "The weekly leaderboard is off.",
+		"leaderboard.on":        "The weekly leaderboard is on.",
+		"leaderboard.empty":     "Nobody has finished anything this week yet.",
+		"import.empty.file":     "the file is empty",
+		"import.unparsed":       "Could not read the file: %v",
+		"import.none":           "The file has no items.",
+		"import.done":           "Items imported: %d.",
+		"secret.usage":          "Example: /secret 12 — hide item #12 (again to show it)",
+		"secret.off":            "#%d is no longer secret.",
+		"secret.on":             "#%d is hidden: lists and digests show %s, the text is behind the 👁 button.",
+		"secret.ttl":            "disappears in %d s",
+		"retro.new":             "new",
+		"retro.done":            "Done: %d (%s vs last month)",
+		"retro.tags":            "Top tags: %s",
+		"retro.longest":         "Waited longest (%d days): %s",
+		"retro.streak":          "Streak: %d days in a row (last month %d)",
+		"retro.title":           "SUMMARY %s:",
+		"quiet.off":             "Quiet hours are off.",
+		"quiet.enable":          "Turn on: /quiet 23:30-07:30",
+		"quiet.status":          "Quiet hours: %s. Turn off: /quiet off",
+		"quiet.usage":           "Example: /quiet 23:30-07:30",
+		"quiet.set":             "Quiet hours: %s. Notifications from that time arrive at %s.",
+
+		"history.empty":     "The archive is empty.",
+		"history.hint":      "Details: /history YYYY-MM",
+		"history.usage":     "Example: /history 2024-03",
+		"channel.status":    "The digest is mirrored to channel %d. Turn off: /digestchannel off",
+		"channel.usage":     "Make the bot a channel admin and send: /digestchannel @channel or /digestchannel -100…",
+		"channel.off":       "The digest channel is off.",
+		"channel.notfound":  "Channel not found. The bot has to be its admin.",
+		"channel.cantpost":  "The bot may not post in the channel.",
+		"channel.notadmin":  "Only a channel admin can connect it.",
+		"channel.set":       "The morning digest will be mirrored to “%s”.",
+		"tz.current":        "Timezone: %s, it's %s now.",
+		"tz.hint":           "Change: /timezone Europe/Moscow or send your location.",
+		"tz.approx":         "This is approximate, by longitude and without daylight saving; for precision: /timezone Europe/Moscow",
+		"tz.default":        "Default timezone: %s.",
+		"tz.unknown":        "I don't know that zone. Example: /timezone Europe/Moscow or /timezone +3",
+		"someday.activate":  "Activate",
+		"someday.keep":      "Keep",
+		"someday.delete":    "Delete",
+		"someday.review":    "SOMEDAY: still relevant?",
+		"someday.activated": "Activated",
+		"someday.moved":     "Moved to %s",
+		"someday.kept":      "Kept",
+		"someday.stays":     "Stays in %s",
+		"gcal.unset":        "Google Calendar is not configured: set GCAL_CALENDAR_ID, GCAL_CLIENT_ID and GCAL_CLIENT_SECRET.",
+		"gcal.start.failed": "Could not start authorization: %v",
+		"gcal.code":         "Open %s and enter the code %s (valid for %d min).",
+		"gcal.poll.failed":  "Authorization not completed: %v",
+		"gcal.done":         "Google Calendar connected.",
+		"backup.caption":    "Backup %s",
+		"backup.failed":     "Backup failed: %v",
+		"backup.primary":    "The primary bot makes the backups of all bots.",
+		"backup.sent":       "Backup sent (files: %d).",
+
+		"export.caption":        "Items: %d.",
+		"export.usage":          "Export items: /export csv or /export json, settings: /export settings",
+		"digesttpl.current":     "Digest template:",
+		"digesttpl.help":        "Available: .Date, .Weekday, .Sections (Name, Title, Body, Empty), .S.weather and so on, functions upper/lower.\nReset: /digesttemplate reset",
+		"digesttpl.reset":       "Digest template reset.",
+		"digesttpl.error":       "Template error: %v",
+		"digesttpl.saved":       "Template saved. Check: /digest now",
+		"contexts.none":         "No contexts. Add a tag to an item, e.g. “call mom @calls”.",
+		"contexts.pick":         "Pick a context:",
+		"contexts.header":       "Context %s:",
+		"compact.on":            "Compact mode: the keyboard is hidden. Commands: /menu, /today, /next, /capture. Bring it back: /compact off",
+		"compact.off":           "Keyboard is back.",
+		"compact.status.on":     "Compact mode is on.",
+		"compact.status.off":    "Compact mode is off.",
+		"attachment.photo":      "photo from %s",
+		"anniversary.year":      "A year ago",
+		"anniversary.years.few": "%d years ago",
+		"anniversary.years":     "%d years ago",
+		"anniversary.line":      "%s you finished: %s",
+		"event.soon":            "SOON: %s at %s",
+		"event.leave":           "travel ~%d min — leave by %s",
+		"prep.title":            "Prepare: %s (%s)",
+
+		"webhook.stalled":    "The webhook isn't getting updates (%s). The bot switched to long polling until restart.",
+		"voice.note":         "voice note %d:%02d from %s",
+		"voice.unrecognized": "Could not recognize the speech, saved item #%d with the voice note.",
+
+		"triage.nudge": "SORT THE INBOX: %d items have waited over %d days.",
+		"triage.start": "Sort",
+		"triage.done":  "The inbox is sorted.",
+		"triage.empty": "The inbox is empty.",
+	},
+}
+
+func tr(lang, key string, args ...any) string {
+	s, ok := messages[lang][key]
+	if !ok {
+		s, ok = messages[DefaultLang][key]
+	}
+	if !ok {
+		s = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
+
+func supportedLang(lang string) bool {
+	_, ok := messages[lang]
+	return ok
+}
+
+// langFromTelegram maps a Telegram language_code (IETF tag) to a catalog locale.
+func langFromTelegram(code string) string {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(code)), "-")
+	switch base {
+	case "":
+		return DefaultLang
+	case "ru", "uk", "be", "kk":
+		return LangRU
+	}
+	if supportedLang(base) {
+		return base
+	}
+	return LangEN
+}
+
+func chatKey(chatID int64, name string) string {
+	return fmt.Sprintf("chat:%d:%s", chatID, name)
+}
+
+func (s *sqlStore) GetKV(k string) (string, bool, error) {
+	var v string
+	err := s.kvDB(k).QueryRow(`SELECT v FROM kv WHERE k=?`, k).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return v, true, nil
+}
+
+func (s *sqlStore) SetKV(k, v string) error {
+	_, err := s.kvDB(k).Exec(`INSERT INTO kv(k, v) VALUES(?,?) ON CONFLICT(k) DO UPDATE SET v=excluded.v`, k, v)
+	return err
+}
+
+func (s *sqlStore) DeleteKV(k string) error {
+	_, err := s.kvDB(k).Exec(`DELETE FROM kv WHERE k=?`, k)
+	return err
+}
+
+// Lang returns the chat's locale, DefaultLang if none was chosen yet.
+func (s *sqlStore) Lang(chatID int64) string {
+	v, ok, err := s.GetKV(chatKey(chatID, "lang"))
+	if err != nil {
+		log.Printf("lang lookup error: %v", err)
+	}
+	if !ok || !supportedLang(v) {
+		return DefaultLang
+	}
+	return v
+}
+
+func (s *sqlStore) SetLang(chatID int64, lang string) error {
+	return s.SetKV(chatKey(chatID, "lang"), lang)
+}
+
+// detectLang stores the user's Telegram language on first contact.
+// A locale chosen manually via /language is never overwritten.
+func (a *App) detectLang(chatID int64, code string) {
+	_, ok, err := a.Store.GetKV(chatKey(chatID, "lang"))
+	if err != nil || ok {
+		return
+	}
+	if err := a.Store.SetLang(chatID, langFromTelegram(code)); err != nil {
+		log.Printf("save lang error: %v", err)
+	}
+}
+
+func (a *App) tr(chatID int64, key string, args ...any) string {
+	return tr(a.Store.Lang(chatID), key, args...)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var verbRe = regexp.MustCompile(`%(?:\[\d+\])?[-+# 0-9.]*[a-zA-Z]`)
+
+// verbs is the sorted verb letters of a format, so that a translation may
+// reorder its arguments with %[n]s.
+func verbs(s string) []string {
+	var out []string
+	for _, v := range verbRe.FindAllString(strings.ReplaceAll(s, "%%", ""), -1) {
+		out = append(out, v[len(v)-1:])
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestCatalogsMatch(t *testing.T) {
+	for lang, catalog := range messages {
+		for key, s := range messages[DefaultLang] {
+			other, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: no %q", lang, key)
+				continue
+			}
+			if !slices.Equal(verbs(s), verbs(other)) {
+				t.Errorf("%s %q: verbs %v, %s has %v", lang, key, verbs(other), DefaultLang, verbs(s))
+			}
+		}
+		for key := range catalog {
+			if _, ok := messages[DefaultLang][key]; !ok {
+				t.Errorf("%s: %q is not in %s", lang, key, DefaultLang)
+			}
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// "/import" (in a reply to a file, or as its caption) loads items from
+// another tool: the CSV or JSON of /export, or plain text with one item per
+// line under topic headers:
+//
+//	# Покупки
+//	молоко
+//	- хлеб
+//	[x] батарейки
+//
+// Lines before any header go to the basket; "[x]" marks done. Items get new
+// IDs; status, dates and flags come over when the file has them.
+
+func (s *sqlStore) ImportItem(chatID int64, r ExportRow) (int64, error) {
+	stored, norm, err := s.sealText(chatID, r.Text)
+	if err != nil {
+		return 0, err
+	}
+	flagged := 0
+	if r.Flagged {
+		flagged = 1
+	}
+	var id int64
+	err = s.db(chatID).QueryRow(
+		`INSERT INTO items(chat_id, topic, text, norm, status, flagged, created_at, completed_at, completed_by_name, due_at, remind_at)
+		 VALUES(?,?,?,?,?,?,?,?,?,?,?) RETURNING id`,
+		chatID, r.Topic, stored, norm, r.Status, flagged, r.CreatedAt, r.CompletedAt, r.CompletedBy, r.DueAt, r.RemindAt,
+	).Scan(&id)
+	if err == nil {
+		s.logAction(chatID, id, actionCreated, "")
+	}
+	return id, err
+}
+
+// importTopic maps a topic key or name from the file to one of the chat's
+// topics.
+func importTopic(store Store, chatID int64, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if slices.Contains(chatTopics(store, chatID), name) {
+		return name, true
+	}
+	return topicFromName(store, chatID, name)
+}
+
+// parseImport reads JSON, CSV (with a header that has "text") or plain text.
+func parseImport(lang string, raw []byte) ([]ExportRow, error) {
+	raw = bytes.TrimPrefix(raw, []byte("\ufeff"))
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return nil, errors.New(tr(lang, "import.empty.file"))
+	}
+	if trimmed[0] == '[' {
+		var rows []ExportRow
+		if err := json.Unmarshal(trimmed, &rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+	first, _, _ := bytes.Cut(trimmed, []byte("\n"))
+	if header := strings.Split(strings.ToLower(strings.TrimSpace(string(first))), ","); slices.Contains(header, "text") {
+		return parseImportCSV(trimmed)
+	}
+	return parseImportText(string(trimmed)), nil
+}
+
+func parseImportCSV(raw []byte) ([]ExportRow, error) {
+	r := csv.NewReader(bytes.NewReader(raw))
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	get := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+	var out []ExportRow
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		flagged, _ := strconv.ParseBool(get(rec, "flagged"))
+		out = append(out, ExportRow{
+			Topic: get(rec, "topic"), Status: get(rec, "status"), Text: get(rec, "text"), Flagged: flagged,
+			CreatedAt: get(rec, "created_at"), CompletedAt: get(rec, "completed_at"), CompletedBy: get(rec, "completed_by"),
+			DueAt: get(rec, "due_at"), RemindAt: get(rec, "remind_at"),
+		})
+	}
+}
+
+func parseImportText(raw string) []ExportRow {
+	var out []ExportRow
+	topic := ""
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" {
+			continue
+		}
+		// "# Покупки" and "## Покупки" are headers; "#тег текст" is an item
+		if h := strings.TrimLeft(line, "#"); h != line && strings.HasPrefix(h, " ") {
+			topic = strings.TrimSpace(h)
+			continue
+		}
+		status := StatusActive
+		for _, p := range []string{"[x] ", "[X] ", "☑ ", "✅ "} {
+			if rest, ok := strings.CutPrefix(line, p); ok {
+				line, status = rest, StatusDone
+			}
+		}
+		for _, p := range []string{"- ", "* ", "☐ ", "[ ] "} {
+			line = strings.TrimPrefix(line, p)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, ExportRow{Topic: topic, Status: status, Text: line})
+		}
+	}
+	return out
+}
+
+// importRows cleans up parsed rows for this chat: known topics (else
+// basket), known statuses, dates that parse.
+func importRows(store Store, chatID int64, rows []ExportRow, now time.Time) []ExportRow {
+	validTime := func(s string) string {
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return ""
+		}
+		return s
+	}
+	var out []ExportRow
+	for _, r := range rows {
+		if r.Text = strings.TrimSpace(r.Text); r.Text == "" {
+			continue
+		}
+		topic, ok := importTopic(store, chatID, r.Topic)
+		if !ok {
+			topic = TopicBasket
+		}
+		r.Topic = topic
+		switch r.Status {
+		case StatusDone, StatusArchived:
+		case statusCompacted:
+			r.Status = StatusDone
+		default:
+			r.Status = StatusActive
+		}
+		if r.CreatedAt = validTime(r.CreatedAt); r.CreatedAt == "" {
+			r.CreatedAt = now.UTC().Format(time.RFC3339)
+		}
+		r.CompletedAt = validTime(r.CompletedAt)
+		if r.Status != StatusActive && r.CompletedAt == "" {
+			r.CompletedAt = now.UTC().Format(time.RFC3339)
+		}
+		if r.Status == StatusActive {
+			r.CompletedAt, r.CompletedBy = "", ""
+		}
+		r.DueAt, r.RemindAt = validTime(r.DueAt), validTime(r.RemindAt)
+		out = append(out, r)
+	}
+	return out
+}
+
+func (a *App) importItems(ctx context.Context, chatID int64, d *tgbotapi.Document) {
+	lang := a.Store.Lang(chatID)
+	raw, err := a.readDocument(ctx, d, maxImportBytes)
+	if err != nil {
+		a.send(chatID, tr(lang, "file.download.failed"))
+		return
+	}
+	parsed, err := parseImport(lang, raw)
+	if err != nil {
+		a.send(chatID, tr(lang, "import.unparsed", err))
+		return
+	}
+	rows := importRows(a.Store, chatID, parsed, time.Now())
+	if len(rows) == 0 {
+		a.send(chatID, tr(lang, "import.none"))
+		return
+	}
+	perTopic := map[string]int{}
+	err = a.Store.InTx(chatID, func(tx Store) error {
+		for _, r := range rows {
+			if _, err := tx.ImportItem(chatID, r); err != nil {
+				return err
+			}
+			perTopic[r.Topic]++
+		}
+		return nil
+	})
+	if errors.Is(err, errLocked) {
+		a.sendLocked(chatID)
+		return
+	}
+	if err != nil {
+		log.Printf("import items error: %v", err)
+		a.send(chatID, tr(lang, "err.write"))
+		return
+	}
+	var b strings.Builder
+	b.WriteString(tr(lang, "import.done", len(rows)))
+	for _, t := range chatTopics(a.Store, chatID) {
+		if n := perTopic[t]; n > 0 {
+			fmt.Fprintf(&b, "\n— %s: %d", topicLabel(lang, t), n)
+		}
+	}
+	a.send(chatID, b.String())
+}
+
+// importFromCaption handles a file sent with "/import" as its caption.
+func (a *App) importFromCaption(ctx context.Context, m *tgbotapi.Message) bool {
+	if m.Document == nil {
+		return false
+	}
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(m.Caption), " ")
+	if cmd, _, _ = strings.Cut(cmd, "@"); cmd != "/import" || strings.TrimSpace(arg) == "settings" {
+		return false
+	}
+	a.importItems(ctx, m.Chat.ID, m.Document)
+	return true
+}
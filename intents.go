@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Plain-language commands, so nobody has to learn slashes: "покажи задачи",
+// "что у меня сегодня", "удали пункт 3", "сделал 5", "найди молоко". Messages
+// are matched against a few strict rules first; anything else is captured
+// as before. With INTENT_LLM_URL (an OpenAI-compatible chat completions
+// endpoint) a message that reads like a request but matches no rule is
+// classified by the model instead — a premium feature when payments are on.
+// "/intents off" turns this off for a chat.
+
+type Intent struct {
+	Name  string `json:"intent"` // list, today, next, done, delete, search, help; "" = none
+	Topic string `json:"topic,omitempty"`
+	ID    int64  `json:"id,omitempty"`
+	Query string `json:"query,omitempty"`
+}
+
+var (
+	intentList   = regexp.MustCompile(`^(?:покажи|показать|открой|выведи|show)(?: мне)?(?: мои| все| мой)? (.+)$`)
+	intentToday  = regexp.MustCompile(`^(?:что (?:у меня |мне )?(?:на |по планам на )?сегодня|планы? на сегодня|what(?:'s| is) (?:on )?today)$`)
+	intentNext   = regexp.MustCompile(`^(?:что (?:дальше|делать|сделать)|чем заняться|с чего начать|what next)$`)
+	intentDelete = regexp.MustCompile(`^(?:удали|удалить|убери|delete|remove)(?: пункт| задачу| запись| номер| item)? #?(\d+)$`)
+	intentDone   = regexp.MustCompile(`^(?:сделал|сделала|сделано|готово|выполнил|выполнила|выполнено|отметь|done)(?: пункт| задачу| запись| номер| item)? #?(\d+)$`)
+	intentSearch = regexp.MustCompile(`^(?:найди|поищи|find|search) (.+)$`)
+	intentHelp   = regexp.MustCompile(`^(?:что ты умеешь|помощь|help|как (?:тобой|этим) пользоваться)$`)
+)
+
+// intentTopics maps what people say after "покажи" to topic names.
+var intentTopics = map[string]string{
+	"корзину": "корзина", "задач": "задачи", "покупок": "покупки", "список покупок": "покупки",
+	"напоминаний": "напоминания", "напоминалки": "напоминания",
+}
+
+// matchIntent applies the rules to text.
+func matchIntent(text string) (Intent, bool) {
+	s := strings.TrimRight(normalizeText(text), "?!. ")
+	if m := intentDelete.FindStringSubmatch(s); m != nil {
+		id, _ := strconv.ParseInt(m[1], 10, 64)
+		return Intent{Name: "delete", ID: id}, true
+	}
+	if m := intentDone.FindStringSubmatch(s); m != nil {
+		id, _ := strconv.ParseInt(m[1], 10, 64)
+		return Intent{Name: "done", ID: id}, true
+	}
+	switch {
+	case intentToday.MatchString(s):
+		return Intent{Name: "today"}, true
+	case intentNext.MatchString(s):
+		return Intent{Name: "next"}, true
+	case intentHelp.MatchString(s):
+		return Intent{Name: "help"}, true
+	}
+	if m := intentList.FindStringSubmatch(s); m != nil {
+		topic := m[1]
+		if t, ok := intentTopics[topic]; ok {
+			topic = t
+		}
+		if topic == "список" || topic == "всё" || topic == "все" || topic == "записи" {
+			topic = ""
+		}
+		return Intent{Name: "list", Topic: topic}, true
+	}
+	if m := intentSearch.FindStringSubmatch(s); m != nil {
+		// search with the original words, not the normalized ones
+		_, q, _ := strings.Cut(strings.TrimSpace(text), " ")
+		return Intent{Name: "search", Query: strings.TrimSpace(q)}, true
+	}
+	return Intent{}, false
+}
+
+// requestWords start messages that may be requests; only those go to the
+// model, so ordinary captures don't wait for it.
+var requestWords = []string{"покажи", "удали", "убери", "что", "сколько", "найди", "отметь", "какие", "где", "открой"}
+
+func looksLikeRequest(text string) bool {
+	s := normalizeText(text)
+	if strings.HasSuffix(strings.TrimSpace(text), "?") {
+		return true
+	}
+	first, _, _ := strings.Cut(s, " ")
+	for _, w := range requestWords {
+		if first == w {
+			return true
+		}
+	}
+	return false
+}
+
+const intentPrompt = `You route messages of a Telegram to-do bot. Reply with JSON only:
+{"intent": "list|today|next|done|delete|search|help|none", "topic": "...", "id": 0, "query": "..."}
+list: show a list (topic: задачи, покупки, напоминания, корзина or "" for all); today: what is planned today;
+next: what to do next; done/delete: item number id; search: query words; none: the message is a note to save.`
+
+// llmIntent asks INTENT_LLM_URL to classify text.
+func llmIntent(ctx context.Context, text string) (Intent, bool) {
+	endpoint := strings.TrimSpace(os.Getenv("INTENT_LLM_URL"))
+	if endpoint == "" {
+		return Intent{}, false
+	}
+	body, _ := json.Marshal(map[string]any{
+		"model":       envOr("INTENT_LLM_MODEL", "gpt-4o-mini"),
+		"temperature": 0,
+		"messages": []map[string]string{
+			{"role": "system", "content": intentPrompt},
+			{"role": "user", "content": text},
+		},
+	})
+	ctx, cancel := context.WithTimeout(ctx, 8*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Intent{}, false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key := strings.TrimSpace(os.Getenv("INTENT_LLM_KEY")); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Intent{}, false
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&out) != nil || len(out.Choices) == 0 {
+		return Intent{}, false
+	}
+	content := strings.TrimSpace(out.Choices[0].Message.Content)
+	content = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(content, "```json"), "```"), "```")
+	var in Intent
+	if json.Unmarshal([]byte(content), &in) != nil || in.Name == "" || in.Name == "none" {
+		return Intent{}, false
+	}
+	return in, true
+}
+
+// intentFromText runs a plain-language command; false means capture the
+// text as usual.
+func (a *App) intentFromText(ctx context.Context, m *tgbotapi.Message) bool {
+	chatID := m.Chat.ID
+	if v, _, _ := a.Store.GetKV(chatKey(chatID, "intents")); v == "off" {
+		return false
+	}
+	in, ok := matchIntent(m.Text)
+	if !ok && looksLikeRequest(m.Text) && a.Store.HasFeature(chatID, FeatureLLMTriage, time.Now()) {
+		in, ok = llmIntent(ctx, m.Text)
+	}
+	if !ok {
+		return false
+	}
+	return a.runIntent(chatID, m.From, in)
+}
+
+func (a *App) runIntent(chatID int64, from *tgbotapi.User, in Intent) bool {
+	switch in.Name {
+	case "list":
+		if in.Topic != "" {
+			if _, ok := a.topicFromButton(chatID, in.Topic); !ok {
+				return false // "покажи Маше фото" is a note
+			}
+		}
+		a.handleList(chatID, in.Topic)
+	case "today":
+		a.handleToday(chatID)
+	case "next":
+		a.handleNext(chatID)
+	case "search":
+		if in.Query == "" {
+			return false
+		}
+		a.handleSearch(chatID, in.Query)
+	case "help":
+		a.send(chatID, a.tr(chatID, "intents.help"))
+	case "done", "delete":
+		it, err := a.Store.GetItem(chatID, in.ID)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return true
+		}
+		if it == nil || !it.CompletedAt.IsZero() {
+			a.send(chatID, a.tr(chatID, "intents.no_item", in.ID))
+			return true
+		}
+		if in.Name == "done" {
+			err = a.Store.FinishItem(chatID, in.ID, from, time.Now())
+		} else {
+			err = a.Store.DeleteItem(chatID, in.ID)
+		}
+		if errors.Is(err, errLocked) {
+			a.sendLocked(chatID)
+			return true
+		}
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return true
+		}
+		if in.Name == "done" {
+			a.send(chatID, a.tr(chatID, "intents.done", in.ID, shownText(*it)))
+		} else {
+			a.send(chatID, a.tr(chatID, "intents.deleted", in.ID, shownText(*it)))
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// handleIntents handles "/intents on|off".
+func (a *App) handleIntents(chatID int64, arg string) {
+	switch strings.TrimSpace(arg) {
+	case "on":
+		if err := a.Store.DeleteKV(chatKey(chatID, "intents")); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "intents.on")+"\n\n"+a.tr(chatID, "intents.help"))
+	case "off":
+		if err := a.Store.SetKV(chatKey(chatID, "intents"), "off"); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "intents.off"))
+	default:
+		a.send(chatID, a.tr(chatID, "intents.help")+"\n\n"+a.tr(chatID, "intents.hint"))
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestMatchIntent(t *testing.T) {
+	tests := []struct {
+		text string
+		want Intent
+		ok   bool
+	}{
+		{"покажи задачи", Intent{Name: "list", Topic: "задачи"}, true},
+		{"Покажи мне список покупок", Intent{Name: "list", Topic: "покупки"}, true},
+		{"открой корзину", Intent{Name: "list", Topic: "корзина"}, true},
+		{"покажи все записи", Intent{Name: "list"}, true},
+		{"покажи Маше фото", Intent{Name: "list", Topic: "маше фото"}, true},
+		{"Что у меня сегодня?", Intent{Name: "today"}, true},
+		{"планы на сегодня", Intent{Name: "today"}, true},
+		{"what's today", Intent{Name: "today"}, true},
+		{"что дальше", Intent{Name: "next"}, true},
+		{"с чего начать?", Intent{Name: "next"}, true},
+		{"сделал 5", Intent{Name: "done", ID: 5}, true},
+		{"выполнено задачу #12", Intent{Name: "done", ID: 12}, true},
+		{"удали пункт 3", Intent{Name: "delete", ID: 3}, true},
+		{"remove item 7", Intent{Name: "delete", ID: 7}, true},
+		{"найди Молоко", Intent{Name: "search", Query: "Молоко"}, true},
+		{"помощь", Intent{Name: "help"}, true},
+		{"купить молоко", Intent{}, false},
+		{"сделал ремонт в ванной", Intent{}, false},
+		{"удали", Intent{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := matchIntent(tt.text)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("matchIntent(%q) = %+v, %v; want %+v, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLooksLikeRequest(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"покажи что осталось", true},
+		{"Сколько задач на неделе", true},
+		{"купить хлеба?", true},
+		{"купить хлеба", false},
+		{"позвонить маме", false},
+	}
+	for _, tt := range tests {
+		if got := looksLikeRequest(tt.text); got != tt.want {
+			t.Errorf("looksLikeRequest(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
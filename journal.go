@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Journal. "/journal on 21:30" asks "Как прошёл день?" every evening (at
+// JOURNAL_TIME without a time); a reply to the question, or "/journal
+// <текст>", is that day's entry. "/journal week" shows the last seven days
+// and "/journal md [week|month|all]" sends them as a Markdown file. Entries
+// are sealed like items in encrypted chats.
+
+type JournalEntry struct {
+	ID        int64
+	Day       string // 2006-01-02 in the chat's timezone
+	Text      string
+	CreatedAt time.Time
+}
+
+func (s *sqlStore) AddJournal(chatID int64, day, text string) error {
+	stored, _, err := s.sealText(chatID, text)
+	if err != nil {
+		return err
+	}
+	_, err = s.db(chatID).Exec(
+		`INSERT INTO journal(chat_id, day, text, created_at) VALUES(?,?,?,?)`,
+		chatID, day, stored, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// JournalSince lists entries from day `from` (inclusive) on, oldest first.
+func (s *sqlStore) JournalSince(chatID int64, from string) ([]JournalEntry, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT id, day, text, created_at FROM journal WHERE chat_id=? AND day>=? ORDER BY day, id`, chatID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []JournalEntry
+	for rows.Next() {
+		var e JournalEntry
+		var created string
+		if err := rows.Scan(&e.ID, &e.Day, &e.Text, &created); err != nil {
+			return nil, err
+		}
+		e.Text = s.openText(chatID, e.Text)
+		e.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (s *Scheduler) sendJournalPrompt(chatID int64, hhmm string) {
+	at, ok, err := s.store.GetKV(chatKey(chatID, "journal"))
+	if err != nil || !ok || at != hhmm {
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, tr(s.store.Lang(chatID), "journal.prompt")+" "+tr(s.store.Lang(chatID), "journal.prompt.reply"))
+	msg.ReplyMarkup = tgbotapi.ForceReply{ForceReply: true, Selective: true}
+	_ = s.deliver("journal", msg)
+}
+
+// isJournalPrompt reports whether text is the evening question in any
+// language, so a reply still lands after the chat switches.
+func isJournalPrompt(text string) bool {
+	for lang := range messages {
+		if strings.HasPrefix(text, tr(lang, "journal.prompt")) {
+			return true
+		}
+	}
+	return false
+}
+
+// journalFromReply stores a reply to the evening question under the day
+// it was asked.
+func (a *App) journalFromReply(m *tgbotapi.Message) bool {
+	r := m.ReplyToMessage
+	if r == nil || r.From == nil || r.From.ID != a.Bot.Self.ID || !isJournalPrompt(r.Text) {
+		return false
+	}
+	text := strings.TrimSpace(m.Text)
+	if text == "" {
+		return false
+	}
+	a.addJournal(m.Chat.ID, r.Time().In(a.tz(m.Chat.ID)), text)
+	return true
+}
+
+func (a *App) addJournal(chatID int64, day time.Time, text string) {
+	err := a.Store.AddJournal(chatID, day.Format("2006-01-02"), text)
+	if errors.Is(err, errLocked) {
+		a.sendLocked(chatID)
+		return
+	}
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "journal.added", day.Format("02.01")))
+}
+
+// journalDays groups entries by day, in order.
+func journalDays(entries []JournalEntry) ([]string, map[string][]JournalEntry) {
+	var days []string
+	byDay := map[string][]JournalEntry{}
+	for _, e := range entries {
+		if _, ok := byDay[e.Day]; !ok {
+			days = append(days, e.Day)
+		}
+		byDay[e.Day] = append(byDay[e.Day], e)
+	}
+	return days, byDay
+}
+
+func formatJournal(lang string, entries []JournalEntry) string {
+	days, byDay := journalDays(entries)
+	var b strings.Builder
+	b.WriteString(tr(lang, "journal.header"))
+	for _, day := range days {
+		d, _ := time.Parse("2006-01-02", day)
+		fmt.Fprintf(&b, "\n\n%s %s", weekdayShort(lang, (int(d.Weekday())+6)%7), d.Format("02.01"))
+		for _, e := range byDay[day] {
+			b.WriteString("\n— " + e.Text)
+		}
+	}
+	return b.String()
+}
+
+func journalMarkdown(lang string, entries []JournalEntry) string {
+	days, byDay := journalDays(entries)
+	var b strings.Builder
+	b.WriteString("# " + tr(lang, "journal.title") + "\n")
+	for _, day := range days {
+		fmt.Fprintf(&b, "\n## %s\n", day)
+		for _, e := range byDay[day] {
+			b.WriteString("\n" + e.Text + "\n")
+		}
+	}
+	return b.String()
+}
+
+// journalFrom is the first day of "week", "month" or "all" before now.
+func journalFrom(period string, now time.Time) (string, bool) {
+	switch period {
+	case "", "week", "неделя":
+		return now.AddDate(0, 0, -6).Format("2006-01-02"), true
+	case "month", "месяц":
+		return now.AddDate(0, -1, 1).Format("2006-01-02"), true
+	case "all", "все", "всё":
+		return "", true
+	}
+	return "", false
+}
+
+// handleJournal handles "/journal [<текст> | week | md [week|month|all] | on [ЧЧ:ММ] | off]".
+func (a *App) handleJournal(chatID int64, arg string) {
+	lang := a.Store.Lang(chatID)
+	arg = strings.TrimSpace(arg)
+	now := time.Now().In(a.tz(chatID))
+	first, rest, _ := strings.Cut(arg, " ")
+	rest = strings.TrimSpace(rest)
+	key := chatKey(chatID, "journal")
+
+	switch first {
+	case "":
+		at, ok, err := a.Store.GetKV(key)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		status := tr(lang, "journal.status.off")
+		if ok {
+			status = tr(lang, "journal.status.on", at)
+		}
+		a.send(chatID, tr(lang, "journal.help")+"\n"+status)
+	case "on":
+		at := rest
+		if at == "" {
+			at = envOr("JOURNAL_TIME", "21:30")
+		}
+		t, err := time.Parse("15:04", at)
+		if err != nil {
+			a.send(chatID, tr(lang, "journal.on.usage"))
+			return
+		}
+		at = t.Format("15:04")
+		if err := a.Store.SetKV(key, at); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "journal.on", at))
+	case "off":
+		if err := a.Store.DeleteKV(key); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "journal.off"))
+	case "week", "md":
+		period := rest
+		if first == "week" {
+			period = "week"
+		}
+		from, ok := journalFrom(period, now)
+		if !ok {
+			a.send(chatID, tr(lang, "journal.md.usage"))
+			return
+		}
+		if a.Store.Locked(chatID) {
+			a.sendLocked(chatID)
+			return
+		}
+		entries, err := a.Store.JournalSince(chatID, from)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if len(entries) == 0 {
+			a.send(chatID, tr(lang, "journal.empty"))
+			return
+		}
+		if first == "week" {
+			a.send(chatID, formatJournal(lang, entries))
+			return
+		}
+		name := fmt.Sprintf("journal-%s.md", now.Format("20060102"))
+		doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: []byte(journalMarkdown(lang, entries))})
+		doc.Caption = tr(lang, "journal.caption", len(entries))
+		if _, err := a.Bot.Send(doc); err != nil {
+			a.send(chatID, tr(lang, "file.send.failed"))
+		}
+	default:
+		a.addJournal(chatID, now, arg)
+	}
+}
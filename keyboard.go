@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var keyboardTopics = []string{TopicTasks, TopicReminders, TopicShopping, TopicBasket, TopicSomeday}
+
+// Optional buttons shown after the topics; toggled per chat with /keyboard.
+var keyboardExtras = []string{"today", "review"}
+
+const defaultKeyboardExtras = "today,review"
+
+func (s *sqlStore) KeyboardExtras(chatID int64) map[string]bool {
+	v, ok, _ := s.GetKV(chatKey(chatID, "keyboard_extras"))
+	if !ok {
+		v = defaultKeyboardExtras
+	}
+	out := map[string]bool{}
+	for _, n := range strings.Split(v, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			out[n] = true
+		}
+	}
+	return out
+}
+
+func (s *sqlStore) SetKeyboardExtras(chatID int64, on map[string]bool) error {
+	var names []string
+	for _, n := range keyboardExtras {
+		if on[n] {
+			names = append(names, n)
+		}
+	}
+	return s.SetKV(chatKey(chatID, "keyboard_extras"), strings.Join(names, ","))
+}
+
+// TopicName returns the chat's custom button text for a topic, if any.
+func (s *sqlStore) TopicName(chatID int64, topic string) (string, bool) {
+	v, ok, _ := s.GetKV(chatKey(chatID, "topic_name:"+topic))
+	return v, ok && v != ""
+}
+
+func (a *App) topicButton(chatID int64, lang, topic string) string {
+	if name, ok := a.Store.TopicName(chatID, topic); ok {
+		return name
+	}
+	if name, ok := customTopicName(a.Store, chatID, topic); ok {
+		return name
+	}
+	return tr(lang, "btn."+topic)
+}
+
+// topicFromButton recognises custom topic names before the built-in ones.
+func (a *App) topicFromButton(chatID int64, text string) (string, bool) {
+	return topicFromName(a.Store, chatID, text)
+}
+
+func topicFromName(store Store, chatID int64, text string) (string, bool) {
+	norm := normalizeText(text)
+	for _, topic := range chatTopics(store, chatID) {
+		if name, ok := store.TopicName(chatID, topic); ok && normalizeText(name) == norm {
+			return topic, true
+		}
+	}
+	if topic, ok := isTopicButtonText(text); ok {
+		return topic, true
+	}
+	custom, _ := store.CustomTopics(chatID)
+	for _, t := range custom {
+		if t.Topic == customTopicKey(text) {
+			return t.Topic, true
+		}
+	}
+	return "", false
+}
+
+// defaultKeyboard lays out the chat's reply keyboard in two rows: the first
+// three topics on top, the rest plus enabled extras below, and the chat's
+// own lists three to a row after them. "Обзор" only appears while the
+// basket has something to review.
+func (a *App) defaultKeyboard(chatID int64) tgbotapi.ReplyKeyboardMarkup {
+	lang := a.Store.Lang(chatID)
+	var top, bottom []tgbotapi.KeyboardButton
+	for i, topic := range keyboardTopics {
+		btn := tgbotapi.NewKeyboardButton(a.topicButton(chatID, lang, topic))
+		if i < 3 {
+			top = append(top, btn)
+		} else {
+			bottom = append(bottom, btn)
+		}
+	}
+
+	extras := a.Store.KeyboardExtras(chatID)
+	if extras["today"] {
+		bottom = append(bottom, tgbotapi.NewKeyboardButton(tr(lang, "btn.today")))
+	}
+	if extras["review"] {
+		if items, err := a.Store.ListActive(chatID, TopicBasket); err == nil && len(items) > 0 {
+			bottom = append(bottom, tgbotapi.NewKeyboardButton(tr(lang, "btn.review")))
+		}
+	}
+
+	kb := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(top...),
+		tgbotapi.NewKeyboardButtonRow(bottom...),
+	)
+	custom, _ := a.Store.CustomTopics(chatID)
+	for i := 0; i < len(custom); i += 3 {
+		var row []tgbotapi.KeyboardButton
+		for _, t := range custom[i:min(i+3, len(custom))] {
+			row = append(row, tgbotapi.NewKeyboardButton(a.topicButton(chatID, lang, t.Topic)))
+		}
+		kb.Keyboard = append(kb.Keyboard, row)
+	}
+	kb.ResizeKeyboard = true
+	return kb
+}
+
+// keyboardAction maps an extra button's text to its name. Both languages
+// are accepted so switching /language doesn't break a keyboard still on screen.
+func keyboardAction(text string) string {
+	norm := normalizeText(text)
+	for _, lang := range []string{LangRU, LangEN} {
+		for _, n := range keyboardExtras {
+			if norm == normalizeText(tr(lang, "btn."+n)) {
+				return n
+			}
+		}
+	}
+	return ""
+}
+
+func (a *App) handleKeyboardAction(chatID int64, text string) bool {
+	switch keyboardAction(text) {
+	case "today":
+		a.handleToday(chatID)
+	case "review":
+		a.startReview(chatID)
+	default:
+		return false
+	}
+	return true
+}
+
+// handleKeyboard handles "/keyboard [<button> on|off]".
+func (a *App) handleKeyboard(chatID int64, arg string) {
+	fields := strings.Fields(strings.ToLower(arg))
+	extras := a.Store.KeyboardExtras(chatID)
+	lang := a.Store.Lang(chatID)
+	if len(fields) != 2 || (fields[1] != "on" && fields[1] != "off") {
+		var b strings.Builder
+		b.WriteString(tr(lang, "keyboard.header") + "\n")
+		for _, n := range keyboardExtras {
+			mark := "▫️"
+			if extras[n] {
+				mark = "✅"
+			}
+			fmt.Fprintf(&b, "%s %s\n", mark, n)
+		}
+		b.WriteString("\n" + tr(lang, "keyboard.hint"))
+		a.send(chatID, b.String())
+		return
+	}
+
+	name := fields[0]
+	known := false
+	for _, n := range keyboardExtras {
+		known = known || n == name
+	}
+	if !known {
+		a.send(chatID, tr(lang, "keyboard.unknown", strings.Join(keyboardExtras, ", ")))
+		return
+	}
+	extras[name] = fields[1] == "on"
+	if err := a.Store.SetKeyboardExtras(chatID, extras); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, tr(lang, "keyboard.updated"))
+}
+
+// handleRename handles "/rename <список> <название>"; without a name the
+// built-in button text is restored.
+func (a *App) handleRename(chatID int64, arg string) {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		a.send(chatID, a.tr(chatID, "rename.usage"))
+		return
+	}
+	topic, ok := a.topicFromButton(chatID, fields[0])
+	if !ok {
+		a.send(chatID, a.tr(chatID, "topic.unknown"))
+		return
+	}
+	key := chatKey(chatID, "topic_name:"+topic)
+	name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(arg), fields[0]))
+
+	if t, taken := a.topicFromButton(chatID, name); name != "" && ((taken && t != topic) || keyboardAction(name) != "") {
+		a.send(chatID, a.tr(chatID, "newlist.taken"))
+		return
+	}
+
+	var err error
+	if name == "" {
+		err = a.Store.DeleteKV(key)
+	} else {
+		err = a.Store.SetKV(key, name)
+	}
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "keyboard.updated"))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type Score struct {
+	UserID int64
+	Name   string
+	Done   int
+}
+
+func userDisplayName(u *tgbotapi.User) string {
+	if u.UserName != "" {
+		return "@" + u.UserName
+	}
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+func isGroupChat(chatID int64) bool {
+	return chatID < 0
+}
+
+// GroupChats returns group chats that have any items.
+func (s *sqlStore) GroupChats() ([]int64, error) {
+	var out []int64
+	for _, sh := range s.Shards() {
+		ids, err := sh.groupChats()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ids...)
+	}
+	return out, nil
+}
+
+func (s *sqlStore) groupChats() ([]int64, error) {
+	rows, err := s.DB.Query(`SELECT DISTINCT chat_id FROM items WHERE chat_id<0`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) Leaderboard(chatID int64, from, to time.Time) ([]Score, error) {
+	rows, err := s.readDB(chatID).Query(
+		`SELECT completed_by, MAX(completed_by_name), COUNT(*) FROM items
+		 WHERE chat_id=? AND status=? AND completed_by<>0 AND completed_at>=? AND completed_at<?
+		 GROUP BY completed_by ORDER BY COUNT(*) DESC`,
+		chatID, StatusDone, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Score
+	for rows.Next() {
+		var sc Score
+		if err := rows.Scan(&sc.UserID, &sc.Name, &sc.Done); err != nil {
+			return nil, err
+		}
+		out = append(out, sc)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) LeaderboardMuted(chatID int64) bool {
+	v, _, _ := s.GetKV(chatKey(chatID, "leaderboard"))
+	return v == "off"
+}
+
+func formatLeaderboard(lang string, scores []Score) string {
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Done > scores[j].Done })
+	medals := []string{"🥇", "🥈", "🥉"}
+
+	var b strings.Builder
+	b.WriteString("🏁 " + tr(lang, "leaderboard.header"))
+	for i, sc := range scores {
+		mark := "▫️"
+		if i < len(medals) {
+			mark = medals[i]
+		}
+		fmt.Fprintf(&b, "\n%s %s — %d", mark, sc.Name, sc.Done)
+	}
+	if len(scores) > 0 && scores[0].Done >= 10 {
+		b.WriteString("\n\n🔥 " + tr(lang, "leaderboard.fire"))
+	}
+	return b.String()
+}
+
+func (a *App) handleLeaderboard(chatID int64, arg string) {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "off":
+		_ = a.Store.SetKV(chatKey(chatID, "leaderboard"), "off")
+		a.send(chatID, a.tr(chatID, "leaderboard.off"))
+		return
+	case "on":
+		_ = a.Store.SetKV(chatKey(chatID, "leaderboard"), "on")
+		a.send(chatID, a.tr(chatID, "leaderboard.on"))
+		return
+	}
+
+	now := time.Now()
+	scores, err := a.Store.Leaderboard(chatID, now.AddDate(0, 0, -7), now)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if len(scores) == 0 {
+		a.send(chatID, a.tr(chatID, "leaderboard.empty"))
+		return
+	}
+	a.send(chatID, formatLeaderboard(a.Store.Lang(chatID), scores))
+}
+
+// sendLeaderboards posts last week's leaderboard to every group chat
+// that has not muted it.
+func (s *Scheduler) sendLeaderboards(now time.Time) {
+	chats, err := s.store.GroupChats()
+	if err != nil {
+		log.Printf("scheduler: group chats error: %v", err)
+		return
+	}
+	for _, chatID := range chats {
+		if s.store.LeaderboardMuted(chatID) {
+			continue
+		}
+		scores, err := s.store.Leaderboard(chatID, now.AddDate(0, 0, -7), now)
+		if err != nil {
+			log.Printf("scheduler: leaderboard error: %v", err)
+			continue
+		}
+		if len(scores) < 2 {
+			continue
+		}
+		s.send("leaderboard", tgbotapi.NewMessage(chatID, formatLeaderboard(s.store.Lang(chatID), scores)))
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+// Link titles: when a captured message is just a link, the page is fetched
+// in the background and the item becomes "Go Concurrency Patterns — go.dev"
+// with the link on the next line, so the reading list says what each link
+// is. Only the first 512 KB of an HTML page is read, within 5 seconds, and
+// never from loopback or private addresses. LINK_TITLES=off turns it off.
+
+const (
+	maxTitlePage  = 512 << 10
+	maxTitleRunes = 120
+)
+
+var (
+	bareLinkRe = regexp.MustCompile(`^https?://\S+$`)
+	ogTitleRe  = regexp.MustCompile(`(?is)<meta[^>]+property=["']og:title["'][^>]+content=["']([^"']+)["']`)
+	titleRe    = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+var titleClient = &http.Client{
+	Timeout: 5 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 3 * time.Second, Control: publicOnly}).DialContext,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		return nil
+	},
+}
+
+// publicOnly refuses connections to the bot's own network.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%s is not a public address", host)
+	}
+	return nil
+}
+
+// bareLink returns text's URL when text is nothing but a link.
+func bareLink(text string) (*url.URL, bool) {
+	text = strings.TrimSpace(text)
+	if !bareLinkRe.MatchString(text) {
+		return nil, false
+	}
+	u, err := url.Parse(text)
+	if err != nil || u.Hostname() == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// pageTitle extracts og:title, else <title>, from an HTML page.
+func pageTitle(page string) string {
+	var raw string
+	if m := ogTitleRe.FindStringSubmatch(page); m != nil {
+		raw = m[1]
+	} else if m := titleRe.FindStringSubmatch(page); m != nil {
+		raw = m[1]
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(raw)), " ")
+	if !utf8.ValidString(title) {
+		return "" // some legacy charset
+	}
+	if r := []rune(title); len(r) > maxTitleRunes {
+		title = strings.TrimSpace(string(r[:maxTitleRunes-1])) + "…"
+	}
+	return title
+}
+
+func fetchTitle(ctx context.Context, u *url.URL) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "gtdBot link preview")
+	req.Header.Set("Accept", "text/html")
+	resp, err := titleClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", resp.Status)
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "text/html" && mt != "application/xhtml+xml" {
+		return "", fmt.Errorf("not a page: %s", mt)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxTitlePage))
+	if err != nil {
+		return "", err
+	}
+	return pageTitle(string(page)), nil
+}
+
+// titledLink is the item text for a link with a known title.
+func titledLink(title string, u *url.URL) string {
+	return title + " — " + strings.TrimPrefix(u.Hostname(), "www.") + "\n" + u.String()
+}
+
+// titleLink fetches the page title of item id, stored as the bare link
+// text, and puts it into the item unless it was edited or done meanwhile.
+func (a *App) titleLink(chatID, id int64, text string) {
+	if strings.EqualFold(os.Getenv("LINK_TITLES"), "off") {
+		return
+	}
+	u, ok := bareLink(text)
+	if !ok {
+		return
+	}
+	go func() {
+		title, err := fetchTitle(context.Background(), u)
+		if err != nil || title == "" {
+			if err != nil {
+				log.Printf("link title: %s: %v", u.Hostname(), err)
+			}
+			return
+		}
+		it, err := a.Store.GetItem(chatID, id)
+		if err != nil || it == nil || !it.CompletedAt.IsZero() || it.Text != text {
+			return
+		}
+		if err := a.Store.EditItem(chatID, id, titledLink(title, u)); err != nil && !errors.Is(err, errLocked) {
+			log.Printf("link title: item %d: %v", id, err)
+		}
+	}()
+}
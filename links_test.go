@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBareLink(t *testing.T) {
+	tests := []struct {
+		text string
+		want string // "" when not a bare link
+	}{
+		{"https://go.dev/talks/2012/concurrency.slide", "https://go.dev/talks/2012/concurrency.slide"},
+		{"  http://example.com/a?b=c  ", "http://example.com/a?b=c"},
+		{"почитать https://go.dev", ""},
+		{"https://go.dev и ещё", ""},
+		{"ftp://example.com/file", ""},
+		{"https://", ""},
+		{"go.dev", ""},
+	}
+	for _, tt := range tests {
+		u, ok := bareLink(tt.text)
+		got := ""
+		if ok {
+			got = u.String()
+		}
+		if got != tt.want {
+			t.Errorf("bareLink(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPageTitle(t *testing.T) {
+	long := strings.Repeat("я", maxTitleRunes+10)
+	tests := []struct {
+		page string
+		want string
+	}{
+		{`<html><head><title>Go Concurrency Patterns</title></head></html>`, "Go Concurrency Patterns"},
+		{`<meta property="og:title" content="Из og:title"><title>Из title</title>`, "Из og:title"},
+		{"<TITLE lang=\"en\">\n  Tom &amp; Jerry\n\t— cartoons </TITLE>", "Tom & Jerry — cartoons"},
+		{`<title>` + long + `</title>`, strings.Repeat("я", maxTitleRunes-1) + "…"},
+		{"<title>\xff\xfe</title>", ""},
+		{`<p>no title here</p>`, ""},
+	}
+	for _, tt := range tests {
+		if got := pageTitle(tt.page); got != tt.want {
+			t.Errorf("pageTitle(%.40q) = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestPublicOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		ok      bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:4700::1111]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.5:80", false},
+		{"192.168.1.1:443", false},
+		{"172.16.0.1:443", false},
+		{"169.254.169.254:80", false},
+		{"[fd00::1]:443", false},
+		{"0.0.0.0:80", false},
+		{"localhost:80", false},
+		{"93.184.216.34", false},
+	}
+	for _, tt := range tests {
+		err := publicOnly("tcp", tt.address, nil)
+		if (err == nil) != tt.ok {
+			t.Errorf("publicOnly(%q) = %v, want ok=%v", tt.address, err, tt.ok)
+		}
+	}
+}
+
+func TestTitledLink(t *testing.T) {
+	u, _ := bareLink("https://www.go.dev/blog")
+	if got, want := titledLink("Blog", u), "Blog — go.dev\nhttps://www.go.dev/blog"; got != want {
+		t.Errorf("titledLink = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// /list renders active items as one numbered message with ◀️/▶️ buttons
+// that edit it in place, for lists too long to send item by item.
+
+const listPageSize = 15
+
+const listAll = "all"
+
+func listPage(items []Item, page int) ([]Item, int, int) {
+	pages := max(1, (len(items)+listPageSize-1)/listPageSize)
+	page = min(max(page, 0), pages-1)
+	from := page * listPageSize
+	return items[from:min(from+listPageSize, len(items))], page, pages
+}
+
+func (a *App) listTitle(chatID int64, topic string) string {
+	if topic == listAll {
+		return a.tr(chatID, "list.all")
+	}
+	return strings.ToUpper(a.topicButton(chatID, a.Store.Lang(chatID), topic))
+}
+
+// renderList returns the text and pager for one page of a topic ("all" for
+// every active item).
+func (a *App) renderList(chatID int64, topic string, page int) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	q := topic
+	if topic == listAll {
+		q = ""
+	}
+	items, err := a.Store.ListActive(chatID, q)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+	lang := a.Store.Lang(chatID)
+	banner := ""
+	if f, ok := a.focusFor(chatID); ok {
+		items = f.Filter(items)
+		banner = f.Banner(lang, a.tz(chatID)) + "\n"
+	}
+	if len(items) == 0 {
+		return banner + a.listTitle(chatID, topic) + ":\n" + tr(lang, "empty"), tgbotapi.InlineKeyboardMarkup{}, nil
+	}
+
+	shown, page, pages := listPage(items, page)
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString(banner)
+	b.WriteString(a.listTitle(chatID, topic))
+	if pages > 1 {
+		fmt.Fprintf(&b, " (%d/%d)", page+1, pages)
+	}
+	b.WriteString(":")
+	withMedia, _ := a.Store.ItemsWithMedia(chatID)
+	var reveal, files []tgbotapi.InlineKeyboardButton
+	for i, it := range shown {
+		text := shownText(it)
+		if it.Secret {
+			reveal = append(reveal, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("👁 #%d", it.ID), fmt.Sprintf("reveal:%d", it.ID)))
+		}
+		if it.Flagged {
+			text = "🚩 " + text
+		}
+		if withMedia[it.ID] {
+			text = "📎 " + text
+			files = append(files, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📎 #%d", it.ID), fmt.Sprintf("att:%d", it.ID)))
+		}
+		fmt.Fprintf(&b, "\n%d. %s #%d%s", page*listPageSize+i+1, text, it.ID, dueMark(lang, it.Due, now, a.tz(chatID)))
+		if topic == listAll {
+			b.WriteString(" · " + a.topicButton(chatID, lang, it.Topic))
+		}
+	}
+
+	var markup tgbotapi.InlineKeyboardMarkup
+	if pages > 1 {
+		var row []tgbotapi.InlineKeyboardButton
+		if page > 0 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️", fmt.Sprintf("pg:%s:%d", topic, page-1)))
+		}
+		if page < pages-1 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("▶️", fmt.Sprintf("pg:%s:%d", topic, page+1)))
+		}
+		markup = tgbotapi.NewInlineKeyboardMarkup(row)
+	}
+	for _, buttons := range [][]tgbotapi.InlineKeyboardButton{reveal, files} {
+		for len(buttons) > 0 {
+			n := min(len(buttons), 4)
+			markup.InlineKeyboard = append(markup.InlineKeyboard, buttons[:n])
+			buttons = buttons[n:]
+		}
+	}
+	return b.String(), markup, nil
+}
+
+// handleList handles "/list [список]" and the per-topic shortcuts.
+func (a *App) handleList(chatID int64, arg string) {
+	if a.Store.Locked(chatID) {
+		a.sendLocked(chatID)
+		return
+	}
+	topic := listAll
+	if arg = strings.TrimSpace(arg); arg != "" {
+		t, ok := a.topicFromButton(chatID, arg)
+		if !ok {
+			a.send(chatID, a.tr(chatID, "topic.unknown")+" "+a.tr(chatID, "list.usage"))
+			return
+		}
+		topic = t
+	}
+	text, markup, err := a.renderList(chatID, topic, 0)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	if len(markup.InlineKeyboard) > 0 {
+		msg.ReplyMarkup = markup
+	}
+	_, _ = a.Bot.Send(msg)
+}
+
+// handleListCallback handles "pg:<topic>:<page>" by editing the list in place.
+func (a *App) handleListCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	topic, pageStr, _ := strings.Cut(data, ":")
+	page, _ := strconv.Atoi(pageStr)
+	text, markup, err := a.renderList(chatID, topic, page)
+	if err != nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.read")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	if len(markup.InlineKeyboard) > 0 {
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageTextAndMarkup(chatID, cq.Message.MessageID, text, markup))
+	} else {
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, text))
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Maintenance schedules are long recurrences by time, by distance or by
+// whichever comes first: "/maint add замена масла каждые 6 месяцев или
+// 10000 км". Distance comes from odometer check-ins ("/odo 48200"). The
+// morning run warns MAINT_LEAD_DAYS / MAINT_LEAD_KM ahead and again once
+// the schedule is due; "✅ Сделано" ("maint:<id>") starts the next cycle
+// from today and the current odometer.
+
+type Maintenance struct {
+	ID          int64
+	Title       string
+	EveryMonths int // 0: no time interval
+	EveryKm     int // 0: no distance interval
+	LastDone    time.Time
+	LastKm      int
+	Notified    string // "", "lead" or "due" for the current cycle
+}
+
+func (m Maintenance) dueAt() time.Time {
+	if m.EveryMonths == 0 {
+		return time.Time{}
+	}
+	return m.LastDone.AddDate(0, m.EveryMonths, 0)
+}
+
+func (m Maintenance) dueKm() int {
+	if m.EveryKm == 0 {
+		return 0
+	}
+	return m.LastKm + m.EveryKm
+}
+
+// state is "due", "lead" or "" for the given day and odometer reading.
+func (m Maintenance) state(now time.Time, odo, leadDays, leadKm int) string {
+	at, km := m.dueAt(), m.dueKm()
+	switch {
+	case !at.IsZero() && !now.Before(at), km > 0 && odo >= km:
+		return "due"
+	case !at.IsZero() && !now.Before(at.AddDate(0, 0, -leadDays)), km > 0 && odo >= km-leadKm:
+		return "lead"
+	}
+	return ""
+}
+
+func maintLead() (days, km int) {
+	days, err := strconv.Atoi(envOr("MAINT_LEAD_DAYS", "14"))
+	if err != nil || days < 0 {
+		days = 14
+	}
+	km, err = strconv.Atoi(envOr("MAINT_LEAD_KM", "500"))
+	if err != nil || km < 0 {
+		km = 500
+	}
+	return days, km
+}
+
+func (s *sqlStore) AddMaintenance(chatID int64, m Maintenance) (int64, error) {
+	var id int64
+	err := s.db(chatID).QueryRow(
+		`INSERT INTO maintenance(chat_id, title, every_months, every_km, last_done_at, last_done_km, notified, created_at)
+		 VALUES(?,?,?,?,?,?,'',?) RETURNING id`,
+		chatID, m.Title, m.EveryMonths, m.EveryKm, m.LastDone.UTC().Format(time.RFC3339), m.LastKm, time.Now().UTC().Format(time.RFC3339),
+	).Scan(&id)
+	return id, err
+}
+
+func (s *sqlStore) ListMaintenance(chatID int64) ([]Maintenance, error) {
+	rows, err := s.readDB(chatID).Query(
+		`SELECT id, title, every_months, every_km, last_done_at, last_done_km, notified FROM maintenance WHERE chat_id=? ORDER BY id`,
+		chatID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Maintenance
+	for rows.Next() {
+		var m Maintenance
+		var done string
+		if err := rows.Scan(&m.ID, &m.Title, &m.EveryMonths, &m.EveryKm, &done, &m.LastKm, &m.Notified); err != nil {
+			return nil, err
+		}
+		m.LastDone, _ = time.Parse(time.RFC3339, done)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// DoneMaintenance starts a new cycle; false if there is no such schedule.
+func (s *sqlStore) DoneMaintenance(chatID, id int64, at time.Time, km int) (bool, error) {
+	res, err := s.db(chatID).Exec(
+		`UPDATE maintenance SET last_done_at=?, last_done_km=?, notified='' WHERE chat_id=? AND id=?`,
+		at.UTC().Format(time.RFC3339), km, chatID, id,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (s *sqlStore) SetMaintenanceNotified(chatID, id int64, state string) error {
+	_, err := s.db(chatID).Exec(`UPDATE maintenance SET notified=? WHERE chat_id=? AND id=?`, state, chatID, id)
+	return err
+}
+
+func (s *sqlStore) DeleteMaintenance(chatID, id int64) (bool, error) {
+	res, err := s.db(chatID).Exec(`DELETE FROM maintenance WHERE chat_id=? AND id=?`, chatID, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// odometer is the chat's last check-in, 0 if none.
+func odometer(store Store, chatID int64) (int, time.Time) {
+	raw, ok, err := store.GetKV(chatKey(chatID, "odometer"))
+	if err != nil || !ok {
+		return 0, time.Time{}
+	}
+	kmRaw, atRaw, _ := strings.Cut(raw, "|")
+	km, _ := strconv.Atoi(kmRaw)
+	at, _ := time.Parse(time.RFC3339, atRaw)
+	return km, at
+}
+
+func setOdometer(store Store, chatID int64, km int, at time.Time) error {
+	return store.SetKV(chatKey(chatID, "odometer"), strconv.Itoa(km)+"|"+at.UTC().Format(time.RFC3339))
+}
+
+var maintRe = regexp.MustCompile(`(?i)^(.+?)\s+(?:каждые|каждый|каждую|every)\s+(?:(\d+)\s*(мес\S*|год\S*|лет|months?|years?))?(?:\s*(?:или|or|/)\s*)?(?:(\d+)\s*(?:км|km))?$`)
+
+// parseMaintenance reads "<что> каждые <N> месяцев|лет [или <N> км]".
+func parseMaintenance(text string) (Maintenance, bool) {
+	m := maintRe.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil || (m[2] == "" && m[4] == "") {
+		return Maintenance{}, false
+	}
+	out := Maintenance{Title: strings.TrimSpace(m[1])}
+	if m[2] != "" {
+		n, _ := strconv.Atoi(m[2])
+		out.EveryMonths = n
+		if unit := strings.ToLower(m[3]); strings.HasPrefix(unit, "год") || unit == "лет" || strings.HasPrefix(unit, "year") {
+			out.EveryMonths = n * 12
+		}
+	}
+	if m[4] != "" {
+		out.EveryKm, _ = strconv.Atoi(m[4])
+	}
+	return out, out.EveryMonths > 0 || out.EveryKm > 0
+}
+
+func formatMaintenance(lang string, m Maintenance, tz *time.Location) string {
+	var every, next []string
+	if m.EveryMonths > 0 {
+		every = append(every, tr(lang, "maint.months", m.EveryMonths))
+		next = append(next, m.dueAt().In(tz).Format("02.01.2006"))
+	}
+	if m.EveryKm > 0 {
+		every = append(every, tr(lang, "maint.km", m.EveryKm))
+		next = append(next, tr(lang, "maint.km", m.dueKm()))
+	}
+	or := tr(lang, "maint.or")
+	return tr(lang, "maint.line", m.ID, m.Title, strings.Join(every, or), strings.Join(next, or))
+}
+
+func maintDoneButton(lang string, id int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "maint.done.button"), fmt.Sprintf("maint:%d", id)),
+	))
+}
+
+// handleMaintenance handles "/maint [add <что> каждые … | done <id> [км] | del <id>]".
+func (a *App) handleMaintenance(chatID int64, arg string) {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	rest = strings.TrimSpace(rest)
+	odo, _ := odometer(a.Store, chatID)
+	lang := a.Store.Lang(chatID)
+	switch sub {
+	case "":
+		list, err := a.Store.ListMaintenance(chatID)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if len(list) == 0 {
+			a.send(chatID, tr(lang, "maint.none"))
+			return
+		}
+		var b strings.Builder
+		b.WriteString(tr(lang, "maint.header"))
+		for _, m := range list {
+			b.WriteString("\n" + formatMaintenance(lang, m, a.tz(chatID)))
+		}
+		if odo > 0 {
+			b.WriteString("\n\n" + tr(lang, "odo.show", odo))
+		}
+		a.send(chatID, b.String())
+	case "add":
+		m, ok := parseMaintenance(rest)
+		if !ok {
+			a.send(chatID, tr(lang, "maint.add.usage"))
+			return
+		}
+		m.LastDone, m.LastKm = time.Now(), odo
+		id, err := a.Store.AddMaintenance(chatID, m)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		m.ID = id
+		text := "🔧 " + formatMaintenance(lang, m, a.tz(chatID))
+		if m.EveryKm > 0 && odo == 0 {
+			text += "\n" + tr(lang, "maint.km.start")
+		}
+		a.send(chatID, text)
+	case "done", "del":
+		idRaw, kmRaw, _ := strings.Cut(rest, " ")
+		id, err := strconv.ParseInt(idRaw, 10, 64)
+		if err != nil {
+			a.send(chatID, tr(lang, "maint.id.usage", sub))
+			return
+		}
+		var ok bool
+		if sub == "del" {
+			ok, err = a.Store.DeleteMaintenance(chatID, id)
+		} else {
+			km := odo
+			if n, err := strconv.Atoi(strings.TrimSpace(kmRaw)); err == nil {
+				km = n
+			}
+			ok, err = a.Store.DoneMaintenance(chatID, id, time.Now(), km)
+		}
+		switch {
+		case err != nil:
+			a.send(chatID, a.tr(chatID, "err.write"))
+		case !ok:
+			a.send(chatID, tr(lang, "maint.notfound", id))
+		case sub == "del":
+			a.send(chatID, tr(lang, "maint.deleted", id))
+		default:
+			a.send(chatID, tr(lang, "maint.done"))
+		}
+	default:
+		a.send(chatID, tr(lang, "maint.usage"))
+	}
+}
+
+// handleOdometer handles "/odo <км>" and says what the new reading makes due.
+func (a *App) handleOdometer(chatID int64, arg string) {
+	km, err := strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(arg), " ", ""))
+	if err != nil || km <= 0 {
+		odo, at := odometer(a.Store, chatID)
+		if odo == 0 {
+			a.send(chatID, a.tr(chatID, "odo.usage"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "odo.show.at", odo, at.In(a.tz(chatID)).Format("02.01.2006")))
+		return
+	}
+	list, err := a.Store.ListMaintenance(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	err = a.Store.InTx(chatID, func(tx Store) error {
+		prev, _ := odometer(tx, chatID)
+		if err := setOdometer(tx, chatID, km, time.Now()); err != nil {
+			return err
+		}
+		// Distance schedules added before the first check-in count from it
+		for _, m := range list {
+			if prev == 0 && m.EveryKm > 0 && m.LastKm == 0 {
+				if _, err := tx.DoneMaintenance(chatID, m.ID, m.LastDone, km); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "odo.set", km))
+
+	leadDays, leadKm := maintLead()
+	for _, m := range list {
+		if m.EveryKm == 0 || m.LastKm == 0 {
+			continue
+		}
+		if st := m.state(time.Now(), km, leadDays, leadKm); st != "" {
+			lang := a.Store.Lang(chatID)
+			msg := tgbotapi.NewMessage(chatID, maintReminderText(lang, m, st, a.tz(chatID)))
+			msg.ReplyMarkup = maintDoneButton(lang, m.ID)
+			_, _ = a.Bot.Send(msg)
+			_ = a.Store.SetMaintenanceNotified(chatID, m.ID, st)
+		}
+	}
+}
+
+func maintReminderText(lang string, m Maintenance, state string, tz *time.Location) string {
+	if state == "due" {
+		return tr(lang, "maint.due", m.Title) + "\n" + formatMaintenance(lang, m, tz)
+	}
+	return tr(lang, "maint.soon", m.Title) + "\n" + formatMaintenance(lang, m, tz)
+}
+
+// handleMaintCallback handles "maint:<id>".
+func (a *App) handleMaintCallback(cq *tgbotapi.CallbackQuery, idStr string) {
+	chatID := cq.Message.Chat.ID
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	odo, _ := odometer(a.Store, chatID)
+	ok, err := a.Store.DoneMaintenance(chatID, id, time.Now(), odo)
+	if err != nil || !ok {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.write")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "maint.done.answer")))
+	_, _ = a.Bot.Send(tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, cq.Message.Text+"\n"+a.tr(chatID, "maint.done.button")))
+}
+
+// sendMaintenanceReminders warns once when a schedule comes within lead
+// time and once more when it's due. Distance schedules wait for the first
+// odometer check-in; on the 1st a stale reading (over a month) is asked for.
+func (s *Scheduler) sendMaintenanceReminders(chatID int64, now time.Time) {
+	list, err := s.store.ListMaintenance(chatID)
+	if err != nil {
+		log.Printf("scheduler: maintenance error: %v", err)
+		return
+	}
+	odo, odoAt := odometer(s.store, chatID)
+	leadDays, leadKm := maintLead()
+	lang := s.store.Lang(chatID)
+	askOdo := false
+	for _, m := range list {
+		if m.EveryKm > 0 {
+			askOdo = true
+		}
+		reading := odo
+		if m.LastKm == 0 {
+			reading = 0
+		}
+		st := m.state(now, reading, leadDays, leadKm)
+		if st == "" || st == m.Notified {
+			continue
+		}
+		msg := tgbotapi.NewMessage(chatID, maintReminderText(lang, m, st, now.Location()))
+		msg.ReplyMarkup = maintDoneButton(lang, m.ID)
+		if err := s.deliver("maintenance", msg); err != nil {
+			continue
+		}
+		if err := s.store.SetMaintenanceNotified(chatID, m.ID, st); err != nil {
+			log.Printf("scheduler: maintenance notified error: %v", err)
+		}
+	}
+	if askOdo && now.Day() == 1 && now.Sub(odoAt) > 30*24*time.Hour {
+		s.send("odometer", tgbotapi.NewMessage(chatID, tr(lang, "odo.ask")))
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Meal planning. Recipes are named ingredient lists:
+//
+//	/recipe Борщ: свёкла 2 шт, капуста 0,5 кг, сметана
+//
+// and the week plan is one dish per weekday ("/meal пн борщ"). "/meal shop"
+// puts the week's ingredients (or only the given days or dishes) into
+// shopping. Ingredients end in an optional quantity and unit; equal names
+// with equal units are summed, both within the plan and with what's already
+// on the shopping list.
+
+type Recipe struct {
+	Key         string
+	Name        string
+	Ingredients []string
+}
+
+var weekdayNames = [7][]string{
+	{"пн", "понедельник", "mon", "monday"},
+	{"вт", "вторник", "tue", "tuesday"},
+	{"ср", "среда", "wed", "wednesday"},
+	{"чт", "четверг", "thu", "thursday"},
+	{"пт", "пятница", "fri", "friday"},
+	{"сб", "суббота", "sat", "saturday"},
+	{"вс", "воскресенье", "sun", "sunday"},
+}
+
+// parseWeekday maps a day name to 0 (Monday) .. 6 (Sunday).
+func parseWeekday(s string) (int, bool) {
+	s = normalizeText(s)
+	for i, names := range weekdayNames {
+		for _, n := range names {
+			if s == n {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// weekdayShort is the short name of day 0 (Monday) .. 6 (Sunday) in lang.
+func weekdayShort(lang string, day int) string {
+	if lang == LangEN {
+		return weekdayNames[day][2]
+	}
+	return weekdayNames[day][0]
+}
+
+func (s *sqlStore) PutRecipe(chatID int64, r Recipe) error {
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO recipes(chat_id, recipe, name, ingredients, created_at) VALUES(?,?,?,?,?)
+		 ON CONFLICT(chat_id, recipe) DO UPDATE SET name=excluded.name, ingredients=excluded.ingredients`,
+		chatID, r.Key, r.Name, strings.Join(r.Ingredients, "\n"), time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (s *sqlStore) Recipes(chatID int64) ([]Recipe, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT recipe, name, ingredients FROM recipes WHERE chat_id=? ORDER BY recipe`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Recipe
+	for rows.Next() {
+		var r Recipe
+		var ingredients string
+		if err := rows.Scan(&r.Key, &r.Name, &ingredients); err != nil {
+			return nil, err
+		}
+		if ingredients != "" {
+			r.Ingredients = strings.Split(ingredients, "\n")
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// DeleteRecipe drops the recipe and takes it off the week plan.
+func (s *sqlStore) DeleteRecipe(chatID int64, key string) (bool, error) {
+	var found bool
+	err := s.For(chatID).inTx(func(tx *sqlStore) error {
+		res, err := tx.DB.Exec(`DELETE FROM recipes WHERE chat_id=? AND recipe=?`, chatID, key)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		found = n > 0
+		_, err = tx.DB.Exec(`DELETE FROM meal_plan WHERE chat_id=? AND recipe=?`, chatID, key)
+		return err
+	})
+	return found, err
+}
+
+// SetMeal puts a recipe on a weekday; an empty key clears the day.
+func (s *sqlStore) SetMeal(chatID int64, day int, key string) error {
+	if key == "" {
+		_, err := s.db(chatID).Exec(`DELETE FROM meal_plan WHERE chat_id=? AND day=?`, chatID, day)
+		return err
+	}
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO meal_plan(chat_id, day, recipe) VALUES(?,?,?) ON CONFLICT(chat_id, day) DO UPDATE SET recipe=excluded.recipe`,
+		chatID, day, key,
+	)
+	return err
+}
+
+// MealPlan maps weekdays (0 = Monday) to recipe keys.
+func (s *sqlStore) MealPlan(chatID int64) (map[int]string, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT day, recipe FROM meal_plan WHERE chat_id=?`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int]string{}
+	for rows.Next() {
+		var day int
+		var key string
+		if err := rows.Scan(&day, &key); err != nil {
+			return nil, err
+		}
+		out[day] = key
+	}
+	return out, rows.Err()
+}
+
+var ingredientRe = regexp.MustCompile(`^(.+?)\s+(\d+(?:[.,]\d+)?)\s*(\pL*)$`)
+
+type ingredient struct {
+	Name string
+	Qty  float64 // 0: no quantity
+	Unit string
+}
+
+func parseIngredient(s string) ingredient {
+	s = strings.TrimSpace(s)
+	m := ingredientRe.FindStringSubmatch(s)
+	if m == nil {
+		return ingredient{Name: s}
+	}
+	qty, _ := parseNumber(m[2])
+	return ingredient{Name: strings.TrimSpace(m[1]), Qty: qty, Unit: m[3]}
+}
+
+func (in ingredient) key() string {
+	return normalizeText(in.Name) + "\x00" + normalizeText(in.Unit)
+}
+
+func (in ingredient) String() string {
+	if in.Qty == 0 {
+		return in.Name
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", in.Name, formatAmount(math.Round(in.Qty*1000)/1000), in.Unit))
+}
+
+// mergeIngredients sums quantities of equal ingredients, keeping first-seen
+// order.
+func mergeIngredients(lines []string) []ingredient {
+	var out []ingredient
+	at := map[string]int{}
+	for _, l := range lines {
+		in := parseIngredient(l)
+		if in.Name == "" {
+			continue
+		}
+		if i, ok := at[in.key()]; ok {
+			out[i].Qty += in.Qty
+			continue
+		}
+		at[in.key()] = len(out)
+		out = append(out, in)
+	}
+	return out
+}
+
+// handleRecipe handles "/recipe [<название>: <ингредиенты> | del <название>]".
+func (a *App) handleRecipe(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	if name, ok := strings.CutPrefix(arg, "del "); ok {
+		found, err := a.Store.DeleteRecipe(chatID, normalizeText(name))
+		switch {
+		case err != nil:
+			a.send(chatID, a.tr(chatID, "err.write"))
+		case !found:
+			a.send(chatID, a.tr(chatID, "recipe.notfound", strings.TrimSpace(name)))
+		default:
+			a.send(chatID, a.tr(chatID, "recipe.deleted", strings.TrimSpace(name)))
+		}
+		return
+	}
+	if arg == "" {
+		recipes, err := a.Store.Recipes(chatID)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if len(recipes) == 0 {
+			a.send(chatID, a.tr(chatID, "recipes.none")+" "+a.tr(chatID, "recipe.usage"))
+			return
+		}
+		var b strings.Builder
+		b.WriteString(a.tr(chatID, "recipes.header"))
+		for _, r := range recipes {
+			fmt.Fprintf(&b, "\n\n%s: %s", r.Name, strings.Join(r.Ingredients, ", "))
+		}
+		a.send(chatID, b.String())
+		return
+	}
+
+	name, list, ok := strings.Cut(arg, ":")
+	name = strings.TrimSpace(name)
+	var ingredients []string
+	for _, l := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '\n' || r == ';' }) {
+		if l = strings.TrimSpace(l); l != "" {
+			ingredients = append(ingredients, l)
+		}
+	}
+	if !ok || name == "" || len(ingredients) == 0 {
+		a.send(chatID, a.tr(chatID, "recipe.usage"))
+		return
+	}
+	if err := a.Store.PutRecipe(chatID, Recipe{Key: normalizeText(name), Name: name, Ingredients: ingredients}); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "recipe.saved", name, len(ingredients), name))
+}
+
+func findRecipe(recipes []Recipe, key string) (Recipe, bool) {
+	for _, r := range recipes {
+		if r.Key == key {
+			return r, true
+		}
+	}
+	return Recipe{}, false
+}
+
+// handleMeal handles "/meal [<день> <рецепт|-> | shop [дни или рецепты через запятую]]".
+func (a *App) handleMeal(chatID int64, arg string) {
+	recipes, err := a.Store.Recipes(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	plan, err := a.Store.MealPlan(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+	first, rest, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	rest = strings.TrimSpace(rest)
+
+	if first == "" {
+		var b strings.Builder
+		b.WriteString(tr(lang, "meals.header"))
+		for day := range weekdayNames {
+			dish := "—"
+			if r, ok := findRecipe(recipes, plan[day]); ok {
+				dish = r.Name
+			}
+			fmt.Fprintf(&b, "\n%s: %s", weekdayShort(lang, day), dish)
+		}
+		b.WriteString("\n\n" + tr(lang, "meals.shop.hint"))
+		a.send(chatID, b.String())
+		return
+	}
+	if first == "shop" {
+		var picks []string
+		for _, p := range strings.Split(rest, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				picks = append(picks, p)
+			}
+		}
+		a.shopMeals(chatID, recipes, plan, picks)
+		return
+	}
+
+	day, ok := parseWeekday(first)
+	if !ok || rest == "" {
+		a.send(chatID, tr(lang, "meal.usage"))
+		return
+	}
+	key := ""
+	if rest != "-" {
+		r, ok := findRecipe(recipes, normalizeText(rest))
+		if !ok {
+			a.send(chatID, tr(lang, "meal.norecipe", rest, rest))
+			return
+		}
+		key = r.Key
+	}
+	if err := a.Store.SetMeal(chatID, day, key); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	if key == "" {
+		a.send(chatID, tr(lang, "meal.cleared", weekdayShort(lang, day)))
+		return
+	}
+	a.send(chatID, fmt.Sprintf("%s: %s.", weekdayShort(lang, day), rest))
+}
+
+// mealDays reads "пн ср пт"; false if any word isn't a day.
+func mealDays(s string) ([]int, bool) {
+	var days []int
+	for _, w := range strings.Fields(s) {
+		day, ok := parseWeekday(w)
+		if !ok {
+			return nil, false
+		}
+		days = append(days, day)
+	}
+	return days, len(days) > 0
+}
+
+// shopMeals adds the ingredients of the chosen days or dishes (the whole
+// week without any) to shopping, adding quantities to matching items
+// already there.
+func (a *App) shopMeals(chatID int64, recipes []Recipe, plan map[int]string, picks []string) {
+	var keys []string
+	if len(picks) == 0 {
+		for day := range weekdayNames {
+			if key, ok := plan[day]; ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	for _, p := range picks {
+		days, ok := mealDays(p)
+		if !ok {
+			keys = append(keys, normalizeText(p))
+			continue
+		}
+		for _, day := range days {
+			if key, ok := plan[day]; ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	var lines []string
+	for _, key := range keys {
+		if r, ok := findRecipe(recipes, key); ok {
+			lines = append(lines, r.Ingredients...)
+		}
+	}
+	if len(lines) == 0 {
+		a.send(chatID, a.tr(chatID, "meal.shop.empty"))
+		return
+	}
+
+	existing, err := a.Store.ListActive(chatID, TopicShopping)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	onList := map[string]Item{}
+	for _, it := range existing {
+		onList[parseIngredient(it.Text).key()] = it
+	}
+	var added, merged int
+	err = a.Store.InTx(chatID, func(tx Store) error {
+		for _, in := range mergeIngredients(lines) {
+			if it, ok := onList[in.key()]; ok {
+				if in.Qty == 0 {
+					continue
+				}
+				have := parseIngredient(it.Text)
+				have.Qty += in.Qty
+				if err := tx.EditItem(chatID, it.ID, have.String()); err != nil {
+					return err
+				}
+				merged++
+				continue
+			}
+			if _, err := tx.AddItem(chatID, TopicShopping, in.String()); err != nil {
+				return err
+			}
+			added++
+		}
+		return nil
+	})
+	if errors.Is(err, errLocked) {
+		a.sendLocked(chatID)
+		return
+	}
+	if err != nil {
+		log.Printf("meal shop error: %v", err)
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "meal.shop.done", added, merged))
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// BlobStore keeps downloaded Telegram files.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+type localBlobStore struct {
+	dir string
+}
+
+func (s *localBlobStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+func (s *localBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp := p + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (s *localBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+func (s *localBlobStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *localBlobStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var out []string
+	root := s.path(prefix)
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return filepath.SkipDir
+		}
+		if err != nil || d.IsDir() || strings.HasSuffix(p, ".part") {
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+		out = append(out, filepath.ToSlash(rel))
+		return nil
+	})
+	return out, err
+}
+
+// blobStoreFromEnv picks MEDIA_STORAGE: local (MEDIA_DIR), s3 or off.
+func blobStoreFromEnv() (BlobStore, error) {
+	switch strings.ToLower(envOr("MEDIA_STORAGE", "local")) {
+	case "local":
+		return &localBlobStore{dir: envOr("MEDIA_DIR", "media")}, nil
+	case "s3":
+		return s3ClientFromEnv()
+	case "off", "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("MEDIA_STORAGE: unknown backend %q", os.Getenv("MEDIA_STORAGE"))
+	}
+}
+
+// FileRef is a Telegram file referenced by a message.
+type FileRef struct {
+	FileID   string
+	UniqueID string
+	Kind     string // voice, photo, document, audio, video
+	Size     int64
+	Mime     string
+	Name     string
+}
+
+// mediaRefs lists the files in a message; for photos only the largest size.
+func mediaRefs(m *tgbotapi.Message) []FileRef {
+	var out []FileRef
+	if v := m.Voice; v != nil {
+		out = append(out, FileRef{FileID: v.FileID, UniqueID: v.FileUniqueID, Kind: "voice", Size: int64(v.FileSize), Mime: v.MimeType})
+	}
+	if n := len(m.Photo); n > 0 {
+		p := m.Photo[n-1]
+		out = append(out, FileRef{FileID: p.FileID, UniqueID: p.FileUniqueID, Kind: "photo", Size: int64(p.FileSize), Mime: "image/jpeg"})
+	}
+	if d := m.Document; d != nil {
+		out = append(out, FileRef{FileID: d.FileID, UniqueID: d.FileUniqueID, Kind: "document", Size: int64(d.FileSize), Mime: d.MimeType, Name: d.FileName})
+	}
+	if au := m.Audio; au != nil {
+		out = append(out, FileRef{FileID: au.FileID, UniqueID: au.FileUniqueID, Kind: "audio", Size: int64(au.FileSize), Mime: au.MimeType, Name: au.FileName})
+	}
+	if v := m.Video; v != nil {
+		out = append(out, FileRef{FileID: v.FileID, UniqueID: v.FileUniqueID, Kind: "video", Size: int64(v.FileSize), Mime: v.MimeType, Name: v.FileName})
+	}
+	return out
+}
+
+// MediaManager downloads files referenced by items into a BlobStore. Files
+// are stored once per file_unique_id, however many items point at them.
+type MediaManager struct {
+	bot      *tgbotapi.BotAPI
+	http     *http.Client
+	store    Store
+	blobs    BlobStore // nil: keep file_ids only
+	prefix   string    // "media/", "media/bots/<name>/" for an extra bot
+	maxBytes int64
+}
+
+func NewMediaManagerFromEnv(bot *tgbotapi.BotAPI, client *http.Client, store Store) (*MediaManager, error) {
+	blobs, err := blobStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	// The public Bot API refuses downloads over 20 MB; a local server doesn't
+	def := "20"
+	if os.Getenv("BOT_API_URL") != "" {
+		def = "2000"
+	}
+	mb, err := strconv.Atoi(envOr("MEDIA_MAX_MB", def))
+	if err != nil || mb <= 0 {
+		mb = 20
+	}
+	return &MediaManager{bot: bot, http: client, store: store, blobs: blobs, prefix: "media/", maxBytes: int64(mb) << 20}, nil
+}
+
+func (mm *MediaManager) key(uniqueID string) string {
+	return mm.prefix + uniqueID
+}
+
+type MediaFile struct {
+	FileRef
+	StorageKey string
+}
+
+func (s *sqlStore) GetMedia(uniqueID string) (*MediaFile, error) {
+	var f MediaFile
+	err := s.DB.QueryRow(
+		`SELECT file_unique_id, file_id, kind, size, mime, name, storage_key FROM media WHERE file_unique_id=?`, uniqueID,
+	).Scan(&f.UniqueID, &f.FileID, &f.Kind, &f.Size, &f.Mime, &f.Name, &f.StorageKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (s *sqlStore) PutMedia(f MediaFile) error {
+	_, err := s.DB.Exec(
+		`INSERT INTO media(file_unique_id, file_id, kind, size, mime, name, storage_key, created_at) VALUES(?,?,?,?,?,?,?,?)
+		 ON CONFLICT(file_unique_id) DO UPDATE SET file_id=excluded.file_id, storage_key=excluded.storage_key`,
+		f.UniqueID, f.FileID, f.Kind, f.Size, f.Mime, f.Name, f.StorageKey, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (s *sqlStore) LinkItemMedia(chatID, itemID int64, uniqueID string) error {
+	_, err := s.DB.Exec(
+		`INSERT INTO item_media(chat_id, item_id, file_unique_id) VALUES(?,?,?) ON CONFLICT DO NOTHING`,
+		chatID, itemID, uniqueID,
+	)
+	return err
+}
+
+func (s *sqlStore) ItemMedia(chatID, itemID int64) ([]MediaFile, error) {
+	rows, err := s.DB.Query(
+		`SELECT m.file_unique_id, m.file_id, m.kind, m.size, m.mime, m.name, m.storage_key
+		 FROM item_media im JOIN media m ON m.file_unique_id = im.file_unique_id
+		 WHERE im.chat_id=? AND im.item_id=? ORDER BY m.created_at, m.file_unique_id`,
+		chatID, itemID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []MediaFile
+	for rows.Next() {
+		var f MediaFile
+		if err := rows.Scan(&f.UniqueID, &f.FileID, &f.Kind, &f.Size, &f.Mime, &f.Name, &f.StorageKey); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// UnlinkedMedia returns files no item links to.
+func (s *sqlStore) UnlinkedMedia() ([]MediaFile, error) {
+	rows, err := s.DB.Query(
+		`SELECT file_unique_id, file_id, kind, size, mime, name, storage_key FROM media m
+		 WHERE NOT EXISTS (SELECT 1 FROM item_media im WHERE im.file_unique_id = m.file_unique_id)`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []MediaFile
+	for rows.Next() {
+		var f MediaFile
+		if err := rows.Scan(&f.UniqueID, &f.FileID, &f.Kind, &f.Size, &f.Mime, &f.Name, &f.StorageKey); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) DeleteMedia(uniqueID string) error {
+	_, err := s.DB.Exec(`DELETE FROM media WHERE file_unique_id=?`, uniqueID)
+	return err
+}
+
+func (s *sqlStore) MediaKeyInUse(key string) (bool, error) {
+	var n int
+	err := s.DB.QueryRow(`SELECT COUNT(*) FROM media WHERE storage_key=?`, key).Scan(&n)
+	return n > 0, err
+}
+
+// PruneItemMedia drops links to items that no longer exist. item_media sits
+// next to media in the main database, so with sharding each link is checked
+// against the chat's shard.
+func (s *sqlStore) PruneItemMedia() error {
+	if s.router == nil {
+		_, err := s.DB.Exec(
+			`DELETE FROM item_media WHERE NOT EXISTS (SELECT 1 FROM items i WHERE i.chat_id = item_media.chat_id AND i.id = item_media.item_id)`,
+		)
+		return err
+	}
+	rows, err := s.DB.Query(`SELECT chat_id, item_id FROM item_media`)
+	if err != nil {
+		return err
+	}
+	type link struct{ chatID, itemID int64 }
+	var links []link
+	for rows.Next() {
+		var l link
+		if err := rows.Scan(&l.chatID, &l.itemID); err != nil {
+			rows.Close()
+			return err
+		}
+		links = append(links, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, l := range links {
+		it, err := s.GetItem(l.chatID, l.itemID)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			if _, err := s.DB.Exec(`DELETE FROM item_media WHERE chat_id=? AND item_id=?`, l.chatID, l.itemID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// open returns the file contents from Telegram: straight from disk for a
+// --local Bot API server, over HTTP otherwise.
+func (mm *MediaManager) open(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	f, err := mm.bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		return nil, err
+	}
+	if localBotAPI() && filepath.IsAbs(f.FilePath) {
+		return os.Open(f.FilePath)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL(mm.bot.Token, f.FilePath), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := mm.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download %s: %s", fileID, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Attach links a file to an item and downloads it unless that file is
+// already stored. Files over the size limit are kept as file_id only.
+func (mm *MediaManager) Attach(ctx context.Context, chatID, itemID int64, ref FileRef) error {
+	if err := mm.store.LinkItemMedia(chatID, itemID, ref.UniqueID); err != nil {
+		return err
+	}
+	existing, err := mm.store.GetMedia(ref.UniqueID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && (existing.StorageKey != "" || mm.blobs == nil) {
+		return nil
+	}
+
+	f := MediaFile{FileRef: ref}
+	if mm.blobs != nil && ref.Size <= mm.maxBytes {
+		if err := mm.download(ctx, ref); err != nil {
+			log.Printf("media: download %s: %v", ref.UniqueID, err)
+		} else {
+			f.StorageKey = mm.key(ref.UniqueID)
+		}
+	}
+	return mm.store.PutMedia(f)
+}
+
+func (mm *MediaManager) download(ctx context.Context, ref FileRef) error {
+	body, err := mm.open(ctx, ref.FileID)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	// Size from the message can be missing; enforce the limit while copying
+	r := io.LimitReader(body, mm.maxBytes+1)
+	cr := &countingReader{r: r}
+	if err := mm.blobs.Put(ctx, mm.key(ref.UniqueID), cr, ref.Size); err != nil {
+		return err
+	}
+	if cr.n > mm.maxBytes {
+		_ = mm.blobs.Delete(ctx, mm.key(ref.UniqueID))
+		return fmt.Errorf("file over %d bytes", mm.maxBytes)
+	}
+	return nil
+}
+
+func formatBytes(lang string, n int64) string {
+	switch {
+	case n >= 1<<20:
+		return tr(lang, "bytes.mb", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return tr(lang, "bytes.kb", n>>10)
+	default:
+		return tr(lang, "bytes.b", n)
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Open returns a stored file's contents.
+func (mm *MediaManager) Open(ctx context.Context, f MediaFile) (io.ReadCloser, error) {
+	if mm.blobs == nil || f.StorageKey == "" {
+		return nil, fmt.Errorf("media %s not stored", f.UniqueID)
+	}
+	return mm.blobs.Get(ctx, f.StorageKey)
+}
+
+// Cleanup drops links to deleted items, then files nothing links to, then
+// blobs without a media row (left over from crashes).
+func (mm *MediaManager) Cleanup(ctx context.Context) error {
+	if err := mm.store.PruneItemMedia(); err != nil {
+		return err
+	}
+	orphans, err := mm.store.UnlinkedMedia()
+	if err != nil {
+		return err
+	}
+
+	for _, o := range orphans {
+		if o.StorageKey != "" && mm.blobs != nil {
+			if err := mm.blobs.Delete(ctx, o.StorageKey); err != nil {
+				log.Printf("media: delete %s: %v", o.StorageKey, err)
+				continue
+			}
+		}
+		if err := mm.store.DeleteMedia(o.UniqueID); err != nil {
+			return err
+		}
+	}
+
+	if mm.blobs == nil {
+		return nil
+	}
+	keys, err := mm.blobs.List(ctx, mm.prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if strings.Contains(strings.TrimPrefix(key, mm.prefix), "/") {
+			continue // an extra bot's files
+		}
+		used, err := mm.store.MediaKeyInUse(key)
+		if err != nil {
+			return err
+		}
+		if !used {
+			if err := mm.blobs.Delete(ctx, key); err != nil {
+				log.Printf("media: delete %s: %v", key, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every query goes through timedConn, which records its duration under the
+// sqlStore method that issued it and logs the ones slower than
+// DB_SLOW_MS. METRICS_LISTEN serves the histograms in Prometheus text
+// format at /metrics.
+
+var queryBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+type histogram struct {
+	counts []uint64 // per bucket, plus +Inf
+	sum    float64
+	total  uint64
+}
+
+type queryStats struct {
+	mu   sync.Mutex
+	byOp map[string]*histogram
+
+	slowOnce sync.Once // DB_SLOW_MS is read after .env is loaded
+	slow     time.Duration
+}
+
+var dbStats = &queryStats{byOp: map[string]*histogram{}}
+
+func slowQueryThreshold() time.Duration {
+	ms, err := strconv.Atoi(envOr("DB_SLOW_MS", "200"))
+	if err != nil || ms <= 0 {
+		ms = 200
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func (q *queryStats) observe(op string, d time.Duration) {
+	sec := d.Seconds()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	h := q.byOp[op]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(queryBuckets)+1)}
+		q.byOp[op] = h
+	}
+	i := sort.SearchFloat64s(queryBuckets, sec)
+	h.counts[i]++
+	h.sum += sec
+	h.total++
+}
+
+// writeProm renders cumulative buckets in the Prometheus exposition format.
+func (q *queryStats) writeProm(w *strings.Builder) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ops := make([]string, 0, len(q.byOp))
+	for op := range q.byOp {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	w.WriteString("# HELP gtd_db_query_duration_seconds Store query latency by method.\n")
+	w.WriteString("# TYPE gtd_db_query_duration_seconds histogram\n")
+	for _, op := range ops {
+		h := q.byOp[op]
+		var cum uint64
+		for i, le := range queryBuckets {
+			cum += h.counts[i]
+			fmt.Fprintf(w, "gtd_db_query_duration_seconds_bucket{query=%q,le=\"%g\"} %d\n", op, le, cum)
+		}
+		fmt.Fprintf(w, "gtd_db_query_duration_seconds_bucket{query=%q,le=\"+Inf\"} %d\n", op, h.total)
+		fmt.Fprintf(w, "gtd_db_query_duration_seconds_sum{query=%q} %g\n", op, h.sum)
+		fmt.Fprintf(w, "gtd_db_query_duration_seconds_count{query=%q} %d\n", op, h.total)
+	}
+}
+
+// queryOp names the sqlStore method up the stack, e.g. "ListActive".
+func queryOp() string {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if _, method, ok := strings.Cut(f.Function, "(*sqlStore)."); ok {
+			name, _, _ := strings.Cut(method, ".")
+			return name
+		}
+		if !more {
+			return "other"
+		}
+	}
+}
+
+// timedConn times every call on the connection it wraps.
+type timedConn struct {
+	c dbConn
+}
+
+func (t timedConn) done(query string, start time.Time) {
+	d := time.Since(start)
+	op := queryOp()
+	dbStats.observe(op, d)
+	dbStats.slowOnce.Do(func() { dbStats.slow = slowQueryThreshold() })
+	if d >= dbStats.slow {
+		log.Printf("slow query %s (%s): %s", op, d.Round(time.Millisecond), strings.Join(strings.Fields(query), " "))
+	}
+}
+
+func (t timedConn) Exec(query string, args ...any) (sql.Result, error) {
+	defer t.done(query, time.Now())
+	return t.c.Exec(query, args...)
+}
+
+func (t timedConn) Query(query string, args ...any) (*sql.Rows, error) {
+	defer t.done(query, time.Now())
+	return t.c.Query(query, args...)
+}
+
+func (t timedConn) QueryRow(query string, args ...any) *sql.Row {
+	defer t.done(query, time.Now())
+	return t.c.QueryRow(query, args...)
+}
+
+func startMetricsServer(ctx context.Context) {
+	addr := strings.TrimSpace(os.Getenv("METRICS_LISTEN"))
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		dbStats.writeProm(&b)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte(b.String()))
+	})
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("metrics: server: %v", err)
+		}
+	}()
+	log.Printf("metrics: listening on %s", addr)
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// "/migrate export" dumps everything a chat has (items with all their
+// columns, notes, goals, the compacted archive and settings) into a
+// versioned, gzipped JSON file; "/migrate import" in a reply to that file
+// loads it on another instance, e.g. moving from a test bot to production.
+// Item IDs are kept when the target database doesn't use them yet.
+// An encrypted chat exports plain text (it must be unlocked) and the
+// passphrase stays behind; the target seals the text with its own, so
+// importing into an encrypted chat needs it unlocked too.
+//
+// Left behind: attachments (file_id is per bot), comment threads (message
+// IDs are per bot in private chats), chat links and routes.
+
+const (
+	migrationFormat  = "gtdbot-migration"
+	migrationVersion = 1
+	maxMigrateBytes  = 20 << 20 // the Bot API download limit
+)
+
+type MigrationDoc struct {
+	Format     string           `json:"format"`
+	Version    int              `json:"version"`
+	ChatID     int64            `json:"chat_id"`
+	ExportedAt string           `json:"exported_at"`
+	Items      []map[string]any `json:"items"`
+	Notes      []map[string]any `json:"notes,omitempty"`
+	Goals      []map[string]any `json:"goals,omitempty"`
+	GoalLinks  []map[string]any `json:"goal_links,omitempty"`
+	History    []HistoryBlob    `json:"history,omitempty"`
+	Topics     []CustomTopic    `json:"topics,omitempty"`
+	Settings   SettingsDoc      `json:"settings"`
+}
+
+type HistoryBlob struct {
+	Month string `json:"month"`
+	Topic string `json:"topic"`
+	Count int    `json:"count"`
+	Items []byte `json:"items"` // packArchive output
+}
+
+type MigrationStats struct {
+	Items, KeptIDs, Notes, Goals, Months int
+}
+
+// dumpRows reads every column of the query's rows, whatever the schema
+// version, so new item columns migrate without changes here.
+func dumpRows(db dbConn, query string, args ...any) ([]map[string]any, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var out []map[string]any
+	for rows.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(cols))
+		for i, c := range cols {
+			if b, ok := vals[i].([]byte); ok {
+				vals[i] = string(b)
+			}
+			row[c] = vals[i]
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+func tableColumns(db dbConn, table string) ([]string, error) {
+	rows, err := db.Query(`SELECT * FROM ` + table + ` WHERE 1=0`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
+
+// insertRow inserts the row's columns that the table has. Without an "id"
+// the database assigns one, which is returned.
+func insertRow(db dbConn, table string, cols []string, row map[string]any) (int64, error) {
+	var names, marks []string
+	var args []any
+	for _, c := range cols {
+		v, ok := row[c]
+		if !ok {
+			continue
+		}
+		if n, isNum := v.(json.Number); isNum {
+			if i, err := n.Int64(); err == nil {
+				v = i
+			} else if v, err = n.Float64(); err != nil {
+				return 0, fmt.Errorf("%s.%s: %w", table, c, err)
+			}
+		}
+		names = append(names, c)
+		marks = append(marks, "?")
+		args = append(args, v)
+	}
+	var id int64
+	err := db.QueryRow(
+		`INSERT INTO `+table+`(`+strings.Join(names, ", ")+`) VALUES(`+strings.Join(marks, ",")+`) RETURNING id`, args...,
+	).Scan(&id)
+	return id, err
+}
+
+func rowID(row map[string]any) int64 {
+	switch v := row["id"].(type) {
+	case int64:
+		return v
+	case json.Number:
+		id, _ := v.Int64()
+		return id
+	}
+	return 0
+}
+
+// rewriteArchive applies fn to the text of each item in a packArchive blob.
+func rewriteArchive(blob []byte, fn func(text string) (string, error)) ([]byte, error) {
+	items, err := unpackArchive(blob)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if items[i].Text, err = fn(items[i].Text); err != nil {
+			return nil, err
+		}
+	}
+	return packArchive(items)
+}
+
+func (s *sqlStore) ExportChat(chatID int64) (*MigrationDoc, error) {
+	if s.Locked(chatID) {
+		return nil, errLocked
+	}
+	db := s.db(chatID)
+	doc := &MigrationDoc{Format: migrationFormat, Version: migrationVersion, ChatID: chatID, ExportedAt: time.Now().UTC().Format(time.RFC3339)}
+	var err error
+	if doc.Items, err = dumpRows(db, `SELECT * FROM items WHERE chat_id=? ORDER BY id`, chatID); err != nil {
+		return nil, err
+	}
+	for _, row := range doc.Items {
+		if text, ok := row["text"].(string); ok {
+			text = s.openText(chatID, text)
+			row["text"], row["norm"] = text, normalizeText(text)
+		}
+	}
+	if doc.Notes, err = dumpRows(db, `SELECT * FROM item_notes WHERE chat_id=? ORDER BY id`, chatID); err != nil {
+		return nil, err
+	}
+	if doc.Goals, err = dumpRows(db, `SELECT * FROM goals WHERE chat_id=? ORDER BY id`, chatID); err != nil {
+		return nil, err
+	}
+	if doc.GoalLinks, err = dumpRows(db, `SELECT gl.* FROM goal_links gl JOIN goals g ON g.id = gl.goal_id WHERE g.chat_id=?`, chatID); err != nil {
+		return nil, err
+	}
+	if doc.Topics, err = s.CustomTopics(chatID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT month, topic, count, items FROM item_history WHERE chat_id=? ORDER BY month, topic`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var h HistoryBlob
+		if err := rows.Scan(&h.Month, &h.Topic, &h.Count, &h.Items); err != nil {
+			return nil, err
+		}
+		open := func(text string) (string, error) { return s.openText(chatID, text), nil }
+		if h.Items, err = rewriteArchive(h.Items, open); err != nil {
+			return nil, err
+		}
+		doc.History = append(doc.History, h)
+	}
+	return doc, rows.Err()
+}
+
+// ImportChat loads doc into chatID in one transaction.
+func (s *sqlStore) ImportChat(chatID int64, doc *MigrationDoc) (MigrationStats, error) {
+	var st MigrationStats
+	err := s.For(chatID).inTx(func(tx *sqlStore) error {
+		itemCols, err := tableColumns(tx.DB, "items")
+		if err != nil {
+			return err
+		}
+		// Rows keeping their ID go first, so a new ID can't take one of theirs
+		var keep, renumber []map[string]any
+		for _, row := range doc.Items {
+			var taken bool
+			if err := tx.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM items WHERE id=?)`, rowID(row)).Scan(&taken); err != nil {
+				return err
+			}
+			if taken {
+				renumber = append(renumber, row)
+			} else {
+				keep = append(keep, row)
+			}
+		}
+		itemIDs := map[int64]int64{}
+		insert := func(row map[string]any, keepID bool) error {
+			old := rowID(row)
+			row["chat_id"] = chatID
+			row["message_id"] = 0  // messages of the old bot
+			row["notify_chat"] = 0 // chat links aren't migrated
+			if !keepID {
+				delete(row, "id")
+			}
+			if text, ok := row["text"].(string); ok {
+				stored, norm, err := tx.sealText(chatID, text)
+				if err != nil {
+					return err
+				}
+				row["text"], row["norm"] = stored, norm
+			}
+			id, err := insertRow(tx.DB, "items", itemCols, row)
+			if err != nil {
+				return err
+			}
+			itemIDs[old] = id
+			st.Items++
+			if id == old {
+				st.KeptIDs++
+			}
+			return nil
+		}
+		for _, row := range keep {
+			if err := insert(row, true); err != nil {
+				return err
+			}
+		}
+		for _, row := range renumber {
+			if err := insert(row, false); err != nil {
+				return err
+			}
+		}
+
+		noteCols, err := tableColumns(tx.DB, "item_notes")
+		if err != nil {
+			return err
+		}
+		for _, row := range doc.Notes {
+			id, ok := itemIDs[jsonInt(row["item_id"])]
+			if !ok {
+				continue
+			}
+			delete(row, "id")
+			row["chat_id"], row["item_id"] = chatID, id
+			if _, err := insertRow(tx.DB, "item_notes", noteCols, row); err != nil {
+				return err
+			}
+			st.Notes++
+		}
+
+		goalCols, err := tableColumns(tx.DB, "goals")
+		if err != nil {
+			return err
+		}
+		goalIDs := map[int64]int64{}
+		for _, row := range doc.Goals {
+			old := rowID(row)
+			delete(row, "id")
+			row["chat_id"] = chatID
+			id, err := insertRow(tx.DB, "goals", goalCols, row)
+			if err != nil {
+				return err
+			}
+			goalIDs[old] = id
+			st.Goals++
+		}
+		for _, row := range doc.GoalLinks {
+			item, ok1 := itemIDs[jsonInt(row["item_id"])]
+			goal, ok2 := goalIDs[jsonInt(row["goal_id"])]
+			if !ok1 || !ok2 {
+				continue
+			}
+			n, _ := row["amount"].(json.Number)
+			amount, _ := n.Float64()
+			if _, err := tx.DB.Exec(`INSERT INTO goal_links(chat_id, item_id, goal_id, amount) VALUES(?,?,?,?) ON CONFLICT(chat_id, item_id) DO NOTHING`, chatID, item, goal, amount); err != nil {
+				return err
+			}
+		}
+
+		for _, t := range doc.Topics {
+			if _, err := tx.DB.Exec(
+				`INSERT INTO topics(chat_id, topic, name, created_at) VALUES(?,?,?,?) ON CONFLICT(chat_id, topic) DO NOTHING`,
+				chatID, t.Topic, t.Name, time.Now().UTC().Format(time.RFC3339Nano),
+			); err != nil {
+				return err
+			}
+		}
+
+		seal := func(text string) (string, error) {
+			stored, _, err := tx.sealText(chatID, text)
+			return stored, err
+		}
+		for _, h := range doc.History {
+			blob, err := rewriteArchive(h.Items, seal)
+			if errors.Is(err, errLocked) {
+				return err
+			}
+			if err != nil {
+				return fmt.Errorf("item_history %s %s: %w", h.Month, h.Topic, err)
+			}
+			if _, err := tx.DB.Exec(
+				`INSERT INTO item_history(chat_id, month, topic, count, items) VALUES(?,?,?,?,?)
+				 ON CONFLICT(chat_id, month, topic) DO NOTHING`,
+				chatID, h.Month, h.Topic, h.Count, blob,
+			); err != nil {
+				return err
+			}
+			st.Months++
+		}
+
+		// Explicit IDs don't move the Postgres sequences
+		if s.driver == driverPostgres {
+			for _, t := range []string{"items", "item_notes", "goals"} {
+				if _, err := tx.DB.Exec(`SELECT setval(pg_get_serial_sequence('` + t + `', 'id'), COALESCE((SELECT MAX(id) FROM ` + t + `), 0) + 1, false)`); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return st, err
+}
+
+func jsonInt(v any) int64 {
+	if n, ok := v.(json.Number); ok {
+		i, _ := n.Int64()
+		return i
+	}
+	return 0
+}
+
+func decodeMigration(lang string, raw []byte) (*MigrationDoc, error) {
+	if zr, err := gzip.NewReader(bytes.NewReader(raw)); err == nil {
+		if raw, err = io.ReadAll(io.LimitReader(zr, 10*maxMigrateBytes)); err != nil {
+			return nil, err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc MigrationDoc
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Format != migrationFormat {
+		return nil, errors.New(tr(lang, "migrate.notours"))
+	}
+	if doc.Version < 1 || doc.Version > migrationVersion {
+		return nil, errors.New(tr(lang, "import.version", doc.Version))
+	}
+	return &doc, nil
+}
+
+// handleMigrate handles "/migrate export" and "/migrate import [force]",
+// the latter as a reply to the exported file.
+func (a *App) handleMigrate(ctx context.Context, m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	args := strings.Fields(m.CommandArguments())
+	switch {
+	case len(args) == 1 && args[0] == "export":
+		a.migrateExport(chatID)
+	case len(args) >= 1 && args[0] == "import":
+		a.migrateImport(ctx, m, slices.Contains(args[1:], "force"))
+	default:
+		a.send(chatID, a.tr(chatID, "migrate.usage"))
+	}
+}
+
+func (a *App) migrateExport(chatID int64) {
+	doc, err := a.Store.ExportChat(chatID)
+	if errors.Is(err, errLocked) {
+		a.sendLocked(chatID)
+		return
+	}
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if doc.Settings, err = exportSettings(a.Store, chatID); err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(doc); err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if err := zw.Close(); err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if buf.Len() > maxMigrateBytes {
+		a.send(chatID, a.tr(chatID, "migrate.toobig", formatBytes(a.Store.Lang(chatID), maxMigrateBytes)))
+		return
+	}
+	name := fmt.Sprintf("gtd-migrate-%d-%s.json.gz", chatID, time.Now().In(a.tz(chatID)).Format("20060102"))
+	msg := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: buf.Bytes()})
+	msg.Caption = a.tr(chatID, "migrate.caption", len(doc.Items), len(doc.History))
+	if _, err := a.Bot.Send(msg); err != nil {
+		a.send(chatID, a.tr(chatID, "file.send.failed"))
+	}
+}
+
+func (a *App) migrateImport(ctx context.Context, m *tgbotapi.Message, force bool) {
+	chatID := m.Chat.ID
+	lang := a.Store.Lang(chatID)
+	if m.ReplyToMessage == nil || m.ReplyToMessage.Document == nil {
+		a.send(chatID, tr(lang, "migrate.reply"))
+		return
+	}
+	if !force {
+		if items, err := a.Store.ListActive(chatID, ""); err == nil && len(items) > 0 {
+			a.send(chatID, tr(lang, "migrate.notempty"))
+			return
+		}
+	}
+	raw, err := a.readDocument(ctx, m.ReplyToMessage.Document, maxMigrateBytes)
+	if err != nil {
+		a.send(chatID, tr(lang, "file.download.failed"))
+		return
+	}
+	doc, err := decodeMigration(lang, raw)
+	if err != nil {
+		a.send(chatID, tr(lang, "import.failed", err))
+		return
+	}
+	st, err := a.Store.ImportChat(chatID, doc)
+	if errors.Is(err, errLocked) {
+		a.sendLocked(chatID)
+		return
+	}
+	if err != nil {
+		a.send(chatID, tr(lang, "import.failed", err))
+		return
+	}
+	reply := tr(lang, "migrate.done", st.Items, st.KeptIDs, st.Notes, st.Goals, st.Months)
+	if len(doc.Settings.Settings) > 0 {
+		if n, err := importSettings(a.Store, chatID, doc.Settings); err != nil {
+			reply += "\n" + tr(lang, "migrate.settings.failed", err)
+		} else {
+			reply += "\n" + tr(lang, "migrate.settings", n)
+		}
+	}
+	a.send(chatID, reply)
+}
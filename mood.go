@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Mood check-ins. "/mood on 20:00" asks once a day (MOOD_TIME without a
+// time) for mood and energy, one tap on a five-point scale; "/mood" asks
+// right away. "/mood month [ММ.ГГГГ]" charts the month day by day against
+// completed tasks, with the average per mood and how closely the two go
+// together; the previous month's chart also comes on the 1st.
+
+var moodFaces = [6]string{"", "😞", "🙁", "😐", "🙂", "😄"}
+
+func (s *sqlStore) SetMood(chatID int64, day string, mood int) error {
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO moods(chat_id, day, mood, created_at) VALUES(?,?,?,?)
+		 ON CONFLICT(chat_id, day) DO UPDATE SET mood=excluded.mood`,
+		chatID, day, mood, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// Moods maps days in [from, to] (2006-01-02) to their check-in.
+func (s *sqlStore) Moods(chatID int64, from, to string) (map[string]int, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT day, mood FROM moods WHERE chat_id=? AND day>=? AND day<=?`, chatID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var day string
+		var mood int
+		if err := rows.Scan(&day, &mood); err != nil {
+			return nil, err
+		}
+		out[day] = mood
+	}
+	return out, rows.Err()
+}
+
+func moodKeyboard(day string) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	for n := 1; n <= 5; n++ {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(moodFaces[n], fmt.Sprintf("mood:%s:%d", day, n)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+func moodQuestion(lang string, chatID int64, day time.Time) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatID, tr(lang, "mood.question"))
+	msg.ReplyMarkup = moodKeyboard(day.Format("2006-01-02"))
+	return msg
+}
+
+func (s *Scheduler) sendMoodCheckin(chatID int64, now time.Time, hhmm string) {
+	at, ok, err := s.store.GetKV(chatKey(chatID, "mood"))
+	if err != nil || !ok || at != hhmm {
+		return
+	}
+	today := now.Format("2006-01-02")
+	if done, err := s.store.Moods(chatID, today, today); err != nil || len(done) > 0 {
+		return
+	}
+	_ = s.deliver("mood", moodQuestion(s.store.Lang(chatID), chatID, now))
+}
+
+// moodChart is the month's mood and completed tasks per day, with the
+// average completed per mood and Pearson's r over days with a check-in.
+func moodChart(lang string, month time.Time, moods map[string]int, done []Item) string {
+	perDay := map[string]int{}
+	for _, it := range done {
+		if it.Topic == TopicTasks {
+			perDay[it.CompletedAt.In(month.Location()).Format("2006-01-02")]++
+		}
+	}
+	var b strings.Builder
+	b.WriteString(tr(lang, "mood.header", month.Format("01.2006")))
+	var sum, count [6]int
+	var xs, ys []float64
+	for d := month; d.Month() == month.Month(); d = d.AddDate(0, 0, 1) {
+		day := d.Format("2006-01-02")
+		mood, n := moods[day], perDay[day]
+		face := "▫️"
+		if mood > 0 {
+			face = moodFaces[mood]
+			sum[mood] += n
+			count[mood]++
+			xs = append(xs, float64(mood))
+			ys = append(ys, float64(n))
+		}
+		fmt.Fprintf(&b, "\n%s %s %s%d", d.Format("02"), face, strings.Repeat("█", min(n, 15)), n)
+	}
+
+	var avg []string
+	for m := 5; m >= 1; m-- {
+		if count[m] > 0 {
+			avg = append(avg, fmt.Sprintf("%s %.1f", moodFaces[m], float64(sum[m])/float64(count[m])))
+		}
+	}
+	if len(avg) == 0 {
+		b.WriteString("\n\n" + tr(lang, "mood.none"))
+		return b.String()
+	}
+	b.WriteString("\n\n" + tr(lang, "mood.average", strings.Join(avg, " · ")))
+	if r, ok := pearson(xs, ys); ok {
+		note := tr(lang, "mood.r.none")
+		switch {
+		case r >= 0.3:
+			note = tr(lang, "mood.r.good")
+		case r <= -0.3:
+			note = tr(lang, "mood.r.bad")
+		}
+		b.WriteString("\n" + tr(lang, "mood.r", r, note))
+	}
+	return b.String()
+}
+
+// pearson is the correlation of xs and ys; false with fewer than three
+// points or no spread.
+func pearson(xs, ys []float64) (float64, bool) {
+	n := float64(len(xs))
+	if len(xs) < 3 {
+		return 0, false
+	}
+	var sx, sy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+	}
+	mx, my := sx/n, sy/n
+	var cov, vx, vy float64
+	for i := range xs {
+		cov += (xs[i] - mx) * (ys[i] - my)
+		vx += (xs[i] - mx) * (xs[i] - mx)
+		vy += (ys[i] - my) * (ys[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(vx*vy), true
+}
+
+func buildMoodChart(store Store, chatID int64, month time.Time) (string, bool, error) {
+	end := month.AddDate(0, 1, 0)
+	moods, err := store.Moods(chatID, month.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+	if err != nil {
+		return "", false, err
+	}
+	done, err := store.ListCompleted(chatID, month, end)
+	if err != nil {
+		return "", false, err
+	}
+	return moodChart(store.Lang(chatID), month, moods, done), len(moods) > 0, nil
+}
+
+// sendMoodReport sends last month's chart on the 1st to chats that checked in.
+func (s *Scheduler) sendMoodReport(chatID int64, now time.Time) {
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -1, 0)
+	text, checked, err := buildMoodChart(s.store, chatID, month)
+	if err != nil {
+		log.Printf("scheduler: mood report error: %v", err)
+		return
+	}
+	if checked {
+		s.send("mood", tgbotapi.NewMessage(chatID, text))
+	}
+}
+
+// handleMood handles "/mood [on [ЧЧ:ММ] | off | month [ММ.ГГГГ]]".
+func (a *App) handleMood(chatID int64, arg string) {
+	lang := a.Store.Lang(chatID)
+	now := time.Now().In(a.tz(chatID))
+	first, rest, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	rest = strings.TrimSpace(rest)
+	key := chatKey(chatID, "mood")
+
+	switch first {
+	case "":
+		_, _ = a.Bot.Send(moodQuestion(lang, chatID, now))
+	case "on":
+		at := rest
+		if at == "" {
+			at = envOr("MOOD_TIME", "20:00")
+		}
+		t, err := time.Parse("15:04", at)
+		if err != nil {
+			a.send(chatID, tr(lang, "mood.on.usage"))
+			return
+		}
+		at = t.Format("15:04")
+		if err := a.Store.SetKV(key, at); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "mood.on", at))
+	case "off":
+		if err := a.Store.DeleteKV(key); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "mood.off"))
+	case "month":
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		if rest != "" {
+			t, err := time.ParseInLocation("01.2006", rest, now.Location())
+			if err != nil {
+				a.send(chatID, tr(lang, "mood.month.usage"))
+				return
+			}
+			month = t
+		}
+		text, _, err := buildMoodChart(a.Store, chatID, month)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		a.send(chatID, text)
+	default:
+		a.send(chatID, tr(lang, "mood.usage"))
+	}
+}
+
+// handleMoodCallback handles "mood:<день>:<1-5>".
+func (a *App) handleMoodCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	day, nStr, _ := strings.Cut(data, ":")
+	n, err := strconv.Atoi(nStr)
+	d, derr := time.Parse("2006-01-02", day)
+	if err != nil || derr != nil || n < 1 || n > 5 {
+		return
+	}
+	if err := a.Store.SetMood(chatID, day, n); err != nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.write")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, moodFaces[n]))
+	text := a.tr(chatID, "mood.set", d.Format("02.01"), moodFaces[n])
+	_, _ = a.Bot.Send(tgbotapi.NewEditMessageTextAndMarkup(chatID, cq.Message.MessageID, text, moodKeyboard(day)))
+}
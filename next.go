@@ -0,0 +1,145 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var effortRe = regexp.MustCompile(`(?i)~\s*(\d+(?:[.,]\d+)?)\s*(мин|м|min|m|ч|h)`)
+
+// parseEffort extracts an estimate tag like "~30м" or "~1.5ч" in minutes.
+func parseEffort(text string) (int, bool) {
+	m := effortRe.FindStringSubmatch(text)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, false
+	}
+	switch strings.ToLower(m[2]) {
+	case "ч", "h":
+		n *= 60
+	}
+	return int(n + 0.5), true
+}
+
+// parsePriority counts a standalone "!", "!!" or "!!!" marker in the text.
+func parsePriority(text string) int {
+	p := 0
+	for _, f := range strings.Fields(text) {
+		if strings.Trim(f, "!") == "" {
+			p = max(p, min(len(f), 3))
+		}
+	}
+	return p
+}
+
+type nextCandidate struct {
+	Item    Item
+	Score   float64
+	reasons []nextReason
+}
+
+// nextReason is a catalog key with its arguments, rendered per chat.
+type nextReason struct {
+	key  string
+	args []any
+}
+
+// Reason is why the candidate ranks where it does, in lang.
+func (c nextCandidate) Reason(lang string) string {
+	parts := make([]string, 0, len(c.reasons))
+	for _, r := range c.reasons {
+		parts = append(parts, tr(lang, r.key, r.args...))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// scoreNext ranks an item for /next: priority first, then a due date
+// within a week (overdue most), then age, with a bonus for quick wins and a
+// small penalty for big chunks.
+func scoreNext(it Item, now time.Time) nextCandidate {
+	var reasons []nextReason
+	score := 0.0
+
+	if p := parsePriority(it.Text); p > 0 {
+		score += float64(p) * 10
+		reasons = append(reasons, nextReason{"next.priority", []any{strings.Repeat("!", p)}})
+	}
+
+	if !it.Due.IsZero() {
+		switch left := it.Due.Sub(now); {
+		case left < 0:
+			score += 20
+			reasons = append(reasons, nextReason{key: "next.overdue"})
+		case left < 7*24*time.Hour:
+			days := int(left.Hours() / 24)
+			score += 15 - 2*float64(days)
+			if days == 0 {
+				reasons = append(reasons, nextReason{key: "next.due.day"})
+			} else {
+				reasons = append(reasons, nextReason{"next.due.days", []any{days}})
+			}
+		}
+	}
+
+	age := int(now.Sub(it.CreatedAt).Hours() / 24)
+	if age > 0 {
+		score += min(float64(age), 30) * 0.5
+		reasons = append(reasons, nextReason{"next.age", []any{age}})
+	}
+
+	if eff, ok := parseEffort(it.Text); ok {
+		switch {
+		case eff <= 15:
+			score += 5
+		case eff > 120:
+			score -= 3
+		}
+		reasons = append(reasons, nextReason{"next.effort", []any{eff}})
+	}
+
+	return nextCandidate{Item: it, Score: score, reasons: reasons}
+}
+
+func suggestNext(items []Item, now time.Time, n int) []nextCandidate {
+	out := make([]nextCandidate, 0, len(items))
+	for _, it := range items {
+		out = append(out, scoreNext(it, now))
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+func (a *App) handleNext(chatID int64) {
+	items, err := a.Store.ListActive(chatID, TopicTasks)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+	if len(items) == 0 {
+		a.send(chatID, tr(lang, "next.none"))
+		return
+	}
+
+	a.send(chatID, tr(lang, "next.header"))
+	for _, c := range suggestNext(items, time.Now(), 3) {
+		text := formatSingleItem(lang, TopicTasks, c.Item)
+		if reason := c.Reason(lang); reason != "" {
+			text += "\n(" + reason + ")"
+		}
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ReplyMarkup = singleKeyboard(c.Item.ID)
+		_, _ = a.Bot.Send(msg)
+	}
+}
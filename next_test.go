@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEffort(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+		ok   bool
+	}{
+		{"позвонить ~30м", 30, true},
+		{"отчёт ~1.5ч", 90, true},
+		{"отчёт ~2,5 h", 150, true},
+		{"разобрать почту ~45 min", 45, true},
+		{"~10m полить цветы", 10, true},
+		{"созвон ~1Ч", 60, true},
+		{"купить хлеб", 0, false},
+		{"30м без тильды", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseEffort(tt.text)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseEffort(%q) = %d, %v; want %d, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestScoreNextDue(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	item := func(due time.Time) Item {
+		return Item{Text: "задача", CreatedAt: now, Due: due}
+	}
+	overdue := scoreNext(item(now.Add(-time.Hour)), now)
+	soon := scoreNext(item(now.Add(6*time.Hour)), now)
+	later := scoreNext(item(now.Add(5*24*time.Hour)), now)
+	none := scoreNext(item(time.Time{}), now)
+	far := scoreNext(item(now.AddDate(0, 1, 0)), now)
+
+	if !(overdue.Score > soon.Score && soon.Score > later.Score && later.Score > none.Score) {
+		t.Errorf("scores overdue %v, soon %v, later %v, none %v: want decreasing", overdue.Score, soon.Score, later.Score, none.Score)
+	}
+	if far.Score != none.Score {
+		t.Errorf("due in a month scored %v, want %v as without a due date", far.Score, none.Score)
+	}
+	if r := overdue.Reason(LangRU); r != "просрочено" {
+		t.Errorf("overdue reason = %q", r)
+	}
+}
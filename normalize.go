@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// latinToCyrillic maps latin letters that look like cyrillic ones.
+// It is applied only to words that already contain cyrillic letters,
+// so "мoлоко" typed with a latin "o" matches "молоко", while "tomato" stays as is.
+var latinToCyrillic = map[rune]rune{
+	'a': 'а', 'c': 'с', 'e': 'е', 'k': 'к', 'm': 'м', 'o': 'о',
+	'p': 'р', 't': 'т', 'x': 'х', 'y': 'у', 'h': 'н', 'b': 'в',
+}
+
+// normalizeText produces the matching form of user text: lower case,
+// ё→е, emoji and symbols removed, lookalike letters unified and
+// whitespace collapsed. It is used for button matching, duplicate
+// detection and search.
+func normalizeText(s string) string {
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, "ё", "е")
+
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || isDecoration(r)
+	})
+	for i, w := range words {
+		words[i] = unifyScript(w)
+	}
+	return strings.Join(words, " ")
+}
+
+// isDecoration reports runes that carry no meaning for matching:
+// emoji, pictographs, variation selectors and joiners.
+func isDecoration(r rune) bool {
+	switch {
+	case unicode.Is(unicode.So, r), unicode.Is(unicode.Sk, r):
+		return true
+	case r == '\u200d', r >= '\ufe00' && r <= '\ufe0f':
+		return true
+	case r >= 0x1f000 && r <= 0x1faff:
+		return true
+	}
+	return false
+}
+
+func unifyScript(w string) string {
+	hasCyr := false
+	for _, r := range w {
+		if unicode.Is(unicode.Cyrillic, r) {
+			hasCyr = true
+			break
+		}
+	}
+	if !hasCyr {
+		return w
+	}
+	return strings.Map(func(r rune) rune {
+		if c, ok := latinToCyrillic[r]; ok {
+			return c
+		}
+		return r
+	}, w)
+}
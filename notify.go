@@ -0,0 +1,268 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Reminders can be delivered to another chat: per item or per topic. A
+// target chat is linked first with a one-time code so the bot only posts
+// where the same user has confirmed it.
+
+const linkCodeTTL = 10 * time.Minute
+
+type ChatLink struct {
+	TargetID int64
+	Title    string
+}
+
+func (s *sqlStore) AddChatLink(chatID, targetID int64, title string) error {
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO chat_links(chat_id, target_id, title, created_at) VALUES(?,?,?,?)
+		 ON CONFLICT(chat_id, target_id) DO UPDATE SET title=excluded.title`,
+		chatID, targetID, title, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (s *sqlStore) ChatLinks(chatID int64) ([]ChatLink, error) {
+	rows, err := s.db(chatID).Query(`SELECT target_id, title FROM chat_links WHERE chat_id=? ORDER BY created_at, target_id`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ChatLink
+	for rows.Next() {
+		var l ChatLink
+		if err := rows.Scan(&l.TargetID, &l.Title); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// DeleteChatLink removes a link and every route pointing at it.
+func (s *sqlStore) DeleteChatLink(chatID, targetID int64) error {
+	return s.For(chatID).inTx(func(tx *sqlStore) error {
+		if _, err := tx.DB.Exec(`DELETE FROM chat_links WHERE chat_id=? AND target_id=?`, chatID, targetID); err != nil {
+			return err
+		}
+		if _, err := tx.DB.Exec(`UPDATE items SET notify_chat=0 WHERE chat_id=? AND notify_chat=?`, chatID, targetID); err != nil {
+			return err
+		}
+		for _, topic := range keyboardTopics {
+			key := chatKey(chatID, "route:"+topic)
+			if v, ok, _ := tx.GetKV(key); ok && v == strconv.FormatInt(targetID, 10) {
+				if err := tx.DeleteKV(key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) SetItemRoute(chatID, id, targetID int64) error {
+	_, err := s.db(chatID).Exec(`UPDATE items SET notify_chat=? WHERE chat_id=? AND id=?`, targetID, chatID, id)
+	return err
+}
+
+func (s *sqlStore) SetTopicRoute(chatID int64, topic string, targetID int64) error {
+	if targetID == 0 {
+		return s.DeleteKV(chatKey(chatID, "route:"+topic))
+	}
+	return s.SetKV(chatKey(chatID, "route:"+topic), strconv.FormatInt(targetID, 10))
+}
+
+// NotifyChat picks where reminders about an item go: the item's own route,
+// then its topic's route, then the chat it was captured in.
+func (s *sqlStore) NotifyChat(chatID int64, it Item) int64 {
+	var target int64
+	_ = s.db(chatID).QueryRow(`SELECT notify_chat FROM items WHERE chat_id=? AND id=?`, chatID, it.ID).Scan(&target)
+	if target != 0 {
+		return target
+	}
+	if v, ok, _ := s.GetKV(chatKey(chatID, "route:"+it.Topic)); ok {
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return id
+		}
+	}
+	return chatID
+}
+
+func newLinkCode() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// handleLinkChat handles "/linkchat" in the source chat (issues a code) and
+// "/linkchat <код>" in the target chat (confirms the link).
+func (a *App) handleLinkChat(m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	if m.From == nil {
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(m.CommandArguments()))
+	if code == "" {
+		code, err := newLinkCode()
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		v := fmt.Sprintf("%d:%d:%s", chatID, m.From.ID, time.Now().Add(linkCodeTTL).UTC().Format(time.RFC3339))
+		if err := a.Store.SetKV("linkcode:"+code, v); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "link.code", code, int(linkCodeTTL.Minutes())))
+		return
+	}
+
+	v, ok, _ := a.Store.GetKV("linkcode:" + code)
+	parts := strings.SplitN(v, ":", 3)
+	if !ok || len(parts) != 3 {
+		a.send(chatID, a.tr(chatID, "link.notfound"))
+		return
+	}
+	_ = a.Store.DeleteKV("linkcode:" + code)
+	source, _ := strconv.ParseInt(parts[0], 10, 64)
+	userID, _ := strconv.ParseInt(parts[1], 10, 64)
+	expires, _ := time.Parse(time.RFC3339, parts[2])
+	if time.Now().After(expires) || userID != m.From.ID {
+		a.send(chatID, a.tr(chatID, "link.invalid"))
+		return
+	}
+	if source == chatID {
+		a.send(chatID, a.tr(chatID, "link.same"))
+		return
+	}
+
+	title := m.Chat.Title
+	if title == "" {
+		title = userDisplayName(m.From)
+	}
+	if err := a.Store.AddChatLink(source, chatID, title); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "link.done"))
+	a.send(source, a.tr(source, "link.linked", title))
+}
+
+func (a *App) routesText(chatID int64, links []ChatLink) string {
+	lang := a.Store.Lang(chatID)
+	var b strings.Builder
+	b.WriteString(tr(lang, "route.header") + "\n")
+	if len(links) == 0 {
+		b.WriteString(tr(lang, "route.none") + "\n")
+	}
+	names := map[int64]string{}
+	for i, l := range links {
+		names[l.TargetID] = l.Title
+		fmt.Fprintf(&b, "%d. %s\n", i+1, l.Title)
+	}
+	for _, topic := range keyboardTopics {
+		if v, ok, _ := a.Store.GetKV(chatKey(chatID, "route:"+topic)); ok {
+			id, _ := strconv.ParseInt(v, 10, 64)
+			fmt.Fprintf(&b, "%s → %s\n", topicLabel(lang, topic), names[id])
+		}
+	}
+	b.WriteString("\n" + tr(lang, "route.help"))
+	return b.String()
+}
+
+// handleRoute handles "/route", "/route <список|#id> <номер|->" and
+// "/route unlink <номер>".
+func (a *App) handleRoute(chatID int64, arg string) {
+	links, err := a.Store.ChatLinks(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	fields := strings.Fields(arg)
+	if len(fields) != 2 {
+		a.send(chatID, a.routesText(chatID, links))
+		return
+	}
+
+	var target int64
+	if fields[1] != "-" {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 1 || n > len(links) {
+			a.send(chatID, a.tr(chatID, "route.notfound"))
+			return
+		}
+		target = links[n-1].TargetID
+	}
+
+	switch {
+	case fields[0] == "unlink":
+		if target == 0 {
+			a.send(chatID, a.tr(chatID, "route.unlink.usage"))
+			return
+		}
+		err = a.Store.DeleteChatLink(chatID, target)
+	case strings.HasPrefix(fields[0], "#"):
+		id, perr := strconv.ParseInt(strings.TrimPrefix(fields[0], "#"), 10, 64)
+		if perr != nil {
+			a.send(chatID, a.tr(chatID, "route.item.usage"))
+			return
+		}
+		err = a.Store.SetItemRoute(chatID, id, target)
+	default:
+		topic, ok := a.topicFromButton(chatID, fields[0])
+		if !ok {
+			a.send(chatID, a.tr(chatID, "topic.unknown"))
+			return
+		}
+		err = a.Store.SetTopicRoute(chatID, topic, target)
+	}
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "route.done"))
+}
+
+func (s *sqlStore) IsChatLinked(chatID, targetID int64) bool {
+	var n int
+	_ = s.db(chatID).QueryRow(`SELECT COUNT(*) FROM chat_links WHERE chat_id=? AND target_id=?`, chatID, targetID).Scan(&n)
+	return n > 0
+}
+
+// routedKeyboard is singleKeyboard for a reminder delivered to a linked
+// chat; the callback carries the source chat so the item can be found.
+func routedKeyboard(source, id int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅", fmt.Sprintf("rdone:%d:%d", source, id)),
+	))
+}
+
+// handleRoutedDone handles "rdone:<source>:<id>" pressed in a linked chat.
+func (a *App) handleRoutedDone(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	srcStr, idStr, _ := strings.Cut(data, ":")
+	source, _ := strconv.ParseInt(srcStr, 10, 64)
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	if !a.Store.IsChatLinked(source, chatID) {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "link.gone")))
+		return
+	}
+	if err := a.Store.FinishItem(source, id, cq.From, time.Now()); err != nil {
+		log.Printf("finish item error: %v", err)
+	}
+
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "deleted")))
+	_, _ = a.Bot.Send(tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, a.tr(chatID, "deleted.mark")))
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Premium features are paid with Telegram Stars (currency XTR).
+// If PREMIUM_PRICE_STARS is empty or zero, payments are disabled and
+// every feature is available for free.
+const (
+	PlanPremium = "premium"
+
+	FeatureLLMTriage    = "llm_triage"
+	FeatureCalendarSync = "calendar_sync"
+
+	starsCurrency = "XTR"
+)
+
+type Entitlement struct {
+	ChatID    int64
+	Plan      string
+	ExpiresAt time.Time
+	ChargeID  string
+}
+
+func premiumPrice() int {
+	n, err := strconv.Atoi(envOr("PREMIUM_PRICE_STARS", "0"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func premiumDays() int {
+	n, err := strconv.Atoi(envOr("PREMIUM_DAYS", "30"))
+	if err != nil || n <= 0 {
+		return 30
+	}
+	return n
+}
+
+func paymentsEnabled() bool {
+	return premiumPrice() > 0
+}
+
+func (s *sqlStore) GetEntitlement(chatID int64) (*Entitlement, error) {
+	var e Entitlement
+	var expires string
+	err := s.DB.QueryRow(
+		`SELECT chat_id, plan, expires_at, charge_id FROM entitlements WHERE chat_id=?`, chatID,
+	).Scan(&e.ChatID, &e.Plan, &expires, &e.ChargeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.ExpiresAt, _ = time.Parse(time.RFC3339, expires)
+	return &e, nil
+}
+
+// ExtendEntitlement prolongs the plan by d, counting from the current expiry
+// if it is still in the future, otherwise from now.
+func (s *sqlStore) ExtendEntitlement(chatID int64, plan string, d time.Duration, chargeID string, now time.Time) (time.Time, error) {
+	cur, err := s.GetEntitlement(chatID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	from := now
+	if cur != nil && cur.ExpiresAt.After(now) {
+		from = cur.ExpiresAt
+	}
+	until := from.Add(d).UTC()
+	_, err = s.DB.Exec(
+		`INSERT INTO entitlements(chat_id, plan, expires_at, charge_id, notified_at) VALUES(?,?,?,?,'')
+		 ON CONFLICT(chat_id) DO UPDATE SET plan=excluded.plan, expires_at=excluded.expires_at, charge_id=excluded.charge_id, notified_at=''`,
+		chatID, plan, until.Format(time.RFC3339), chargeID,
+	)
+	return until, err
+}
+
+// ExpiringEntitlements returns entitlements that expire before the given
+// moment and have not been warned about yet.
+func (s *sqlStore) ExpiringEntitlements(before time.Time) ([]Entitlement, error) {
+	rows, err := s.DB.Query(
+		`SELECT chat_id, plan, expires_at, charge_id FROM entitlements WHERE expires_at<? AND notified_at=''`,
+		before.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Entitlement
+	for rows.Next() {
+		var e Entitlement
+		var expires string
+		if err := rows.Scan(&e.ChatID, &e.Plan, &expires, &e.ChargeID); err != nil {
+			return nil, err
+		}
+		e.ExpiresAt, _ = time.Parse(time.RFC3339, expires)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) MarkEntitlementNotified(chatID int64, now time.Time) error {
+	_, err := s.DB.Exec(`UPDATE entitlements SET notified_at=? WHERE chat_id=?`, now.UTC().Format(time.RFC3339), chatID)
+	return err
+}
+
+// HasFeature reports whether the chat may use a premium feature right now.
+func (s *sqlStore) HasFeature(chatID int64, feature string, now time.Time) bool {
+	if !paymentsEnabled() {
+		return true
+	}
+	e, err := s.GetEntitlement(chatID)
+	if err != nil {
+		log.Printf("entitlement lookup error: %v", err)
+		return false
+	}
+	return e != nil && e.ExpiresAt.After(now)
+}
+
+func premiumPayload(chatID int64) string {
+	return fmt.Sprintf("%s:%d", PlanPremium, chatID)
+}
+
+func parsePremiumPayload(p string) (int64, bool) {
+	raw, ok := strings.CutPrefix(p, PlanPremium+":")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	return id, err == nil
+}
+
+func (a *App) handlePremium(chatID int64) {
+	if !paymentsEnabled() {
+		a.send(chatID, a.tr(chatID, "premium.free"))
+		return
+	}
+
+	now := time.Now()
+	e, err := a.Store.GetEntitlement(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if e != nil && e.ExpiresAt.After(now) {
+		a.send(chatID, a.tr(chatID, "premium.active", e.ExpiresAt.In(a.tz(chatID)).Format("2006-01-02 15:04")))
+	}
+
+	inv := tgbotapi.NewInvoice(
+		chatID,
+		a.tr(chatID, "premium.title"),
+		a.tr(chatID, "premium.description", premiumDays()),
+		premiumPayload(chatID),
+		"",
+		"",
+		starsCurrency,
+		[]tgbotapi.LabeledPrice{{Label: a.tr(chatID, "premium.label"), Amount: premiumPrice()}},
+	)
+	if _, err := a.Bot.Send(inv); err != nil {
+		log.Printf("send invoice error: %v", err)
+		a.send(chatID, a.tr(chatID, "premium.invoice.failed"))
+	}
+}
+
+func (a *App) handlePreCheckout(q *tgbotapi.PreCheckoutQuery) {
+	answer := tgbotapi.PreCheckoutConfig{PreCheckoutQueryID: q.ID, OK: true}
+	if _, ok := parsePremiumPayload(q.InvoicePayload); !ok || q.Currency != starsCurrency || q.TotalAmount != premiumPrice() {
+		answer.OK = false
+		answer.ErrorMessage = a.tr(q.From.ID, "premium.invoice.stale")
+	}
+	if _, err := a.Bot.Request(answer); err != nil {
+		log.Printf("answer pre-checkout error: %v", err)
+	}
+}
+
+func (a *App) handleSuccessfulPayment(m *tgbotapi.Message) {
+	p := m.SuccessfulPayment
+	chatID, ok := parsePremiumPayload(p.InvoicePayload)
+	if !ok {
+		chatID = m.Chat.ID
+	}
+
+	d := time.Duration(premiumDays()) * 24 * time.Hour
+	until, err := a.Store.ExtendEntitlement(chatID, PlanPremium, d, p.TelegramPaymentChargeID, time.Now())
+	if err != nil {
+		log.Printf("extend entitlement error: %v (charge %s)", err, p.TelegramPaymentChargeID)
+		a.send(m.Chat.ID, a.tr(m.Chat.ID, "premium.save.failed"))
+		return
+	}
+	a.send(m.Chat.ID, a.tr(m.Chat.ID, "premium.thanks", until.In(a.tz(chatID)).Format("2006-01-02 15:04")))
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Plugins let a fork add commands, message handling and periodic jobs in
+// its own files: implement Plugin (embedding BasePlugin for the hooks you
+// don't need) and call RegisterPlugin from an init func.
+//
+//	func init() { RegisterPlugin(&myPlugin{}) }
+//
+// Plugins see updates before the core handlers, in registration order.
+
+type Plugin interface {
+	Name() string
+	// Init is called once at startup with the running app.
+	Init(a *App) error
+	// Commands the plugin handles; core commands with the same name win.
+	Commands() []PluginCommand
+	// OnMessage sees every non-command message; return true to stop the
+	// core from handling it.
+	OnMessage(ctx context.Context, m *tgbotapi.Message) bool
+	// OnCallback sees every button press; return true if it was handled.
+	OnCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) bool
+	// OnSchedule runs once a minute with the current time in the bot's TZ.
+	OnSchedule(ctx context.Context, now time.Time)
+}
+
+type PluginCommand struct {
+	Name   string // without the slash
+	Handle func(ctx context.Context, m *tgbotapi.Message)
+}
+
+// BasePlugin provides no-op hooks to embed.
+type BasePlugin struct{}
+
+func (BasePlugin) Init(*App) error                                          { return nil }
+func (BasePlugin) Commands() []PluginCommand                                { return nil }
+func (BasePlugin) OnMessage(context.Context, *tgbotapi.Message) bool        { return false }
+func (BasePlugin) OnCallback(context.Context, *tgbotapi.CallbackQuery) bool { return false }
+func (BasePlugin) OnSchedule(context.Context, time.Time)                    {}
+
+var (
+	pluginsMu sync.Mutex
+	plugins   []Plugin
+)
+
+func RegisterPlugin(p Plugin) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	plugins = append(plugins, p)
+}
+
+// initPlugins runs Init for every registered plugin and indexes their
+// commands. A plugin whose Init fails is skipped.
+func (a *App) initPlugins() {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	a.pluginCommands = map[string]PluginCommand{}
+	for _, p := range plugins {
+		if err := p.Init(a); err != nil {
+			log.Printf("plugin %s: init: %v", p.Name(), err)
+			continue
+		}
+		a.plugins = append(a.plugins, p)
+		for _, c := range p.Commands() {
+			if _, dup := a.pluginCommands[c.Name]; dup {
+				log.Printf("plugin %s: command /%s already registered", p.Name(), c.Name)
+				continue
+			}
+			a.pluginCommands[c.Name] = c
+		}
+		log.Printf("plugin %s loaded", p.Name())
+	}
+}
+
+func (a *App) pluginCommand(ctx context.Context, m *tgbotapi.Message) bool {
+	c, ok := a.pluginCommands[m.Command()]
+	if !ok {
+		return false
+	}
+	c.Handle(ctx, m)
+	return true
+}
+
+func (a *App) pluginMessage(ctx context.Context, m *tgbotapi.Message) bool {
+	for _, p := range a.plugins {
+		if p.OnMessage(ctx, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *App) pluginCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) bool {
+	for _, p := range a.plugins {
+		if p.OnCallback(ctx, cq) {
+			return true
+		}
+	}
+	return false
+}
+
+// runPluginSchedule ticks OnSchedule at the top of every minute.
+func (a *App) runPluginSchedule(ctx context.Context) {
+	if len(a.plugins) == 0 {
+		return
+	}
+	go func() {
+		for {
+			now := time.Now()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(now.Truncate(time.Minute).Add(time.Minute).Sub(now)):
+			}
+			now = time.Now().In(a.TZ)
+			for _, p := range a.plugins {
+				p.OnSchedule(ctx, now)
+			}
+		}
+	}()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// DB_DRIVER=postgres runs the same store on PostgreSQL. Queries are
+// written once with SQLite's ? placeholders and rebound to $1, $2… on the
+// way out; the schema is translated type by type (see pgTypes).
+
+const (
+	driverSQLite   = "sqlite"
+	driverPostgres = "postgres"
+
+	pgMaxConns = 10
+)
+
+// dbFromEnv returns the driver and DSN: DB_PATH for SQLite, DATABASE_URL
+// for Postgres.
+func dbFromEnv() (driver, dsn string, err error) {
+	switch d := strings.ToLower(envOr("DB_DRIVER", driverSQLite)); d {
+	case driverSQLite, "sqlite3":
+		return driverSQLite, envOr("DB_PATH", "gtd.db"), nil
+	case driverPostgres, "postgresql", "pgx":
+		dsn := strings.TrimSpace(os.Getenv("DATABASE_URL"))
+		if dsn == "" {
+			return "", "", fmt.Errorf("DB_DRIVER=postgres requires DATABASE_URL")
+		}
+		return driverPostgres, dsn, nil
+	default:
+		return "", "", fmt.Errorf("unknown DB_DRIVER %q (sqlite, postgres)", d)
+	}
+}
+
+func openDB(driver, dsn string) (*sql.DB, error) {
+	name := driver
+	if driver == driverPostgres {
+		name = "pgx"
+	}
+	db, err := sql.Open(name, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == driverSQLite {
+		db.SetMaxOpenConns(1)
+	} else {
+		db.SetMaxOpenConns(pgMaxConns)
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// pgConn rebinds placeholders for a *sql.DB or *sql.Tx.
+type pgConn struct {
+	c dbConn
+}
+
+func (p pgConn) Exec(query string, args ...any) (sql.Result, error) {
+	return p.c.Exec(rebind(query), args...)
+}
+
+func (p pgConn) Query(query string, args ...any) (*sql.Rows, error) {
+	return p.c.Query(rebind(query), args...)
+}
+
+func (p pgConn) QueryRow(query string, args ...any) *sql.Row {
+	return p.c.QueryRow(rebind(query), args...)
+}
+
+// wrapConn adds placeholder rebinding for Postgres and query timing.
+func wrapConn(driver string, c dbConn) dbConn {
+	if driver == driverPostgres {
+		c = pgConn{c: c}
+	}
+	return timedConn{c: c}
+}
+
+// unwrapConn returns the *sql.DB or *sql.Tx behind c.
+func unwrapConn(c dbConn) dbConn {
+	for {
+		switch w := c.(type) {
+		case timedConn:
+			c = w.c
+		case pgConn:
+			c = w.c
+		default:
+			return c
+		}
+	}
+}
+
+// rebind turns ? placeholders into $n, leaving quoted literals alone.
+func rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var pgTypeWords = regexp.MustCompile(`\b(INTEGER PRIMARY KEY AUTOINCREMENT|INTEGER|REAL|BLOB)\b`)
+
+// pgTypes maps SQLite column types in DDL to Postgres ones. Chat and user
+// ids don't fit in 32 bits, so INTEGER becomes BIGINT.
+func pgTypes(ddl string) string {
+	return pgTypeWords.ReplaceAllStringFunc(ddl, func(t string) string {
+		switch t {
+		case "INTEGER PRIMARY KEY AUTOINCREMENT":
+			return "BIGSERIAL PRIMARY KEY"
+		case "INTEGER":
+			return "BIGINT"
+		case "REAL":
+			return "DOUBLE PRECISION"
+		default:
+			return "BYTEA"
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// PrepRule turns a matching calendar event into a preparation task
+// created DaysBefore days ahead of the event.
+type PrepRule struct {
+	Name       string
+	Pattern    *regexp.Regexp
+	DaysBefore int
+	Checklist  []string
+}
+
+// parsePrepRules reads PREP_RULES in the form
+//
+//	pattern=days:item,item;pattern=days:item
+//
+// e.g. "рейс|flight=2:паспорт,билеты,зарядка;стоматолог|dentist=1:полис".
+func parsePrepRules(raw string) ([]PrepRule, error) {
+	var out []PrepRule
+	for _, part := range strings.Split(raw, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		pattern, rest, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("prep rule %q: missing '='", part)
+		}
+		daysRaw, list, _ := strings.Cut(rest, ":")
+		days, err := strconv.Atoi(strings.TrimSpace(daysRaw))
+		if err != nil || days < 0 {
+			return nil, fmt.Errorf("prep rule %q: bad days", part)
+		}
+		re, err := regexp.Compile("(?i)" + strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("prep rule %q: %w", part, err)
+		}
+		r := PrepRule{Name: strings.TrimSpace(pattern), Pattern: re, DaysBefore: days}
+		for _, it := range strings.Split(list, ",") {
+			if it = strings.TrimSpace(it); it != "" {
+				r.Checklist = append(r.Checklist, it)
+			}
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func prepRulesFromEnv() []PrepRule {
+	rules, err := parsePrepRules(os.Getenv("PREP_RULES"))
+	if err != nil {
+		log.Printf("scheduler: %v", err)
+		return nil
+	}
+	return rules
+}
+
+func formatPrepTask(lang string, ev CalendarEvent, r PrepRule, tz *time.Location) string {
+	var b strings.Builder
+	b.WriteString(tr(lang, "prep.title", ev.Summary, ev.Start.In(tz).Format("02.01 15:04")))
+	for _, it := range r.Checklist {
+		b.WriteString("\n☐ ")
+		b.WriteString(it)
+	}
+	return b.String()
+}
+
+// createPrepTasks looks at upcoming events and adds a prep task for every
+// event whose prep day has come. Each event/rule pair is handled once.
+func (s *Scheduler) createPrepTasks(ctx context.Context, chatID int64, now time.Time) {
+	if len(s.prepRules) == 0 {
+		return
+	}
+	horizon := 0
+	for _, r := range s.prepRules {
+		horizon = max(horizon, r.DaysBefore)
+	}
+	events, err := s.calendar.ListEvents(ctx, now, now.AddDate(0, 0, horizon+1))
+	if errors.Is(err, ErrCalendarNotConfigured) {
+		return
+	}
+	if err != nil {
+		log.Printf("scheduler: list events error: %v", err)
+		return
+	}
+
+	today := now.Format("2006-01-02")
+	for _, ev := range events {
+		for _, r := range s.prepRules {
+			if !r.Pattern.MatchString(ev.Summary) {
+				continue
+			}
+			if ev.Start.In(now.Location()).AddDate(0, 0, -r.DaysBefore).Format("2006-01-02") > today {
+				continue
+			}
+			key := chatKey(chatID, "prep:"+ev.ID+":"+r.Name)
+			if _, done, _ := s.store.GetKV(key); done {
+				continue
+			}
+
+			text := formatPrepTask(s.store.Lang(chatID), ev, r, now.Location())
+			var id int64
+			err := s.store.InTx(chatID, func(tx Store) error {
+				var err error
+				if id, err = tx.AddItem(chatID, TopicTasks, text); err != nil {
+					return err
+				}
+				return tx.SetKV(key, today)
+			})
+			if err != nil {
+				log.Printf("scheduler: add prep task error: %v", err)
+				continue
+			}
+
+			it := Item{ID: id, ChatID: chatID, Topic: TopicTasks, Text: text, CreatedAt: now}
+			msg := tgbotapi.NewMessage(chatID, formatSingleItem(s.store.Lang(chatID), TopicTasks, it))
+			msg.ReplyMarkup = singleKeyboard(id)
+			s.send("prep", msg)
+		}
+	}
+}
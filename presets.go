@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimePreset is a named time of day ("утром" = 08:30) shared by the date
+// picker, the date parser and snooze buttons.
+type TimePreset struct {
+	Name  string
+	Clock string // HH:MM
+}
+
+const defaultTimePresets = "утром=08:30,днём=13:00,вечером=20:00"
+
+func parseTimePresets(raw string) []TimePreset {
+	var out []TimePreset
+	for _, part := range strings.Split(raw, ",") {
+		name, clock, ok := strings.Cut(part, "=")
+		name, clock = normalizeText(name), strings.TrimSpace(clock)
+		if !ok || name == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", clock); err != nil {
+			continue
+		}
+		out = append(out, TimePreset{Name: name, Clock: clock})
+	}
+	return out
+}
+
+func formatTimePresets(ps []TimePreset) string {
+	parts := make([]string, 0, len(ps))
+	for _, p := range ps {
+		parts = append(parts, p.Name+"="+p.Clock)
+	}
+	return strings.Join(parts, ",")
+}
+
+// TimePresets returns the chat's presets in display order.
+func (s *sqlStore) TimePresets(chatID int64) []TimePreset {
+	v, ok, _ := s.GetKV(chatKey(chatID, "time_presets"))
+	if !ok {
+		v = defaultTimePresets
+	}
+	return parseTimePresets(v)
+}
+
+func (s *sqlStore) SetTimePresets(chatID int64, ps []TimePreset) error {
+	return s.SetKV(chatKey(chatID, "time_presets"), formatTimePresets(ps))
+}
+
+// PresetClock resolves a preset name to HH:MM.
+func (s *sqlStore) PresetClock(chatID int64, name string) (string, bool) {
+	name = normalizeText(name)
+	for _, p := range s.TimePresets(chatID) {
+		if p.Name == name {
+			return p.Clock, true
+		}
+	}
+	return "", false
+}
+
+// handleTimes handles "/times", "/times <имя> <ЧЧ:ММ>", "/times <имя> -" and
+// "/times reset".
+func (a *App) handleTimes(chatID int64, arg string) {
+	fields := strings.Fields(arg)
+	presets := a.Store.TimePresets(chatID)
+	lang := a.Store.Lang(chatID)
+
+	switch {
+	case len(fields) == 0:
+		var b strings.Builder
+		b.WriteString(tr(lang, "times.header") + "\n")
+		for _, p := range presets {
+			fmt.Fprintf(&b, "%s — %s\n", p.Name, p.Clock)
+		}
+		b.WriteString("\n" + tr(lang, "times.hint"))
+		a.send(chatID, b.String())
+		return
+	case len(fields) == 1 && fields[0] == "reset":
+		if err := a.Store.DeleteKV(chatKey(chatID, "time_presets")); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "times.reset"))
+		return
+	case len(fields) != 2:
+		a.send(chatID, tr(lang, "times.usage"))
+		return
+	}
+
+	name, clock := normalizeText(fields[0]), fields[1]
+	if strings.ContainsAny(name, "=,") {
+		a.send(chatID, tr(lang, "times.badname"))
+		return
+	}
+	if clock != "-" {
+		t, err := time.Parse("15:04", clock)
+		if err != nil {
+			a.send(chatID, tr(lang, "times.badclock"))
+			return
+		}
+		clock = t.Format("15:04")
+	}
+
+	var out []TimePreset
+	found := false
+	for _, p := range presets {
+		if p.Name == name {
+			found = true
+			if clock == "-" {
+				continue
+			}
+			p.Clock = clock
+		}
+		out = append(out, p)
+	}
+	if !found && clock != "-" {
+		out = append(out, TimePreset{Name: name, Clock: clock})
+	}
+	if err := a.Store.SetTimePresets(chatID, out); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	if clock == "-" {
+		a.send(chatID, tr(lang, "times.deleted", name))
+		return
+	}
+	a.send(chatID, fmt.Sprintf("%s — %s", name, clock))
+}
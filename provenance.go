@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Provenance records where an item came from: the capture message, the
+// kind of chat and, for forwards, the original sender.
+type Provenance struct {
+	MessageID   int
+	ChatType    string
+	ForwardFrom string
+	ForwardLink string
+	ForwardDate time.Time
+}
+
+func provenanceOf(m *tgbotapi.Message) Provenance {
+	p := Provenance{MessageID: m.MessageID}
+	if m.Chat != nil {
+		p.ChatType = m.Chat.Type
+	}
+	switch {
+	case m.ForwardFromChat != nil:
+		p.ForwardFrom = m.ForwardFromChat.Title
+		if m.ForwardFromChat.UserName != "" && m.ForwardFromMessageID != 0 {
+			p.ForwardLink = fmt.Sprintf("https://t.me/%s/%d", m.ForwardFromChat.UserName, m.ForwardFromMessageID)
+		}
+	case m.ForwardFrom != nil:
+		p.ForwardFrom = userDisplayName(m.ForwardFrom)
+	case m.ForwardSenderName != "":
+		p.ForwardFrom = m.ForwardSenderName
+	}
+	if m.ForwardDate != 0 {
+		p.ForwardDate = time.Unix(int64(m.ForwardDate), 0)
+	}
+	return p
+}
+
+func (s *sqlStore) SetProvenance(chatID, id int64, p Provenance) error {
+	fwdDate := ""
+	if !p.ForwardDate.IsZero() {
+		fwdDate = p.ForwardDate.UTC().Format(time.RFC3339)
+	}
+	_, err := s.db(chatID).Exec(
+		`UPDATE items SET message_id=?, chat_type=?, forward_from=?, forward_link=?, forward_date=? WHERE chat_id=? AND id=?`,
+		p.MessageID, p.ChatType, p.ForwardFrom, p.ForwardLink, fwdDate, chatID, id,
+	)
+	return err
+}
+
+func (s *sqlStore) GetProvenance(chatID, id int64) (Provenance, error) {
+	var p Provenance
+	var fwdDate string
+	err := s.db(chatID).QueryRow(
+		`SELECT message_id, chat_type, forward_from, forward_link, forward_date FROM items WHERE chat_id=? AND id=?`,
+		chatID, id,
+	).Scan(&p.MessageID, &p.ChatType, &p.ForwardFrom, &p.ForwardLink, &fwdDate)
+	if err != nil {
+		return Provenance{}, err
+	}
+	p.ForwardDate, _ = time.Parse(time.RFC3339, fwdDate)
+	return p, nil
+}
+
+// messageLink builds a t.me link to a message in a supergroup or channel.
+// Private chats and basic groups have no message links.
+func messageLink(chatID int64, messageID int) (string, bool) {
+	s := strconv.FormatInt(chatID, 10)
+	if messageID == 0 || !strings.HasPrefix(s, "-100") {
+		return "", false
+	}
+	return fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(s, "-100"), messageID), true
+}
+
+// itemDetailKeyboard offers "go to message": a URL where Telegram
+// supports one, otherwise a callback that replies to the original message.
+func itemDetailKeyboard(lang string, chatID, id int64, p Provenance) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	if link, ok := messageLink(chatID, p.MessageID); ok {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonURL("↩️ "+tr(lang, "item.goto"), link))
+	} else if p.MessageID != 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("↩️ "+tr(lang, "item.goto"), fmt.Sprintf("goto:%d", id)))
+	}
+	if p.ForwardLink != "" {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonURL("📨 "+tr(lang, "item.original"), p.ForwardLink))
+	}
+	row = append(row,
+		tgbotapi.NewInlineKeyboardButtonData("✅", fmt.Sprintf("done:%d", id)),
+		tgbotapi.NewInlineKeyboardButtonData("📅", fmt.Sprintf("due:%d", id)),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// handleItem handles "/item <id>": the item with its provenance.
+func (a *App) handleItem(chatID int64, arg string) {
+	lang := a.Store.Lang(chatID)
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(arg), "#"), 10, 64)
+	if err != nil {
+		a.send(chatID, tr(lang, "item.usage"))
+		return
+	}
+	it, err := a.Store.GetItem(chatID, id)
+	if err != nil || it == nil {
+		a.send(chatID, tr(lang, "item.missing"))
+		return
+	}
+	p, err := a.Store.GetProvenance(chatID, id)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(formatSingleItem(lang, it.Topic, *it))
+	b.WriteString("\n" + tr(lang, "item.created", it.CreatedAt.In(a.tz(chatID)).Format("02.01.2006 15:04")))
+	if due, err := a.Store.Due(chatID, id); err == nil && !due.IsZero() {
+		b.WriteString("\n" + tr(lang, "item.due", formatDue(due, a.tz(chatID))))
+	}
+	if !it.CompletedAt.IsZero() {
+		b.WriteString("\n" + tr(lang, "item.completed", it.CompletedAt.In(a.tz(chatID)).Format("02.01.2006 15:04")))
+	}
+	if p.ForwardFrom != "" {
+		b.WriteString("\n" + tr(lang, "item.forwarded", p.ForwardFrom))
+		if !p.ForwardDate.IsZero() {
+			fmt.Fprintf(&b, " (%s)", p.ForwardDate.In(a.tz(chatID)).Format("02.01.2006 15:04"))
+		}
+	}
+
+	if files, err := a.Store.ItemMedia(chatID, id); err == nil {
+		for _, f := range files {
+			fmt.Fprintf(&b, "\n📎 %s %s", f.Kind, formatBytes(lang, f.Size))
+			if f.Name != "" {
+				b.WriteString(" " + f.Name)
+			}
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	msg.ReplyMarkup = itemDetailKeyboard(lang, chatID, id, p)
+	_, _ = a.Bot.Send(msg)
+}
+
+// handleGotoCallback handles "goto:<id>" by replying to the capture message,
+// which makes the client scroll to it when the quote is tapped.
+func (a *App) handleGotoCallback(cq *tgbotapi.CallbackQuery, idStr string) {
+	chatID := cq.Message.Chat.ID
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	p, err := a.Store.GetProvenance(chatID, id)
+	if err != nil || p.MessageID == 0 {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "item.source.none")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	msg := tgbotapi.NewMessage(chatID, a.tr(chatID, "item.source", id))
+	msg.ReplyToMessageID = p.MessageID
+	if _, err := a.Bot.Send(msg); err != nil {
+		a.send(chatID, a.tr(chatID, "item.source.deleted"))
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Quiet hours: "/quiet 23:30-07:30". While the window lasts, whatever the
+// scheduler would send to the chat (digests, reminders, reports) is parked
+// in deferred_messages and sent when the window ends, in order. Replies to
+// the chat's own messages are never held back.
+
+type DeferredMessage struct {
+	ID      int64
+	Kind    string
+	ChatID  int64
+	Payload string
+}
+
+func (s *sqlStore) DeferMessage(kind string, chatID int64, payload string) error {
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO deferred_messages(kind, chat_id, payload, created_at) VALUES(?,?,?,?)`,
+		kind, chatID, payload, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (s *sqlStore) DeferredMessages(chatID int64) ([]DeferredMessage, error) {
+	rows, err := s.db(chatID).Query(`SELECT id, kind, chat_id, payload FROM deferred_messages WHERE chat_id=? ORDER BY id`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []DeferredMessage
+	for rows.Next() {
+		var d DeferredMessage
+		if err := rows.Scan(&d.ID, &d.Kind, &d.ChatID, &d.Payload); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) DeleteDeferred(chatID, id int64) error {
+	_, err := s.db(chatID).Exec(`DELETE FROM deferred_messages WHERE chat_id=? AND id=?`, chatID, id)
+	return err
+}
+
+// parseQuiet reads "23:30-07:30" into minutes since midnight.
+func parseQuiet(s string) (from, to int, ok bool) {
+	a, b, found := strings.Cut(strings.ReplaceAll(strings.TrimSpace(s), "–", "-"), "-")
+	if !found {
+		return 0, 0, false
+	}
+	ta, errA := time.Parse("15:04", strings.TrimSpace(a))
+	tb, errB := time.Parse("15:04", strings.TrimSpace(b))
+	if errA != nil || errB != nil || ta.Equal(tb) {
+		return 0, 0, false
+	}
+	return ta.Hour()*60 + ta.Minute(), tb.Hour()*60 + tb.Minute(), true
+}
+
+// inQuiet reports whether now falls in the window.
+func inQuiet(window string, now time.Time) bool {
+	from, to, ok := parseQuiet(window)
+	if !ok {
+		return false
+	}
+	m := now.Hour()*60 + now.Minute()
+	if from > to { // over midnight
+		return m >= from || m < to
+	}
+	return from <= m && m < to
+}
+
+func (s *Scheduler) quiet(chatID int64) bool {
+	window, ok, err := s.store.GetKV(chatKey(chatID, "quiet"))
+	if err != nil || !ok {
+		return false
+	}
+	now := time.Now()
+	return inQuiet(window, now.In(s.location(chatID, now)))
+}
+
+// hold parks msg if the chat is in its quiet hours.
+func (s *Scheduler) hold(kind string, msg tgbotapi.MessageConfig) bool {
+	if !s.quiet(msg.ChatID) {
+		return false
+	}
+	payload, _ := json.Marshal(deadMessage{ChatID: msg.ChatID, Text: msg.Text, ReplyTo: msg.ReplyToMessageID, ReplyMarkup: msg.ReplyMarkup})
+	if err := s.store.DeferMessage(kind, msg.ChatID, string(payload)); err != nil {
+		log.Printf("scheduler: defer %s error: %v", kind, err)
+		return false
+	}
+	return true
+}
+
+// send is bot.Send for scheduler broadcasts, held back in quiet hours.
+func (s *Scheduler) send(kind string, msg tgbotapi.MessageConfig) {
+	if s.hold(kind, msg) {
+		return
+	}
+	_, _ = s.bot.Send(msg)
+}
+
+// flushQuiet sends what quiet hours held back, once they're over.
+func (s *Scheduler) flushQuiet(chatID int64) {
+	if s.quiet(chatID) {
+		return
+	}
+	held, err := s.store.DeferredMessages(chatID)
+	if err != nil {
+		log.Printf("scheduler: deferred messages error: %v", err)
+		return
+	}
+	for _, d := range held {
+		var dm deadMessage
+		if err := json.Unmarshal([]byte(d.Payload), &dm); err == nil {
+			msg := tgbotapi.NewMessage(dm.ChatID, dm.Text)
+			msg.ReplyToMessageID = dm.ReplyTo
+			msg.AllowSendingWithoutReply = dm.ReplyTo != 0
+			msg.ReplyMarkup = dm.ReplyMarkup
+			_ = deliver(s.bot, s.store, d.Kind, msg)
+		}
+		if err := s.store.DeleteDeferred(chatID, d.ID); err != nil {
+			log.Printf("scheduler: delete deferred error: %v", err)
+			return
+		}
+	}
+}
+
+// handleQuiet handles "/quiet [ЧЧ:ММ-ЧЧ:ММ | off]".
+func (a *App) handleQuiet(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	key := chatKey(chatID, "quiet")
+	switch arg {
+	case "":
+		window, ok, err := a.Store.GetKV(key)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if !ok {
+			a.send(chatID, a.tr(chatID, "quiet.off")+" "+a.tr(chatID, "quiet.enable"))
+			return
+		}
+		a.send(chatID, "🌙 "+a.tr(chatID, "quiet.status", window))
+	case "off":
+		if err := a.Store.DeleteKV(key); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "quiet.off"))
+	default:
+		from, to, ok := parseQuiet(arg)
+		if !ok {
+			a.send(chatID, a.tr(chatID, "quiet.usage"))
+			return
+		}
+		window := fmt.Sprintf("%02d:%02d-%02d:%02d", from/60, from%60, to/60, to%60)
+		if err := a.Store.SetKV(key, window); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, "🌙 "+a.tr(chatID, "quiet.set", window, window[6:]))
+	}
+}
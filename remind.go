@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Timed reminders: a reminder captured as "17:30 позвонить врачу" or
+// "завтра утром оплатить счёт" gets remind_at and its own ping at that
+// moment, instead of riding along with the fixed REMINDER_TIMES broadcasts.
+
+var (
+	clockRe   = regexp.MustCompile(`^([01]?\d|2[0-3])[:.]([0-5]\d)$`)
+	dayDateRe = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})$`)
+)
+
+var dayWords = map[string]int{
+	"сегодня": 0, "today": 0,
+	"завтра": 1, "tomorrow": 1,
+	"послезавтра": 2,
+}
+
+// parseRemindAt reads an optional day ("завтра", "15.10") and a time
+// ("9:00", "в 9:00", a /times preset like "утром") off the front of text.
+// A bare time already past today means tomorrow.
+func parseRemindAt(text string, now time.Time, preset func(name string) (string, bool)) (time.Time, string, bool) {
+	words := strings.Fields(text)
+	i := 0
+	next := func() string {
+		if i < len(words) {
+			return strings.ToLower(strings.TrimRight(words[i], ","))
+		}
+		return ""
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	explicitDay := false
+	if n, ok := dayWords[next()]; ok {
+		day = day.AddDate(0, 0, n)
+		explicitDay = true
+		i++
+	} else if m := dayDateRe.FindStringSubmatch(next()); m != nil {
+		d, _ := strconv.Atoi(m[1])
+		mon, _ := strconv.Atoi(m[2])
+		t := time.Date(now.Year(), time.Month(mon), d, 0, 0, 0, 0, now.Location())
+		if t.Day() != d || t.Month() != time.Month(mon) {
+			return time.Time{}, text, false
+		}
+		if t.Before(day) {
+			t = t.AddDate(1, 0, 0)
+		}
+		day, explicitDay = t, true
+		i++
+	}
+
+	if w := next(); w == "в" || w == "at" {
+		i++
+	}
+	clock := next()
+	if c, ok := preset(clock); ok {
+		clock = c
+	}
+	m := clockRe.FindStringSubmatch(clock)
+	if m == nil {
+		return time.Time{}, text, false
+	}
+	i++
+	h, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	at := time.Date(day.Year(), day.Month(), day.Day(), h, min, 0, 0, now.Location())
+	if !explicitDay && !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+
+	rest := strings.TrimSpace(strings.TrimLeft(strings.Join(words[i:], " "), "—-: "))
+	if rest == "" {
+		return time.Time{}, text, false
+	}
+	return at, rest, true
+}
+
+func formatRemindAt(lang string, at, now time.Time) string {
+	at = at.In(now.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, now.Location()).Sub(today) {
+	case 0:
+		return tr(lang, "remind.today", at.Format("15:04"))
+	case 24 * time.Hour:
+		return tr(lang, "remind.tomorrow", at.Format("15:04"))
+	}
+	return tr(lang, "remind.on", at.Format("02.01"), at.Format("15:04"))
+}
+
+func (s *sqlStore) SetRemindAt(chatID, id int64, at time.Time) error {
+	_, err := s.db(chatID).Exec(
+		`UPDATE items SET remind_at=?, reminded_at='' WHERE chat_id=? AND id=?`,
+		at.UTC().Format(time.RFC3339), chatID, id,
+	)
+	return err
+}
+
+func (s *sqlStore) RemindAt(chatID, id int64) (time.Time, error) {
+	var v string
+	err := s.db(chatID).QueryRow(`SELECT remind_at FROM items WHERE chat_id=? AND id=?`, chatID, id).Scan(&v)
+	if err != nil || v == "" {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// DueReminders returns active items whose remind_at has come and that
+// haven't been sent yet.
+func (s *sqlStore) DueReminders(chatID int64, now time.Time) ([]Item, error) {
+	rows, err := s.db(chatID).Query(
+		`SELECT id, chat_id, topic, text, created_at FROM items
+		 WHERE chat_id=? AND status=? AND remind_at<>'' AND remind_at<=? AND reminded_at=''
+		 ORDER BY remind_at`,
+		chatID, StatusActive, now.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Item
+	for rows.Next() {
+		var it Item
+		var created string
+		if err := rows.Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &created); err != nil {
+			return nil, err
+		}
+		it.Text = s.openText(chatID, it.Text)
+		it.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) MarkReminded(chatID, id int64, now time.Time) error {
+	_, err := s.db(chatID).Exec(
+		`UPDATE items SET reminded_at=? WHERE chat_id=? AND id=?`,
+		now.UTC().Format(time.RFC3339), chatID, id,
+	)
+	return err
+}
+
+// WipeReminders is the night wipe: every active reminder is archived
+// except the ones still waiting for their time or snoozed past now.
+func (s *sqlStore) WipeReminders(chatID int64, now time.Time) error {
+	ts := now.UTC().Format(time.RFC3339)
+	_, err := s.db(chatID).Exec(
+		`UPDATE items SET status=?, completed_at=? WHERE chat_id=? AND topic=? AND status=? AND (remind_at='' OR remind_at<=?) AND (snoozed_until='' OR snoozed_until<=?)`,
+		StatusArchived, ts, chatID, TopicReminders, StatusActive, ts, ts,
+	)
+	return err
+}
+
+// applyRemindAt strips a leading time off a reminder before it is stored.
+func (a *App) applyRemindAt(chatID int64, text string) (string, time.Time) {
+	at, rest, ok := parseRemindAt(text, time.Now().In(a.tz(chatID)), func(name string) (string, bool) {
+		return a.Store.PresetClock(chatID, name)
+	})
+	if !ok {
+		return text, time.Time{}
+	}
+	return rest, at
+}
+
+func (a *App) confirmRemindAt(chatID int64, at time.Time) {
+	if a.Store.AckMode(chatID) != AckFull {
+		return
+	}
+	a.send(chatID, a.tr(chatID, "remind.set", formatRemindAt(a.Store.Lang(chatID), at, time.Now().In(a.tz(chatID)))))
+}
+
+// reminderMessage is one reminder with its ✅ button, sent to the chat it
+// is routed to; in the capture chat it replies to the original message.
+func reminderMessage(store Store, chatID int64, it Item, lang string) tgbotapi.MessageConfig {
+	target := store.NotifyChat(chatID, it)
+	msg := tgbotapi.NewMessage(target, formatSingleItem(lang, TopicReminders, it))
+	if target == chatID {
+		msg.ReplyMarkup = reminderKeyboard(lang, it.ID, store.TimePresets(chatID))
+		msg.ReplyToMessageID = store.ItemMessage(chatID, it.ID)
+		msg.AllowSendingWithoutReply = true
+	} else {
+		msg.ReplyMarkup = routedKeyboard(chatID, it.ID)
+	}
+	return msg
+}
+
+func (s *Scheduler) sendTimedReminders(chatID int64, now time.Time) {
+	items, err := s.store.DueReminders(chatID, now)
+	if err != nil {
+		log.Printf("scheduler: due reminders error: %v", err)
+		return
+	}
+	lang := s.store.Lang(chatID)
+	f, focused := activeFocus(s.store, chatID, now)
+	for _, it := range items {
+		if focused && !f.Matches(it.Text) {
+			continue // fires once focus ends
+		}
+		msg := reminderMessage(s.store, chatID, it, lang)
+		msg.Text = fmt.Sprintf("⏰ %s", msg.Text)
+		err := s.deliver("timed_reminder", msg)
+		// Marked either way: a failed send is in the dead-letter queue
+		if mErr := s.store.MarkReminded(chatID, it.ID, now); mErr != nil {
+			log.Printf("scheduler: mark reminded error: %v", mErr)
+		}
+		if err == nil {
+			s.events.Publish(Event{Kind: EventReminderSent, ChatID: chatID, ItemID: it.ID, Topic: TopicReminders, Text: it.Text, At: now})
+		}
+	}
+}
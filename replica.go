@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A read replica (DB_READ_DSN) serves list, history and stats queries so
+// reporting load doesn't queue behind writes on the primary. Replication
+// is asynchronous: anything that reads its own write (duplicate checks,
+// item lookups after a button press) stays on the primary.
+//
+// The replica covers the main database; shards are always read
+// directly.
+
+const replicaMaxConns = 4
+
+func (s *sqlStore) attachReplica(dsn string) error {
+	dsn = strings.TrimSpace(dsn)
+	if dsn == "" {
+		return nil
+	}
+	db, err := openDB(s.driver, dsn)
+	if err != nil {
+		return fmt.Errorf("read replica: %w", err)
+	}
+	if s.driver == driverSQLite {
+		db.SetMaxOpenConns(replicaMaxConns)
+	}
+	s.read = wrapConn(s.driver, db)
+	return nil
+}
+
+// readDB returns the connection for lag-tolerant reads of chatID's data.
+// Inside a transaction it is the transaction itself.
+func (s *sqlStore) readDB(chatID int64) dbConn {
+	sh := s.For(chatID)
+	if sh.read != nil {
+		return sh.read
+	}
+	return sh.DB
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var hashtagRe = regexp.MustCompile(`(?:^|\s)(#[\p{L}\p{N}_]+)`)
+
+// ListCompleted returns items completed in [from, to).
+func (s *sqlStore) ListCompleted(chatID int64, from, to time.Time) ([]Item, error) {
+	rows, err := s.readDB(chatID).Query(
+		`SELECT id, chat_id, topic, text, flagged, secret, created_at, completed_at FROM items
+		 WHERE chat_id=? AND status=? AND completed_at>=? AND completed_at<? ORDER BY completed_at`,
+		chatID, StatusDone, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Item
+	for rows.Next() {
+		var it Item
+		var created, completed string
+		if err := rows.Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &it.Flagged, &it.Secret, &created, &completed); err != nil {
+			return nil, err
+		}
+		it.Text = s.openText(chatID, it.Text)
+		it.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		it.CompletedAt, _ = time.Parse(time.RFC3339, completed)
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func itemTags(text string) []string {
+	tags := parseContexts(text)
+	for _, m := range hashtagRe.FindAllStringSubmatch(text, -1) {
+		tags = append(tags, normalizeText(m[1]))
+	}
+	return tags
+}
+
+// longestStreak returns the longest run of consecutive days with at least
+// one completion.
+func longestStreak(items []Item, tz *time.Location) int {
+	days := map[string]bool{}
+	for _, it := range items {
+		days[it.CompletedAt.In(tz).Format("2006-01-02")] = true
+	}
+	best := 0
+	for d := range days {
+		t, _ := time.ParseInLocation("2006-01-02", d, tz)
+		if days[t.AddDate(0, 0, -1).Format("2006-01-02")] {
+			continue // not the start of a run
+		}
+		n := 0
+		for days[t.AddDate(0, 0, n).Format("2006-01-02")] {
+			n++
+		}
+		best = max(best, n)
+	}
+	return best
+}
+
+func formatDelta(lang string, cur, prev int) string {
+	if prev == 0 {
+		if cur == 0 {
+			return "="
+		}
+		return tr(lang, "retro.new")
+	}
+	pct := (cur - prev) * 100 / prev
+	if pct >= 0 {
+		return fmt.Sprintf("+%d%%", pct)
+	}
+	return fmt.Sprintf("%d%%", pct)
+}
+
+func buildRetro(cur, prev []Item, tz *time.Location, lang string) string {
+	countBy := func(items []Item) map[string]int {
+		m := map[string]int{}
+		for _, it := range items {
+			m[it.Topic]++
+		}
+		return m
+	}
+	curBy, prevBy := countBy(cur), countBy(prev)
+
+	topics := make([]string, 0, len(curBy))
+	for t := range curBy {
+		topics = append(topics, t)
+	}
+	for t := range prevBy {
+		if _, ok := curBy[t]; !ok {
+			topics = append(topics, t)
+		}
+	}
+	sort.Slice(topics, func(i, j int) bool { return curBy[topics[i]] > curBy[topics[j]] })
+
+	var b strings.Builder
+	b.WriteString(tr(lang, "retro.done", len(cur), formatDelta(lang, len(cur), len(prev))) + "\n")
+	for _, t := range topics {
+		fmt.Fprintf(&b, "— %s: %d (%s)\n", topicLabel(lang, t), curBy[t], formatDelta(lang, curBy[t], prevBy[t]))
+	}
+
+	tagCount := map[string]int{}
+	for _, it := range cur {
+		for _, t := range itemTags(it.Text) {
+			tagCount[t]++
+		}
+	}
+	if len(tagCount) > 0 {
+		tags := make([]string, 0, len(tagCount))
+		for t := range tagCount {
+			tags = append(tags, t)
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			if tagCount[tags[i]] != tagCount[tags[j]] {
+				return tagCount[tags[i]] > tagCount[tags[j]]
+			}
+			return tags[i] < tags[j]
+		})
+		if len(tags) > 5 {
+			tags = tags[:5]
+		}
+		parts := make([]string, 0, len(tags))
+		for _, t := range tags {
+			parts = append(parts, fmt.Sprintf("%s ×%d", t, tagCount[t]))
+		}
+		b.WriteString("\n" + tr(lang, "retro.tags", strings.Join(parts, ", ")) + "\n")
+	}
+
+	var longest *Item
+	for i := range cur {
+		if longest == nil || cur[i].CompletedAt.Sub(cur[i].CreatedAt) > longest.CompletedAt.Sub(longest.CreatedAt) {
+			longest = &cur[i]
+		}
+	}
+	if longest != nil {
+		days := int(longest.CompletedAt.Sub(longest.CreatedAt).Hours() / 24)
+		b.WriteString("\n" + tr(lang, "retro.longest", days, shownText(*longest)) + "\n")
+	}
+
+	b.WriteString("\n" + tr(lang, "retro.streak", longestStreak(cur, tz), longestStreak(prev, tz)))
+	return b.String()
+}
+
+// sendMonthlyRetro reports on the previous calendar month on the 1st.
+func (s *Scheduler) sendMonthlyRetro(chatID int64, now time.Time) {
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	lastMonth := thisMonth.AddDate(0, -1, 0)
+	prevMonth := thisMonth.AddDate(0, -2, 0)
+
+	cur, err := s.store.ListCompleted(chatID, lastMonth, thisMonth)
+	if err != nil {
+		log.Printf("scheduler: retro error: %v", err)
+		return
+	}
+	prev, err := s.store.ListCompleted(chatID, prevMonth, lastMonth)
+	if err != nil {
+		log.Printf("scheduler: retro error: %v", err)
+		return
+	}
+	if len(cur) == 0 && len(prev) == 0 {
+		return
+	}
+
+	lang := s.store.Lang(chatID)
+	text := tr(lang, "retro.title", lastMonth.Format("01.2006")) + "\n" + buildRetro(cur, prev, now.Location(), lang)
+	s.send("retro", tgbotapi.NewMessage(chatID, text))
+}
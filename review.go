@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// /review is the GTD clarify step: basket items one at a time, oldest
+// first, each with a decision. Callback data is "rv:<id>:<step>":
+//
+//	now        done right away
+//	t, s, m    move to tasks, shopping, someday
+//	r          ask when to remind (the chat's time presets)
+//	r:15:04    move to reminders, remind at the next 15:04
+//	r:-        move to reminders without a time
+//	del        delete
+//	skip       leave it in the basket
+//
+// After each decision the message shows the outcome and the next item
+// (the first basket item after this one) follows.
+
+func reviewData(id int64, step string) string {
+	return fmt.Sprintf("rv:%d:%s", id, step)
+}
+
+func (a *App) reviewKeyboard(chatID, id int64) tgbotapi.InlineKeyboardMarkup {
+	lang := a.Store.Lang(chatID)
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ "+tr(lang, "review.now"), reviewData(id, "now")),
+			tgbotapi.NewInlineKeyboardButtonData(a.topicButton(chatID, lang, TopicTasks), reviewData(id, "t")),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⏰ "+a.topicButton(chatID, lang, TopicReminders), reviewData(id, "r")),
+			tgbotapi.NewInlineKeyboardButtonData(a.topicButton(chatID, lang, TopicShopping), reviewData(id, "s")),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(a.topicButton(chatID, lang, TopicSomeday), reviewData(id, "m")),
+			tgbotapi.NewInlineKeyboardButtonData("🗑 "+tr(lang, "review.delete"), reviewData(id, "del")),
+			tgbotapi.NewInlineKeyboardButtonData("⏭", reviewData(id, "skip")),
+		),
+	)
+}
+
+func reviewTimeKeyboard(lang string, id int64, presets []TimePreset) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, p := range presets {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(p.Name+" "+p.Clock, reviewData(id, "r:"+p.Clock)))
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "review.notime"), reviewData(id, "r:-")),
+	))...)
+}
+
+// nextClock is the next moment the clock shows hh:mm, today or tomorrow.
+func nextClock(clock string, now time.Time) (time.Time, bool) {
+	t, err := time.ParseInLocation("2006-01-02 15:04", now.Format("2006-01-02")+" "+clock, now.Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	if !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+// handleReview handles "/review".
+func (a *App) handleReview(chatID int64) {
+	a.sendReviewItem(chatID, 0)
+}
+
+// sendReviewItem sends the first basket item after afterID.
+func (a *App) sendReviewItem(chatID, afterID int64) {
+	lang := a.Store.Lang(chatID)
+	items, err := a.Store.ListActive(chatID, TopicBasket)
+	if err != nil {
+		a.send(chatID, tr(lang, "err.read"))
+		return
+	}
+	for i, it := range items {
+		if it.ID <= afterID {
+			continue
+		}
+		text := tr(lang, "review.header", i+1, len(items)) + "\n\n" + formatSingleItem(lang, TopicBasket, it)
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ReplyMarkup = a.reviewKeyboard(chatID, it.ID)
+		_, _ = a.Bot.Send(msg)
+		return
+	}
+	if len(items) == 0 {
+		a.send(chatID, tr(lang, "review.empty"))
+		return
+	}
+	a.send(chatID, tr(lang, "review.over", len(items)))
+}
+
+// handleReviewCallback handles "rv:<id>:<step>".
+func (a *App) handleReviewCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	msgID := cq.Message.MessageID
+	idStr, step, _ := strings.Cut(data, ":")
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	lang := a.Store.Lang(chatID)
+
+	it, err := a.Store.GetItem(chatID, id)
+	if err != nil || it == nil || it.Topic != TopicBasket {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "review.gone")))
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, msgID, tgbotapi.InlineKeyboardMarkup{}))
+		return
+	}
+	if step == "r" {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "review.when")))
+		_, _ = a.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, msgID, reviewTimeKeyboard(lang, id, a.Store.TimePresets(chatID))))
+		return
+	}
+
+	var outcome string
+	switch {
+	case step == "now":
+		err = a.Store.FinishItem(chatID, id, cq.From, time.Now())
+		outcome = "✅ " + tr(lang, "review.done")
+	case step == "t", step == "s", step == "m":
+		topic := map[string]string{"t": TopicTasks, "s": TopicShopping, "m": TopicSomeday}[step]
+		err = a.Store.MoveItem(chatID, id, topic)
+		outcome = "→ " + a.topicButton(chatID, lang, topic)
+	case strings.HasPrefix(step, "r:"):
+		outcome = "→ " + a.topicButton(chatID, lang, TopicReminders)
+		var at time.Time
+		if clock := strings.TrimPrefix(step, "r:"); clock != "-" {
+			at, _ = nextClock(clock, time.Now().In(a.tz(chatID)))
+		}
+		err = a.Store.InTx(chatID, func(tx Store) error {
+			if err := tx.MoveItem(chatID, id, TopicReminders); err != nil {
+				return err
+			}
+			if at.IsZero() {
+				return nil
+			}
+			return tx.SetRemindAt(chatID, id, at)
+		})
+		if !at.IsZero() {
+			outcome += ", ⏰ " + formatRemindAt(lang, at, time.Now().In(a.tz(chatID)))
+		}
+	case step == "del":
+		err = a.Store.DeleteItem(chatID, id)
+		outcome = "🗑 " + tr(lang, "review.deleted")
+	case step == "skip":
+		outcome = "⏭ " + tr(lang, "review.skipped")
+	default:
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+		return
+	}
+	if err != nil {
+		log.Printf("review %s #%d error: %v", step, id, err)
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.write")))
+		return
+	}
+
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	_, _ = a.Bot.Send(tgbotapi.NewEditMessageText(chatID, msgID, formatSingleItem(lang, TopicBasket, *it)+"\n"+outcome))
+	a.sendReviewItem(chatID, id)
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Roles in shared chats: an owner changes settings and wipes lists, an
+// editor adds and ticks off items, a viewer only reads. A group has no
+// roles until someone sets one; from then on each message and button is
+// checked before it is handled. Group admins and OWNER_ID are always
+// owners; everyone else has the role given to them or the chat's default
+// (editor unless "/role default viewer"). Private chats are never checked.
+//
+//	/role                      who has which role
+//	/role editor|viewer|owner  in reply to a member's message
+//	/role <user id> <role>     the same by id; "off" drops the member's role
+//	/role default <role>       the role of members not listed
+//	/role reset                no roles, everyone may do everything again
+
+type Role int
+
+const (
+	RoleViewer Role = iota + 1
+	RoleEditor
+	RoleOwner
+)
+
+var roleNames = map[Role]string{RoleViewer: "viewer", RoleEditor: "editor", RoleOwner: "owner"}
+
+func (r Role) Label(lang string) string {
+	if name, ok := roleNames[r]; ok {
+		return tr(lang, "role."+name)
+	}
+	return tr(lang, "role.editor")
+}
+
+func parseRole(s string) (Role, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "owner", "владелец":
+		return RoleOwner, true
+	case "editor", "редактор":
+		return RoleEditor, true
+	case "viewer", "читатель":
+		return RoleViewer, true
+	}
+	return 0, false
+}
+
+// Commands a viewer may run; the rest need an editor unless listed in
+// ownerCommands. Aliases from handleCommand are looked up by the name in
+// commandAliases.
+var (
+	viewerCommands = []string{
+		"start", "list", "tasks", "задачи", "напоминания", "покупки", "корзина", "когда-нибудь", "archive",
+		"today", "next", "goals", "search", "history", "leaderboard", "usage", "export", "plan", "estimates", "item", "notes", "role",
+	}
+	ownerCommands = []string{
+		"language", "capacity", "rename", "compact", "ack", "digest", "digestchannel", "digesttemplate", "timezone",
+		"linkchat", "route", "deadletters", "backup", "restore", "times", "retention", "gcalauth", "rules", "script", "import",
+		"migrate", "encrypt", "decrypt", "lock", "unlock", "clear", "newlist", "dellist", "quiet", "keyboard",
+		"intents", "speak", "busy", "maint",
+	}
+	commandAliases = map[string]string{
+		"menu": "start", "ctx": "contexts", "tz": "timezone", "shopping": "tasks", "reminders": "tasks",
+		"bills": "bill", "cards": "card", "recipes": "recipe", "meals": "meal", "dnd": "quiet", "maintenance": "maint",
+	}
+	viewerCallbacks = []string{"pg:", "arch:", "reveal:", "att:", "card:", "thread:", "goto:", "ctx:"}
+	ownerCallbacks  = []string{"lang:", "dg:"}
+	// plain-language commands that only read
+	viewerIntents = []string{"list", "today", "next", "search", "help"}
+)
+
+func commandRole(cmd string) Role {
+	if name, ok := commandAliases[cmd]; ok {
+		cmd = name
+	}
+	switch {
+	case slices.Contains(viewerCommands, cmd):
+		return RoleViewer
+	case slices.Contains(ownerCommands, cmd):
+		return RoleOwner
+	}
+	return RoleEditor
+}
+
+// messageRole is what a message needs: its command's role, or for text
+// the role of what it will do — a topic or "today" button and a read-only
+// plain-language request only read, anything else is captured.
+func (a *App) messageRole(m *tgbotapi.Message) Role {
+	if m.IsCommand() {
+		return commandRole(m.Command())
+	}
+	if m.Text == "" {
+		return RoleEditor
+	}
+	if keyboardAction(m.Text) == "today" {
+		return RoleViewer
+	}
+	if _, ok := a.topicFromButton(m.Chat.ID, m.Text); ok {
+		return RoleViewer
+	}
+	if v, _, _ := a.Store.GetKV(chatKey(m.Chat.ID, "intents")); v != "off" {
+		if in, ok := matchIntent(m.Text); ok && slices.Contains(viewerIntents, in.Name) {
+			return RoleViewer
+		}
+	}
+	return RoleEditor
+}
+
+func callbackRole(data string) Role {
+	for _, p := range viewerCallbacks {
+		if strings.HasPrefix(data, p) {
+			return RoleViewer
+		}
+	}
+	for _, p := range ownerCallbacks {
+		if strings.HasPrefix(data, p) {
+			return RoleOwner
+		}
+	}
+	return RoleEditor
+}
+
+// chatRoles holds a chat's members' roles, stored as "<user id> <role>
+// <name>" lines.
+type chatRoles struct {
+	Default Role
+	Members map[int64]Role
+	Names   map[int64]string
+}
+
+func loadRoles(store Store, chatID int64) (chatRoles, bool) {
+	rs := chatRoles{Default: RoleEditor, Members: map[int64]Role{}, Names: map[int64]string{}}
+	raw, hasRoles, _ := store.GetKV(chatKey(chatID, "roles"))
+	def, hasDef, _ := store.GetKV(chatKey(chatID, "role_default"))
+	if r, ok := parseRole(def); ok {
+		rs.Default = r
+	}
+	for _, line := range strings.Split(raw, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		id, err := strconv.ParseInt(f[0], 10, 64)
+		r, ok := parseRole(f[1])
+		if err != nil || !ok {
+			continue
+		}
+		rs.Members[id] = r
+		rs.Names[id] = strings.Join(f[2:], " ")
+	}
+	return rs, hasRoles || hasDef
+}
+
+func saveRoles(store Store, chatID int64, rs chatRoles) error {
+	ids := make([]int64, 0, len(rs.Members))
+	for id := range rs.Members {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	var lines []string
+	for _, id := range ids {
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("%d %s %s", id, roleNames[rs.Members[id]], rs.Names[id])))
+	}
+	if len(lines) == 0 {
+		return store.DeleteKV(chatKey(chatID, "roles"))
+	}
+	return store.SetKV(chatKey(chatID, "roles"), strings.Join(lines, "\n"))
+}
+
+// isChatAdmin asks Telegram whether user runs the group.
+func (a *App) isChatAdmin(chatID, userID int64) bool {
+	m, err := a.Bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		log.Printf("roles: chat %d member %d: %v", chatID, userID, err)
+		return false
+	}
+	return m.IsCreator() || m.IsAdministrator()
+}
+
+// permitted reports whether user may do what needs role in chatID.
+func (a *App) permitted(chatID int64, user *tgbotapi.User, need Role) bool {
+	if !isGroupChat(chatID) {
+		return true
+	}
+	rs, ok := loadRoles(a.Store, chatID)
+	if !ok {
+		return true
+	}
+	if user == nil {
+		return need <= rs.Default
+	}
+	r, listed := rs.Members[user.ID]
+	if !listed {
+		r = rs.Default
+	}
+	if r >= need {
+		return true
+	}
+	return isOwnerUser(user) || a.isChatAdmin(chatID, user.ID)
+}
+
+func (a *App) denied(chatID int64, need Role) {
+	lang := a.Store.Lang(chatID)
+	a.send(chatID, tr(lang, "role.denied", need.Label(lang)))
+}
+
+// permitMessage is the check for a message before it is handled.
+func (a *App) permitMessage(m *tgbotapi.Message) bool {
+	need := a.messageRole(m)
+	if a.permitted(m.Chat.ID, m.From, need) {
+		return true
+	}
+	a.denied(m.Chat.ID, need)
+	return false
+}
+
+// permitCallback is the check for a button press.
+func (a *App) permitCallback(cq *tgbotapi.CallbackQuery) bool {
+	need := callbackRole(cq.Data)
+	chatID := cq.From.ID // a button under an inline message has no chat
+	if cq.Message != nil {
+		chatID = cq.Message.Chat.ID
+	}
+	if a.permitted(chatID, cq.From, need) {
+		return true
+	}
+	lang := a.Store.Lang(chatID)
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "role.denied.button", need.Label(lang))))
+	return false
+}
+
+// handleRole handles "/role". Showing roles is open to all; changing them
+// is for owners.
+func (a *App) handleRole(m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	lang := a.Store.Lang(chatID)
+	if !isGroupChat(chatID) {
+		a.send(chatID, tr(lang, "role.private"))
+		return
+	}
+	args := strings.Fields(m.CommandArguments())
+	rs, set := loadRoles(a.Store, chatID)
+	if len(args) == 0 {
+		a.send(chatID, formatRoles(lang, rs, set))
+		return
+	}
+	if !a.permitted(chatID, m.From, RoleOwner) {
+		a.denied(chatID, RoleOwner)
+		return
+	}
+
+	switch {
+	case args[0] == "reset":
+		_ = a.Store.DeleteKV(chatKey(chatID, "role_default"))
+		if err := a.Store.DeleteKV(chatKey(chatID, "roles")); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "role.reset"))
+		return
+	case args[0] == "default" && len(args) == 2:
+		r, ok := parseRole(args[1])
+		if !ok || r == RoleOwner {
+			a.send(chatID, tr(lang, "role.default.usage"))
+			return
+		}
+		if err := a.Store.SetKV(chatKey(chatID, "role_default"), roleNames[r]); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "role.default.set", r.Label(lang)))
+		return
+	}
+
+	var userID int64
+	var name, roleArg string
+	switch {
+	case len(args) == 1 && m.ReplyToMessage != nil && m.ReplyToMessage.From != nil:
+		userID, name, roleArg = m.ReplyToMessage.From.ID, userDisplayName(m.ReplyToMessage.From), args[0]
+	case len(args) == 2:
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			a.send(chatID, tr(lang, "role.usage"))
+			return
+		}
+		userID, name, roleArg = id, rs.Names[id], args[1]
+	default:
+		a.send(chatID, tr(lang, "role.usage"))
+		return
+	}
+
+	if roleArg == "off" {
+		delete(rs.Members, userID)
+	} else {
+		r, ok := parseRole(roleArg)
+		if !ok {
+			a.send(chatID, tr(lang, "role.unknown"))
+			return
+		}
+		rs.Members[userID], rs.Names[userID] = r, name
+	}
+	if len(rs.Members) == 0 {
+		// a list emptied by "off" would otherwise turn the checks off
+		_ = a.Store.SetKV(chatKey(chatID, "role_default"), roleNames[rs.Default])
+	}
+	if err := saveRoles(a.Store, chatID, rs); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, formatRoles(lang, rs, true))
+}
+
+func formatRoles(lang string, rs chatRoles, set bool) string {
+	if !set {
+		return tr(lang, "roles.none")
+	}
+	if len(rs.Members) == 0 {
+		return tr(lang, "roles.default", rs.Default.Label(lang))
+	}
+	ids := make([]int64, 0, len(rs.Members))
+	for id := range rs.Members {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(x, y int64) int { return int(rs.Members[y]) - int(rs.Members[x]) })
+	var b strings.Builder
+	b.WriteString(tr(lang, "roles.header"))
+	for _, id := range ids {
+		name := rs.Names[id]
+		if name == "" {
+			name = strconv.FormatInt(id, 10)
+		}
+		fmt.Fprintf(&b, "\n%s — %s", name, rs.Members[id].Label(lang))
+	}
+	b.WriteString("\n" + tr(lang, "roles.rest", rs.Default.Label(lang)))
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Role
+		ok   bool
+	}{
+		{"owner", RoleOwner, true},
+		{" Editor ", RoleEditor, true},
+		{"VIEWER", RoleViewer, true},
+		{"владелец", RoleOwner, true},
+		{"Редактор", RoleEditor, true},
+		{"читатель", RoleViewer, true},
+		{"admin", 0, false},
+		{"off", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRole(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRole(%q) = %v, %v; want %v, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCommandRole(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want Role
+	}{
+		{"list", RoleViewer},
+		{"покупки", RoleViewer},
+		{"today", RoleViewer},
+		{"role", RoleViewer},
+		{"language", RoleOwner},
+		{"clear", RoleOwner},
+		{"encrypt", RoleOwner},
+		{"restore", RoleOwner},
+		{"archive", RoleViewer},
+		{"route", RoleOwner},
+		{"maint", RoleOwner},
+		{"done", RoleEditor},
+		{"undo", RoleEditor},
+		{"someplugincommand", RoleEditor},
+		// aliases from handleCommand
+		{"menu", RoleViewer},
+		{"shopping", RoleViewer},
+		{"reminders", RoleViewer},
+		{"tz", RoleOwner},
+		{"dnd", RoleOwner},
+		{"maintenance", RoleOwner},
+		{"ctx", RoleEditor},
+		{"bills", RoleEditor},
+		{"cards", RoleEditor},
+		{"recipes", RoleEditor},
+		{"meals", RoleEditor},
+	}
+	for _, tt := range tests {
+		if got := commandRole(tt.cmd); got != tt.want {
+			t.Errorf("commandRole(%q) = %v, want %v", tt.cmd, got, tt.want)
+		}
+	}
+	for alias, name := range commandAliases {
+		if commandRole(alias) != commandRole(name) {
+			t.Errorf("commandRole(%q) = %v, but /%s is %v", alias, commandRole(alias), name, commandRole(name))
+		}
+	}
+}
+
+func TestIsOwnerUser(t *testing.T) {
+	t.Setenv("CHAT_ID", "-100")
+	t.Setenv("OWNER_ID", "")
+	if isOwnerUser(&tgbotapi.User{ID: 7}) {
+		t.Error("a CHAT_ID member counted as owner without OWNER_ID")
+	}
+	t.Setenv("OWNER_ID", "7")
+	if !isOwnerUser(&tgbotapi.User{ID: 7}) {
+		t.Error("OWNER_ID not counted as owner")
+	}
+	if isOwnerUser(&tgbotapi.User{ID: 8}) || isOwnerUser(nil) {
+		t.Error("someone else counted as owner")
+	}
+}
+
+func TestCallbackRole(t *testing.T) {
+	tests := []struct {
+		data string
+		want Role
+	}{
+		{"pg:tasks:2", RoleViewer},
+		{"arch:tasks:1", RoleViewer},
+		{"reveal:12", RoleViewer},
+		{"card:12", RoleViewer},
+		{"goto:12", RoleViewer},
+		{"thread:12", RoleViewer},
+		{"lang:en", RoleOwner},
+		{"dg:weather", RoleOwner},
+		{"done:12", RoleEditor},
+		{"del:12", RoleEditor},
+		{"snz:12:1h", RoleEditor},
+	}
+	for _, tt := range tests {
+		if got := callbackRole(tt.data); got != tt.want {
+			t.Errorf("callbackRole(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestRoleLabel(t *testing.T) {
+	if got := RoleViewer.Label(LangRU); got != "читатель" {
+		t.Errorf("ru viewer = %q", got)
+	}
+	if got := RoleOwner.Label(LangEN); got != "owner" {
+		t.Errorf("en owner = %q", got)
+	}
+	// every role name parses back to its role
+	for r, name := range roleNames {
+		if got, ok := parseRole(name); !ok || got != r {
+			t.Errorf("parseRole(%q) = %v, %v; want %v", name, got, ok, r)
+		}
+		if got, ok := parseRole(r.Label(LangRU)); !ok || got != r {
+			t.Errorf("parseRole(%q) = %v, %v; want %v", r.Label(LangRU), got, ok, r)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// RoutingClient estimates travel time from home to an address.
+type RoutingClient interface {
+	TravelTime(ctx context.Context, from GeoPoint, to string) (time.Duration, error)
+}
+
+var ErrRoutingNotConfigured = errors.New("routing not configured")
+
+// osrmClient geocodes the destination with Nominatim and asks an OSRM
+// server for the route duration.
+type osrmClient struct {
+	enabled      bool
+	osrmURL      string
+	nominatimURL string
+	profile      string
+	http         *http.Client
+}
+
+func NewRoutingClientFromEnv() (RoutingClient, error) {
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("ROUTING_DISABLED")), "true") {
+		return &osrmClient{enabled: false}, nil
+	}
+	if _, ok := geoFromEnv(); !ok {
+		return &osrmClient{enabled: false}, nil
+	}
+	return &osrmClient{
+		enabled:      true,
+		osrmURL:      strings.TrimRight(envOr("OSRM_URL", "https://router.project-osrm.org"), "/"),
+		nominatimURL: strings.TrimRight(envOr("NOMINATIM_URL", "https://nominatim.openstreetmap.org"), "/"),
+		profile:      envOr("ROUTING_PROFILE", "driving"),
+		http:         &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (c *osrmClient) getJSON(ctx context.Context, u string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "gtdBot/1.0")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *osrmClient) geocode(ctx context.Context, address string) (GeoPoint, error) {
+	var res []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	q := url.Values{"q": {address}, "format": {"json"}, "limit": {"1"}}
+	if err := c.getJSON(ctx, c.nominatimURL+"/search?"+q.Encode(), &res); err != nil {
+		return GeoPoint{}, err
+	}
+	if len(res) == 0 {
+		return GeoPoint{}, fmt.Errorf("address not found: %s", address)
+	}
+	lat, err1 := strconv.ParseFloat(res[0].Lat, 64)
+	lon, err2 := strconv.ParseFloat(res[0].Lon, 64)
+	if err1 != nil || err2 != nil {
+		return GeoPoint{}, fmt.Errorf("bad geocode result for %s", address)
+	}
+	return GeoPoint{Lat: lat, Lon: lon}, nil
+}
+
+func (c *osrmClient) TravelTime(ctx context.Context, from GeoPoint, to string) (time.Duration, error) {
+	if !c.enabled {
+		return 0, ErrRoutingNotConfigured
+	}
+	dst, err := c.geocode(ctx, to)
+	if err != nil {
+		return 0, err
+	}
+
+	var res struct {
+		Code   string `json:"code"`
+		Routes []struct {
+			Duration float64 `json:"duration"`
+		} `json:"routes"`
+	}
+	u := fmt.Sprintf("%s/route/v1/%s/%f,%f;%f,%f?overview=false", c.osrmURL, c.profile, from.Lon, from.Lat, dst.Lon, dst.Lat)
+	if err := c.getJSON(ctx, u, &res); err != nil {
+		return 0, err
+	}
+	if res.Code != "Ok" || len(res.Routes) == 0 {
+		return 0, fmt.Errorf("osrm: no route (%s)", res.Code)
+	}
+	return time.Duration(res.Routes[0].Duration) * time.Second, nil
+}
+
+func eventReminderLead() time.Duration {
+	n, err := strconv.Atoi(envOr("EVENT_REMINDER_MINUTES", "30"))
+	if err != nil || n <= 0 {
+		n = 30
+	}
+	return time.Duration(n) * time.Minute
+}
+
+func commuteBuffer() time.Duration {
+	n, err := strconv.Atoi(envOr("COMMUTE_BUFFER_MINUTES", "10"))
+	if err != nil || n < 0 {
+		n = 10
+	}
+	return time.Duration(n) * time.Minute
+}
+
+// leaveTime returns when to leave for an event with a location. Routing
+// results are cached per event for the lifetime of the scheduler.
+func (s *Scheduler) leaveTime(ctx context.Context, ev CalendarEvent) (time.Time, time.Duration, bool) {
+	if ev.Location == "" || !s.hasGeo {
+		return time.Time{}, 0, false
+	}
+	d, ok := s.travelCache[ev.ID]
+	if !ok {
+		var err error
+		d, err = s.routing.TravelTime(ctx, s.geo, ev.Location)
+		if err != nil {
+			if !errors.Is(err, ErrRoutingNotConfigured) {
+				log.Printf("scheduler: routing %q: %v", ev.Location, err)
+			}
+			return time.Time{}, 0, false
+		}
+		s.travelCache[ev.ID] = d
+	}
+	return ev.Start.Add(-d - commuteBuffer()), d, true
+}
+
+func roundUpMinutes(d time.Duration) int {
+	return int((d + time.Minute - 1) / time.Minute)
+}
+
+// sendEventReminders warns about upcoming events. For events with a
+// location the reminder moves earlier by the estimated travel time and
+// says when to leave.
+func (s *Scheduler) sendEventReminders(ctx context.Context, chatID int64, now time.Time) {
+	events, err := s.calendar.ListEvents(ctx, now, now.Add(4*time.Hour))
+	if errors.Is(err, ErrCalendarNotConfigured) {
+		return
+	}
+	if err != nil {
+		log.Printf("scheduler: list events error: %v", err)
+		return
+	}
+
+	lead := eventReminderLead()
+	for _, ev := range events {
+		if ev.AllDay {
+			continue
+		}
+		key := chatKey(chatID, "evrem:"+ev.ID+":"+ev.Start.UTC().Format(time.RFC3339))
+		if _, done, _ := s.store.GetKV(key); done {
+			continue
+		}
+
+		fireAt := ev.Start.Add(-lead)
+		leave, travel, hasLeave := s.leaveTime(ctx, ev)
+		if hasLeave {
+			fireAt = leave.Add(-15 * time.Minute)
+		}
+		if now.Before(fireAt) {
+			continue
+		}
+
+		lang := s.store.Lang(chatID)
+		text := tr(lang, "event.soon", ev.Summary, ev.Start.In(now.Location()).Format("15:04"))
+		if ev.Location != "" {
+			text += "\n📍 " + ev.Location
+		}
+		if hasLeave {
+			text += "\n🚗 " + tr(lang, "event.leave", roundUpMinutes(travel), leave.In(now.Location()).Format("15:04"))
+		}
+		// A failed send is dead-lettered; don't queue it again next tick
+		_ = s.deliver("event_reminder", tgbotapi.NewMessage(chatID, text))
+		_ = s.store.SetKV(key, now.UTC().Format(time.RFC3339))
+	}
+}
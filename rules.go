@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rules are per-chat automations written in a small DSL:
+//
+//	если текст содержит 'купить' → список=покупки
+//	если добавлено после 22:00 → молча
+//	если список корзина и старше 7 дней → список=когда-нибудь
+//
+// Rules without "старше" run on capture; rules with it run hourly over the
+// active items. They are stored parsed, as JSON, under chat:<id>:rules.
+
+const maxRules = 50
+
+type Rule struct {
+	Source    string   `json:"source"`
+	Contains  []string `json:"contains,omitempty"` // normalized
+	Topic     string   `json:"topic,omitempty"`
+	After     string   `json:"after,omitempty"`  // HH:MM, inclusive
+	Before    string   `json:"before,omitempty"` // HH:MM, exclusive
+	OlderDays int      `json:"older_days,omitempty"`
+
+	SetTopic string `json:"set_topic,omitempty"`
+	Silent   bool   `json:"silent,omitempty"`
+	Flag     bool   `json:"flag,omitempty"`
+}
+
+var (
+	ruleContainsRe = regexp.MustCompile(`(?i)^(?:текст\s+содержит|text\s+contains)\s+['"«](.+)['"»]$`)
+	ruleClockRe    = regexp.MustCompile(`^(?:добавлено\s+|added\s+)?(после|до|after|before)\s+(\d{1,2}:\d{2})$`)
+	ruleTopicRe    = regexp.MustCompile(`^(?:список|topic)\s*(?:=\s*|\s+)(.+)$`)
+	ruleOlderRe    = regexp.MustCompile(`^(?:старше|older\s+than)\s+(\d+)\s*(?:дн\S*|день|days?|d)$`)
+)
+
+// splitOutside splits s on sep, ignoring separators inside quotes.
+func splitOutside(s, sep string) []string {
+	var out []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"':
+			quoted = !quoted
+			continue
+		}
+		if !quoted && strings.HasPrefix(s[i:], sep) {
+			out = append(out, s[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(out, s[start:])
+}
+
+// parseRule parses one rule. topicOf resolves list names the way the
+// keyboard does, so renamed lists work too.
+func parseRule(lang, src string, topicOf func(string) (string, bool)) (Rule, error) {
+	src = strings.TrimSpace(src)
+	r := Rule{Source: src}
+	sep := "→"
+	if !strings.Contains(src, sep) {
+		sep = "->"
+	}
+	parts := splitOutside(src, sep)
+	if len(parts) != 2 {
+		return r, errors.New(tr(lang, "rule.err.arrow"))
+	}
+
+	cond := strings.TrimSpace(parts[0])
+	for _, w := range []string{"если ", "if "} {
+		if strings.HasPrefix(strings.ToLower(cond), w) {
+			cond = strings.TrimSpace(cond[len(w):])
+		}
+	}
+	var conds []string
+	for _, c := range splitOutside(cond, " и ") {
+		conds = append(conds, splitOutside(c, " and ")...)
+	}
+	for _, c := range conds {
+		c = strings.TrimSpace(c)
+		lc := strings.ToLower(c)
+		switch {
+		case ruleContainsRe.MatchString(c):
+			word := normalizeText(ruleContainsRe.FindStringSubmatch(c)[1])
+			if word == "" {
+				return r, errors.New(tr(lang, "rule.err.empty", c))
+			}
+			r.Contains = append(r.Contains, word)
+		case ruleClockRe.MatchString(lc):
+			m := ruleClockRe.FindStringSubmatch(lc)
+			t, err := time.Parse("15:04", m[2])
+			if err != nil {
+				return r, errors.New(tr(lang, "rule.err.time", c))
+			}
+			if m[1] == "после" || m[1] == "after" {
+				r.After = t.Format("15:04")
+			} else {
+				r.Before = t.Format("15:04")
+			}
+		case ruleOlderRe.MatchString(lc):
+			n, _ := strconv.Atoi(ruleOlderRe.FindStringSubmatch(lc)[1])
+			if n <= 0 {
+				return r, errors.New(tr(lang, "rule.err.older", c))
+			}
+			r.OlderDays = n
+		case ruleTopicRe.MatchString(lc):
+			topic, ok := topicOf(ruleTopicRe.FindStringSubmatch(lc)[1])
+			if !ok {
+				return r, errors.New(tr(lang, "rule.err.topic", c))
+			}
+			r.Topic = topic
+		default:
+			return r, errors.New(tr(lang, "rule.err.cond", c))
+		}
+	}
+
+	for _, act := range strings.Split(parts[1], ",") {
+		act = strings.TrimSpace(act)
+		switch lc := strings.ToLower(act); {
+		case lc == "молча" || lc == "тихо" || lc == "silent":
+			r.Silent = true
+		case lc == "пометить" || lc == "flag":
+			r.Flag = true
+		case ruleTopicRe.MatchString(lc):
+			topic, ok := topicOf(ruleTopicRe.FindStringSubmatch(lc)[1])
+			if !ok {
+				return r, errors.New(tr(lang, "rule.err.topic", act))
+			}
+			r.SetTopic = topic
+		default:
+			return r, errors.New(tr(lang, "rule.err.action", act))
+		}
+	}
+	if r.SetTopic == "" && !r.Silent && !r.Flag {
+		return r, errors.New(tr(lang, "rule.err.noaction"))
+	}
+	if r.OlderDays > 0 && r.SetTopic == "" && !r.Flag {
+		return r, errors.New(tr(lang, "rule.err.scheduled"))
+	}
+	return r, nil
+}
+
+// scheduled reports whether the rule runs hourly instead of on capture.
+func (r Rule) scheduled() bool {
+	return r.OlderDays > 0
+}
+
+// match checks an item created at `at` (in the chat's zone) as of now.
+func (r Rule) match(topic, text string, at, now time.Time) bool {
+	if r.Topic != "" && r.Topic != topic {
+		return false
+	}
+	norm := " " + normalizeText(text) + " "
+	for _, w := range r.Contains {
+		if !strings.Contains(norm, w) {
+			return false
+		}
+	}
+	clock := at.Format("15:04")
+	if r.After != "" && clock < r.After {
+		return false
+	}
+	if r.Before != "" && clock >= r.Before {
+		return false
+	}
+	if r.OlderDays > 0 && now.Sub(at) < time.Duration(r.OlderDays)*24*time.Hour {
+		return false
+	}
+	return true
+}
+
+func (s *sqlStore) Rules(chatID int64) ([]Rule, error) {
+	v, ok, err := s.GetKV(chatKey(chatID, "rules"))
+	if err != nil || !ok {
+		return nil, err
+	}
+	var rules []Rule
+	err = json.Unmarshal([]byte(v), &rules)
+	return rules, err
+}
+
+func (s *sqlStore) SetRules(chatID int64, rules []Rule) error {
+	if len(rules) == 0 {
+		return s.DeleteKV(chatKey(chatID, "rules"))
+	}
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	return s.SetKV(chatKey(chatID, "rules"), string(b))
+}
+
+// ruleOutcome is what the capture rules decided for one message.
+type ruleOutcome struct {
+	Topic  string
+	Silent bool
+	Flag   bool
+}
+
+// captureRules runs the chat's capture rules over a new message; later
+// rules win on topic.
+func (a *App) captureRules(chatID int64, topic, text string) ruleOutcome {
+	out := ruleOutcome{Topic: topic}
+	rules, err := a.Store.Rules(chatID)
+	if err != nil {
+		log.Printf("rules error: %v", err)
+		return out
+	}
+	now := time.Now().In(a.tz(chatID))
+	for _, r := range rules {
+		if r.scheduled() || !r.match(topic, text, now, now) {
+			continue
+		}
+		if r.SetTopic != "" {
+			out.Topic = r.SetTopic
+		}
+		out.Silent = out.Silent || r.Silent
+		out.Flag = out.Flag || r.Flag
+	}
+	return out
+}
+
+// applyRules runs the chat's scheduled rules over its active items.
+func (s *Scheduler) applyRules(chatID int64, now time.Time) {
+	rules, err := s.store.Rules(chatID)
+	if err != nil {
+		log.Printf("scheduler: rules error: %v", err)
+		return
+	}
+	loc := s.location(chatID, now)
+	changed := 0
+	for _, topic := range keyboardTopics {
+		items, err := s.store.ListActive(chatID, topic)
+		if err != nil {
+			log.Printf("scheduler: rules list error: %v", err)
+			return
+		}
+		for _, it := range items {
+			for _, r := range rules {
+				if !r.scheduled() || !r.match(it.Topic, it.Text, it.CreatedAt.In(loc), now) {
+					continue
+				}
+				if r.Flag && !it.Flagged {
+					if err := s.store.FlagItem(chatID, it.ID); err != nil {
+						log.Printf("scheduler: rule flag error: %v", err)
+						continue
+					}
+					it.Flagged = true
+					changed++
+				}
+				if r.SetTopic != "" && r.SetTopic != it.Topic {
+					if err := s.store.MoveItem(chatID, it.ID, r.SetTopic); err != nil {
+						log.Printf("scheduler: rule move error: %v", err)
+						continue
+					}
+					it.Topic = r.SetTopic
+					changed++
+				}
+			}
+		}
+	}
+	if changed > 0 {
+		log.Printf("scheduler: rules changed %d item(s) in chat %d", changed, chatID)
+	}
+}
+
+// handleRules handles "/rules", "/rules add <правило>" and "/rules del <n>".
+func (a *App) handleRules(chatID int64, arg string) {
+	rules, err := a.Store.Rules(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+	cmd, rest, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch strings.ToLower(cmd) {
+	case "":
+		if len(rules) == 0 {
+			a.send(chatID, tr(lang, "rules.none")+"\n\n"+tr(lang, "rules.help"))
+			return
+		}
+		var b strings.Builder
+		b.WriteString(tr(lang, "rules.header") + "\n")
+		for i, r := range rules {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, r.Source)
+		}
+		b.WriteString("\n" + tr(lang, "rules.help"))
+		a.send(chatID, b.String())
+	case "add":
+		if len(rules) >= maxRules {
+			a.send(chatID, tr(lang, "rules.max", maxRules))
+			return
+		}
+		r, err := parseRule(lang, rest, func(name string) (string, bool) { return a.topicFromButton(chatID, name) })
+		if err != nil {
+			a.send(chatID, tr(lang, "rule.err", err)+"\n\n"+tr(lang, "rules.help"))
+			return
+		}
+		if err := a.Store.SetRules(chatID, append(rules, r)); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "rule.added", len(rules)+1))
+	case "del":
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 1 || n > len(rules) {
+			a.send(chatID, tr(lang, "rule.notfound"))
+			return
+		}
+		if err := a.Store.SetRules(chatID, append(rules[:n-1:n-1], rules[n:]...)); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "rule.deleted"))
+	default:
+		a.send(chatID, tr(lang, "rules.help"))
+	}
+}
@@ -2,11 +2,8 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
-	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -14,24 +11,52 @@ import (
 
 type Scheduler struct {
 	bot      *tgbotapi.BotAPI
-	store    *Store
+	store    Store
 	calendar CalendarClient
+	digest   *Digest
+	routing  RoutingClient
+	media    *MediaManager
+	events   *EventBus
 	tz       *time.Location
 
-	reminderTimes []string // HH:MM in tz
+	reminderTimes []string // HH:MM in tz, or sunrise/sunset with offset
 	wipeTime      string   // HH:MM
 	morningTime   string   // HH:MM
+	backupTime    string   // HH:MM, "" = no nightly backup
+	backupS3Time  string   // HH:MM, "" = no snapshots in S3
+
+	prepRules []PrepRule
+	geo       GeoPoint
+	hasGeo    bool
+
+	travelCache map[string]time.Duration // event id -> travel time, loop goroutine only
+
+	peers []*Scheduler // extra bots ticked by this loop, see Attach
+
+	done chan struct{} // closed when the loop returns
 }
 
-func NewScheduler(bot *tgbotapi.BotAPI, store *Store, cal CalendarClient, tz *time.Location) *Scheduler {
+func NewScheduler(bot *tgbotapi.BotAPI, store Store, cal CalendarClient, digest *Digest, routing RoutingClient, media *MediaManager, events *EventBus, tz *time.Location) *Scheduler {
+	geo, hasGeo := geoFromEnv()
 	return &Scheduler{
 		bot:           bot,
 		store:         store,
 		calendar:      cal,
+		digest:        digest,
+		routing:       routing,
+		media:         media,
+		events:        events,
 		tz:            tz,
-		reminderTimes: []string{"08:00", "10:00", "14:00", "19:00", "23:00"},
-		wipeTime:      "03:00",
+		reminderTimes: parseTimeList(envOr("REMINDER_TIMES", "08:00,10:00,14:00,19:00,23:00")),
+		wipeTime:      envOr("WIPE_TIME", "03:00"),
 		morningTime:   envOr("MORNING_TIME", "08:00"),
+		backupTime:    envOr("BACKUP_TIME", ""),
+		backupS3Time:  envOr("BACKUP_S3_TIME", ""),
+		prepRules:     prepRulesFromEnv(),
+		geo:           geo,
+		hasGeo:        hasGeo,
+		travelCache:   map[string]time.Duration{},
+		done:          make(chan struct{}),
 	}
 }
 
@@ -39,8 +64,26 @@ func (s *Scheduler) Start(ctx context.Context) {
 	go s.loop(ctx)
 }
 
+// Done is closed once the loop has stopped after ctx is cancelled; a tick
+// in progress runs to the end of the current chat first.
+func (s *Scheduler) Done() <-chan struct{} {
+	return s.done
+}
+
+// Attach has s's loop also run p, an extra bot's scheduler. The backups
+// stay with s: they already cover every bot's database.
+func (s *Scheduler) Attach(p *Scheduler) {
+	p.backupTime, p.backupS3Time = "", ""
+	s.peers = append(s.peers, p)
+}
+
 func (s *Scheduler) loop(ctx context.Context) {
-	lastFired := map[string]string{} // key=kind:time -> date
+	defer close(s.done)
+	all := append([]*Scheduler{s}, s.peers...)
+	fired := make([]map[string]string, len(all)) // key=[chat:]kind:time -> date
+	for i := range fired {
+		fired[i] = map[string]string{}
+	}
 
 	ticker := time.NewTicker(15 * time.Second)
 	defer ticker.Stop()
@@ -50,67 +93,140 @@ func (s *Scheduler) loop(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			now := time.Now().In(s.tz)
-			hhmm := now.Format("15:04")
-			today := now.Format("2006-01-02")
-
-			// Morning digest
-			if hhmm == s.morningTime && lastFired["morning:"+hhmm] != today {
-				lastFired["morning:"+hhmm] = today
-				s.sendMorningDigest(ctx, now)
-			}
-
-			// Reminders
-			for _, t := range s.reminderTimes {
-				if hhmm == t && lastFired["reminders:"+t] != today {
-					lastFired["reminders:"+t] = today
-					s.sendReminders(now)
+			now := time.Now()
+			for i, sc := range all {
+				lastFired := fired[i]
+				sc.tickGlobal(ctx, now.In(sc.tz), lastFired)
+				for _, chatID := range sc.chats() {
+					if ctx.Err() != nil {
+						return
+					}
+					sc.tickChat(ctx, chatID, now.In(sc.location(chatID, now)), lastFired)
 				}
 			}
-
-			// Night wipe
-			if hhmm == s.wipeTime && lastFired["wipe:"+hhmm] != today {
-				lastFired["wipe:"+hhmm] = today
-				s.wipeReminders(now)
-			}
 		}
 	}
 }
 
-func (s *Scheduler) targetChatID() (int64, bool) {
-	raw := strings.TrimSpace(os.Getenv("CHAT_ID"))
-	if raw == "" {
-		return 0, false
+// once reports whether key hasn't fired with val yet, and records it.
+func once(lastFired map[string]string, key, val string) bool {
+	if lastFired[key] == val {
+		return false
 	}
-	id, err := strconv.ParseInt(raw, 10, 64)
-	if err != nil {
-		return 0, false
+	lastFired[key] = val
+	return true
+}
+
+// tickGlobal runs the jobs that span all chats, in the home timezone.
+func (s *Scheduler) tickGlobal(ctx context.Context, now time.Time, lastFired map[string]string) {
+	hhmm := now.Format("15:04")
+	today := now.Format("2006-01-02")
+
+	if hhmm == s.morningTime && once(lastFired, "morning:"+hhmm, today) {
+		s.notifyExpiringPremium(now)
+		if now.Weekday() == time.Monday {
+			s.sendLeaderboards(now)
+		}
+	}
+
+	if hhmm == s.wipeTime && once(lastFired, "wipe:"+hhmm, today) {
+		s.compactHistory(now)
+		if err := s.media.Cleanup(ctx); err != nil {
+			log.Printf("scheduler: media cleanup error: %v", err)
+		}
+	}
+
+	if s.backupTime != "" && hhmm == s.backupTime && once(lastFired, "backup:"+hhmm, today) {
+		s.backupDatabase(ctx, now)
+	}
+	if s.backupS3Time != "" && hhmm == s.backupS3Time && once(lastFired, "backup_s3:"+hhmm, today) {
+		s.backupToS3(ctx, now)
 	}
-	return id, true
 }
 
-func (s *Scheduler) sendMorningDigest(ctx context.Context, now time.Time) {
-	chatID, ok := s.targetChatID()
-	if !ok {
-		log.Printf("scheduler: CHAT_ID not set; skipping morning digest")
-		return
+// tickChat runs one chat's jobs in that chat's current timezone. Calendar
+// jobs only run for CHAT_ID.
+func (s *Scheduler) tickChat(ctx context.Context, chatID int64, now time.Time, lastFired map[string]string) {
+	hhmm := now.Format("15:04")
+	today := now.Format("2006-01-02")
+	key := func(k string) string { return strconv.FormatInt(chatID, 10) + ":" + k }
+	home, _ := homeChatID()
+	isHome := chatID == home
+
+	// Morning digest
+	if hhmm == s.morningTime && once(lastFired, key("morning:"+hhmm), today) {
+		s.sendMorningDigest(ctx, chatID, now)
+		s.sendBillReminders(chatID, now)
+		s.sendMaintenanceReminders(chatID, now)
+		if isHome {
+			s.createPrepTasks(ctx, chatID, now)
+		}
+		if now.Day() == 1 {
+			s.sendSomedayReview(chatID, now)
+			s.sendGoalsReport(chatID, now)
+			s.sendMonthlyRetro(chatID, now)
+			s.sendBillsReport(chatID, now)
+			s.sendMoodReport(chatID, now)
+		}
+		if now.Weekday() == time.Monday {
+			s.sendBasketNudge(chatID, now)
+		}
 	}
 
-	text, err := s.calendar.GetTodaySchedule(ctx, now)
-	if err != nil {
-		text = fmt.Sprintf("Ошибка чтения календаря: %v", err)
+	// Timed reminders and saved views, checked every minute
+	if once(lastFired, key("minute"), today+" "+hhmm) {
+		s.flushQuiet(chatID)
+		s.endFocus(chatID, now)
+		s.sendTimedReminders(chatID, now)
+		s.sendScheduledViews(chatID, now, hhmm)
+		s.sendJournalPrompt(chatID, hhmm)
+		s.sendMoodCheckin(chatID, now, hhmm)
+
+		// Scheduled rules ("старше N дней"), hourly
+		if now.Minute() == 0 {
+			s.applyRules(chatID, now)
+		}
+
+		// Upcoming calendar events, every 5 minutes
+		if isHome && now.Minute()%5 == 0 {
+			s.sendEventReminders(ctx, chatID, now)
+		}
 	}
 
-	msg := tgbotapi.NewMessage(chatID, "РАСПИСАНИЕ НА СЕГОДНЯ:\n"+text)
-	_, _ = s.bot.Send(msg)
+	// Reminders
+	for _, spec := range s.reminderTimes {
+		t, err := resolveTimeSpec(spec, now, s.geo, s.hasGeo)
+		if err != nil {
+			if once(lastFired, "reminders-err:"+spec, today) {
+				log.Printf("scheduler: reminder time %s: %v", spec, err)
+			}
+			continue
+		}
+		if hhmm == t && once(lastFired, key("reminders:"+spec), today) {
+			s.sendReminders(chatID, now)
+		}
+	}
+
+	// Night wipe
+	if hhmm == s.wipeTime && once(lastFired, key("wipe:"+hhmm), today) {
+		s.wipeReminders(chatID, now)
+		s.purgeArchive(chatID, now)
+	}
 }
 
-func (s *Scheduler) sendReminders(now time.Time) {
-	chatID, ok := s.targetChatID()
-	if !ok {
-		log.Printf("scheduler: CHAT_ID not set; skipping reminders")
+func (s *Scheduler) sendMorningDigest(ctx context.Context, chatID int64, now time.Time) {
+	text := s.digest.Compose(ctx, chatID, now)
+	if text == "" {
 		return
 	}
+	if s.deliver("digest", tgbotapi.NewMessage(chatID, text)) == nil {
+		s.events.Publish(Event{Kind: EventDigestSent, ChatID: chatID, Text: text, At: now})
+	}
+	s.sendDigestToChannel(chatID, text)
+}
+
+func (s *Scheduler) sendReminders(chatID int64, now time.Time) {
+	s.sendOverdue(chatID, now)
 
 	items, err := s.store.ListActive(chatID, TopicReminders)
 	if err != nil {
@@ -121,27 +237,56 @@ func (s *Scheduler) sendReminders(now time.Time) {
 		return
 	}
 
-	// One message per reminder with ✅ delete button
+	// One message per reminder with ✅ delete button (see reminderMessage).
+	// Timed reminders get their own ping instead; snoozed ones wait.
+	lang := s.store.Lang(chatID)
+	f, focused := activeFocus(s.store, chatID, now)
 	for _, it := range items {
-		msg := tgbotapi.NewMessage(chatID, formatSingleItem(TopicReminders, it))
-		msg.ReplyMarkup = singleKeyboard(it.ID)
-		_, _ = s.bot.Send(msg)
+		if at, err := s.store.RemindAt(chatID, it.ID); err == nil && !at.IsZero() {
+			continue
+		}
+		if focused && !f.Matches(it.Text) {
+			continue
+		}
+		if snoozed(s.store, chatID, it.ID, now) {
+			continue
+		}
+		if s.deliver("reminder", reminderMessage(s.store, chatID, it, lang)) == nil {
+			s.events.Publish(Event{Kind: EventReminderSent, ChatID: chatID, ItemID: it.ID, Topic: TopicReminders, Text: it.Text, At: now})
+		}
 	}
 }
 
-func (s *Scheduler) wipeReminders(now time.Time) {
-	chatID, ok := s.targetChatID()
-	if !ok {
-		log.Printf("scheduler: CHAT_ID not set; skipping wipe")
+func (s *Scheduler) wipeReminders(chatID int64, now time.Time) {
+	// Chats without reminders don't need the nightly notice
+	if items, err := s.store.ListActive(chatID, TopicReminders); err != nil || len(items) == 0 {
 		return
 	}
-
-	_, err := s.store.DB.Exec(`DELETE FROM items WHERE chat_id=? AND topic=?`, chatID, TopicReminders)
-	if err != nil {
+	if err := s.store.WipeReminders(chatID, now); err != nil {
 		log.Printf("scheduler: wipe reminders error: %v", err)
 		return
 	}
 
-	msg := tgbotapi.NewMessage(chatID, "НАПОМИНАНИЯ ОЧИЩЕНЫ (ночной вайп).")
-	_, _ = s.bot.Send(msg)
+	s.send("wipe", tgbotapi.NewMessage(chatID, tr(s.store.Lang(chatID), "wipe.done")))
+}
+
+func (s *Scheduler) notifyExpiringPremium(now time.Time) {
+	if !paymentsEnabled() {
+		return
+	}
+
+	list, err := s.store.ExpiringEntitlements(now.Add(3 * 24 * time.Hour))
+	if err != nil {
+		log.Printf("scheduler: list entitlements error: %v", err)
+		return
+	}
+	for _, e := range list {
+		lang := s.store.Lang(e.ChatID)
+		text := tr(lang, "premium.expiring", e.ExpiresAt.In(chatTimezone(s.store, e.ChatID, s.tz)).Format("2006-01-02"))
+		if !e.ExpiresAt.After(now) {
+			text = tr(lang, "premium.expired")
+		}
+		_ = s.deliver("premium", tgbotapi.NewMessage(e.ChatID, text))
+		_ = s.store.MarkEntitlementNotified(e.ChatID, now)
+	}
 }
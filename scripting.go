@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+	"go.starlark.net/syntax"
+)
+
+// Per-chat Starlark scripts. A script may define any of:
+//
+//	def on_capture(text, topic): return text  # rewrite captured text
+//	def digest(items): return "..."           # "script" digest section
+//	def on_event(event): return "..."         # reply to item events
+//
+// Scripts have no load(), no I/O and no while/recursion. Each call is
+// bounded by SCRIPT_MAX_STEPS and SCRIPT_TIMEOUT_MS; calls of one chat run
+// one at a time. SCRIPT_MAX_MEM_MB is best-effort: Go can't count one
+// goroutine's allocations, so a call is stopped when the whole process has
+// allocated that much since it began. The bot's other work counts too, and
+// a big allocation between two samples is only caught after it is made.
+
+const maxScriptSize = 16 << 10
+
+var scriptFileOptions = &syntax.FileOptions{Set: true, TopLevelControl: true, GlobalReassign: true}
+
+type ScriptRunner struct {
+	store    Store
+	maxSteps uint64
+	timeout  time.Duration
+	maxMem   uint64
+
+	mu    sync.Mutex
+	chats map[int64]*sync.Mutex
+}
+
+func envUint(key string, def uint64) uint64 {
+	n, err := strconv.ParseUint(envOr(key, ""), 10, 64)
+	if err != nil || n == 0 {
+		return def
+	}
+	return n
+}
+
+func newScriptRunnerFromEnv(store Store) *ScriptRunner {
+	return &ScriptRunner{
+		store:    store,
+		maxSteps: envUint("SCRIPT_MAX_STEPS", 1_000_000),
+		timeout:  time.Duration(envUint("SCRIPT_TIMEOUT_MS", 500)) * time.Millisecond,
+		maxMem:   envUint("SCRIPT_MAX_MEM_MB", 32) << 20,
+		chats:    map[int64]*sync.Mutex{},
+	}
+}
+
+// lock serializes the calls of one chat's script.
+func (r *ScriptRunner) lock(chatID int64) (unlock func()) {
+	r.mu.Lock()
+	m, ok := r.chats[chatID]
+	if !ok {
+		m = &sync.Mutex{}
+		r.chats[chatID] = m
+	}
+	r.mu.Unlock()
+	m.Lock()
+	return m.Unlock
+}
+
+func (r *ScriptRunner) source(chatID int64) (string, bool) {
+	src, ok, err := r.store.GetKV(chatKey(chatID, "script"))
+	if err != nil {
+		log.Printf("script: chat %d: %v", chatID, err)
+	}
+	return src, ok && src != ""
+}
+
+func heapAllocs() uint64 {
+	s := []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s[0].Value.Uint64()
+}
+
+// run executes src and then, if defined, calls fn. Output of print() is
+// collected in out. ok is false when the script doesn't define fn.
+func (r *ScriptRunner) run(chatID int64, src, fn string, args ...starlark.Value) (v starlark.Value, out string, ok bool, err error) {
+	defer r.lock(chatID)()
+
+	var printed strings.Builder
+	thread := &starlark.Thread{
+		Name:  fmt.Sprintf("chat %d", chatID),
+		Print: func(_ *starlark.Thread, msg string) { printed.WriteString(msg + "\n") },
+	}
+	thread.SetMaxExecutionSteps(r.maxSteps)
+
+	start := heapAllocs()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		deadline := time.NewTimer(r.timeout)
+		defer deadline.Stop()
+		tick := time.NewTicker(5 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-deadline.C:
+				thread.Cancel("time limit exceeded")
+				return
+			case <-tick.C:
+				if heapAllocs()-start > r.maxMem {
+					thread.Cancel("memory limit exceeded")
+					return
+				}
+			}
+		}
+	}()
+
+	predeclared := starlark.StringDict{"json": starlarkjson.Module}
+	globals, err := starlark.ExecFileOptions(scriptFileOptions, thread, "script.star", src, predeclared)
+	if err != nil {
+		return nil, printed.String(), false, err
+	}
+	if fn == "" {
+		return starlark.None, printed.String(), true, nil
+	}
+	f, ok := globals[fn].(starlark.Callable)
+	if !ok {
+		return nil, printed.String(), false, nil
+	}
+	v, err = starlark.Call(thread, f, args, nil)
+	if err == nil && heapAllocs()-start > r.maxMem {
+		// One big allocation can finish between two samples
+		err = errors.New("memory limit exceeded")
+	}
+	out = printed.String()
+	if len(out) > 4096 {
+		out = out[:4096]
+	}
+	return v, out, true, err
+}
+
+// Call runs fn from the chat's script and returns its result as a string
+// ("" for None).
+func (r *ScriptRunner) Call(chatID int64, fn string, args ...starlark.Value) (string, bool, error) {
+	src, ok := r.source(chatID)
+	if !ok {
+		return "", false, nil
+	}
+	v, _, ok, err := r.run(chatID, src, fn, args...)
+	if err != nil || !ok {
+		return "", ok, err
+	}
+	return scriptResult(v)
+}
+
+func scriptResult(v starlark.Value) (string, bool, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return "", true, nil
+	case starlark.String:
+		return string(v), true, nil
+	default:
+		return "", true, fmt.Errorf("expected str or None, got %s", v.Type())
+	}
+}
+
+func itemValue(it Item) starlark.Value {
+	d := starlark.NewDict(5)
+	_ = d.SetKey(starlark.String("id"), starlark.MakeInt64(it.ID))
+	_ = d.SetKey(starlark.String("topic"), starlark.String(it.Topic))
+	_ = d.SetKey(starlark.String("text"), starlark.String(it.Text))
+	_ = d.SetKey(starlark.String("flagged"), starlark.Bool(it.Flagged))
+	_ = d.SetKey(starlark.String("created_at"), starlark.String(it.CreatedAt.UTC().Format(time.RFC3339)))
+	return d
+}
+
+func eventValue(ev Event) starlark.Value {
+	d := starlark.NewDict(5)
+	_ = d.SetKey(starlark.String("kind"), starlark.String(string(ev.Kind)))
+	_ = d.SetKey(starlark.String("item_id"), starlark.MakeInt64(ev.ItemID))
+	_ = d.SetKey(starlark.String("topic"), starlark.String(ev.Topic))
+	_ = d.SetKey(starlark.String("text"), starlark.String(ev.Text))
+	_ = d.SetKey(starlark.String("at"), starlark.String(ev.At.UTC().Format(time.RFC3339)))
+	return d
+}
+
+// scriptCapture passes captured text through on_capture, keeping the
+// original on error or an empty result.
+func (a *App) scriptCapture(chatID int64, topic, text string) string {
+	out, ok, err := a.Scripts.Call(chatID, "on_capture", starlark.String(text), starlark.String(topic))
+	if err != nil {
+		log.Printf("script: chat %d on_capture: %v", chatID, err)
+		return text
+	}
+	if !ok || strings.TrimSpace(out) == "" {
+		return text
+	}
+	return strings.TrimSpace(out)
+}
+
+// startScriptEvents feeds item events to on_event and sends back whatever
+// it returns.
+func (a *App) startScriptEvents() {
+	a.Events.Subscribe(func(ev Event) {
+		if _, ok := a.Scripts.source(ev.ChatID); !ok {
+			return
+		}
+		out, _, err := a.Scripts.Call(ev.ChatID, "on_event", eventValue(ev))
+		if err != nil {
+			log.Printf("script: chat %d on_event: %v", ev.ChatID, err)
+			return
+		}
+		if out != "" {
+			a.send(ev.ChatID, out)
+		}
+	}, EventItemCreated, EventItemCompleted, EventItemDeleted, EventItemMoved)
+}
+
+// scriptSection is the digest section computed by the chat's digest(items).
+type scriptSection struct {
+	store   Store
+	scripts *ScriptRunner
+}
+
+func (s *scriptSection) Name() string             { return "script" }
+func (s *scriptSection) Title(lang string) string { return "" }
+
+func (s *scriptSection) Render(ctx context.Context, chatID int64, now time.Time) (string, error) {
+	if _, ok := s.scripts.source(chatID); !ok {
+		return "", nil
+	}
+	var items []starlark.Value
+	for _, topic := range keyboardTopics {
+		list, err := s.store.ListActive(chatID, topic)
+		if err != nil {
+			return "", err
+		}
+		for _, it := range list {
+			items = append(items, itemValue(it))
+		}
+	}
+	out, _, err := s.scripts.Call(chatID, "digest", starlark.NewList(items))
+	return out, err
+}
+
+// handleScript handles "/script", "/script <code>", "/script test <text>"
+// and "/script reset".
+func (a *App) handleScript(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	key := chatKey(chatID, "script")
+	cmd, rest, _ := strings.Cut(arg, " ")
+	lang := a.Store.Lang(chatID)
+	switch {
+	case arg == "":
+		cur, ok := a.Scripts.source(chatID)
+		if !ok {
+			a.send(chatID, tr(lang, "script.none")+"\n\n"+tr(lang, "script.help"))
+			return
+		}
+		a.send(chatID, tr(lang, "script.current")+"\n\n"+cur+"\n\n"+tr(lang, "script.help"))
+		return
+	case arg == "reset":
+		_ = a.Store.DeleteKV(key)
+		a.send(chatID, tr(lang, "script.reset"))
+		return
+	case cmd == "test":
+		src, ok := a.Scripts.source(chatID)
+		if !ok {
+			a.send(chatID, tr(lang, "script.none"))
+			return
+		}
+		v, out, ok, err := a.Scripts.run(chatID, src, "on_capture", starlark.String(strings.TrimSpace(rest)), starlark.String(TopicBasket))
+		var b strings.Builder
+		switch {
+		case err != nil:
+			b.WriteString(tr(lang, "script.error", scriptError(err)))
+		case !ok:
+			b.WriteString(tr(lang, "script.nocapture"))
+		default:
+			b.WriteString("→ " + v.String())
+		}
+		if out != "" {
+			b.WriteString("\n\nprint:\n" + out)
+		}
+		a.send(chatID, b.String())
+		return
+	}
+
+	if len(arg) > maxScriptSize {
+		a.send(chatID, tr(lang, "script.toobig", maxScriptSize>>10))
+		return
+	}
+	if _, _, _, err := a.Scripts.run(chatID, arg, ""); err != nil {
+		a.send(chatID, tr(lang, "script.invalid", scriptError(err)))
+		return
+	}
+	if err := a.Store.SetKV(key, arg); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, tr(lang, "script.saved"))
+}
+
+func scriptError(err error) string {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return evalErr.Backtrace()
+	}
+	return err.Error()
+}
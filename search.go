@@ -0,0 +1,327 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// /search looks for text in every topic, active and done, and optionally
+// in the compacted history, grouping results by topic and status. On
+// SQLite it goes through the items_fts index (FTS5 over norm, kept in step
+// by triggers), so words match in any order and by prefix; Postgres and
+// encrypted chats fall back to substring matching. Active results get ✅
+// and ↪️ buttons.
+
+const (
+	searchLimit   = 50
+	searchButtons = 10
+)
+
+// ensureSearchIndex creates items_fts and its triggers, filling it from
+// items the first time.
+func (s *sqlStore) ensureSearchIndex() error {
+	if s.driver != driverSQLite {
+		return nil
+	}
+	var n int
+	if err := s.DB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name='items_fts'`).Scan(&n); err != nil {
+		return err
+	}
+	// remove_diacritics would fold й into и
+	_, err := s.DB.Exec(`
+CREATE VIRTUAL TABLE IF NOT EXISTS items_fts USING fts5(norm, content='items', content_rowid='id', tokenize='unicode61 remove_diacritics 0');
+CREATE TRIGGER IF NOT EXISTS items_fts_ai AFTER INSERT ON items BEGIN
+  INSERT INTO items_fts(rowid, norm) VALUES (new.id, new.norm);
+END;
+CREATE TRIGGER IF NOT EXISTS items_fts_ad AFTER DELETE ON items BEGIN
+  INSERT INTO items_fts(items_fts, rowid, norm) VALUES ('delete', old.id, old.norm);
+END;
+CREATE TRIGGER IF NOT EXISTS items_fts_au AFTER UPDATE OF norm ON items BEGIN
+  INSERT INTO items_fts(items_fts, rowid, norm) VALUES ('delete', old.id, old.norm);
+  INSERT INTO items_fts(rowid, norm) VALUES (new.id, new.norm);
+END;`)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = s.DB.Exec(`INSERT INTO items_fts(items_fts) VALUES ('rebuild')`)
+	return err
+}
+
+// ftsQuery turns a query into an FTS5 one: every word, as a prefix.
+func ftsQuery(query string) string {
+	words := strings.FieldsFunc(normalizeText(query), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for i, w := range words {
+		words[i] = `"` + w + `"*`
+	}
+	return strings.Join(words, " ")
+}
+
+// likePattern escapes s for LIKE ... ESCAPE '\'.
+func likePattern(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(s) + "%"
+}
+
+// SearchItems matches the normalized text of active and done items, best
+// match first (newest first without the index).
+func (s *sqlStore) SearchItems(chatID int64, query string, limit int) ([]Item, error) {
+	if s.Encrypted(chatID) {
+		return s.searchSealed(chatID, query, limit)
+	}
+	var rows *sql.Rows
+	var err error
+	if match := ftsQuery(query); s.driver == driverSQLite && match != "" {
+		rows, err = s.readDB(chatID).Query(
+			`SELECT i.id, i.chat_id, i.topic, i.text, i.flagged, i.secret, i.created_at, i.completed_at
+			 FROM items_fts JOIN items i ON i.id=items_fts.rowid
+			 WHERE items_fts MATCH ? AND i.chat_id=? ORDER BY items_fts.rank LIMIT ?`,
+			match, chatID, limit,
+		)
+	} else {
+		rows, err = s.readDB(chatID).Query(
+			`SELECT id, chat_id, topic, text, flagged, secret, created_at, completed_at FROM items
+			 WHERE chat_id=? AND norm LIKE ? ESCAPE '\' ORDER BY id DESC LIMIT ?`,
+			chatID, likePattern(normalizeText(query)), limit,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Item
+	for rows.Next() {
+		var it Item
+		var created, completed string
+		if err := rows.Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &it.Flagged, &it.Secret, &created, &completed); err != nil {
+			return nil, err
+		}
+		it.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		it.CompletedAt, _ = time.Parse(time.RFC3339, completed)
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+type ArchiveHit struct {
+	Month string
+	Topic string
+	Item  ArchivedItem
+}
+
+// SearchHistory scans the chat's compacted months. The archive is gzipped
+// JSON, so matching happens here rather than in SQL.
+func (s *sqlStore) SearchHistory(chatID int64, query string, limit int) ([]ArchiveHit, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT month, topic, items FROM item_history WHERE chat_id=? ORDER BY month DESC, topic`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	norm := normalizeText(query)
+	var out []ArchiveHit
+	for rows.Next() && len(out) < limit {
+		var month, topic string
+		var blob []byte
+		if err := rows.Scan(&month, &topic, &blob); err != nil {
+			return nil, err
+		}
+		items, err := unpackArchive(blob)
+		if err != nil {
+			return nil, fmt.Errorf("history %d %s %s: %w", chatID, month, topic, err)
+		}
+		for _, it := range items {
+			it.Text = s.openText(chatID, it.Text)
+			if strings.Contains(normalizeText(it.Text), norm) {
+				out = append(out, ArchiveHit{Month: month, Topic: topic, Item: it})
+				if len(out) == limit {
+					break
+				}
+			}
+		}
+	}
+	return out, rows.Err()
+}
+
+// handleSearch handles "/search <текст>" and "/search архив <текст>"
+// (also -a), which includes the archive.
+func (a *App) handleSearch(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	withArchive := false
+	if first, rest, ok := strings.Cut(arg, " "); ok && (first == "-a" || normalizeText(first) == "архив" || first == "archive") {
+		withArchive, arg = true, strings.TrimSpace(rest)
+	}
+	if normalizeText(arg) == "" {
+		a.send(chatID, a.tr(chatID, "search.usage"))
+		return
+	}
+
+	items, err := a.Store.SearchItems(chatID, arg, searchLimit)
+	if errors.Is(err, errLocked) {
+		a.sendLocked(chatID)
+		return
+	}
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	var archived []ArchiveHit
+	if withArchive {
+		if archived, err = a.Store.SearchHistory(chatID, arg, searchLimit); err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+	}
+	if len(items) == 0 && len(archived) == 0 {
+		a.send(chatID, a.tr(chatID, "search.none"))
+		return
+	}
+
+	lang := a.Store.Lang(chatID)
+	type group struct{ topic, status string }
+	groups := map[group][]Item{}
+	var order []group
+	for _, it := range items {
+		g := group{it.Topic, StatusActive}
+		if !it.CompletedAt.IsZero() {
+			g.status = StatusDone
+		}
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		groups[g] = append(groups[g], it)
+	}
+	// Topics in keyboard order, active before done
+	rank := func(g group) int {
+		for i, t := range keyboardTopics {
+			if t == g.topic {
+				return i
+			}
+		}
+		return len(keyboardTopics)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		ri, rj := rank(order[i]), rank(order[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return order[i].status == StatusActive && order[j].status == StatusDone
+	})
+
+	tz := a.tz(chatID)
+	var b strings.Builder
+	var rows [][]tgbotapi.InlineKeyboardButton
+	b.WriteString(tr(lang, "search.header", arg))
+	for _, g := range order {
+		status := tr(lang, "search.active")
+		if g.status == StatusDone {
+			status = tr(lang, "search.done")
+		}
+		fmt.Fprintf(&b, "\n\n%s — %s:", topicLabel(lang, g.topic), status)
+		for _, it := range groups[g] {
+			fmt.Fprintf(&b, "\n#%d %s · %s", it.ID, shownText(it), it.CreatedAt.In(tz).Format("02.01.2006"))
+			if !it.CompletedAt.IsZero() {
+				b.WriteString(" → ✅ " + it.CompletedAt.In(tz).Format("02.01.2006"))
+			} else if len(rows) < searchButtons {
+				rows = append(rows, searchRow(it.ID))
+			}
+		}
+	}
+	if len(archived) > 0 {
+		b.WriteString("\n\n" + tr(lang, "archive.title") + ":")
+		for _, h := range archived {
+			fmt.Fprintf(&b, "\n%s %s #%d %s", h.Month, topicLabel(lang, h.Topic), h.Item.ID, h.Item.shownText())
+		}
+	} else if !withArchive {
+		b.WriteString("\n\n" + tr(lang, "search.archive.hint", arg))
+	}
+	if len(items) == searchLimit {
+		b.WriteString("\n\n" + tr(lang, "search.limit", searchLimit))
+	}
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	if len(rows) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	_, _ = a.Bot.Send(msg)
+}
+
+func searchRow(id int64) []tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✅ #%d", id), fmt.Sprintf("sr:done:%d", id)),
+		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("↪️ #%d", id), fmt.Sprintf("sr:move:%d", id)),
+	)
+}
+
+// withSearchRow is the results keyboard with the rows of item id (its
+// buttons or its topic picker) replaced by rows, or dropped when rows is nil.
+func withSearchRow(markup *tgbotapi.InlineKeyboardMarkup, id int64, rows [][]tgbotapi.InlineKeyboardButton) tgbotapi.InlineKeyboardMarkup {
+	suffix := ":" + strconv.FormatInt(id, 10)
+	out := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+	if markup == nil {
+		return out
+	}
+	for _, r := range markup.InlineKeyboard {
+		if len(r) > 0 && r[0].CallbackData != nil && strings.HasSuffix(*r[0].CallbackData, suffix) {
+			out.InlineKeyboard = append(out.InlineKeyboard, rows...)
+			rows = nil
+			continue
+		}
+		out.InlineKeyboard = append(out.InlineKeyboard, r)
+	}
+	return out
+}
+
+// handleSearchCallback handles the result buttons: "sr:done:<id>",
+// "sr:move:<id>" (topic picker), "sr:mv:<topic>:<id>" and "sr:back:<id>".
+func (a *App) handleSearchCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	action, rest, _ := strings.Cut(data, ":")
+	topic := ""
+	if action == "mv" {
+		topic, rest, _ = strings.Cut(rest, ":")
+	}
+	id, _ := strconv.ParseInt(rest, 10, 64)
+	answer := ""
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	switch action {
+	case "done":
+		if err := a.Store.FinishItem(chatID, id, cq.From, time.Now()); err != nil {
+			_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.write")))
+			return
+		}
+		answer = fmt.Sprintf("✅ #%d", id)
+	case "move":
+		it, err := a.Store.GetItem(chatID, id)
+		if err != nil || it == nil {
+			_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "deleted")))
+			return
+		}
+		rows = append(a.moveRows(chatID, "sr:", *it), tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✖", fmt.Sprintf("sr:back:%d", id)),
+		))
+	case "mv":
+		var ok bool
+		if topic, ok = moveTarget(chatTopics(a.Store, chatID), topic); !ok {
+			_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "deleted")))
+			return
+		}
+		if err := a.Store.MoveItem(chatID, id, topic); err != nil {
+			_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.write")))
+			return
+		}
+		answer = fmt.Sprintf("#%d → %s", id, a.moveLabel(chatID, topic))
+	case "back":
+		rows = [][]tgbotapi.InlineKeyboardButton{searchRow(id)}
+	default:
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, answer))
+	_, _ = a.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, cq.Message.MessageID, withSearchRow(cq.Message.ReplyMarkup, id, rows)))
+}
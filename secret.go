@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// /secret <id> marks an item secret: lists and digests show ••• instead of
+// its text, and 👁 sends the text in a message that deletes itself after
+// secretRevealTTL.
+
+const (
+	secretMask      = "•••"
+	secretRevealTTL = 30 * time.Second
+)
+
+func (s *sqlStore) SetSecret(chatID, id int64, on bool) error {
+	v := 0
+	if on {
+		v = 1
+	}
+	_, err := s.db(chatID).Exec(`UPDATE items SET secret=? WHERE chat_id=? AND id=?`, v, chatID, id)
+	return err
+}
+
+// shownText is the item's text as listings may show it.
+func shownText(it Item) string {
+	if it.Secret {
+		return secretMask
+	}
+	return it.Text
+}
+
+func revealButton(id int64) tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardButtonData("👁", fmt.Sprintf("reveal:%d", id))
+}
+
+// handleSecret handles "/secret <id>", which toggles the flag.
+func (a *App) handleSecret(chatID int64, arg string) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(arg), "#"), 10, 64)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "secret.usage"))
+		return
+	}
+	it, err := a.Store.GetItem(chatID, id)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if it == nil {
+		a.send(chatID, a.tr(chatID, "item.notfound", id))
+		return
+	}
+	if err := a.Store.SetSecret(chatID, id, !it.Secret); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	if it.Secret {
+		a.send(chatID, a.tr(chatID, "secret.off", id))
+		return
+	}
+	a.send(chatID, "🤫 "+a.tr(chatID, "secret.on", id, secretMask))
+}
+
+// handleRevealCallback handles "reveal:<id>".
+func (a *App) handleRevealCallback(cq *tgbotapi.CallbackQuery, idStr string) {
+	chatID := cq.Message.Chat.ID
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	it, err := a.Store.GetItem(chatID, id)
+	if err != nil || it == nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "deleted")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	sent, err := a.Bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("🤫 #%d: %s\n\n(%s)", it.ID, it.Text, a.tr(chatID, "secret.ttl", int(secretRevealTTL.Seconds())))))
+	if err != nil {
+		return
+	}
+	time.AfterFunc(secretRevealTTL, func() {
+		if _, err := a.Bot.Request(tgbotapi.NewDeleteMessage(chatID, sent.MessageID)); err != nil {
+			log.Printf("delete revealed secret error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// "/export settings" sends the chat's configuration as a JSON document and
+// "/import settings" (as a reply to that document, or with the JSON inline)
+// loads it, so a setup can move to another bot or chat. Only settings are
+// covered, custom topics and the chat's checklist templates included; state
+// such as travel stays behind. A topic's route comes over
+// only if the importing chat is linked to the same target.
+
+const settingsVersion = 1
+
+// settingsKeys are the exported kv names; JSON values are kept as JSON.
+var settingsKeys = []string{
+	"lang", "ack", "capacity", "compact", "keyboard_extras", "leaderboard",
+	"digest_sections", "digest_template", "time_presets", "rules", "views",
+	"watches", "script", "timezone", "quiet", "busy", "retention", "intents",
+	"speak", "digest_channel", "roles", "role_default",
+}
+
+// settingsTopicKeys are the per-topic kv names, "<prefix><topic>".
+var settingsTopicKeys = []string{"topic_name:", "route:"}
+
+type SettingsDoc struct {
+	Version   int                 `json:"version"`
+	ChatID    int64               `json:"chat_id"`
+	Settings  map[string]string   `json:"settings"`
+	Topics    []CustomTopic       `json:"topics,omitempty"`
+	Templates []ChecklistTemplate `json:"templates,omitempty"`
+}
+
+func exportSettings(store Store, chatID int64) (SettingsDoc, error) {
+	doc := SettingsDoc{Version: settingsVersion, ChatID: chatID, Settings: map[string]string{}}
+	var err error
+	if doc.Topics, err = store.CustomTopics(chatID); err != nil {
+		return doc, err
+	}
+	if doc.Templates, err = store.ChatTemplates(chatID); err != nil {
+		return doc, err
+	}
+	names := append([]string{}, settingsKeys...)
+	for _, t := range chatTopics(store, chatID) {
+		for _, prefix := range settingsTopicKeys {
+			names = append(names, prefix+t)
+		}
+	}
+	for _, name := range names {
+		v, ok, err := store.GetKV(chatKey(chatID, name))
+		if err != nil {
+			return doc, err
+		}
+		if ok {
+			doc.Settings[name] = v
+		}
+	}
+	return doc, nil
+}
+
+// settingsKeyAllowed reports whether name may be imported; per-topic keys
+// must name one of topics.
+func settingsKeyAllowed(name string, topics []string) bool {
+	for _, prefix := range settingsTopicKeys {
+		if t, ok := strings.CutPrefix(name, prefix); ok {
+			return slices.Contains(topics, t)
+		}
+	}
+	return slices.Contains(settingsKeys, name)
+}
+
+// checkSetting rejects a value its feature couldn't have stored.
+func checkSetting(lang, name, v string) error {
+	bad := func(what string) error {
+		return errors.New(tr(lang, "settings.bad", name, tr(lang, "settings.bad."+what)))
+	}
+	switch {
+	case slices.Contains([]string{"rules", "views", "watches", "keyboard_extras", "time_presets"}, name):
+		if !json.Valid([]byte(v)) {
+			return bad("json")
+		}
+	case name == "timezone":
+		if _, err := time.LoadLocation(v); err != nil {
+			return bad("timezone")
+		}
+	case name == "quiet":
+		if _, _, ok := parseQuiet(v); !ok {
+			return bad("quiet")
+		}
+	case name == "busy":
+		for _, line := range strings.Split(v, "\n") {
+			if _, ok := parseBusy(line); !ok {
+				return bad("busy")
+			}
+		}
+	case name == "retention":
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			return bad("days")
+		}
+	case name == "intents" || name == "speak":
+		if v != "off" {
+			return bad("off")
+		}
+	case name == "digest_channel" || strings.HasPrefix(name, "route:"):
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return bad("chat")
+		}
+	case name == "role_default":
+		if r, ok := parseRole(v); !ok || r == RoleOwner {
+			return bad("role")
+		}
+	case name == "roles":
+		for _, line := range strings.Split(v, "\n") {
+			f := strings.Fields(line)
+			if len(f) < 2 {
+				return bad("roles")
+			}
+			if _, err := strconv.ParseInt(f[0], 10, 64); err != nil {
+				return bad("roles")
+			}
+			if _, ok := parseRole(f[1]); !ok {
+				return bad("roles")
+			}
+		}
+	}
+	return nil
+}
+
+// importSettings writes doc into chatID, replacing the keys it carries.
+// Routes to chats chatID isn't linked to are dropped, and imported watches
+// all notify chatID: a private chat one notified belongs to someone from
+// the source chat, who shouldn't get this chat's captures.
+func importSettings(store Store, chatID int64, doc SettingsDoc) (int, error) {
+	lang := store.Lang(chatID)
+	if doc.Version < 1 || doc.Version > settingsVersion {
+		return 0, errors.New(tr(lang, "import.version", doc.Version))
+	}
+	topics, err := importTopics(store, chatID, doc.Topics)
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range doc.Templates {
+		if t.Key == "" || t.Key != templateKey(t.Name) || len(t.Items) == 0 || (t.Topic != TopicTasks && t.Topic != TopicShopping) {
+			return 0, errors.New(tr(lang, "settings.bad.template", t.Name))
+		}
+	}
+	for name, v := range doc.Settings {
+		if !settingsKeyAllowed(name, topics) {
+			return 0, errors.New(tr(lang, "settings.unknown", name))
+		}
+		if err := checkSetting(lang, name, v); err != nil {
+			return 0, err
+		}
+	}
+	for name, v := range doc.Settings {
+		if strings.HasPrefix(name, "route:") {
+			if target, _ := strconv.ParseInt(v, 10, 64); !store.IsChatLinked(chatID, target) {
+				delete(doc.Settings, name)
+			}
+		}
+	}
+	if raw, ok := doc.Settings["watches"]; ok {
+		var ws []Watch
+		if err := json.Unmarshal([]byte(raw), &ws); err != nil {
+			return 0, fmt.Errorf("watches: %w", err)
+		}
+		for i := range ws {
+			ws[i].NotifyID = chatID
+		}
+		b, _ := json.Marshal(ws)
+		doc.Settings["watches"] = string(b)
+	}
+
+	err = store.InTx(chatID, func(tx Store) error {
+		have, err := tx.CustomTopics(chatID)
+		if err != nil {
+			return err
+		}
+		for _, t := range doc.Topics {
+			if slices.ContainsFunc(have, func(h CustomTopic) bool { return h.Topic == t.Topic }) {
+				continue
+			}
+			if err := tx.AddTopic(chatID, t.Topic, t.Name); err != nil {
+				return err
+			}
+		}
+		for _, t := range doc.Templates {
+			if err := tx.PutTemplate(chatID, t); err != nil {
+				return err
+			}
+		}
+		for name, v := range doc.Settings {
+			if err := tx.SetKV(chatKey(chatID, name), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(doc.Settings), err
+}
+
+// importTopics checks the document's custom topics and returns every topic
+// the chat will have once they are added.
+func importTopics(store Store, chatID int64, in []CustomTopic) ([]string, error) {
+	lang := store.Lang(chatID)
+	topics := chatTopics(store, chatID)
+	custom := len(topics) - len(keyboardTopics)
+	for _, t := range in {
+		if t.Topic == "" || t.Topic != customTopicKey(t.Name) || t.Topic == listAll ||
+			utf8.RuneCountInString(t.Name) > maxTopicNameRunes || slices.Contains(keyboardTopics, t.Topic) {
+			return nil, errors.New(tr(lang, "settings.bad.topic", t.Name))
+		}
+		if slices.Contains(topics, t.Topic) {
+			continue
+		}
+		if custom++; custom > maxCustomTopics {
+			return nil, errors.New(tr(lang, "settings.topics.max", maxCustomTopics))
+		}
+		topics = append(topics, t.Topic)
+	}
+	return topics, nil
+}
+
+// sendSettingsExport sends "/export settings" as a file.
+func (a *App) sendSettingsExport(chatID int64) {
+	doc, err := exportSettings(a.Store, chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	msg := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: "gtd-settings.json", Bytes: b})
+	msg.Caption = a.tr(chatID, "settings.caption", len(doc.Settings))
+	if _, err := a.Bot.Send(msg); err != nil {
+		a.send(chatID, a.tr(chatID, "file.send.failed"))
+	}
+}
+
+// handleImport handles "/import settings" as a reply to an exported file,
+// or "/import settings {...}"; plain "/import" in a reply loads items.
+func (a *App) handleImport(ctx context.Context, m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	kind, inline, _ := strings.Cut(strings.TrimSpace(m.CommandArguments()), " ")
+	if kind != "settings" {
+		if m.ReplyToMessage == nil || m.ReplyToMessage.Document == nil {
+			a.send(chatID, a.tr(chatID, "import.usage"))
+			return
+		}
+		a.importItems(ctx, chatID, m.ReplyToMessage.Document)
+		return
+	}
+
+	raw := []byte(strings.TrimSpace(inline))
+	if len(raw) == 0 {
+		if m.ReplyToMessage == nil || m.ReplyToMessage.Document == nil {
+			a.send(chatID, a.tr(chatID, "settings.reply"))
+			return
+		}
+		var err error
+		if raw, err = a.readDocument(ctx, m.ReplyToMessage.Document, maxImportBytes); err != nil {
+			a.send(chatID, a.tr(chatID, "file.download.failed"))
+			return
+		}
+	}
+
+	var doc SettingsDoc
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		a.send(chatID, a.tr(chatID, "settings.notdoc", err))
+		return
+	}
+	n, err := importSettings(a.Store, chatID, doc)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "import.failed", err))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "settings.done", n))
+}
+
+const maxImportBytes = 8 << 20
+
+func (a *App) readDocument(ctx context.Context, d *tgbotapi.Document, limit int) ([]byte, error) {
+	if d.FileSize > limit {
+		return nil, fmt.Errorf("file over %d bytes", limit)
+	}
+	r, err := a.Media.open(ctx, d.FileID)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, int64(limit)))
+}
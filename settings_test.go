@@ -0,0 +1,186 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImportSettingsRetargetsWatches(t *testing.T) {
+	s, err := openStore(driverSQLite, filepath.Join(t.TempDir(), "settings.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	const source, author, target = -100, 7, -200
+	if err := s.SetWatches(source, []Watch{{Query: "#срочно", NotifyID: source}, {Query: "ремонт", NotifyID: author}}); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := exportSettings(s, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importSettings(s, target, doc); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := s.Watches(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Watch{{Query: "#срочно", NotifyID: target}, {Query: "ремонт", NotifyID: target}}
+	if !reflect.DeepEqual(ws, want) {
+		t.Errorf("imported watches = %+v, want %+v", ws, want)
+	}
+}
+
+func TestSettingsRoundTrip(t *testing.T) {
+	s, err := openStore(driverSQLite, filepath.Join(t.TempDir(), "settings.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	const source, target, family = -100, -200, -300
+	busy, _ := parseBusy("пн-пт 09:00-18:00 работа")
+	kv := map[string]string{
+		"timezone":       "Asia/Yekaterinburg",
+		"quiet":          "23:30-07:30",
+		"busy":           busy.String(),
+		"retention":      "90",
+		"intents":        "off",
+		"speak":          "off",
+		"digest_channel": "-1001234",
+		"roles":          "7 viewer Аня\n8 owner Боря",
+		"role_default":   "viewer",
+		"route:shopping": "-300",
+		"route:tasks":    "-400",
+	}
+	for name, v := range kv {
+		if err := checkSetting(LangRU, name, v); err != nil {
+			t.Errorf("checkSetting(%q, %q) = %v", name, v, err)
+		}
+		if err := s.SetKV(chatKey(source, name), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.AddChatLink(target, family, "Семья"); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := exportSettings(s, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importSettings(s, target, doc); err != nil {
+		t.Fatal(err)
+	}
+	for name, v := range kv {
+		got, ok, _ := s.GetKV(chatKey(target, name))
+		if name == "route:tasks" {
+			// the target chat has no link to -400
+			if ok {
+				t.Errorf("%s imported as %q", name, got)
+			}
+			continue
+		}
+		if got != v {
+			t.Errorf("%s = %q, want %q", name, got, v)
+		}
+	}
+}
+
+func TestCheckSetting(t *testing.T) {
+	tests := []struct{ name, v string }{
+		{"rules", "{"},
+		{"timezone", "Mars/Olympus"},
+		{"quiet", "поздно"},
+		{"busy", "когда-нибудь"},
+		{"retention", "-1"},
+		{"intents", "on"},
+		{"digest_channel", "@channel"},
+		{"route:tasks", "x"},
+		{"role_default", "owner"},
+		{"roles", "7 admin"},
+	}
+	for _, tt := range tests {
+		if err := checkSetting(LangRU, tt.name, tt.v); err == nil {
+			t.Errorf("checkSetting(%q, %q) accepted", tt.name, tt.v)
+		}
+	}
+}
+
+func TestSettingsCarryCustomTopics(t *testing.T) {
+	s, err := openStore(driverSQLite, filepath.Join(t.TempDir(), "settings.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	const source, target = -100, -200
+	books := CustomTopic{Topic: customTopicKey("📚 Книги"), Name: "📚 Книги"}
+	if err := s.AddTopic(source, books.Topic, books.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKV(chatKey(source, "topic_name:"+books.Topic), "📖 Чтение"); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := exportSettings(s, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc.Topics, []CustomTopic{books}) {
+		t.Fatalf("exported topics = %+v", doc.Topics)
+	}
+	// twice: the second import finds the topic already there
+	for range 2 {
+		if _, err := importSettings(s, target, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := s.CustomTopics(target); !reflect.DeepEqual(got, []CustomTopic{books}) {
+		t.Errorf("imported topics = %+v", got)
+	}
+	if name, _ := s.TopicName(target, books.Topic); name != "📖 Чтение" {
+		t.Errorf("imported topic name = %q", name)
+	}
+
+	doc.Topics = []CustomTopic{{Topic: "tasks", Name: "tasks"}}
+	if _, err := importSettings(s, target, doc); err == nil {
+		t.Error("imported a custom topic over a built-in one")
+	}
+	doc.Topics = nil
+	doc.Settings = map[string]string{"topic_name:нет такого": "x"}
+	if _, err := importSettings(s, target, doc); err == nil {
+		t.Error("imported the name of a topic the chat doesn't have")
+	}
+}
+
+func TestSettingsCarryTemplates(t *testing.T) {
+	s, err := openStore(driverSQLite, filepath.Join(t.TempDir(), "settings.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	const source, target = -100, -200
+	want := []ChecklistTemplate{ // in key order, as ChatTemplates lists them
+		{Key: "moving", Name: "Переезд", Topic: TopicTasks, Items: []string{"коробки", "скотч"}, Own: true},
+		{Key: templateKey("Дача"), Name: "Дача", Topic: TopicTasks, Items: []string{"полить", "закрыть"}, Own: true},
+	}
+	for _, tpl := range want {
+		if err := s.PutTemplate(source, tpl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	doc, err := exportSettings(s, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importSettings(s, target, doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.ChatTemplates(target); !reflect.DeepEqual(got, want) {
+		t.Errorf("imported templates = %+v, want %+v", got, want)
+	}
+
+	doc.Templates = []ChecklistTemplate{{Key: "moving", Name: "Дача", Topic: TopicTasks, Items: []string{"x"}}}
+	if _, err := importSettings(s, target, doc); err == nil {
+		t.Error("imported a template whose key doesn't match its name")
+	}
+}
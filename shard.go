@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// With DB_SHARDS set, chat data (items, notes, threads, goals, links,
+// history and per-chat settings) is spread over several databases so
+// one busy chat can't hold the write lock for everyone. Shard 0 is the main
+// database, which also keeps the global tables (payments, dead letters,
+// media) and chat_shards, the record of where each chat lives.
+//
+// A chat stays on the shard it was first assigned to; chats that already
+// had data before sharding was enabled stay on shard 0, new chats are
+// spread over the extra files.
+
+type shardRouter struct {
+	main   *sqlStore
+	shards []*sqlStore
+
+	mu     sync.Mutex
+	byChat map[int64]int
+}
+
+func shardPathsFromEnv() []string {
+	var out []string
+	for _, p := range strings.Split(os.Getenv("DB_SHARDS"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// attachShards opens the shard files and turns s into a router.
+func (s *sqlStore) attachShards(paths []string) error {
+	var maxShard int
+	if err := s.DB.QueryRow(`SELECT COALESCE(MAX(shard), 0) FROM chat_shards`).Scan(&maxShard); err != nil {
+		return err
+	}
+	if maxShard > len(paths) {
+		return fmt.Errorf("chats are assigned to shard %d but DB_SHARDS lists %d file(s)", maxShard, len(paths))
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	r := &shardRouter{
+		main:   &sqlStore{DB: s.DB, driver: s.driver, read: s.read, vault: s.vault},
+		byChat: map[int64]int{},
+	}
+	r.shards = append(r.shards, r.main)
+	for _, p := range paths {
+		sh, err := openStore(s.driver, p)
+		if err != nil {
+			for _, open := range r.shards[1:] {
+				_ = open.Close()
+			}
+			return fmt.Errorf("shard %s: %w", p, err)
+		}
+		sh.vault = s.vault
+		r.shards = append(r.shards, sh)
+	}
+	s.router = r
+	return nil
+}
+
+// For returns the Store holding chatID's data. Without sharding that is s.
+func (s *sqlStore) For(chatID int64) *sqlStore {
+	if s.router == nil {
+		return s
+	}
+	return s.router.shards[s.router.shardOf(chatID)]
+}
+
+func (s *sqlStore) db(chatID int64) dbConn {
+	return s.For(chatID).DB
+}
+
+// kvDB routes per-chat keys (see chatKey) to the chat's shard.
+func (s *sqlStore) kvDB(k string) dbConn {
+	rest, ok := strings.CutPrefix(k, "chat:")
+	if !ok {
+		return s.DB
+	}
+	idStr, _, _ := strings.Cut(rest, ":")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return s.DB
+	}
+	return s.db(id)
+}
+
+// Shards returns every database holding chat data, for jobs that scan all
+// chats.
+func (s *sqlStore) Shards() []*sqlStore {
+	if s.router == nil {
+		return []*sqlStore{s}
+	}
+	return s.router.shards
+}
+
+func (r *shardRouter) shardOf(chatID int64) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n, ok := r.byChat[chatID]; ok {
+		return n
+	}
+
+	db := r.main.DB
+	var n int
+	err := db.QueryRow(`SELECT shard FROM chat_shards WHERE chat_id=?`, chatID).Scan(&n)
+	switch {
+	case err == nil:
+	case errors.Is(err, sql.ErrNoRows):
+		var existing bool
+		err = db.QueryRow(
+			`SELECT EXISTS(SELECT 1 FROM items WHERE chat_id=?) OR EXISTS(SELECT 1 FROM kv WHERE k LIKE ?)`,
+			chatID, chatKey(chatID, "%"),
+		).Scan(&existing)
+		if err != nil {
+			log.Printf("shard: lookup chat %d: %v", chatID, err)
+			return 0
+		}
+		n = 0
+		if !existing {
+			id := chatID
+			if id < 0 {
+				id = -id
+			}
+			n = 1 + int(id%int64(len(r.shards)-1))
+		}
+		if _, err := db.Exec(`INSERT INTO chat_shards(chat_id, shard) VALUES(?,?)`, chatID, n); err != nil {
+			log.Printf("shard: assign chat %d: %v", chatID, err)
+			return n
+		}
+	default:
+		log.Printf("shard: lookup chat %d: %v", chatID, err)
+		return 0
+	}
+	r.byChat[chatID] = n
+	return n
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// On SIGINT/SIGTERM the root context is cancelled. Chat workers finish the
+// updates they have queued, the scheduler ends its tick, queued event
+// deliveries (watches, script hooks) drain, and only then is the DB closed.
+// Each wait is bounded so a stuck send can't hold the process forever.
+
+const shutdownGrace = 10 * time.Second
+
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// shutdown also closes bots, the extra bots, once the scheduler that ticks
+// them has stopped.
+func (a *App) shutdown(sched *Scheduler, bots []*App) {
+	log.Printf("shutting down")
+	deadline := time.Now().Add(shutdownGrace)
+
+	if sched != nil { // nil if this instance never became active
+		select {
+		case <-sched.Done():
+		case <-time.After(time.Until(deadline)):
+			log.Printf("shutdown: scheduler still running, giving up on it")
+		}
+	}
+	for _, b := range bots {
+		b.closeBot(deadline)
+	}
+	if !a.Events.Close(time.Until(deadline)) {
+		log.Printf("shutdown: event queues not drained")
+	}
+	if err := a.Store.Close(); err != nil {
+		log.Printf("shutdown: close store: %v", err)
+	}
+	log.Printf("bye")
+}
+
+// closeBot drains an extra bot's events and closes its database.
+func (a *App) closeBot(deadline time.Time) {
+	if !a.Events.Close(time.Until(deadline)) {
+		log.Printf("shutdown: bot %s: event queues not drained", a.Name)
+	}
+	if err := a.Store.Close(); err != nil {
+		log.Printf("shutdown: bot %s: close store: %v", a.Name, err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Snoozed reminders skip the REMINDER_TIMES broadcasts (and survive the
+// night wipe) until snoozed_until. Timed reminders are re-armed instead.
+
+func (s *sqlStore) Snooze(chatID, id int64, until time.Time) error {
+	_, err := s.db(chatID).Exec(
+		`UPDATE items SET snoozed_until=? WHERE chat_id=? AND id=?`,
+		until.UTC().Format(time.RFC3339), chatID, id,
+	)
+	return err
+}
+
+func (s *sqlStore) SnoozedUntil(chatID, id int64) (time.Time, error) {
+	var v string
+	err := s.db(chatID).QueryRow(`SELECT snoozed_until FROM items WHERE chat_id=? AND id=?`, chatID, id).Scan(&v)
+	if err != nil || v == "" {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// snoozeUntil resolves a snooze button: "1h", "3h" or "tm" (tomorrow at
+// the chat's first time preset).
+func snoozeUntil(code string, now time.Time, presets []TimePreset) (time.Time, bool) {
+	switch code {
+	case "1h":
+		return now.Add(time.Hour), true
+	case "3h":
+		return now.Add(3 * time.Hour), true
+	case "tm":
+		clock := "09:00"
+		if len(presets) > 0 {
+			clock = presets[0].Clock
+		}
+		t, err := time.Parse("15:04", clock)
+		if err != nil {
+			return time.Time{}, false
+		}
+		d := now.AddDate(0, 0, 1)
+		return time.Date(d.Year(), d.Month(), d.Day(), t.Hour(), t.Minute(), 0, 0, now.Location()), true
+	}
+	return time.Time{}, false
+}
+
+func reminderKeyboard(lang string, id int64, presets []TimePreset) tgbotapi.InlineKeyboardMarkup {
+	tomorrow := tr(lang, "snooze.tomorrow")
+	if len(presets) > 0 {
+		tomorrow += " " + presets[0].Name
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅", fmt.Sprintf("done:%d", id)),
+			tgbotapi.NewInlineKeyboardButtonData("📅", fmt.Sprintf("due:%d", id)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💤 +"+tr(lang, "minutes.h", 1), fmt.Sprintf("snz:%d:1h", id)),
+			tgbotapi.NewInlineKeyboardButtonData("💤 +"+tr(lang, "minutes.h", 3), fmt.Sprintf("snz:%d:3h", id)),
+			tgbotapi.NewInlineKeyboardButtonData("💤 "+tomorrow, fmt.Sprintf("snz:%d:tm", id)),
+		),
+	)
+}
+
+// snoozed reports whether a reminder is held back from broadcasts at now.
+func snoozed(store Store, chatID, id int64, now time.Time) bool {
+	until, err := store.SnoozedUntil(chatID, id)
+	return err == nil && until.After(now)
+}
+
+// handleSnoozeCallback handles "snz:<id>:<1h|3h|tm>".
+func (a *App) handleSnoozeCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	idStr, code, _ := strings.Cut(data, ":")
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	lang := a.Store.Lang(chatID)
+	now := time.Now().In(a.tz(chatID))
+	until, ok := snoozeUntil(code, now, a.Store.TimePresets(chatID))
+	if !ok {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+		return
+	}
+	if err := a.Store.Snooze(chatID, id, until); err != nil {
+		log.Printf("snooze error: %v", err)
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.write")))
+		return
+	}
+	if at, err := a.Store.RemindAt(chatID, id); err == nil && !at.IsZero() {
+		if err := a.Store.SetRemindAt(chatID, id, until); err != nil {
+			log.Printf("snooze remind_at error: %v", err)
+		}
+	}
+
+	label := tr(lang, "snooze.done", formatRemindAt(lang, until, now))
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, label))
+	edit := tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, cq.Message.Text+"\n💤 "+label)
+	_, _ = a.Bot.Send(edit)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func (s *sqlStore) RandomActive(chatID int64, topic string, n int) ([]Item, error) {
+	rows, err := s.readDB(chatID).Query(
+		`SELECT id, chat_id, topic, text, flagged, secret, created_at FROM items WHERE chat_id=? AND topic=? AND status=? ORDER BY RANDOM() LIMIT ?`,
+		chatID, topic, StatusActive, n,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Item
+	for rows.Next() {
+		var it Item
+		var created string
+		if err := rows.Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &it.Flagged, &it.Secret, &created); err != nil {
+			return nil, err
+		}
+		it.Text = s.openText(chatID, it.Text)
+		it.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func somedayKeyboard(lang string, id int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("▶️ "+tr(lang, "someday.activate"), fmt.Sprintf("sd:act:%d", id)),
+		tgbotapi.NewInlineKeyboardButtonData("⏸ "+tr(lang, "someday.keep"), fmt.Sprintf("sd:keep:%d", id)),
+		tgbotapi.NewInlineKeyboardButtonData("🗑 "+tr(lang, "someday.delete"), fmt.Sprintf("del:%d", id)),
+	))
+}
+
+// sendSomedayReview asks about a few random someday/maybe items,
+// so the list does not turn into a graveyard.
+func (s *Scheduler) sendSomedayReview(chatID int64, now time.Time) {
+	n, err := strconv.Atoi(envOr("SOMEDAY_REVIEW_COUNT", "3"))
+	if err != nil || n <= 0 {
+		n = 3
+	}
+	items, err := s.store.RandomActive(chatID, TopicSomeday, n)
+	if err != nil {
+		log.Printf("scheduler: someday review error: %v", err)
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	lang := s.store.Lang(chatID)
+	s.send("someday", tgbotapi.NewMessage(chatID, tr(lang, "someday.review")))
+	for _, it := range items {
+		msg := tgbotapi.NewMessage(chatID, formatSingleItem(lang, TopicSomeday, it))
+		msg.ReplyMarkup = somedayKeyboard(lang, it.ID)
+		s.send("someday", msg)
+	}
+}
+
+func (a *App) handleSomedayCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	action, idStr, _ := strings.Cut(data, ":")
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	lang := a.Store.Lang(chatID)
+
+	var answer, text string
+	switch action {
+	case "act":
+		if err := a.Store.MoveItem(chatID, id, TopicTasks); err != nil {
+			_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "err.write")))
+			return
+		}
+		answer, text = tr(lang, "someday.activated"), "▶️ "+tr(lang, "someday.moved", strings.ToUpper(topicLabel(lang, TopicTasks)))
+	case "keep":
+		answer, text = tr(lang, "someday.kept"), "⏸ "+tr(lang, "someday.stays", strings.ToUpper(topicLabel(lang, TopicSomeday)))
+	default:
+		return
+	}
+
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, answer))
+	edit := tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, cq.Message.Text+"\n"+text)
+	_, _ = a.Bot.Send(edit)
+}
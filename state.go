@@ -0,0 +1,156 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// StateHooks let a StateManager load state it has not seen and persist
+// every change. Both are optional and run under the manager's lock, so
+// they must not call back into it.
+type StateHooks struct {
+	Load func(chatID int64) (ChatState, bool)
+	Save func(chatID int64, st ChatState)
+}
+
+// StateManager owns the per-chat conversation state. Callers only ever see
+// copies; all mutation goes through Update under the manager's lock.
+type StateManager struct {
+	mu     sync.Mutex
+	states map[int64]*ChatState
+	ttl    time.Duration
+	hooks  StateHooks
+	now    func() time.Time
+}
+
+func NewStateManager(ttl time.Duration, hooks StateHooks) *StateManager {
+	return &StateManager{
+		states: map[int64]*ChatState{},
+		ttl:    ttl,
+		hooks:  hooks,
+		now:    time.Now,
+	}
+}
+
+// lookup returns the live state, creating it on first use. Caller holds mu.
+func (m *StateManager) lookup(chatID int64) *ChatState {
+	st := m.states[chatID]
+	if st != nil {
+		return st
+	}
+	st = &ChatState{Topic: TopicBasket, LastActivity: m.now()}
+	if m.hooks.Load != nil {
+		if loaded, ok := m.hooks.Load(chatID); ok {
+			*st = loaded
+		}
+	}
+	m.states[chatID] = st
+	return st
+}
+
+func (st ChatState) clone() ChatState {
+	st.Captured = append([]string(nil), st.Captured...)
+	return st
+}
+
+// Get returns a copy of the chat's state without touching it.
+func (m *StateManager) Get(chatID int64) ChatState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lookup(chatID).clone()
+}
+
+// Update applies fn to the chat's state atomically and returns the result.
+func (m *StateManager) Update(chatID int64, fn func(st *ChatState)) ChatState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.lookup(chatID)
+	fn(st)
+	out := st.clone()
+	if m.hooks.Save != nil {
+		m.hooks.Save(chatID, out)
+	}
+	return out
+}
+
+// Touch records activity; after more than ttl of silence the topic falls
+// back to the basket first.
+func (m *StateManager) Touch(chatID int64) ChatState {
+	return m.Update(chatID, func(st *ChatState) {
+		now := m.now()
+		if now.Sub(st.LastActivity) > m.ttl {
+			st.Topic = TopicBasket
+		}
+		st.LastActivity = now
+	})
+}
+
+func (m *StateManager) SetTopic(chatID int64, topic string) {
+	m.Update(chatID, func(st *ChatState) {
+		st.Topic = topic
+		st.LastActivity = m.now()
+	})
+}
+
+func (s *sqlStore) LoadChatState(chatID int64) (ChatState, bool, error) {
+	var st ChatState
+	var last, captured string
+	err := s.db(chatID).QueryRow(
+		`SELECT topic, last_activity, capture_topic, captured FROM chat_state WHERE chat_id=?`, chatID,
+	).Scan(&st.Topic, &last, &st.CaptureTopic, &captured)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ChatState{}, false, nil
+	}
+	if err != nil {
+		return ChatState{}, false, err
+	}
+	st.LastActivity, _ = time.Parse(time.RFC3339, last)
+	if captured != "" {
+		if err := json.Unmarshal([]byte(captured), &st.Captured); err != nil {
+			return ChatState{}, false, err
+		}
+	}
+	return st, true, nil
+}
+
+func (s *sqlStore) SaveChatState(chatID int64, st ChatState) error {
+	captured := ""
+	if len(st.Captured) > 0 {
+		b, err := json.Marshal(st.Captured)
+		if err != nil {
+			return err
+		}
+		captured = string(b)
+	}
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO chat_state(chat_id, topic, last_activity, capture_topic, captured) VALUES(?,?,?,?,?)
+		 ON CONFLICT(chat_id) DO UPDATE SET topic=excluded.topic, last_activity=excluded.last_activity,
+		   capture_topic=excluded.capture_topic, captured=excluded.captured`,
+		chatID, st.Topic, st.LastActivity.UTC().Format(time.RFC3339), st.CaptureTopic, captured,
+	)
+	return err
+}
+
+// storeStateHooks keep chat state in chat_state so a restart mid-session
+// resumes the active topic (and any open /capture batch) with its TTL.
+func storeStateHooks(store Store) StateHooks {
+	return StateHooks{
+		Load: func(chatID int64) (ChatState, bool) {
+			st, ok, err := store.LoadChatState(chatID)
+			if err != nil {
+				log.Printf("state: load chat %d: %v", chatID, err)
+			}
+			return st, ok
+		},
+		Save: func(chatID int64, st ChatState) {
+			if err := store.SaveChatState(chatID, st); err != nil {
+				log.Printf("state: save chat %d: %v", chatID, err)
+			}
+		},
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Store is everything handlers and jobs need from persistence. The SQL
+// implementation (sqlStore, SQLite or Postgres) lives next to the features
+// it serves; other backends implement the same interface.
+type Store interface {
+	ItemStore
+	SettingsStore
+	ChatStateStore
+	ChatLinkStore
+	GoalStore
+	BillStore
+	CardStore
+	MealStore
+	MaintenanceStore
+	TemplateStore
+	JournalStore
+	MoodStore
+	TrackingStore
+	BackupStore
+	EntitlementStore
+	DeadLetterStore
+	MediaStore
+	LeaseStore
+	CryptStore
+
+	// InTx runs fn in one transaction on chatID's data; every call on tx
+	// commits or rolls back together.
+	InTx(chatID int64, fn func(tx Store) error) error
+	Close() error
+}
+
+type ItemStore interface {
+	AddItem(chatID int64, topic, text string) (int64, error)
+	CaptureItem(chatID int64, topic, text string, flagged bool, prov Provenance) (int64, error)
+	GetItem(chatID, id int64) (*Item, error)
+	ItemMessage(chatID, id int64) int
+	ListActive(chatID int64, topic string) ([]Item, error)
+	ListStale(chatID int64, topic string, before time.Time) ([]Item, error)
+	ListCompleted(chatID int64, from, to time.Time) ([]Item, error)
+	RandomActive(chatID int64, topic string, n int) ([]Item, error)
+	FindDuplicate(chatID int64, topic, text string) (*Item, error)
+	MoveItem(chatID, id int64, topic string) error
+	FlagItem(chatID, id int64) error
+	SetSecret(chatID, id int64, on bool) error
+	CompleteItem(chatID, id int64, by *tgbotapi.User, now time.Time) error
+	FinishItem(chatID, id int64, by *tgbotapi.User, now time.Time) error
+	DeleteItem(chatID, id int64) error
+	Undo(chatID int64) (*UndoneAction, error)
+
+	SetRemindAt(chatID, id int64, at time.Time) error
+	RemindAt(chatID, id int64) (time.Time, error)
+	DueReminders(chatID int64, now time.Time) ([]Item, error)
+	MarkReminded(chatID, id int64, now time.Time) error
+	WipeReminders(chatID int64, now time.Time) error
+	ExportItems(chatID int64) ([]ExportRow, error)
+	ImportItem(chatID int64, r ExportRow) (int64, error)
+	ListArchive(chatID int64, topic string) ([]archiveEntry, error)
+	PurgeArchive(chatID int64, before time.Time) (int, error)
+	Snooze(chatID, id int64, until time.Time) error
+	SnoozedUntil(chatID, id int64) (time.Time, error)
+
+	SetProvenance(chatID, id int64, p Provenance) error
+	GetProvenance(chatID, id int64) (Provenance, error)
+	SetDue(chatID, id int64, due time.Time) error
+	Due(chatID, id int64) (time.Time, error)
+	OverdueTasks(chatID int64, now time.Time) ([]Item, error)
+	AttachEvent(chatID, itemID int64, eventID string) error
+	TasksByEvent(chatID int64) (map[string][]Item, error)
+
+	LinkAck(chatID, messageID, itemID int64) error
+	AckItem(chatID, messageID int64) (int64, bool, error)
+	EditItem(chatID, id int64, text string) error
+	LinkThread(chatID, threadID, itemID int64) error
+	ThreadItem(chatID, threadID int64) (int64, bool, error)
+	ItemThread(chatID, itemID int64) (int64, bool, error)
+	AddNote(chatID, itemID int64, author, text string) error
+	ListNotes(chatID, itemID int64) ([]Note, error)
+
+	CompactHistory(before time.Time) (int, error)
+	History(chatID int64) ([]HistoryRow, error)
+	HistoryItems(chatID int64, month string) (map[string][]ArchivedItem, error)
+	SearchItems(chatID int64, query string, limit int) ([]Item, error)
+	SearchHistory(chatID int64, query string, limit int) ([]ArchiveHit, error)
+	Usage(chatID int64, now time.Time) (ChatUsage, error)
+	ExportChat(chatID int64) (*MigrationDoc, error)
+	ImportChat(chatID int64, doc *MigrationDoc) (MigrationStats, error)
+
+	GroupChats() ([]int64, error)
+	RegisterChat(chatID int64, now time.Time) error
+	ScheduledChats() ([]int64, error)
+	Leaderboard(chatID int64, from, to time.Time) ([]Score, error)
+}
+
+// SettingsStore is the key/value store and the per-chat settings kept in it.
+type SettingsStore interface {
+	GetKV(k string) (string, bool, error)
+	SetKV(k, v string) error
+	DeleteKV(k string) error
+
+	Lang(chatID int64) string
+	SetLang(chatID int64, lang string) error
+	AckMode(chatID int64) string
+	Compact(chatID int64) bool
+	Capacity(chatID int64) int
+	KeyboardExtras(chatID int64) map[string]bool
+	SetKeyboardExtras(chatID int64, on map[string]bool) error
+	TopicName(chatID int64, topic string) (string, bool)
+	CustomTopics(chatID int64) ([]CustomTopic, error)
+	AddTopic(chatID int64, topic, name string) error
+	DeleteTopic(chatID int64, topic string) (int, error)
+	TimePresets(chatID int64) []TimePreset
+	SetTimePresets(chatID int64, ps []TimePreset) error
+	PresetClock(chatID int64, name string) (string, bool)
+	GetTravel(chatID int64) (*Travel, error)
+	SetTravel(chatID int64, t Travel) error
+	ClearTravel(chatID int64) error
+	DigestChannel(chatID int64) (int64, bool)
+	LeaderboardMuted(chatID int64) bool
+	Rules(chatID int64) ([]Rule, error)
+	SetRules(chatID int64, rules []Rule) error
+	SavedViews(chatID int64) ([]SavedView, error)
+	SetSavedViews(chatID int64, views []SavedView) error
+	Watches(chatID int64) ([]Watch, error)
+	SetWatches(chatID int64, ws []Watch) error
+}
+
+type ChatStateStore interface {
+	LoadChatState(chatID int64) (ChatState, bool, error)
+	SaveChatState(chatID int64, st ChatState) error
+}
+
+type ChatLinkStore interface {
+	AddChatLink(chatID, targetID int64, title string) error
+	ChatLinks(chatID int64) ([]ChatLink, error)
+	DeleteChatLink(chatID, targetID int64) error
+	IsChatLinked(chatID, targetID int64) bool
+	SetItemRoute(chatID, id, targetID int64) error
+	SetTopicRoute(chatID int64, topic string, targetID int64) error
+	NotifyChat(chatID int64, it Item) int64
+}
+
+type GoalStore interface {
+	AddGoal(chatID int64, g Goal) (int64, error)
+	ListGoals(chatID int64, period string) ([]Goal, error)
+	GoalsForPeriod(chatID int64, period string) ([]Goal, error)
+	AddGoalProgress(chatID, goalID int64, amount float64) error
+	DeleteGoal(chatID, goalID int64) error
+	LinkGoal(chatID, itemID, goalID int64, amount float64) error
+	TakeGoalLink(chatID, itemID int64) (goalID int64, amount float64, ok bool, err error)
+}
+
+type BillStore interface {
+	AddBill(chatID int64, b Bill) (int64, error)
+	DeleteBill(chatID, id int64) error
+	BillsFor(chatID int64, period string, loc *time.Location) ([]BillStatus, error)
+	PayBill(chatID, id int64, period string, now time.Time) (bool, error)
+}
+
+type CardStore interface {
+	PutCard(chatID int64, c Card) error
+	Cards(chatID int64) ([]Card, error)
+	FindCard(chatID int64, name string) (*Card, error)
+	DeleteCard(chatID int64, name string) (bool, error)
+}
+
+type MealStore interface {
+	PutRecipe(chatID int64, r Recipe) error
+	Recipes(chatID int64) ([]Recipe, error)
+	DeleteRecipe(chatID int64, key string) (bool, error)
+	SetMeal(chatID int64, day int, key string) error
+	MealPlan(chatID int64) (map[int]string, error)
+}
+
+type MaintenanceStore interface {
+	AddMaintenance(chatID int64, m Maintenance) (int64, error)
+	ListMaintenance(chatID int64) ([]Maintenance, error)
+	DoneMaintenance(chatID, id int64, at time.Time, km int) (bool, error)
+	SetMaintenanceNotified(chatID, id int64, state string) error
+	DeleteMaintenance(chatID, id int64) (bool, error)
+}
+
+type TemplateStore interface {
+	PutTemplate(chatID int64, t ChecklistTemplate) error
+	ChatTemplates(chatID int64) ([]ChecklistTemplate, error)
+	DeleteTemplate(chatID int64, key string) (bool, error)
+}
+
+type JournalStore interface {
+	AddJournal(chatID int64, day, text string) error
+	JournalSince(chatID int64, from string) ([]JournalEntry, error)
+}
+
+type MoodStore interface {
+	SetMood(chatID int64, day string, mood int) error
+	Moods(chatID int64, from, to string) (map[string]int, error)
+}
+
+type TrackingStore interface {
+	AddTracked(chatID, itemID int64, minutes int) error
+	TrackedMinutes(chatID int64) (map[int64]int, error)
+}
+
+type EntitlementStore interface {
+	GetEntitlement(chatID int64) (*Entitlement, error)
+	ExtendEntitlement(chatID int64, plan string, d time.Duration, chargeID string, now time.Time) (time.Time, error)
+	ExpiringEntitlements(before time.Time) ([]Entitlement, error)
+	MarkEntitlementNotified(chatID int64, now time.Time) error
+	HasFeature(chatID int64, feature string, now time.Time) bool
+}
+
+type DeadLetterStore interface {
+	AddDeadLetter(kind string, chatID int64, payload string, attempts int, cause error) error
+	ListDeadLetters(limit int) ([]DeadLetter, error)
+	GetDeadLetter(id int64) (*DeadLetter, error)
+	TouchDeadLetter(id int64, cause error) error
+	DeleteDeadLetter(id int64) error
+	DeferMessage(kind string, chatID int64, payload string) error
+	DeferredMessages(chatID int64) ([]DeferredMessage, error)
+	DeleteDeferred(chatID, id int64) error
+}
+
+// BackupStore snapshots the SQLite files (see backup.go).
+type BackupStore interface {
+	Snapshot(ctx context.Context, dir string) ([]string, error)
+}
+
+// LeaseStore is the active/standby lease (see ha.go).
+type LeaseStore interface {
+	AcquireLease(name, holder string, now time.Time, ttl time.Duration) (bool, error)
+	ReleaseLease(name, holder string) error
+}
+
+// CryptStore is per-chat encryption of item text (see crypt.go).
+type CryptStore interface {
+	EnableCrypt(chatID int64, pass string) error
+	DisableCrypt(chatID int64, pass string) (bool, error)
+	Unlock(chatID int64, pass string) (bool, error)
+	Lock(chatID int64)
+	Locked(chatID int64) bool
+	Encrypted(chatID int64) bool
+}
+
+type MediaStore interface {
+	GetMedia(uniqueID string) (*MediaFile, error)
+	PutMedia(f MediaFile) error
+	LinkItemMedia(chatID, itemID int64, uniqueID string) error
+	ItemMedia(chatID, itemID int64) ([]MediaFile, error)
+	ItemsWithMedia(chatID int64) (map[int64]bool, error)
+	UnlinkedMedia() ([]MediaFile, error)
+	DeleteMedia(uniqueID string) error
+	MediaKeyInUse(key string) (bool, error)
+	PruneItemMedia() error
+}
+
+var _ Store = (*sqlStore)(nil)
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type GeoPoint struct {
+	Lat, Lon float64
+}
+
+var errNoSunEvent = errors.New("sun does not rise or set on this day")
+
+// geoFromEnv reads LOCATION_LAT / LOCATION_LON.
+func geoFromEnv() (GeoPoint, bool) {
+	lat, err1 := strconv.ParseFloat(strings.TrimSpace(os.Getenv("LOCATION_LAT")), 64)
+	lon, err2 := strconv.ParseFloat(strings.TrimSpace(os.Getenv("LOCATION_LON")), 64)
+	if err1 != nil || err2 != nil {
+		return GeoPoint{}, false
+	}
+	return GeoPoint{Lat: lat, Lon: lon}, true
+}
+
+// sunTimes computes sunrise and sunset for the calendar day of `day` using
+// the NOAA sunrise equation (accuracy is about a minute).
+func sunTimes(day time.Time, p GeoPoint) (rise, set time.Time, err error) {
+	const rad = math.Pi / 180
+
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	jd := float64(midnight.Unix())/86400 + 2440587.5
+	n := math.Ceil(jd - 2451545.0 + 0.0008)
+
+	jStar := n - p.Lon/360
+	m := math.Mod(357.5291+0.98560028*jStar, 360)
+	c := 1.9148*math.Sin(m*rad) + 0.02*math.Sin(2*m*rad) + 0.0003*math.Sin(3*m*rad)
+	lambda := math.Mod(m+c+180+102.9372, 360)
+	jTransit := 2451545.0 + jStar + 0.0053*math.Sin(m*rad) - 0.0069*math.Sin(2*lambda*rad)
+
+	sinDecl := math.Sin(lambda*rad) * math.Sin(23.4397*rad)
+	cosDecl := math.Cos(math.Asin(sinDecl))
+	cosW := (math.Sin(-0.833*rad) - math.Sin(p.Lat*rad)*sinDecl) / (math.Cos(p.Lat*rad) * cosDecl)
+	if cosW < -1 || cosW > 1 {
+		return time.Time{}, time.Time{}, errNoSunEvent
+	}
+	w := math.Acos(cosW) / rad
+
+	toTime := func(j float64) time.Time {
+		return time.Unix(int64(math.Round((j-2440587.5)*86400)), 0).In(day.Location())
+	}
+	return toTime(jTransit - w/360), toTime(jTransit + w/360), nil
+}
+
+// resolveTimeSpec turns a schedule entry into an HH:MM for the given day.
+// Entries are either "HH:MM" or "sunrise"/"sunset" with an optional offset
+// like "sunset-30m" or "sunrise+1h".
+func resolveTimeSpec(spec string, day time.Time, geo GeoPoint, hasGeo bool) (string, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	var base string
+	for _, b := range []string{"sunrise", "sunset"} {
+		if strings.HasPrefix(spec, b) {
+			base = b
+		}
+	}
+	if base == "" {
+		if _, err := time.Parse("15:04", spec); err != nil {
+			return "", fmt.Errorf("bad time %q", spec)
+		}
+		return spec, nil
+	}
+	if !hasGeo {
+		return "", fmt.Errorf("%q needs LOCATION_LAT/LOCATION_LON", spec)
+	}
+
+	var offset time.Duration
+	if rest := strings.TrimPrefix(spec, base); rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return "", fmt.Errorf("bad offset in %q", spec)
+		}
+		offset = d
+	}
+
+	rise, set, err := sunTimes(day, geo)
+	if err != nil {
+		return "", err
+	}
+	t := rise
+	if base == "sunset" {
+		t = set
+	}
+	return t.Add(offset).Format("15:04"), nil
+}
+
+// parseTimeList splits a comma separated schedule list.
+func parseTimeList(raw string) []string {
+	var out []string
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	msk    = time.FixedZone("MSK", 3*3600)
+	moscow = GeoPoint{Lat: 55.7558, Lon: 37.6173}
+)
+
+func TestSunTimes(t *testing.T) {
+	tests := []struct {
+		day       time.Time
+		rise, set string // almanac, MSK
+	}{
+		{time.Date(2026, 6, 21, 12, 0, 0, 0, msk), "03:44", "21:18"},
+		{time.Date(2026, 12, 21, 12, 0, 0, 0, msk), "08:58", "15:58"},
+		{time.Date(2026, 3, 20, 12, 0, 0, 0, msk), "06:31", "18:42"},
+	}
+	near := func(got time.Time, want string) bool {
+		w, _ := time.ParseInLocation("15:04", want, msk)
+		w = time.Date(got.Year(), got.Month(), got.Day(), w.Hour(), w.Minute(), 0, 0, msk)
+		d := got.Sub(w)
+		return d > -3*time.Minute && d < 3*time.Minute
+	}
+	for _, tt := range tests {
+		rise, set, err := sunTimes(tt.day, moscow)
+		if err != nil {
+			t.Errorf("%s: %v", tt.day.Format("2006-01-02"), err)
+			continue
+		}
+		if !near(rise, tt.rise) || !near(set, tt.set) {
+			t.Errorf("%s: sunrise %s, sunset %s; want about %s and %s", tt.day.Format("2006-01-02"),
+				rise.Format("15:04"), set.Format("15:04"), tt.rise, tt.set)
+		}
+	}
+}
+
+func TestSunTimesPolarNight(t *testing.T) {
+	_, _, err := sunTimes(time.Date(2026, 12, 21, 12, 0, 0, 0, time.UTC), GeoPoint{Lat: 78.22, Lon: 15.65})
+	if !errors.Is(err, errNoSunEvent) {
+		t.Errorf("Longyearbyen in December: err = %v, want errNoSunEvent", err)
+	}
+}
+
+func TestResolveTimeSpec(t *testing.T) {
+	day := time.Date(2026, 6, 21, 12, 0, 0, 0, msk)
+	rise, set, err := sunTimes(day, moscow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		spec   string
+		hasGeo bool
+		want   string
+		err    bool
+	}{
+		{"07:30", false, "07:30", false},
+		{" 21:05 ", true, "21:05", false},
+		{"25:00", false, "", true},
+		{"утром", true, "", true},
+		{"sunrise", true, rise.Format("15:04"), false},
+		{"Sunset", true, set.Format("15:04"), false},
+		{"sunset-30m", true, set.Add(-30 * time.Minute).Format("15:04"), false},
+		{"sunrise+1h", true, rise.Add(time.Hour).Format("15:04"), false},
+		{"sunset+1x", true, "", true},
+		{"sunset", false, "", true},
+	}
+	for _, tt := range tests {
+		got, err := resolveTimeSpec(tt.spec, day, moscow, tt.hasGeo)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("resolveTimeSpec(%q, geo=%v) = %q, %v; want %q, error %v", tt.spec, tt.hasGeo, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Checklist templates. /templates lists the built-in library (in the chat's
+// language) and the chat's own; "/template переезд" makes a task with a ☐
+// checklist, or, for shopping templates, puts every line on the list.
+// "/template set Переезд: коробки; скотч" saves the chat's own version
+// (under a built-in's name it replaces the built-in for this chat), and
+// "/template del переезд" drops it again.
+
+type ChecklistTemplate struct {
+	Key   string   `json:"key"`
+	Name  string   `json:"name"`
+	Topic string   `json:"topic"` // TopicTasks or TopicShopping
+	Items []string `json:"items"`
+	Own   bool     `json:"-"` // the chat's own, not built in
+}
+
+type builtinTemplate struct {
+	Key   string
+	Topic string
+	Names map[string]string
+	Items map[string][]string
+}
+
+var builtinTemplates = []builtinTemplate{
+	{"review", TopicTasks,
+		map[string]string{LangRU: "Недельный обзор", LangEN: "Weekly review"},
+		map[string][]string{
+			LangRU: {"разобрать корзину", "закрыть выполненное", "просмотреть задачи и сроки", "календарь на прошлую и следующую неделю", "когда-нибудь: что пора начать", "цели: шаги на неделю"},
+			LangEN: {"empty the basket", "close what's done", "go over tasks and due dates", "calendar: last week and next", "someday: anything to start now", "goals: steps for the week"},
+		}},
+	{"travel", TopicTasks,
+		map[string]string{LangRU: "Поездка", LangEN: "Travel"},
+		map[string][]string{
+			LangRU: {"паспорт и документы", "билеты и брони", "страховка", "деньги и карты", "телефон и зарядка", "лекарства", "вынести мусор, полить цветы", "выключить воду и свет"},
+			LangEN: {"passport and documents", "tickets and bookings", "insurance", "cash and cards", "phone and charger", "medicine", "take out the trash, water the plants", "turn off water and lights"},
+		}},
+	{"moving", TopicTasks,
+		map[string]string{LangRU: "Переезд", LangEN: "Moving"},
+		map[string][]string{
+			LangRU: {"заказать машину и грузчиков", "коробки, скотч, плёнка", "разобрать и выбросить лишнее", "подписать коробки по комнатам", "показания счётчиков", "перенести интернет", "сменить адрес в банке и доставках", "отдать ключи"},
+			LangEN: {"book the van and movers", "boxes, tape, wrap", "sort out and throw away", "label boxes by room", "meter readings", "move the internet", "change address at the bank and deliveries", "hand over the keys"},
+		}},
+	{"groceries", TopicShopping,
+		map[string]string{LangRU: "Продукты", LangEN: "Groceries"},
+		map[string][]string{
+			LangRU: {"хлеб", "молоко", "яйца", "сыр", "овощи", "фрукты", "крупа", "кофе или чай"},
+			LangEN: {"bread", "milk", "eggs", "cheese", "vegetables", "fruit", "rice or pasta", "coffee or tea"},
+		}},
+}
+
+func (b builtinTemplate) in(lang string) ChecklistTemplate {
+	name, ok := b.Names[lang]
+	if !ok {
+		name = b.Names[DefaultLang]
+	}
+	items, ok := b.Items[lang]
+	if !ok {
+		items = b.Items[DefaultLang]
+	}
+	return ChecklistTemplate{Key: b.Key, Name: name, Topic: b.Topic, Items: items}
+}
+
+func (s *sqlStore) PutTemplate(chatID int64, t ChecklistTemplate) error {
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO chat_templates(chat_id, template, name, topic, items, created_at) VALUES(?,?,?,?,?,?)
+		 ON CONFLICT(chat_id, template) DO UPDATE SET name=excluded.name, topic=excluded.topic, items=excluded.items`,
+		chatID, t.Key, t.Name, t.Topic, strings.Join(t.Items, "\n"), time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (s *sqlStore) ChatTemplates(chatID int64) ([]ChecklistTemplate, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT template, name, topic, items FROM chat_templates WHERE chat_id=? ORDER BY template`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ChecklistTemplate
+	for rows.Next() {
+		t := ChecklistTemplate{Own: true}
+		var items string
+		if err := rows.Scan(&t.Key, &t.Name, &t.Topic, &items); err != nil {
+			return nil, err
+		}
+		if items != "" {
+			t.Items = strings.Split(items, "\n")
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) DeleteTemplate(chatID int64, key string) (bool, error) {
+	res, err := s.db(chatID).Exec(`DELETE FROM chat_templates WHERE chat_id=? AND template=?`, chatID, key)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// templates is the library as the chat sees it: built-ins in its language
+// with its own versions put over them, then its own templates.
+func (a *App) templates(chatID int64) ([]ChecklistTemplate, error) {
+	own, err := a.Store.ChatTemplates(chatID)
+	if err != nil {
+		return nil, err
+	}
+	lang := a.Store.Lang(chatID)
+	byKey := map[string]ChecklistTemplate{}
+	for _, t := range own {
+		byKey[t.Key] = t
+	}
+	var out []ChecklistTemplate
+	for _, b := range builtinTemplates {
+		t := b.in(lang)
+		if o, ok := byKey[b.Key]; ok {
+			t = o
+			delete(byKey, b.Key)
+		}
+		out = append(out, t)
+	}
+	for _, t := range own {
+		if _, ok := byKey[t.Key]; ok {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// templateKey maps a name the chat typed to a template key: a built-in's
+// key or its name in any language, else the normalized name itself.
+func templateKey(name string) string {
+	name = normalizeText(name)
+	for _, b := range builtinTemplates {
+		if name == b.Key {
+			return b.Key
+		}
+		for _, n := range b.Names {
+			if name == normalizeText(n) {
+				return b.Key
+			}
+		}
+	}
+	return name
+}
+
+func findTemplate(list []ChecklistTemplate, key string) (ChecklistTemplate, bool) {
+	for _, t := range list {
+		if t.Key == key {
+			return t, true
+		}
+	}
+	return ChecklistTemplate{}, false
+}
+
+func formatTemplateTask(t ChecklistTemplate) string {
+	var b strings.Builder
+	b.WriteString(t.Name)
+	for _, it := range t.Items {
+		b.WriteString("\n☐ ")
+		b.WriteString(it)
+	}
+	return b.String()
+}
+
+// useTemplate adds the template to the chat: one checklist task, or its
+// lines on the shopping list, skipping what's already there.
+func (a *App) useTemplate(chatID int64, t ChecklistTemplate) {
+	if t.Topic != TopicShopping {
+		text := formatTemplateTask(t)
+		id, err := a.Store.AddItem(chatID, TopicTasks, text)
+		if errors.Is(err, errLocked) {
+			a.sendLocked(chatID)
+			return
+		}
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.sendItemsOneByOne(chatID, TopicTasks, []Item{{ID: id, ChatID: chatID, Topic: TopicTasks, Text: text, CreatedAt: time.Now()}})
+		return
+	}
+
+	existing, err := a.Store.ListActive(chatID, TopicShopping)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	onList := map[string]bool{}
+	for _, it := range existing {
+		onList[normalizeText(it.Text)] = true
+	}
+	var added int
+	err = a.Store.InTx(chatID, func(tx Store) error {
+		for _, line := range t.Items {
+			if onList[normalizeText(line)] {
+				continue
+			}
+			if _, err := tx.AddItem(chatID, TopicShopping, line); err != nil {
+				return err
+			}
+			added++
+		}
+		return nil
+	})
+	if errors.Is(err, errLocked) {
+		a.sendLocked(chatID)
+		return
+	}
+	if err != nil {
+		log.Printf("template error: %v", err)
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "template.shopped", t.Name, added, len(t.Items)-added))
+}
+
+// handleTemplates handles "/templates": the library with a button each.
+func (a *App) handleTemplates(chatID int64) {
+	list, err := a.templates(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+	var b strings.Builder
+	b.WriteString(tr(lang, "templates.header"))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, t := range list {
+		mark := ""
+		if t.Own {
+			mark = " ✏️"
+		}
+		where := tr(lang, "template.where.task")
+		if t.Topic == TopicShopping {
+			where = tr(lang, "template.where.shopping")
+		}
+		fmt.Fprintf(&b, "\n\n%s%s (%s): %s", t.Name, mark, where, strings.Join(t.Items, ", "))
+		// Callback data is capped at 64 bytes
+		if data := "tpl:" + t.Key; len(data) <= 64 {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(t.Name, data)))
+		}
+	}
+	b.WriteString("\n\n" + tr(lang, "templates.help"))
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	if len(rows) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	_, _ = a.Bot.Send(msg)
+}
+
+// handleTemplate handles "/template <название> | set <название>: <пункты> | del <название>".
+func (a *App) handleTemplate(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		a.handleTemplates(chatID)
+		return
+	}
+	list, err := a.templates(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	first, rest, _ := strings.Cut(arg, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch first {
+	case "set":
+		name, lines, ok := strings.Cut(rest, ":")
+		name = strings.TrimSpace(name)
+		var items []string
+		for _, l := range strings.FieldsFunc(lines, func(r rune) bool { return r == ';' || r == '\n' }) {
+			if l = strings.TrimSpace(l); l != "" {
+				items = append(items, l)
+			}
+		}
+		if !ok || name == "" || len(items) == 0 {
+			a.send(chatID, a.tr(chatID, "template.set.usage"))
+			return
+		}
+		t := ChecklistTemplate{Key: templateKey(name), Name: name, Topic: TopicTasks, Items: items}
+		if prev, ok := findTemplate(list, t.Key); ok {
+			t.Topic = prev.Topic
+		}
+		if err := a.Store.PutTemplate(chatID, t); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "template.saved", name, len(items), name))
+	case "del":
+		found, err := a.Store.DeleteTemplate(chatID, templateKey(rest))
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		if !found {
+			a.send(chatID, a.tr(chatID, "template.own.notfound"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "template.deleted"))
+	default:
+		t, ok := findTemplate(list, templateKey(arg))
+		if !ok {
+			a.send(chatID, a.tr(chatID, "template.notfound"))
+			return
+		}
+		a.useTemplate(chatID, t)
+	}
+}
+
+// handleTemplateCallback handles "tpl:<key>".
+func (a *App) handleTemplateCallback(cq *tgbotapi.CallbackQuery, key string) {
+	chatID := cq.Message.Chat.ID
+	list, err := a.templates(chatID)
+	if err != nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.read")))
+		return
+	}
+	t, ok := findTemplate(list, key)
+	if !ok {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "template.gone")))
+		return
+	}
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	a.useTemplate(chatID, t)
+}
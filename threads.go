@@ -0,0 +1,289 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type Note struct {
+	ID        int64
+	ItemID    int64
+	Author    string
+	Text      string
+	CreatedAt time.Time
+}
+
+func (s *sqlStore) LinkThread(chatID, threadID, itemID int64) error {
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO item_threads(chat_id, thread_id, item_id) VALUES(?,?,?)
+		 ON CONFLICT(chat_id, thread_id) DO UPDATE SET item_id=excluded.item_id`,
+		chatID, threadID, itemID,
+	)
+	return err
+}
+
+func (s *sqlStore) ThreadItem(chatID, threadID int64) (int64, bool, error) {
+	var itemID int64
+	err := s.db(chatID).QueryRow(`SELECT item_id FROM item_threads WHERE chat_id=? AND thread_id=?`, chatID, threadID).Scan(&itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return itemID, err == nil, err
+}
+
+func (s *sqlStore) ItemThread(chatID, itemID int64) (int64, bool, error) {
+	var threadID int64
+	err := s.db(chatID).QueryRow(`SELECT thread_id FROM item_threads WHERE chat_id=? AND item_id=?`, chatID, itemID).Scan(&threadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return threadID, err == nil, err
+}
+
+func (s *sqlStore) AddNote(chatID, itemID int64, author, text string) error {
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO item_notes(chat_id, item_id, author, text, created_at) VALUES(?,?,?,?,?)`,
+		chatID, itemID, author, text, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (s *sqlStore) ListNotes(chatID, itemID int64) ([]Note, error) {
+	rows, err := s.db(chatID).Query(
+		`SELECT id, item_id, author, text, created_at FROM item_notes WHERE chat_id=? AND item_id=? ORDER BY id`,
+		chatID, itemID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Note
+	for rows.Next() {
+		var n Note
+		var created string
+		if err := rows.Scan(&n.ID, &n.ItemID, &n.Author, &n.Text, &created); err != nil {
+			return nil, err
+		}
+		n.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) GetItem(chatID, id int64) (*Item, error) {
+	var it Item
+	var created, completed string
+	err := s.db(chatID).QueryRow(
+		`SELECT id, chat_id, topic, text, status, flagged, secret, created_at, completed_at FROM items WHERE chat_id=? AND id=?`,
+		chatID, id,
+	).Scan(&it.ID, &it.ChatID, &it.Topic, &it.Text, &it.Status, &it.Flagged, &it.Secret, &created, &completed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	it.Text = s.openText(chatID, it.Text)
+	it.CreatedAt, _ = time.Parse(time.RFC3339, created)
+	it.CompletedAt, _ = time.Parse(time.RFC3339, completed)
+	return &it, nil
+}
+
+// groupItemKeyboard adds a "discuss" button next to ✅ for group chats.
+func groupItemKeyboard(lang string, id int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅", fmt.Sprintf("done:%d", id)),
+		tgbotapi.NewInlineKeyboardButtonData("📅", fmt.Sprintf("due:%d", id)),
+		tgbotapi.NewInlineKeyboardButtonData("↪️", fmt.Sprintf("move:%d", id)),
+		tgbotapi.NewInlineKeyboardButtonData("💬 "+tr(lang, "thread.discuss"), fmt.Sprintf("thread:%d", id)),
+	))
+}
+
+func threadTitle(text string) string {
+	r := []rune(strings.TrimSpace(strings.ReplaceAll(text, "\n", " ")))
+	if len(r) > 120 {
+		r = append(r[:119], '…')
+	}
+	return string(r)
+}
+
+// openItemThread creates a forum topic for the item (or reuses the existing
+// one) and posts the item there. Only works in forum-enabled supergroups.
+func (a *App) openItemThread(cq *tgbotapi.CallbackQuery, itemID int64) {
+	chatID := cq.Message.Chat.ID
+	lang := a.Store.Lang(chatID)
+
+	if threadID, ok, _ := a.Store.ItemThread(chatID, itemID); ok {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "thread.exists", threadID)))
+		return
+	}
+
+	it, err := a.Store.GetItem(chatID, itemID)
+	if err != nil || it == nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "item.missing")))
+		return
+	}
+
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params["name"] = threadTitle(fmt.Sprintf("#%d %s", it.ID, shownText(*it)))
+	resp, err := a.Bot.MakeRequest("createForumTopic", params)
+	if err != nil {
+		log.Printf("create forum topic error: %v", err)
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "thread.unavailable")))
+		return
+	}
+	var topic struct {
+		MessageThreadID int64 `json:"message_thread_id"`
+	}
+	if err := json.Unmarshal(resp.Result, &topic); err != nil || topic.MessageThreadID == 0 {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "thread.failed")))
+		return
+	}
+	if err := a.Store.LinkThread(chatID, topic.MessageThreadID, itemID); err != nil {
+		log.Printf("link thread error: %v", err)
+	}
+
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, tr(lang, "thread.created")))
+	a.sendToThread(chatID, topic.MessageThreadID, formatSingleItem(lang, it.Topic, *it)+"\n\n"+tr(lang, "thread.notes"))
+}
+
+func (a *App) sendToThread(chatID, threadID int64, text string) {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonZero64("message_thread_id", threadID)
+	params["text"] = text
+	if _, err := a.Bot.MakeRequest("sendMessage", params); err != nil {
+		log.Printf("send to thread error: %v", err)
+	}
+}
+
+// topicThreads keeps the forum topic of incoming messages until they are
+// handled. message_thread_id is newer than the Bot API library, which drops
+// it, so the transport reads it from the raw update (see note).
+type topicThreads struct {
+	mu sync.Mutex
+	m  map[[2]int64]int64 // chat id, message id → thread id
+}
+
+// maxTopicThreads bounds the messages noted but never handled, such as
+// commands in a topic.
+const maxTopicThreads = 4096
+
+type topicUpdate struct {
+	Message *struct {
+		MessageID       int64 `json:"message_id"`
+		MessageThreadID int64 `json:"message_thread_id"`
+		IsTopicMessage  bool  `json:"is_topic_message"`
+		Chat            struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+// note records the topics of the messages in raw, one update or a list.
+func (tt *topicThreads) note(raw []byte) {
+	var upds []topicUpdate
+	if json.Unmarshal(raw, &upds) != nil {
+		var u topicUpdate
+		if json.Unmarshal(raw, &u) != nil {
+			return
+		}
+		upds = []topicUpdate{u}
+	}
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	for _, u := range upds {
+		m := u.Message
+		if m == nil || !m.IsTopicMessage || m.MessageThreadID == 0 {
+			continue
+		}
+		if tt.m == nil || len(tt.m) >= maxTopicThreads {
+			tt.m = map[[2]int64]int64{}
+		}
+		tt.m[[2]int64{m.Chat.ID, m.MessageID}] = m.MessageThreadID
+	}
+}
+
+// take returns and forgets the topic of m, 0 outside forum topics.
+func (tt *topicThreads) take(m *tgbotapi.Message) int64 {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	k := [2]int64{m.Chat.ID, int64(m.MessageID)}
+	id := tt.m[k]
+	delete(tt.m, k)
+	return id
+}
+
+// captureThreadNote stores messages posted inside an item's forum topic as
+// notes, found by the message's thread id. Without one it falls back to
+// reply_to_message, which Telegram sets to the topic's service message
+// (whose id equals the thread id) unless the message replies to another.
+func (a *App) captureThreadNote(m *tgbotapi.Message) bool {
+	if !isGroupChat(m.Chat.ID) {
+		return false
+	}
+	chatID := m.Chat.ID
+	threadID := a.topics.take(m)
+	if threadID == 0 && m.ReplyToMessage != nil {
+		threadID = int64(m.ReplyToMessage.MessageID)
+	}
+	if threadID == 0 {
+		return false
+	}
+	itemID, ok, err := a.Store.ThreadItem(chatID, threadID)
+	if err != nil || !ok {
+		return false
+	}
+	text := strings.TrimSpace(m.Text)
+	if text == "" {
+		text = strings.TrimSpace(m.Caption)
+	}
+	if text == "" {
+		return true
+	}
+	author := ""
+	if m.From != nil {
+		author = userDisplayName(m.From)
+	}
+	if err := a.Store.AddNote(chatID, itemID, author, text); err != nil {
+		log.Printf("add note error: %v", err)
+	}
+	return true
+}
+
+func formatNotes(notes []Note, tz *time.Location) string {
+	var b strings.Builder
+	for _, n := range notes {
+		fmt.Fprintf(&b, "\n— %s %s: %s", n.CreatedAt.In(tz).Format("02.01 15:04"), n.Author, n.Text)
+	}
+	return b.String()
+}
+
+func (a *App) handleNotes(chatID int64, arg string) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(arg), "#"), 10, 64)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "notes.usage"))
+		return
+	}
+	notes, err := a.Store.ListNotes(chatID, id)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if len(notes) == 0 {
+		a.send(chatID, a.tr(chatID, "notes.none"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "notes.header", id)+formatNotes(notes, a.tz(chatID)))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Each chat can live in its own timezone: "/timezone Asia/Novosibirsk",
+// or a shared location, which gives the zone by longitude (a fixed offset,
+// no DST). Times the chat types are read in it, and the scheduler fires
+// the chat's digests and reminders by it. TZ stays the default for chats
+// that never set one; /travel still overrides both while it lasts.
+
+// chatTimezone is the chat's own timezone, home if it has none.
+func chatTimezone(store Store, chatID int64, home *time.Location) *time.Location {
+	name, ok, err := store.GetKV(chatKey(chatID, "timezone"))
+	if err != nil || !ok {
+		return home
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return home
+	}
+	return loc
+}
+
+// tz is where the chat is right now: a live /travel override, otherwise
+// the chat's timezone.
+func (a *App) tz(chatID int64) *time.Location {
+	if t, err := a.Store.GetTravel(chatID); err == nil && t != nil && time.Now().Before(t.Until) {
+		return t.Loc
+	}
+	return chatTimezone(a.Store, chatID, a.TZ)
+}
+
+// offsetZone is the Etc/GMT zone for a UTC offset in hours; Etc names
+// have the sign flipped.
+func offsetZone(hours int) string {
+	switch {
+	case hours == 0:
+		return "UTC"
+	case hours > 0:
+		return fmt.Sprintf("Etc/GMT-%d", hours)
+	default:
+		return fmt.Sprintf("Etc/GMT+%d", -hours)
+	}
+}
+
+// parseTimezone takes an IANA name or an offset like "+3", "UTC+5", "GMT-4".
+func parseTimezone(arg string) (*time.Location, error) {
+	arg = strings.TrimSpace(arg)
+	off := strings.TrimPrefix(strings.TrimPrefix(strings.ToUpper(arg), "UTC"), "GMT")
+	if off != "" && (off[0] == '+' || off[0] == '-') {
+		var h int
+		if _, err := fmt.Sscanf(off, "%d", &h); err == nil && h >= -12 && h <= 14 {
+			return time.LoadLocation(offsetZone(h))
+		}
+	}
+	return time.LoadLocation(arg)
+}
+
+func (a *App) setTimezone(chatID int64, loc *time.Location, approx bool) {
+	if err := a.Store.SetKV(chatKey(chatID, "timezone"), loc.String()); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	text := "🕰 " + a.tr(chatID, "tz.current", loc, time.Now().In(loc).Format("15:04"))
+	if approx {
+		text += " " + a.tr(chatID, "tz.approx")
+	}
+	a.send(chatID, text)
+}
+
+// handleTimezone handles "/timezone [<зона> | off]".
+func (a *App) handleTimezone(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	switch arg {
+	case "":
+		loc := chatTimezone(a.Store, chatID, a.TZ)
+		a.send(chatID, "🕰 "+a.tr(chatID, "tz.current", loc, time.Now().In(loc).Format("15:04"))+" "+a.tr(chatID, "tz.hint"))
+	case "off", "reset":
+		if err := a.Store.DeleteKV(chatKey(chatID, "timezone")); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, "🕰 "+a.tr(chatID, "tz.default", a.TZ.String()))
+	default:
+		loc, err := parseTimezone(arg)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "tz.unknown"))
+			return
+		}
+		a.setTimezone(chatID, loc, false)
+	}
+}
+
+// timezoneFromLocation sets the zone from a shared location's longitude.
+func (a *App) timezoneFromLocation(m *tgbotapi.Message) bool {
+	if m.Location == nil {
+		return false
+	}
+	hours := int(math.Round(m.Location.Longitude / 15))
+	loc, err := time.LoadLocation(offsetZone(hours))
+	if err != nil {
+		return false
+	}
+	a.setTimezone(m.Chat.ID, loc, true)
+	return true
+}
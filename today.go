@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultCapacityMinutes = 360
+
+// Capacity returns the chat's daily capacity in minutes:
+// /capacity override, then DAILY_CAPACITY_MINUTES, then the default.
+func (s *sqlStore) Capacity(chatID int64) int {
+	if v, ok, _ := s.GetKV(chatKey(chatID, "capacity")); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	n, err := strconv.Atoi(envOr("DAILY_CAPACITY_MINUTES", strconv.Itoa(defaultCapacityMinutes)))
+	if err != nil || n <= 0 {
+		return defaultCapacityMinutes
+	}
+	return n
+}
+
+func formatMinutes(lang string, m int) string {
+	if m < 60 {
+		return tr(lang, "minutes.m", m)
+	}
+	if m%60 == 0 {
+		return tr(lang, "minutes.h", m/60)
+	}
+	return tr(lang, "minutes.hm", m/60, m%60)
+}
+
+// planDeferrals picks the lowest ranked estimated tasks of today's (see
+// dueToday) to push out until the total fits into capacity. Estimates are scaled by bias.
+func planDeferrals(items []Item, capacity int, now time.Time, bias EstimateBias) []nextCandidate {
+	total := 0
+	var est []nextCandidate
+	for _, it := range items {
+		if e, ok := parseEffort(it.Text); ok {
+			total += bias.Adjust(it, e)
+			est = append(est, scoreNext(it, now))
+		}
+	}
+	sort.SliceStable(est, func(i, j int) bool { return est[i].Score < est[j].Score })
+
+	var out []nextCandidate
+	for _, c := range est {
+		if total <= capacity {
+			break
+		}
+		e, _ := parseEffort(c.Item.Text)
+		total -= bias.Adjust(c.Item, e)
+		out = append(out, c)
+	}
+	return out
+}
+
+// dueToday is the part of items that is scheduled for today: due by the
+// end of the day (overdue included) or with a reminder set for today.
+func (a *App) dueToday(chatID int64, items []Item, now time.Time) []Item {
+	now = now.In(a.tz(chatID))
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 0, 1)
+	var out []Item
+	for _, it := range items {
+		if !it.Due.IsZero() && it.Due.Before(end) {
+			out = append(out, it)
+			continue
+		}
+		if at, err := a.Store.RemindAt(chatID, it.ID); err == nil && !at.Before(start) && at.Before(end) {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+func (a *App) handleToday(chatID int64) {
+	lang := a.Store.Lang(chatID)
+	items, err := a.Store.ListActive(chatID, TopicTasks)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	f, focused := a.focusFor(chatID)
+	if focused {
+		items = f.Filter(items)
+	}
+	if len(items) == 0 {
+		a.send(chatID, tr(lang, "today.none"))
+		return
+	}
+
+	var b strings.Builder
+	if focused {
+		b.WriteString(f.Banner(lang, a.tz(chatID)) + "\n")
+	}
+	b.WriteString(tr(lang, "today.header") + "\n")
+	now := time.Now()
+	bias, err := loadEstimateBias(a.Store, chatID, now)
+	if err != nil {
+		bias = EstimateBias{Ratio: 1}
+	}
+	for _, it := range items {
+		fmt.Fprintf(&b, "#%d %s\n", it.ID, shownText(it))
+	}
+
+	scheduled := a.dueToday(chatID, items, now)
+	total, adjusted, unestimated := 0, 0, 0
+	for _, it := range scheduled {
+		if e, ok := parseEffort(it.Text); ok {
+			total += e
+			adjusted += bias.Adjust(it, e)
+		} else {
+			unestimated++
+		}
+	}
+
+	capacity := a.Store.Capacity(chatID)
+	b.WriteString("\n" + tr(lang, "today.scheduled", len(scheduled), formatMinutes(lang, total), formatMinutes(lang, capacity)))
+	if unestimated > 0 {
+		b.WriteString(tr(lang, "today.unestimated", unestimated))
+	}
+	if adjusted != total {
+		b.WriteString("\n" + tr(lang, "today.adjusted", formatMinutes(lang, adjusted)))
+		if note := bias.Note(lang); note != "" {
+			b.WriteString(" — " + note)
+		}
+	}
+
+	if adjusted > capacity {
+		b.WriteString("\n" + tr(lang, "today.overload", formatMinutes(lang, adjusted-capacity)))
+		for _, c := range planDeferrals(scheduled, capacity, now, bias) {
+			fmt.Fprintf(&b, "\n— #%d %s", c.Item.ID, shownText(c.Item))
+		}
+	}
+	a.send(chatID, b.String())
+}
+
+func (a *App) handleCapacity(chatID int64, arg string) {
+	lang := a.Store.Lang(chatID)
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		a.send(chatID, tr(lang, "capacity.show", formatMinutes(lang, a.Store.Capacity(chatID)))+" "+tr(lang, "capacity.hint"))
+		return
+	}
+	n, ok := parseEffort("~" + strings.TrimPrefix(arg, "~"))
+	if !ok {
+		if v, err := strconv.Atoi(arg); err == nil {
+			n, ok = v, true
+		}
+	}
+	if !ok || n <= 0 {
+		a.send(chatID, tr(lang, "capacity.usage"))
+		return
+	}
+	if err := a.Store.SetKV(chatKey(chatID, "capacity"), strconv.Itoa(n)); err != nil {
+		a.send(chatID, tr(lang, "err.write"))
+		return
+	}
+	a.send(chatID, tr(lang, "capacity.show", formatMinutes(lang, n)))
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Besides the built-in topics a chat can keep its own lists: /newlist
+// книги adds a "книги" button, /dellist книги removes it. A custom topic's
+// key in items.topic is its normalized name; the button shows the name as
+// typed.
+
+const (
+	maxCustomTopics   = 10
+	maxTopicNameRunes = 20
+)
+
+type CustomTopic struct {
+	Topic string `json:"topic"`
+	Name  string `json:"name"`
+}
+
+func (s *sqlStore) CustomTopics(chatID int64) ([]CustomTopic, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT topic, name FROM topics WHERE chat_id=? ORDER BY created_at, topic`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []CustomTopic
+	for rows.Next() {
+		var t CustomTopic
+		if err := rows.Scan(&t.Topic, &t.Name); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) AddTopic(chatID int64, topic, name string) error {
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO topics(chat_id, topic, name, created_at) VALUES(?,?,?,?)`,
+		chatID, topic, name, time.Now().UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+// DeleteTopic drops a custom topic; its active items go to the basket.
+func (s *sqlStore) DeleteTopic(chatID int64, topic string) (int, error) {
+	var moved int
+	err := s.For(chatID).inTx(func(tx *sqlStore) error {
+		res, err := tx.DB.Exec(`UPDATE items SET topic=? WHERE chat_id=? AND topic=? AND status=?`, TopicBasket, chatID, topic, StatusActive)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		moved = int(n)
+		_, err = tx.DB.Exec(`DELETE FROM topics WHERE chat_id=? AND topic=?`, chatID, topic)
+		return err
+	})
+	return moved, err
+}
+
+// chatTopics is every topic of the chat: built-in first, then custom.
+func chatTopics(store Store, chatID int64) []string {
+	out := append([]string{}, keyboardTopics...)
+	custom, _ := store.CustomTopics(chatID)
+	for _, t := range custom {
+		out = append(out, t.Topic)
+	}
+	return out
+}
+
+func customTopicName(store Store, chatID int64, topic string) (string, bool) {
+	custom, _ := store.CustomTopics(chatID)
+	for _, t := range custom {
+		if t.Topic == topic {
+			return t.Name, true
+		}
+	}
+	return "", false
+}
+
+// customTopicKey is the topic key for a list name; ':' is taken by
+// callback data, so it doesn't survive.
+func customTopicKey(name string) string {
+	return strings.ReplaceAll(normalizeText(name), ":", " ")
+}
+
+// handleNewList handles "/newlist <название>".
+func (a *App) handleNewList(chatID int64, arg string) {
+	name := strings.TrimSpace(arg)
+	topic := customTopicKey(name)
+	if topic == "" {
+		a.send(chatID, a.tr(chatID, "newlist.usage"))
+		return
+	}
+	if utf8.RuneCountInString(name) > maxTopicNameRunes {
+		a.send(chatID, a.tr(chatID, "newlist.long", maxTopicNameRunes))
+		return
+	}
+	if _, taken := a.topicFromButton(chatID, name); taken || topic == listAll || keyboardAction(name) != "" {
+		a.send(chatID, a.tr(chatID, "newlist.taken"))
+		return
+	}
+	custom, err := a.Store.CustomTopics(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if len(custom) >= maxCustomTopics {
+		a.send(chatID, a.tr(chatID, "newlist.limit", maxCustomTopics))
+		return
+	}
+	if err := a.Store.AddTopic(chatID, topic, name); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, a.tr(chatID, "newlist.added", name))
+}
+
+// handleDelList handles "/dellist <название>".
+func (a *App) handleDelList(chatID int64, arg string) {
+	name := strings.TrimSpace(arg)
+	topic, ok := a.topicFromButton(chatID, name)
+	if name == "" || !ok {
+		a.send(chatID, a.tr(chatID, "dellist.usage"))
+		return
+	}
+	label, custom := customTopicName(a.Store, chatID, topic)
+	if !custom {
+		a.send(chatID, a.tr(chatID, "dellist.builtin"))
+		return
+	}
+	moved, err := a.Store.DeleteTopic(chatID, topic)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	if err := a.Store.DeleteKV(chatKey(chatID, "topic_name:"+topic)); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	if a.States.Get(chatID).Topic == topic {
+		a.resetToMenu(chatID)
+	}
+	text := a.tr(chatID, "dellist.done", label)
+	if moved > 0 {
+		text += " " + a.tr(chatID, "dellist.moved", moved)
+	}
+	a.send(chatID, text)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time tracking and what it teaches about estimates. "/timer 12" starts
+// the clock on task #12 (stopping whatever ran before), "/timer stop" logs
+// it; "/spent 12 45м" logs time after the fact. Done tasks that had a "~30м"
+// estimate and tracked time give the ratio of actual to estimated, overall
+// and per tag; /today scales its estimates by it before comparing with
+// capacity, and /estimates shows the numbers.
+
+const (
+	minEstimateSamples    = 5 // done tasks before the overall ratio counts
+	minTagEstimateSamples = 3
+	estimateLookback      = 180 // days
+)
+
+func (s *sqlStore) AddTracked(chatID, itemID int64, minutes int) error {
+	_, err := s.db(chatID).Exec(
+		`INSERT INTO time_entries(chat_id, item_id, minutes, created_at) VALUES(?,?,?,?)`,
+		chatID, itemID, minutes, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// TrackedMinutes sums the tracked time per item.
+func (s *sqlStore) TrackedMinutes(chatID int64) (map[int64]int, error) {
+	rows, err := s.readDB(chatID).Query(`SELECT item_id, SUM(minutes) FROM time_entries WHERE chat_id=? GROUP BY item_id`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]int{}
+	for rows.Next() {
+		var id int64
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		out[id] = n
+	}
+	return out, rows.Err()
+}
+
+// EstimateBias is how long tasks actually take per minute estimated.
+type EstimateBias struct {
+	Ratio   float64
+	Samples int
+	Tags    map[string]EstimateBias
+}
+
+// estimateBias compares estimates of done items with their tracked time.
+func estimateBias(done []Item, tracked map[int64]int) EstimateBias {
+	type sums struct{ est, act, n int }
+	var all sums
+	byTag := map[string]*sums{}
+	for _, it := range done {
+		est, ok := parseEffort(it.Text)
+		act := tracked[it.ID]
+		if !ok || est == 0 || act == 0 {
+			continue
+		}
+		all.est, all.act, all.n = all.est+est, all.act+act, all.n+1
+		for _, t := range itemTags(it.Text) {
+			if byTag[t] == nil {
+				byTag[t] = &sums{}
+			}
+			byTag[t].est += est
+			byTag[t].act += act
+			byTag[t].n++
+		}
+	}
+	b := EstimateBias{Ratio: 1, Samples: all.n, Tags: map[string]EstimateBias{}}
+	if all.est > 0 {
+		b.Ratio = float64(all.act) / float64(all.est)
+	}
+	for t, s := range byTag {
+		b.Tags[t] = EstimateBias{Ratio: float64(s.act) / float64(s.est), Samples: s.n}
+	}
+	return b
+}
+
+// Adjust scales an estimate by the item's tags (the first with enough
+// samples), else by the overall ratio once that has enough.
+func (b EstimateBias) Adjust(it Item, minutes int) int {
+	for _, t := range itemTags(it.Text) {
+		if tb, ok := b.Tags[t]; ok && tb.Samples >= minTagEstimateSamples {
+			return int(math.Round(float64(minutes) * tb.Ratio))
+		}
+	}
+	if b.Samples >= minEstimateSamples {
+		return int(math.Round(float64(minutes) * b.Ratio))
+	}
+	return minutes
+}
+
+// Note is "you usually underestimate by 40%", or "" when estimates are
+// about right or there's too little data.
+func (b EstimateBias) Note(lang string) string {
+	if b.Samples < minEstimateSamples {
+		return ""
+	}
+	pct := int(math.Round((b.Ratio - 1) * 100))
+	switch {
+	case pct >= 15:
+		return tr(lang, "estimate.under", pct)
+	case pct <= -15:
+		return tr(lang, "estimate.over", -pct)
+	}
+	return ""
+}
+
+func loadEstimateBias(store Store, chatID int64, now time.Time) (EstimateBias, error) {
+	done, err := store.ListCompleted(chatID, now.AddDate(0, 0, -estimateLookback), now)
+	if err != nil {
+		return EstimateBias{}, err
+	}
+	tracked, err := store.TrackedMinutes(chatID)
+	if err != nil {
+		return EstimateBias{}, err
+	}
+	return estimateBias(done, tracked), nil
+}
+
+// stopTimer logs the running timer, if any; it returns the item and minutes.
+func (a *App) stopTimer(chatID int64) (int64, int, error) {
+	key := chatKey(chatID, "timer")
+	raw, ok, err := a.Store.GetKV(key)
+	if err != nil || !ok {
+		return 0, 0, err
+	}
+	idStr, startStr, _ := strings.Cut(raw, "|")
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	start, _ := time.Parse(time.RFC3339, startStr)
+	minutes := max(1, int(math.Round(time.Since(start).Minutes())))
+	if err := a.Store.AddTracked(chatID, id, minutes); err != nil {
+		return 0, 0, err
+	}
+	return id, minutes, a.Store.DeleteKV(key)
+}
+
+// handleTimer handles "/timer [<id> | stop]".
+func (a *App) handleTimer(chatID int64, arg string) {
+	lang := a.Store.Lang(chatID)
+	arg = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(arg), "#"))
+	key := chatKey(chatID, "timer")
+	switch arg {
+	case "":
+		raw, ok, err := a.Store.GetKV(key)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if !ok {
+			a.send(chatID, tr(lang, "timer.idle")+" "+tr(lang, "timer.hint"))
+			return
+		}
+		idStr, startStr, _ := strings.Cut(raw, "|")
+		start, _ := time.Parse(time.RFC3339, startStr)
+		a.send(chatID, tr(lang, "timer.running", idStr, formatMinutes(lang, int(time.Since(start).Minutes()))))
+	case "stop":
+		id, minutes, err := a.stopTimer(chatID)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		if id == 0 {
+			a.send(chatID, tr(lang, "timer.idle"))
+			return
+		}
+		a.send(chatID, tr(lang, "timer.logged", id, formatMinutes(lang, minutes)))
+	default:
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			a.send(chatID, tr(lang, "timer.usage"))
+			return
+		}
+		it, err := a.Store.GetItem(chatID, id)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.read"))
+			return
+		}
+		if it == nil {
+			a.send(chatID, tr(lang, "item.notfound", id))
+			return
+		}
+		prev, minutes, err := a.stopTimer(chatID)
+		if err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		if err := a.Store.SetKV(key, strconv.FormatInt(id, 10)+"|"+time.Now().UTC().Format(time.RFC3339)); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		text := tr(lang, "timer.started", id, shownText(*it))
+		if prev != 0 {
+			text += tr(lang, "timer.previous", prev, formatMinutes(lang, minutes))
+		}
+		a.send(chatID, text)
+	}
+}
+
+// handleSpent handles "/spent <id> <длительность>".
+func (a *App) handleSpent(chatID int64, arg string) {
+	lang := a.Store.Lang(chatID)
+	idStr, dur, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	id, err := strconv.ParseInt(strings.TrimPrefix(idStr, "#"), 10, 64)
+	minutes, ok := parseEffort("~" + strings.TrimPrefix(strings.TrimSpace(dur), "~"))
+	if err != nil || !ok || minutes <= 0 {
+		a.send(chatID, tr(lang, "spent.usage"))
+		return
+	}
+	it, err := a.Store.GetItem(chatID, id)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if it == nil {
+		a.send(chatID, tr(lang, "item.notfound", id))
+		return
+	}
+	if err := a.Store.AddTracked(chatID, id, minutes); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	a.send(chatID, tr(lang, "timer.logged", id, formatMinutes(lang, minutes)))
+}
+
+// handleEstimates handles "/estimates": actual vs estimated, overall and
+// per tag.
+func (a *App) handleEstimates(chatID int64) {
+	lang := a.Store.Lang(chatID)
+	b, err := loadEstimateBias(a.Store, chatID, time.Now())
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if b.Samples == 0 {
+		a.send(chatID, tr(lang, "estimates.none"))
+		return
+	}
+	var out strings.Builder
+	out.WriteString(tr(lang, "estimates.header", b.Samples, b.Ratio))
+	if note := b.Note(lang); note != "" {
+		out.WriteString(" — " + note)
+	}
+	tags := make([]string, 0, len(b.Tags))
+	for t := range b.Tags {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return b.Tags[tags[i]].Samples > b.Tags[tags[j]].Samples })
+	for _, t := range tags {
+		out.WriteString("\n" + tr(lang, "estimates.tag", t, b.Tags[t].Ratio, b.Tags[t].Samples))
+	}
+	if b.Samples < minEstimateSamples {
+		out.WriteString("\n\n" + tr(lang, "estimates.few", minEstimateSamples))
+	}
+	a.send(chatID, out.String())
+}
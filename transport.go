@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Updates arrive by webhook when WEBHOOK_URL is set, otherwise by long
+// polling. In webhook mode a watchdog asks getWebhookInfo whether Telegram
+// is failing to deliver; if so, and nothing has arrived, the bot drops the
+// webhook, switches to polling for the rest of the run and tells the owner.
+// Serverless deployments (Cloud Run) set WEBHOOK_URL, listen on $PORT and
+// turn the watchdog off with WEBHOOK_CHECK_MINUTES=0. Extra bots (BOTS) get
+// WEBHOOK_URL/<name> on the same listener.
+//
+// Telegram sends WEBHOOK_SECRET (random per run when unset) back in the
+// X-Telegram-Bot-Api-Secret-Token header; requests without it are refused,
+// so knowing the URL isn't enough to forge updates.
+
+type transport struct {
+	bot     *tgbotapi.BotAPI
+	store   Store
+	topics  *topicThreads
+	out     chan tgbotapi.Update
+	lastHit atomic.Int64 // unix time of the last webhook update
+	polling atomic.Bool
+
+	webhookURL string
+	secret     string
+	listen     string
+	interval   time.Duration
+}
+
+func newTransportFromEnv(bot *tgbotapi.BotAPI, store Store, name string, topics *topicThreads) *transport {
+	// 0 turns the watchdog off, e.g. on Cloud Run where polling can't work
+	mins, err := strconv.Atoi(envOr("WEBHOOK_CHECK_MINUTES", "2"))
+	if err != nil || mins < 0 {
+		mins = 2
+	}
+	return &transport{
+		bot:        bot,
+		store:      store,
+		topics:     topics,
+		out:        make(chan tgbotapi.Update, 100),
+		webhookURL: botWebhookURL(strings.TrimSpace(os.Getenv("WEBHOOK_URL")), name),
+		secret:     strings.TrimSpace(os.Getenv("WEBHOOK_SECRET")),
+		listen:     listenAddr(),
+		interval:   time.Duration(mins) * time.Minute,
+	}
+}
+
+// botWebhookURL is base for the BOT_TOKEN bot and base/<name> for the others.
+func botWebhookURL(base, name string) string {
+	if base == "" || name == "" {
+		return base
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + name
+	return u.String()
+}
+
+// listenAddr is LISTEN_ADDR, then the older WEBHOOK_LISTEN, then $PORT as
+// set by Cloud Run and similar platforms.
+func listenAddr() string {
+	for _, k := range []string{"LISTEN_ADDR", "WEBHOOK_LISTEN"} {
+		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+			return v
+		}
+	}
+	if port := strings.TrimSpace(os.Getenv("PORT")); port != "" {
+		return ":" + port
+	}
+	return ":8443"
+}
+
+// Start begins receiving and returns the channel all updates go to.
+func (t *transport) Start(ctx context.Context) (<-chan tgbotapi.Update, error) {
+	if t.webhookURL == "" {
+		if err := t.startPolling(ctx); err != nil {
+			return nil, err
+		}
+		return t.out, nil
+	}
+	if err := t.startWebhook(ctx); err != nil {
+		return nil, err
+	}
+	if t.interval > 0 {
+		go t.watch(ctx)
+	}
+	return t.out, nil
+}
+
+// maxUpdateBytes bounds a webhook request body.
+const maxUpdateBytes = 1 << 20
+
+// decode reads raw updates, one or a list, into v and notes their messages'
+// forum topics, which tgbotapi.Message has no field for.
+func (t *transport) decode(raw []byte, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return err
+	}
+	if t.topics != nil {
+		t.topics.note(raw)
+	}
+	return nil
+}
+
+func (t *transport) startPolling(ctx context.Context) error {
+	// getUpdates refuses to work while a webhook is set
+	if _, err := t.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	t.polling.Store(true)
+
+	// Our own loop rather than GetUpdatesChan, whose stop can't be undone:
+	// after a lost HA lease the next term polls again. Updates fetched but
+	// not handed on are never confirmed, so whoever polls next gets them.
+	go func() {
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 30
+		for ctx.Err() == nil {
+			var upds []tgbotapi.Update
+			resp, err := t.bot.Request(u)
+			if err == nil {
+				err = t.decode(resp.Result, &upds)
+			}
+			if err != nil {
+				log.Printf("transport: getUpdates: %v", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(3 * time.Second):
+				}
+				continue
+			}
+			for _, upd := range upds {
+				if upd.UpdateID < u.Offset {
+					continue
+				}
+				select {
+				case t.out <- upd:
+					u.Offset = upd.UpdateID + 1
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	log.Printf("transport: long polling")
+	return nil
+}
+
+// newWebhookSecret is a secret_token for setWebhook: 1-256 of [A-Za-z0-9_-].
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// secretOK reports whether the request carries the webhook's secret token.
+func (t *transport) secretOK(r *http.Request) bool {
+	got := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	return subtle.ConstantTimeCompare([]byte(got), []byte(t.secret)) == 1
+}
+
+func (t *transport) startWebhook(ctx context.Context) error {
+	u, err := url.Parse(t.webhookURL)
+	if err != nil {
+		return err
+	}
+	if t.secret == "" {
+		if t.secret, err = newWebhookSecret(); err != nil {
+			return err
+		}
+	}
+	// tgbotapi v5.5.1's WebhookConfig has no secret_token field
+	params := tgbotapi.Params{}
+	params["url"] = u.String()
+	params["secret_token"] = t.secret
+	if _, err := t.bot.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("set webhook: %w", err)
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	webhooks.Handle(ctx, t.listen, path, func(w http.ResponseWriter, r *http.Request) {
+		if !t.secretOK(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if t.polling.Load() {
+			// Late delivery after failover; polling gets it too
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST only", http.StatusMethodNotAllowed)
+			return
+		}
+		var upd tgbotapi.Update
+		raw, err := io.ReadAll(io.LimitReader(r.Body, maxUpdateBytes))
+		if err == nil {
+			err = t.decode(raw, &upd)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		t.lastHit.Store(time.Now().Unix())
+		select {
+		case t.out <- upd:
+		case <-ctx.Done():
+			// Shutting down; Telegram redelivers on a non-2xx reply
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		}
+	})
+	t.lastHit.Store(time.Now().Unix())
+	log.Printf("transport: webhook %s (listening on %s)", t.webhookURL, t.listen)
+	return nil
+}
+
+// webhookMux serves every bot's webhook path on one listener. The server
+// starts with the first path and stops when the last one's ctx ends.
+type webhookMux struct {
+	mu     sync.Mutex
+	srv    *http.Server
+	routes map[string]webhookRoute
+	gen    int
+}
+
+type webhookRoute struct {
+	h   http.HandlerFunc
+	gen int // a later term's route for the path outlives this one's ctx
+}
+
+var webhooks = &webhookMux{routes: map[string]webhookRoute{}}
+
+func (m *webhookMux) Handle(ctx context.Context, listen, path string, h http.HandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gen++
+	gen := m.gen
+	m.routes[path] = webhookRoute{h: h, gen: gen}
+	if m.srv == nil {
+		srv := &http.Server{Addr: listen, Handler: m}
+		m.srv = srv
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("transport: webhook server: %v", err)
+			}
+		}()
+	}
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		if m.routes[path].gen == gen {
+			delete(m.routes, path)
+		}
+		srv := m.srv
+		if len(m.routes) > 0 {
+			srv = nil
+		} else {
+			m.srv = nil
+		}
+		m.mu.Unlock()
+		if srv != nil {
+			sctx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+			defer cancel()
+			_ = srv.Shutdown(sctx)
+		}
+	}()
+}
+
+func (m *webhookMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	route, ok := m.routes[r.URL.Path]
+	m.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	route.h(w, r)
+}
+
+// webhookBroken decides from getWebhookInfo whether delivery has stalled:
+// Telegram reports a recent error and nothing reached us since the last check.
+func webhookBroken(info tgbotapi.WebhookInfo, lastHit, now time.Time, interval time.Duration) bool {
+	if now.Sub(lastHit) < interval {
+		return false
+	}
+	if info.URL == "" {
+		return true
+	}
+	recentErr := info.LastErrorDate != 0 && now.Sub(time.Unix(int64(info.LastErrorDate), 0)) < 2*interval
+	return recentErr && info.PendingUpdateCount > 0
+}
+
+func (t *transport) watch(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := t.bot.GetWebhookInfo()
+		if err != nil {
+			log.Printf("transport: getWebhookInfo: %v", err)
+			continue
+		}
+		if !webhookBroken(info, time.Unix(t.lastHit.Load(), 0), time.Now(), t.interval) {
+			continue
+		}
+
+		log.Printf("transport: webhook stalled (%d pending, last error %q); falling back to polling", info.PendingUpdateCount, info.LastErrorMessage)
+		if err := t.startPolling(ctx); err != nil {
+			log.Printf("transport: failover failed: %v", err)
+			continue
+		}
+		t.alertOwner("webhook.stalled", info.LastErrorMessage)
+		return
+	}
+}
+
+func (t *transport) alertOwner(key string, args ...any) {
+	chatID, ok := ownerChatID()
+	if !ok {
+		return
+	}
+	if _, err := t.bot.Send(tgbotapi.NewMessage(chatID, "⚠️ "+tr(t.store.Lang(chatID), key, args...))); err != nil {
+		log.Printf("transport: owner alert: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookSecret(t *testing.T) {
+	secret, err := newWebhookSecret()
+	if err != nil || len(secret) != 64 {
+		t.Fatalf("newWebhookSecret = %q, %v", secret, err)
+	}
+	tr := &transport{secret: secret}
+	tests := []struct {
+		header string
+		ok     bool
+	}{
+		{secret, true},
+		{"", false},
+		{secret[:63], false},
+		{secret + "x", false},
+		{"forged", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", nil)
+		if tt.header != "" {
+			r.Header.Set("X-Telegram-Bot-Api-Secret-Token", tt.header)
+		}
+		if got := tr.secretOK(r); got != tt.ok {
+			t.Errorf("secretOK(%.10q) = %v, want %v", tt.header, got, tt.ok)
+		}
+	}
+}
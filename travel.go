@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type Travel struct {
+	Loc   *time.Location
+	Until time.Time // exclusive, midnight after the last travel day
+}
+
+// GetTravel returns the chat's temporary timezone override, if any.
+func (s *sqlStore) GetTravel(chatID int64) (*Travel, error) {
+	v, ok, err := s.GetKV(chatKey(chatID, "travel"))
+	if err != nil || !ok {
+		return nil, err
+	}
+	name, until, _ := strings.Cut(v, "|")
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		return nil, err
+	}
+	return &Travel{Loc: loc, Until: t}, nil
+}
+
+func (s *sqlStore) SetTravel(chatID int64, t Travel) error {
+	return s.SetKV(chatKey(chatID, "travel"), t.Loc.String()+"|"+t.Until.UTC().Format(time.RFC3339))
+}
+
+func (s *sqlStore) ClearTravel(chatID int64) error {
+	return s.DeleteKV(chatKey(chatID, "travel"))
+}
+
+// parseTravel reads "Asia/Tokyo until 2025-06-10" (or "до 2025-06-10").
+// The last day is inclusive in the destination timezone.
+func parseTravel(arg string) (Travel, error) {
+	f := strings.Fields(arg)
+	if len(f) != 3 || (f[1] != "until" && f[1] != "до") {
+		return Travel{}, fmt.Errorf("want <zone> until <YYYY-MM-DD>")
+	}
+	loc, err := time.LoadLocation(f[0])
+	if err != nil {
+		return Travel{}, err
+	}
+	day, err := time.ParseInLocation("2006-01-02", f[2], loc)
+	if err != nil {
+		return Travel{}, err
+	}
+	return Travel{Loc: loc, Until: day.AddDate(0, 0, 1)}, nil
+}
+
+func (a *App) handleTravel(chatID int64, arg string) {
+	arg = strings.TrimSpace(arg)
+	lang := a.Store.Lang(chatID)
+	switch arg {
+	case "":
+		t, err := a.Store.GetTravel(chatID)
+		if err != nil || t == nil {
+			a.send(chatID, tr(lang, "travel.off")+" "+tr(lang, "travel.usage"))
+			return
+		}
+		a.send(chatID, tr(lang, "travel.status", t.Loc, t.Until.In(t.Loc).AddDate(0, 0, -1).Format("2006-01-02")))
+		return
+	case "off", "stop":
+		_ = a.Store.ClearTravel(chatID)
+		a.send(chatID, tr(lang, "travel.off"))
+		return
+	}
+
+	t, err := parseTravel(arg)
+	if err != nil {
+		a.send(chatID, tr(lang, "travel.bad")+" "+tr(lang, "travel.usage"))
+		return
+	}
+	if !t.Until.After(time.Now()) {
+		a.send(chatID, tr(lang, "travel.past"))
+		return
+	}
+	if err := a.Store.SetTravel(chatID, t); err != nil {
+		a.send(chatID, tr(lang, "err.write"))
+		return
+	}
+	a.send(chatID, tr(lang, "travel.set", t.Loc, t.Until.In(t.Loc).AddDate(0, 0, -1).Format("2006-01-02")))
+}
+
+// location returns the timezone the scheduler should evaluate in right now:
+// the chat's travel override while it lasts, the chat's timezone otherwise.
+// An expired override is removed and the chat is told about it.
+func (s *Scheduler) location(chatID int64, now time.Time) *time.Location {
+	home := chatTimezone(s.store, chatID, s.tz)
+	t, err := s.store.GetTravel(chatID)
+	if err != nil {
+		log.Printf("scheduler: travel lookup error: %v", err)
+		return home
+	}
+	if t == nil {
+		return home
+	}
+	if !now.Before(t.Until) {
+		_ = s.store.ClearTravel(chatID)
+		_, _ = s.bot.Send(tgbotapi.NewMessage(chatID, tr(s.store.Lang(chatID), "travel.ended", home.String())))
+		return home
+	}
+	return t.Loc
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func basketStaleDays() int {
+	n, err := strconv.Atoi(envOr("BASKET_STALE_DAYS", "7"))
+	if err != nil || n <= 0 {
+		return 7
+	}
+	return n
+}
+
+// ListStale returns active items of a topic created before the given moment.
+func (s *sqlStore) ListStale(chatID int64, topic string, before time.Time) ([]Item, error) {
+	items, err := s.ListActive(chatID, topic)
+	if err != nil {
+		return nil, err
+	}
+	var out []Item
+	for _, it := range items {
+		if it.CreatedAt.Before(before) {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+func triageKeyboard(lang string, id int64) tgbotapi.InlineKeyboardMarkup {
+	mv := func(topic string) tgbotapi.InlineKeyboardButton {
+		return tgbotapi.NewInlineKeyboardButtonData(tr(lang, "btn."+topic), fmt.Sprintf("mv:%s:%d", topic, id))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(mv(TopicTasks), mv(TopicReminders), mv(TopicShopping)),
+		tgbotapi.NewInlineKeyboardRow(mv(TopicSomeday), tgbotapi.NewInlineKeyboardButtonData("✅", fmt.Sprintf("done:%d", id))),
+	)
+}
+
+// sendBasketNudge bundles stale basket items into one weekly reminder.
+func (s *Scheduler) sendBasketNudge(chatID int64, now time.Time) {
+	items, err := s.store.ListStale(chatID, TopicBasket, now.AddDate(0, 0, -basketStaleDays()))
+	if err != nil {
+		log.Printf("scheduler: list stale basket error: %v", err)
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	lang := s.store.Lang(chatID)
+	msg := tgbotapi.NewMessage(chatID, tr(lang, "triage.nudge", len(items), basketStaleDays()))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr(lang, "triage.start"), "triage:start"),
+	))
+	s.send("basket_nudge", msg)
+}
+
+func (a *App) startTriage(chatID int64) {
+	items, err := a.Store.ListStale(chatID, TopicBasket, time.Now().AddDate(0, 0, -basketStaleDays()))
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if len(items) == 0 {
+		a.send(chatID, a.tr(chatID, "triage.done"))
+		return
+	}
+	a.sendTriage(chatID, items)
+}
+
+// startReview walks through the whole basket, not only stale items.
+func (a *App) startReview(chatID int64) {
+	items, err := a.Store.ListActive(chatID, TopicBasket)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	if len(items) == 0 {
+		a.send(chatID, a.tr(chatID, "triage.empty"))
+		return
+	}
+	a.sendTriage(chatID, items)
+}
+
+func (a *App) sendTriage(chatID int64, items []Item) {
+	lang := a.Store.Lang(chatID)
+	for _, it := range items {
+		msg := tgbotapi.NewMessage(chatID, formatSingleItem(lang, TopicBasket, it))
+		msg.ReplyMarkup = triageKeyboard(lang, it.ID)
+		_, _ = a.Bot.Send(msg)
+	}
+}
+
+// moveData is the prefix+"mv:" button for moving id to topics[i]: by topic
+// key, or by its place in topics when a long custom name would go over
+// Telegram's 64 bytes of callback data.
+func moveData(prefix string, topics []string, i int, id int64) string {
+	if data := fmt.Sprintf("%smv:%s:%d", prefix, topics[i], id); len(data) <= 64 {
+		return data
+	}
+	return fmt.Sprintf("%smv:#%d:%d", prefix, i, id)
+}
+
+// moveRows are the buttons moving it to each of the chat's other topics,
+// three to a row.
+func (a *App) moveRows(chatID int64, prefix string, it Item) [][]tgbotapi.InlineKeyboardButton {
+	lang := a.Store.Lang(chatID)
+	topics := chatTopics(a.Store, chatID)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for i, t := range topics {
+		if t == it.Topic {
+			continue
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(a.topicButton(chatID, lang, t), moveData(prefix, topics, i, it.ID)))
+		if len(row) == 3 {
+			rows, row = append(rows, row), nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// moveLabel is how a move's answer names topic.
+func (a *App) moveLabel(chatID int64, topic string) string {
+	if name, ok := customTopicName(a.Store, chatID, topic); ok {
+		return name
+	}
+	return topicLabel(a.Store.Lang(chatID), topic)
+}
+
+// moveTarget reads the topic of an "mv:" button back; false if the chat no
+// longer has it.
+func moveTarget(topics []string, s string) (string, bool) {
+	if slices.Contains(topics, s) {
+		return s, true
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(s, "#")); err == nil && strings.HasPrefix(s, "#") && n >= 0 && n < len(topics) {
+		return topics[n], true
+	}
+	return "", false
+}
+
+// handleMovePicker handles "move:<id>": the item's buttons give way to the
+// chat's other topics.
+func (a *App) handleMovePicker(cq *tgbotapi.CallbackQuery, idStr string) {
+	chatID := cq.Message.Chat.ID
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	it, err := a.Store.GetItem(chatID, id)
+	if err != nil || it == nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "deleted")))
+		return
+	}
+	rows := append(a.moveRows(chatID, "", *it), tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✖", fmt.Sprintf("movex:%d", id)),
+	))
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	_, _ = a.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, cq.Message.MessageID, markup))
+}
+
+// handleMoveCancel handles "movex:<id>" by putting the item's buttons back.
+func (a *App) handleMoveCancel(cq *tgbotapi.CallbackQuery, idStr string) {
+	chatID := cq.Message.Chat.ID
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	it, err := a.Store.GetItem(chatID, id)
+	if err != nil || it == nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "deleted")))
+		return
+	}
+	markup := itemKeyboard(a.Store.Lang(chatID), chatID, *it)
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	_, _ = a.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, cq.Message.MessageID, markup))
+}
+
+// handleMoveCallback handles "mv:<topic>:<id>".
+func (a *App) handleMoveCallback(cq *tgbotapi.CallbackQuery, data string) {
+	chatID := cq.Message.Chat.ID
+	target, idStr, _ := strings.Cut(data, ":")
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	topic, ok := moveTarget(chatTopics(a.Store, chatID), target)
+	if !ok {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "deleted")))
+		return
+	}
+
+	if err := a.Store.MoveItem(chatID, id, topic); err != nil {
+		_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, a.tr(chatID, "err.write")))
+		return
+	}
+
+	label := a.moveLabel(chatID, topic)
+	_, _ = a.Bot.Request(tgbotapi.NewCallback(cq.ID, label))
+	edit := tgbotapi.NewEditMessageText(chatID, cq.Message.MessageID, cq.Message.Text+"\n→ "+label)
+	_, _ = a.Bot.Send(edit)
+}
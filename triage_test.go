@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMoveData(t *testing.T) {
+	long := strings.Repeat("я", maxTopicNameRunes)
+	topics := append(append([]string{}, keyboardTopics...), "книги", long)
+	for i := range topics {
+		for _, prefix := range []string{"", "sr:"} {
+			data := moveData(prefix, topics, i, 123456)
+			if len(data) > 64 {
+				t.Errorf("moveData(%q) = %q, over 64 bytes", topics[i], data)
+			}
+			rest, ok := strings.CutPrefix(data, prefix+"mv:")
+			if !ok {
+				t.Fatalf("moveData(%q) = %q", topics[i], data)
+			}
+			target, id, _ := strings.Cut(rest, ":")
+			if got, ok := moveTarget(topics, target); !ok || got != topics[i] || id != "123456" {
+				t.Errorf("moveTarget(%q) = %q, %v; want %q", data, got, ok, topics[i])
+			}
+		}
+	}
+	if data := moveData("", topics, 0, 1); data != "mv:"+topics[0]+":1" {
+		t.Errorf("moveData = %q, want the topic key", data)
+	}
+	for _, bad := range []string{"нет такого", "#99", "#-1", "#x"} {
+		if got, ok := moveTarget(topics, bad); ok {
+			t.Errorf("moveTarget(%q) = %q", bad, got)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// /trip пляж 15.07 22.07 adds a packing task for the trip (due on the
+// departure day, with a ☐ checklist from the trip type's template plus the
+// basics) and a "начать собираться" reminder two days before departure at
+// MORNING_TIME.
+
+const packDaysBefore = 2
+
+type packTemplate struct {
+	Names map[string]string
+	Words []string
+	Items map[string][]string
+}
+
+var packTemplates = []packTemplate{
+	{map[string]string{LangRU: "пляж", LangEN: "beach"}, []string{"пляж", "море", "beach", "sea"}, map[string][]string{
+		LangRU: {"купальник", "солнцезащитный крем", "солнечные очки", "панама", "шлёпанцы", "пляжное полотенце"},
+		LangEN: {"swimsuit", "sunscreen", "sunglasses", "sun hat", "flip-flops", "beach towel"},
+	}},
+	{map[string]string{LangRU: "горы", LangEN: "ski"}, []string{"горы", "лыжи", "ski", "snowboard", "сноуборд"}, map[string][]string{
+		LangRU: {"термобельё", "флиска", "горнолыжная куртка и штаны", "перчатки", "шапка и бафф", "маска", "шлем", "крем от солнца", "гигиеничка"},
+		LangEN: {"thermal underwear", "fleece", "ski jacket and pants", "gloves", "hat and buff", "goggles", "helmet", "sunscreen", "lip balm"},
+	}},
+	{map[string]string{LangRU: "работа", LangEN: "work"}, []string{"работа", "командировка", "work", "business"}, map[string][]string{
+		LangRU: {"ноутбук и зарядка", "рубашки", "костюм", "документы к встрече", "визитки", "переходники"},
+		LangEN: {"laptop and charger", "shirts", "suit", "meeting documents", "business cards", "plug adapters"},
+	}},
+}
+
+var packBasics = map[string][]string{
+	LangRU: {"паспорт", "билеты и брони", "телефон и зарядка", "лекарства", "зубная щётка и паста"},
+	LangEN: {"passport", "tickets and bookings", "phone and charger", "medicine", "toothbrush and toothpaste"},
+}
+
+func findPackTemplate(word string) (packTemplate, bool) {
+	word = normalizeText(word)
+	for _, t := range packTemplates {
+		for _, w := range t.Words {
+			if w == word {
+				return t, true
+			}
+		}
+	}
+	return packTemplate{}, false
+}
+
+// parseTrip reads "<тип> <отъезд> [<возвращение>]"; dates are anything
+// parseDueWord takes, "15.07-22.07" works too.
+func parseTrip(arg string, now time.Time) (packTemplate, time.Time, time.Time, error) {
+	arg = strings.NewReplacer("–", " ", "—", " ").Replace(arg)
+	fields := strings.Fields(arg)
+	if len(fields) == 2 && strings.Count(fields[1], "-") == 1 {
+		from, to, _ := strings.Cut(fields[1], "-")
+		fields = []string{fields[0], from, to}
+	}
+	if len(fields) < 2 || len(fields) > 3 {
+		return packTemplate{}, time.Time{}, time.Time{}, errors.New("want <type> <from> [<to>]")
+	}
+	tmpl, ok := findPackTemplate(fields[0])
+	if !ok {
+		return packTemplate{}, time.Time{}, time.Time{}, fmt.Errorf("unknown trip type %q", fields[0])
+	}
+	from, ok := parseDueWord(fields[1], now)
+	if !ok {
+		return packTemplate{}, time.Time{}, time.Time{}, fmt.Errorf("bad date %q", fields[1])
+	}
+	to := from
+	if len(fields) == 3 {
+		if to, ok = parseDueWord(fields[2], now); !ok || to.Before(from) {
+			return packTemplate{}, time.Time{}, time.Time{}, fmt.Errorf("bad date %q", fields[2])
+		}
+	}
+	return tmpl, from, to, nil
+}
+
+func formatPackTask(lang string, tmpl packTemplate, from, to time.Time) string {
+	var b strings.Builder
+	b.WriteString(tr(lang, "trip.task", tmpl.Names[lang], from.Format("02.01")))
+	if !to.Equal(from) {
+		fmt.Fprintf(&b, "–%s", to.Format("02.01"))
+	}
+	nights := int(to.Sub(from).Hours() / 24)
+	for _, it := range append(append([]string{}, packBasics[lang]...), tmpl.Items[lang]...) {
+		b.WriteString("\n☐ ")
+		b.WriteString(it)
+	}
+	if nights > 0 {
+		b.WriteString("\n☐ " + tr(lang, "trip.socks", nights+1))
+	}
+	return b.String()
+}
+
+// handleTrip handles "/trip <пляж|горы|работа> <отъезд> [<возвращение>]".
+func (a *App) handleTrip(chatID int64, arg string) {
+	lang := a.Store.Lang(chatID)
+	now := time.Now().In(a.tz(chatID))
+	tmpl, from, to, err := parseTrip(arg, now)
+	if err != nil {
+		names := make([]string, len(packTemplates))
+		for i, t := range packTemplates {
+			names[i] = t.Names[lang]
+		}
+		a.send(chatID, tr(lang, "trip.usage", strings.Join(names, ", ")))
+		return
+	}
+
+	remindAt, ok := nextClock(envOr("MORNING_TIME", "08:00"), from.AddDate(0, 0, -packDaysBefore-1))
+	remind := ok && remindAt.After(now)
+	task := formatPackTask(lang, tmpl, from, to)
+	var taskID int64
+	err = a.Store.InTx(chatID, func(tx Store) error {
+		var err error
+		if taskID, err = tx.AddItem(chatID, TopicTasks, task); err != nil {
+			return err
+		}
+		if err := tx.SetDue(chatID, taskID, from); err != nil {
+			return err
+		}
+		if !remind {
+			return nil
+		}
+		id, err := tx.AddItem(chatID, TopicReminders, tr(lang, "trip.reminder", tmpl.Names[lang], from.Format("02.01"), taskID))
+		if err != nil {
+			return err
+		}
+		return tx.SetRemindAt(chatID, id, remindAt)
+	})
+	if errors.Is(err, errLocked) {
+		a.sendLocked(chatID)
+		return
+	}
+	if err != nil {
+		log.Printf("trip error: %v", err)
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+
+	it := Item{ID: taskID, ChatID: chatID, Topic: TopicTasks, Text: task, CreatedAt: now, Due: from}
+	a.sendItemsOneByOne(chatID, TopicTasks, []Item{it})
+	if remind {
+		a.send(chatID, tr(lang, "trip.remind", formatRemindAt(lang, remindAt, now)))
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Spoken answers for the road: with TTS_BACKEND set, a voice note asking
+// "что у меня сегодня" (or "что дальше") is answered with a short voice
+// summary of the day besides the usual text. TTS_BACKEND=openai uses
+// OpenAI's speech API (TTS_KEY); "local" posts the same JSON to TTS_URL
+// (Piper or any compatible server). The audio must come back as OGG/Opus,
+// which Telegram plays as a voice message. "/speak off" keeps a chat silent.
+
+const maxSpokenItems = 5
+
+// Speaker turns text into OGG/Opus speech.
+type Speaker interface {
+	Speak(ctx context.Context, text string) ([]byte, error)
+}
+
+// httpSpeaker posts text to an OpenAI-style /audio/speech endpoint.
+type httpSpeaker struct {
+	url   string
+	key   string
+	model string
+	voice string
+	http  *http.Client
+}
+
+// NewSpeakerFromEnv returns nil when TTS_BACKEND is unset or "off".
+func NewSpeakerFromEnv() (Speaker, error) {
+	s := &httpSpeaker{
+		url:   strings.TrimSpace(os.Getenv("TTS_URL")),
+		key:   strings.TrimSpace(os.Getenv("TTS_KEY")),
+		model: envOr("TTS_MODEL", "tts-1"),
+		voice: envOr("TTS_VOICE", "alloy"),
+		http:  &http.Client{Timeout: time.Minute},
+	}
+	switch b := strings.ToLower(strings.TrimSpace(os.Getenv("TTS_BACKEND"))); b {
+	case "", "off":
+		return nil, nil
+	case "openai":
+		if s.key == "" {
+			return nil, fmt.Errorf("TTS_BACKEND=openai requires TTS_KEY")
+		}
+		if s.url == "" {
+			s.url = "https://api.openai.com/v1/audio/speech"
+		}
+	case "local":
+		if s.url == "" {
+			return nil, fmt.Errorf("TTS_BACKEND=local requires TTS_URL")
+		}
+	default:
+		return nil, fmt.Errorf("unknown TTS_BACKEND %q (openai, local, off)", b)
+	}
+	return s, nil
+}
+
+func (s *httpSpeaker) Speak(ctx context.Context, text string) ([]byte, error) {
+	body, _ := json.Marshal(map[string]string{
+		"model":           s.model,
+		"voice":           s.voice,
+		"input":           text,
+		"response_format": "opus",
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.key != "" {
+		req.Header.Set("Authorization", "Bearer "+s.key)
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("speak: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxVoiceBytes))
+}
+
+// spokenAgenda is the day told in a few sentences: the busy blocks left and
+// the first tasks, without effort marks.
+func spokenAgenda(lang string, items []Item, blocks []BusyBlock, now time.Time) string {
+	var parts []string
+	for _, b := range blocks {
+		if b.Days&dayBit(now) == 0 || b.To <= now.Hour()*60+now.Minute() {
+			continue
+		}
+		s := tr(lang, "speak.busy.block", clockString(b.From), clockString(b.To))
+		if b.Label != "" {
+			s += " " + b.Label
+		}
+		parts = append(parts, s)
+	}
+	var b strings.Builder
+	if len(parts) > 0 {
+		b.WriteString(tr(lang, "speak.busy", strings.Join(parts, ", ")) + " ")
+	}
+	if len(items) == 0 {
+		b.WriteString(tr(lang, "speak.none"))
+		return b.String()
+	}
+	b.WriteString(tr(lang, "speak.count", len(items)) + " ")
+	var texts []string
+	for _, it := range items[:min(len(items), maxSpokenItems)] {
+		texts = append(texts, strings.Join(strings.Fields(effortRe.ReplaceAllString(shownText(it), "")), " "))
+	}
+	b.WriteString(tr(lang, "speak.first", texts[0]) + " ")
+	if len(texts) > 1 {
+		b.WriteString(tr(lang, "speak.rest", strings.Join(texts[1:], ", ")) + " ")
+	}
+	if extra := len(items) - maxSpokenItems; extra > 0 {
+		b.WriteString(tr(lang, "speak.more", extra))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// speakAgenda sends the day's summary as a voice message.
+func (a *App) speakAgenda(ctx context.Context, chatID int64) error {
+	items, err := a.Store.ListActive(chatID, TopicTasks)
+	if err != nil {
+		return err
+	}
+	if f, ok := a.focusFor(chatID); ok {
+		items = f.Filter(items)
+	}
+	text := spokenAgenda(a.Store.Lang(chatID), items, busyBlocks(a.Store, chatID), time.Now().In(a.tz(chatID)))
+	audio, err := a.TTS.Speak(ctx, text)
+	if err != nil {
+		return err
+	}
+	_, err = a.Bot.Send(tgbotapi.NewVoice(chatID, tgbotapi.FileBytes{Name: "agenda.ogg", Bytes: audio}))
+	return err
+}
+
+// voiceReply answers a transcribed voice request aloud; false means the
+// transcript is captured as usual.
+func (a *App) voiceReply(ctx context.Context, chatID int64, transcript string) bool {
+	if a.TTS == nil {
+		return false
+	}
+	if v, _, _ := a.Store.GetKV(chatKey(chatID, "speak")); v == "off" {
+		return false
+	}
+	in, ok := matchIntent(transcript)
+	if !ok || (in.Name != "today" && in.Name != "next") {
+		return false
+	}
+	if in.Name == "next" {
+		a.handleNext(chatID)
+	} else {
+		a.handleToday(chatID)
+	}
+	if err := a.speakAgenda(ctx, chatID); err != nil {
+		log.Printf("tts: chat %d: %v", chatID, err)
+	}
+	return true
+}
+
+// handleSpeak handles "/speak on|off".
+func (a *App) handleSpeak(chatID int64, arg string) {
+	switch strings.TrimSpace(arg) {
+	case "on":
+		if err := a.Store.DeleteKV(chatKey(chatID, "speak")); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "speak.on"))
+	case "off":
+		if err := a.Store.SetKV(chatKey(chatID, "speak"), "off"); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "speak.off"))
+	default:
+		if a.TTS == nil {
+			a.send(chatID, a.tr(chatID, "speak.unset"))
+			return
+		}
+		a.send(chatID, a.tr(chatID, "speak.help"))
+	}
+}
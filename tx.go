@@ -0,0 +1,108 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// dbConn is what sqlStore methods need from the database; both *sql.DB
+// and *sql.Tx satisfy it, so every method also works inside a transaction.
+type dbConn interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// InTx runs fn in a transaction on chatID's database.
+func (s *sqlStore) InTx(chatID int64, fn func(tx Store) error) error {
+	return s.For(chatID).inTx(func(tx *sqlStore) error { return fn(tx) })
+}
+
+// inTx runs fn against a store bound to one transaction, committing if fn
+// returns nil and rolling back otherwise (including on panic). Calls on a
+// store that is already in a transaction join it.
+//
+// The SQLite pool has a single connection: inside fn use only the tx store,
+// never the outer one, or the call blocks. With sharding, start transactions on
+// chat data from s.For(chatID) so they run on the chat's database.
+func (s *sqlStore) inTx(fn func(tx *sqlStore) error) (err error) {
+	raw := unwrapConn(s.DB)
+	if _, ok := raw.(*sql.Tx); ok {
+		return fn(s)
+	}
+	db, ok := raw.(*sql.DB)
+	if !ok {
+		return fmt.Errorf("store: unsupported connection %T", s.DB)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = fn(&sqlStore{DB: wrapConn(s.driver, tx), driver: s.driver, vault: s.vault}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) Close() error {
+	if s.router != nil {
+		for _, sh := range s.router.shards[1:] {
+			_ = sh.Close()
+		}
+	}
+	if db, ok := unwrapConn(s.read).(*sql.DB); ok {
+		_ = db.Close()
+	}
+	if db, ok := unwrapConn(s.DB).(*sql.DB); ok {
+		return db.Close()
+	}
+	return nil
+}
+
+// FinishItem completes an item and credits its linked goal, if any, as one
+// unit: a goal is never credited for an item that stays active.
+func (s *sqlStore) FinishItem(chatID, id int64, by *tgbotapi.User, now time.Time) error {
+	return s.For(chatID).inTx(func(tx *sqlStore) error {
+		goalID, amount, ok, err := tx.TakeGoalLink(chatID, id)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := tx.AddGoalProgress(chatID, goalID, amount); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+		return tx.CompleteItem(chatID, id, by, now)
+	})
+}
+
+// CaptureItem stores a new item together with its flag and provenance.
+func (s *sqlStore) CaptureItem(chatID int64, topic, text string, flagged bool, prov Provenance) (int64, error) {
+	var id int64
+	err := s.For(chatID).inTx(func(tx *sqlStore) error {
+		var err error
+		if id, err = tx.AddItem(chatID, topic, text); err != nil {
+			return err
+		}
+		if flagged {
+			if err := tx.FlagItem(chatID, id); err != nil {
+				return err
+			}
+		}
+		return tx.SetProvenance(chatID, id, prov)
+	})
+	return id, err
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// /undo reverts the chat's latest item change: an added item is removed, a
+// completed one goes back to its list, a deleted one is restored with its
+// ID, a moved one returns to its old topic. Item writes record themselves
+// in item_actions; the last undoLogSize entries per chat are kept.
+// Goal progress from a completion stays counted.
+
+const undoLogSize = 20
+
+const (
+	actionCreated   = "created"
+	actionCompleted = "completed"
+	actionDeleted   = "deleted"
+	actionMoved     = "moved"
+)
+
+// logAction records one change for /undo. Failing to record it doesn't
+// fail the change itself.
+func (s *sqlStore) logAction(chatID, itemID int64, action, payload string) {
+	db := s.db(chatID)
+	_, err := db.Exec(
+		`INSERT INTO item_actions(chat_id, item_id, action, payload, created_at) VALUES(?,?,?,?,?)`,
+		chatID, itemID, action, payload, time.Now().UTC().Format(time.RFC3339),
+	)
+	if err == nil {
+		_, err = db.Exec(
+			`DELETE FROM item_actions WHERE chat_id=? AND id <= (SELECT id FROM item_actions WHERE chat_id=? ORDER BY id DESC LIMIT 1 OFFSET ?)`,
+			chatID, chatID, undoLogSize,
+		)
+	}
+	if err != nil {
+		log.Printf("undo log %s #%d error: %v", action, itemID, err)
+	}
+}
+
+// itemSnapshot is the item's full row as JSON, for restoring it.
+func (s *sqlStore) itemSnapshot(chatID, id int64) (string, error) {
+	rows, err := dumpRows(s.db(chatID), `SELECT * FROM items WHERE chat_id=? AND id=?`, chatID, id)
+	if err != nil || len(rows) == 0 {
+		return "", err
+	}
+	b, err := json.Marshal(rows[0])
+	return string(b), err
+}
+
+type UndoneAction struct {
+	Action string
+	Item   Item // as it is after the undo
+}
+
+// Undo reverts the latest logged change; nil if there is none. Entries
+// whose item has meanwhile gone are skipped.
+func (s *sqlStore) Undo(chatID int64) (*UndoneAction, error) {
+	var done *UndoneAction
+	err := s.For(chatID).inTx(func(tx *sqlStore) error {
+		for {
+			var logID, itemID int64
+			var action, payload string
+			err := tx.DB.QueryRow(
+				`SELECT id, item_id, action, payload FROM item_actions WHERE chat_id=? ORDER BY id DESC LIMIT 1`, chatID,
+			).Scan(&logID, &itemID, &action, &payload)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := tx.DB.Exec(`DELETE FROM item_actions WHERE id=?`, logID); err != nil {
+				return err
+			}
+
+			it, err := tx.GetItem(chatID, itemID)
+			if err != nil {
+				return err
+			}
+			u := &UndoneAction{Action: action}
+			switch action {
+			case actionCreated:
+				if it == nil {
+					continue
+				}
+				if _, err := tx.DB.Exec(`DELETE FROM items WHERE chat_id=? AND id=?`, chatID, itemID); err != nil {
+					return err
+				}
+			case actionCompleted:
+				if it == nil {
+					continue
+				}
+				if _, err := tx.DB.Exec(
+					`UPDATE items SET status=?, completed_at='', completed_by=0, completed_by_name='' WHERE chat_id=? AND id=?`,
+					StatusActive, chatID, itemID,
+				); err != nil {
+					return err
+				}
+				it.CompletedAt = time.Time{}
+			case actionDeleted:
+				if it != nil {
+					continue
+				}
+				dec := json.NewDecoder(bytes.NewReader([]byte(payload)))
+				dec.UseNumber()
+				var row map[string]any
+				if err := dec.Decode(&row); err != nil {
+					return fmt.Errorf("undo #%d: %w", itemID, err)
+				}
+				cols, err := tableColumns(tx.DB, "items")
+				if err != nil {
+					return err
+				}
+				if _, err := insertRow(tx.DB, "items", cols, row); err != nil {
+					return err
+				}
+				if it, err = tx.GetItem(chatID, itemID); err != nil || it == nil {
+					return err
+				}
+			case actionMoved:
+				if it == nil {
+					continue
+				}
+				if _, err := tx.DB.Exec(`UPDATE items SET topic=? WHERE chat_id=? AND id=?`, payload, chatID, itemID); err != nil {
+					return err
+				}
+				it.Topic = payload
+			default:
+				continue
+			}
+			u.Item = *it
+			done = u
+			return nil
+		}
+	})
+	return done, err
+}
+
+// handleUndo handles "/undo".
+func (a *App) handleUndo(chatID int64) {
+	u, err := a.Store.Undo(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	if u == nil {
+		a.send(chatID, a.tr(chatID, "undo.nothing"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+	it := u.Item
+	topic := a.topicButton(chatID, lang, it.Topic)
+	switch u.Action {
+	case actionCreated:
+		a.send(chatID, tr(lang, "undo.created", it.ID, shownText(it)))
+	case actionCompleted:
+		a.send(chatID, tr(lang, "undo.completed", it.ID, shownText(it), topic))
+	case actionDeleted:
+		a.send(chatID, tr(lang, "undo.deleted", it.ID, shownText(it), topic))
+	case actionMoved:
+		a.send(chatID, tr(lang, "undo.moved", topic, it.ID, shownText(it)))
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// /usage shows what a chat keeps: items per topic and status, the
+// compacted archive, stored attachments, and how fast it all grows, so the
+// owner can pick COMPACT_AFTER_DAYS and MEDIA_MAX_MB sensibly.
+
+// usageRowOverhead roughly covers the columns and index entries of a row
+// besides its text.
+const usageRowOverhead = 120
+
+type ChatUsage struct {
+	Counts        map[string]map[string]int // topic -> status -> n
+	Oldest        time.Time
+	ArchivedItems int
+	OldestMonth   string
+	TextBytes     int64 // items, notes and the archive, approximate
+	Files         int
+	StoredBytes   int64 // attachments kept by the bot, not just by file_id
+	RecentItems   int   // created in the last 30 days
+	RecentBytes   int64
+}
+
+func (s *sqlStore) Usage(chatID int64, now time.Time) (ChatUsage, error) {
+	u := ChatUsage{Counts: map[string]map[string]int{}}
+	db := s.readDB(chatID)
+
+	rows, err := db.Query(`SELECT topic, status, COUNT(*), COALESCE(SUM(LENGTH(text)),0) FROM items WHERE chat_id=? GROUP BY topic, status`, chatID)
+	if err != nil {
+		return u, err
+	}
+	for rows.Next() {
+		var topic, status string
+		var n int
+		var size int64
+		if err := rows.Scan(&topic, &status, &n, &size); err != nil {
+			rows.Close()
+			return u, err
+		}
+		if u.Counts[topic] == nil {
+			u.Counts[topic] = map[string]int{}
+		}
+		u.Counts[topic][status] = n
+		u.TextBytes += size + int64(n)*usageRowOverhead
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return u, err
+	}
+
+	var oldest string
+	if err := db.QueryRow(`SELECT COALESCE(MIN(created_at),'') FROM items WHERE chat_id=?`, chatID).Scan(&oldest); err != nil {
+		return u, err
+	}
+	u.Oldest, _ = time.Parse(time.RFC3339, oldest)
+
+	var notes int
+	var noteBytes int64
+	if err := db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(LENGTH(text)),0) FROM item_notes WHERE chat_id=?`, chatID).Scan(&notes, &noteBytes); err != nil {
+		return u, err
+	}
+	u.TextBytes += noteBytes + int64(notes)*usageRowOverhead
+
+	var archiveBytes int64
+	if err := db.QueryRow(
+		`SELECT COALESCE(SUM(count),0), COALESCE(MIN(month),''), COALESCE(SUM(LENGTH(items)),0) FROM item_history WHERE chat_id=?`, chatID,
+	).Scan(&u.ArchivedItems, &u.OldestMonth, &archiveBytes); err != nil {
+		return u, err
+	}
+	u.TextBytes += archiveBytes
+
+	since := now.AddDate(0, 0, -30).UTC().Format(time.RFC3339)
+	if err := db.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(LENGTH(text)),0) FROM items WHERE chat_id=? AND created_at>=?`, chatID, since,
+	).Scan(&u.RecentItems, &u.RecentBytes); err != nil {
+		return u, err
+	}
+	u.RecentBytes += int64(u.RecentItems) * usageRowOverhead
+
+	// media is shared between chats; count each file once per chat
+	err = s.DB.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(CASE WHEN storage_key<>'' THEN size ELSE 0 END),0) FROM media
+		 WHERE file_unique_id IN (SELECT file_unique_id FROM item_media WHERE chat_id=?)`, chatID,
+	).Scan(&u.Files, &u.StoredBytes)
+	return u, err
+}
+
+// handleUsage handles "/usage".
+func (a *App) handleUsage(chatID int64) {
+	now := time.Now()
+	u, err := a.Store.Usage(chatID, now)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+
+	var b strings.Builder
+	b.WriteString(tr(lang, "usage.header") + "\n")
+	total := 0
+	var extra []string
+	for topic := range u.Counts {
+		if !slices.Contains(keyboardTopics, topic) {
+			extra = append(extra, topic)
+		}
+	}
+	sort.Strings(extra)
+	topics := append(append([]string{}, keyboardTopics...), extra...)
+	for _, topic := range topics {
+		c := u.Counts[topic]
+		if len(c) == 0 {
+			continue
+		}
+		b.WriteString("\n" + tr(lang, "usage.topic", topicLabel(lang, topic), c[StatusActive], c[StatusDone]))
+		for _, n := range c {
+			total += n
+		}
+	}
+	if total == 0 {
+		b.WriteString("\n" + tr(lang, "usage.empty"))
+	}
+	if !u.Oldest.IsZero() {
+		b.WriteString("\n\n" + tr(lang, "usage.oldest", u.Oldest.In(a.tz(chatID)).Format("02.01.2006")))
+	}
+	if u.ArchivedItems > 0 {
+		b.WriteString("\n" + tr(lang, "usage.archived", u.ArchivedItems, u.OldestMonth))
+	}
+	if u.Files > 0 {
+		b.WriteString("\n" + tr(lang, "usage.files", u.Files, formatBytes(lang, u.StoredBytes)))
+	}
+	b.WriteString("\n" + tr(lang, "usage.text", formatBytes(lang, u.TextBytes)))
+
+	b.WriteString("\n\n" + tr(lang, "usage.recent", u.RecentItems, formatBytes(lang, u.RecentBytes), formatBytes(lang, u.RecentBytes*365/30)))
+	b.WriteString("\n" + tr(lang, "usage.compact", int(compactAfter()/(24*time.Hour))))
+	a.send(chatID, b.String())
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Saved views are named queries over the active items:
+//
+//	topic:tasks tag:@работа due:<3d sort:priority
+//
+// Terms: topic:<список>, tag:@x (or just @x), due:<Nd / >Nd / overdue /
+// any / none, older:Nd, flagged, sort:priority|due|age|text, limit:N;
+// anything else must appear in the text. Durations take d, h or w.
+// A view can be sent on its own every day at a set time.
+
+const defaultViewLimit = 30
+
+type Query struct {
+	Topics  []string
+	Tags    []string
+	Words   []string
+	Flagged bool
+	DueOp   string // "<", ">", "overdue", "any", "none"
+	DueIn   time.Duration
+	Older   time.Duration
+	Sort    string
+	Limit   int
+}
+
+type SavedView struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	At    string `json:"at,omitempty"` // HH:MM, daily
+}
+
+type viewRow struct {
+	Item Item
+	Due  time.Time
+}
+
+func parseQueryDuration(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	switch s[len(s)-1] {
+	case 'h':
+		return time.Duration(n) * time.Hour, true
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, true
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+func parseQuery(lang, src string, topicOf func(string) (string, bool)) (Query, error) {
+	q := Query{Limit: defaultViewLimit}
+	for _, tok := range strings.Fields(src) {
+		key, val, hasKey := strings.Cut(tok, ":")
+		if !hasKey {
+			switch {
+			case tok == "flagged" || tok == "🚩":
+				q.Flagged = true
+			case strings.HasPrefix(tok, "@"):
+				q.Tags = append(q.Tags, normalizeText(tok))
+			default:
+				if w := normalizeText(tok); w != "" {
+					q.Words = append(q.Words, w)
+				}
+			}
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "topic", "список":
+			topic, ok := topicOf(val)
+			if !ok {
+				return q, errors.New(tr(lang, "view.err.topic", val))
+			}
+			q.Topics = append(q.Topics, topic)
+		case "tag":
+			q.Tags = append(q.Tags, normalizeText("@"+strings.TrimPrefix(val, "@")))
+		case "due":
+			switch val {
+			case "overdue", "any", "none":
+				q.DueOp = val
+			default:
+				if val == "" || (val[0] != '<' && val[0] != '>') {
+					return q, errors.New(tr(lang, "view.err.due"))
+				}
+				d, ok := parseQueryDuration(val[1:])
+				if !ok {
+					return q, errors.New(tr(lang, "view.err.duration", val))
+				}
+				q.DueOp, q.DueIn = val[:1], d
+			}
+		case "older":
+			d, ok := parseQueryDuration(val)
+			if !ok {
+				return q, errors.New(tr(lang, "view.err.older", val))
+			}
+			q.Older = d
+		case "sort":
+			switch val {
+			case "priority", "due", "age", "text":
+				q.Sort = val
+			default:
+				return q, errors.New(tr(lang, "view.err.sort"))
+			}
+		case "limit":
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return q, errors.New(tr(lang, "view.err.limit", val))
+			}
+			q.Limit = n
+		case "is":
+			if val != "flagged" {
+				return q, errors.New(tr(lang, "view.err.is", val))
+			}
+			q.Flagged = true
+		default:
+			if w := normalizeText(tok); w != "" {
+				q.Words = append(q.Words, w)
+			}
+		}
+	}
+	return q, nil
+}
+
+func (q Query) needsDue() bool {
+	return q.DueOp != "" || q.Sort == "due"
+}
+
+func (q Query) match(r viewRow, now time.Time) bool {
+	it := r.Item
+	if len(q.Topics) > 0 && !slices.Contains(q.Topics, it.Topic) {
+		return false
+	}
+	if q.Flagged && !it.Flagged {
+		return false
+	}
+	for _, t := range q.Tags {
+		if !hasContext(it, t) {
+			return false
+		}
+	}
+	norm := normalizeText(it.Text)
+	for _, w := range q.Words {
+		if !strings.Contains(norm, w) {
+			return false
+		}
+	}
+	if q.Older > 0 && now.Sub(it.CreatedAt) < q.Older {
+		return false
+	}
+	switch q.DueOp {
+	case "any":
+		return !r.Due.IsZero()
+	case "none":
+		return r.Due.IsZero()
+	case "overdue":
+		return !r.Due.IsZero() && r.Due.Before(now)
+	case "<":
+		return !r.Due.IsZero() && r.Due.Before(now.Add(q.DueIn))
+	case ">":
+		return !r.Due.IsZero() && r.Due.After(now.Add(q.DueIn))
+	}
+	return true
+}
+
+// runQuery returns the chat's active items matching q, sorted and cut to
+// the limit.
+func runQuery(store Store, chatID int64, q Query, now time.Time) ([]viewRow, error) {
+	items, err := store.ListActive(chatID, "")
+	if err != nil {
+		return nil, err
+	}
+	var rows []viewRow
+	for _, it := range items {
+		r := viewRow{Item: it}
+		if q.needsDue() {
+			if r.Due, err = store.Due(chatID, it.ID); err != nil {
+				return nil, err
+			}
+		}
+		if q.match(r, now) {
+			rows = append(rows, r)
+		}
+	}
+
+	switch q.Sort {
+	case "priority":
+		sort.SliceStable(rows, func(i, j int) bool {
+			return parsePriority(rows[i].Item.Text) > parsePriority(rows[j].Item.Text)
+		})
+	case "due":
+		// Undated items go last
+		sort.SliceStable(rows, func(i, j int) bool {
+			di, dj := rows[i].Due, rows[j].Due
+			if di.IsZero() != dj.IsZero() {
+				return dj.IsZero()
+			}
+			return di.Before(dj)
+		})
+	case "age":
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Item.CreatedAt.Before(rows[j].Item.CreatedAt) })
+	case "text":
+		sort.SliceStable(rows, func(i, j int) bool { return normalizeText(rows[i].Item.Text) < normalizeText(rows[j].Item.Text) })
+	}
+	if len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+	}
+	return rows, nil
+}
+
+func formatView(lang, title string, rows []viewRow, tz *time.Location) string {
+	var b strings.Builder
+	b.WriteString(title + ":")
+	if len(rows) == 0 {
+		b.WriteString("\n" + tr(lang, "empty"))
+		return b.String()
+	}
+	for _, r := range rows {
+		b.WriteString("\n" + formatSingleItem(lang, r.Item.Topic, r.Item))
+		if !r.Due.IsZero() {
+			b.WriteString(" · " + formatDue(r.Due, tz))
+		}
+	}
+	return b.String()
+}
+
+func (s *sqlStore) SavedViews(chatID int64) ([]SavedView, error) {
+	v, ok, err := s.GetKV(chatKey(chatID, "views"))
+	if err != nil || !ok {
+		return nil, err
+	}
+	var views []SavedView
+	err = json.Unmarshal([]byte(v), &views)
+	return views, err
+}
+
+func (s *sqlStore) SetSavedViews(chatID int64, views []SavedView) error {
+	if len(views) == 0 {
+		return s.DeleteKV(chatKey(chatID, "views"))
+	}
+	b, err := json.Marshal(views)
+	if err != nil {
+		return err
+	}
+	return s.SetKV(chatKey(chatID, "views"), string(b))
+}
+
+func findView(views []SavedView, name string) int {
+	name = normalizeText(name)
+	for i, v := range views {
+		if normalizeText(v.Name) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// sendScheduledViews sends every view of the chat scheduled for hhmm.
+func (s *Scheduler) sendScheduledViews(chatID int64, now time.Time, hhmm string) {
+	views, err := s.store.SavedViews(chatID)
+	if err != nil {
+		log.Printf("scheduler: views error: %v", err)
+		return
+	}
+	lang := s.store.Lang(chatID)
+	for _, v := range views {
+		if v.At != hhmm {
+			continue
+		}
+		q, err := parseQuery(lang, v.Query, func(name string) (string, bool) { return topicFromName(s.store, chatID, name) })
+		if err != nil {
+			log.Printf("scheduler: view %q: %v", v.Name, err)
+			continue
+		}
+		rows, err := runQuery(s.store, chatID, q, now)
+		if err != nil {
+			log.Printf("scheduler: view %q: %v", v.Name, err)
+			continue
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		text := formatView(lang, "📋 "+strings.ToUpper(v.Name), rows, s.location(chatID, now))
+		if s.deliver("view", tgbotapi.NewMessage(chatID, text)) == nil {
+			s.events.Publish(Event{Kind: EventDigestSent, ChatID: chatID, Text: text, At: now})
+		}
+	}
+}
+
+// handleView handles "/view" and its save/at/del subcommands.
+func (a *App) handleView(chatID int64, arg string) {
+	views, err := a.Store.SavedViews(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+	topicOf := func(name string) (string, bool) { return a.topicFromButton(chatID, name) }
+	fields := strings.Fields(arg)
+
+	if len(fields) == 0 {
+		var b strings.Builder
+		if len(views) == 0 {
+			b.WriteString(tr(lang, "views.none") + "\n\n")
+		} else {
+			b.WriteString(tr(lang, "views.header") + "\n")
+			for _, v := range views {
+				fmt.Fprintf(&b, "%s — %s", v.Name, v.Query)
+				if v.At != "" {
+					b.WriteString(tr(lang, "views.at", v.At))
+				}
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(tr(lang, "view.help"))
+		a.send(chatID, b.String())
+		return
+	}
+
+	switch fields[0] {
+	case "save":
+		if len(fields) < 3 {
+			a.send(chatID, tr(lang, "view.save.usage"))
+			return
+		}
+		src := strings.Join(fields[2:], " ")
+		if _, err := parseQuery(lang, src, topicOf); err != nil {
+			a.send(chatID, tr(lang, "view.err", err))
+			return
+		}
+		v := SavedView{Name: fields[1], Query: src}
+		if i := findView(views, v.Name); i >= 0 {
+			v.At = views[i].At
+			views[i] = v
+		} else {
+			views = append(views, v)
+		}
+		if err := a.Store.SetSavedViews(chatID, views); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "view.saved", v.Name))
+		return
+	case "at":
+		if len(fields) != 3 {
+			a.send(chatID, tr(lang, "view.at.usage"))
+			return
+		}
+		i := findView(views, fields[1])
+		if i < 0 {
+			a.send(chatID, tr(lang, "view.notfound"))
+			return
+		}
+		at := ""
+		if fields[2] != "-" {
+			t, err := time.Parse("15:04", fields[2])
+			if err != nil {
+				a.send(chatID, tr(lang, "view.at.usage"))
+				return
+			}
+			at = t.Format("15:04")
+		}
+		views[i].At = at
+		if err := a.Store.SetSavedViews(chatID, views); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		if at == "" {
+			a.send(chatID, tr(lang, "view.at.off"))
+		} else {
+			a.send(chatID, tr(lang, "view.at.on", views[i].Name, at))
+		}
+		return
+	case "del":
+		if len(fields) != 2 {
+			a.send(chatID, tr(lang, "view.del.usage"))
+			return
+		}
+		i := findView(views, fields[1])
+		if i < 0 {
+			a.send(chatID, tr(lang, "view.notfound"))
+			return
+		}
+		if err := a.Store.SetSavedViews(chatID, append(views[:i:i], views[i+1:]...)); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "view.deleted"))
+		return
+	}
+
+	title, src := tr(lang, "view.search"), strings.Join(fields, " ")
+	if i := findView(views, src); i >= 0 {
+		title, src = strings.ToUpper(views[i].Name), views[i].Query
+	}
+	q, err := parseQuery(lang, src, topicOf)
+	if err != nil {
+		a.send(chatID, tr(lang, "view.err", err))
+		return
+	}
+	now := time.Now()
+	rows, err := runQuery(a.Store, chatID, q, now)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	a.send(chatID, formatView(lang, title, rows, a.tz(chatID)))
+}
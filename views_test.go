@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testTopicOf(name string) (string, bool) {
+	switch name {
+	case "tasks", "задачи":
+		return TopicTasks, true
+	case "shopping", "покупки":
+		return TopicShopping, true
+	}
+	return "", false
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Query
+		err  bool
+	}{
+		{"", Query{Limit: defaultViewLimit}, false},
+		{"topic:tasks tag:@работа due:<3d sort:priority",
+			Query{Topics: []string{TopicTasks}, Tags: []string{"@работа"}, DueOp: "<", DueIn: 3 * 24 * time.Hour, Sort: "priority", Limit: defaultViewLimit}, false},
+		{"список:покупки @Дом Молоко limit:5",
+			Query{Topics: []string{TopicShopping}, Tags: []string{"@дом"}, Words: []string{"молоко"}, Limit: 5}, false},
+		{"flagged older:2w due:none", Query{Flagged: true, Older: 14 * 24 * time.Hour, DueOp: "none", Limit: defaultViewLimit}, false},
+		{"is:flagged due:>12h", Query{Flagged: true, DueOp: ">", DueIn: 12 * time.Hour, Limit: defaultViewLimit}, false},
+		{"due:overdue sort:due", Query{DueOp: "overdue", Sort: "due", Limit: defaultViewLimit}, false},
+		{"время:10:30", Query{Words: []string{"время:10:30"}, Limit: defaultViewLimit}, false},
+		{"topic:работа", Query{}, true},
+		{"due:soon", Query{}, true},
+		{"due:<3x", Query{}, true},
+		{"older:week", Query{}, true},
+		{"sort:random", Query{}, true},
+		{"limit:0", Query{}, true},
+		{"is:done", Query{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseQuery(LangRU, tt.src, testTopicOf)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseQuery(%q) = %+v, want an error", tt.src, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseQuery(%q) = %+v, %v; want %+v", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	row := func(topic, text string, flagged bool, age time.Duration, due time.Time) viewRow {
+		return viewRow{Item: Item{Topic: topic, Text: text, Flagged: flagged, CreatedAt: now.Add(-age)}, Due: due}
+	}
+	day := 24 * time.Hour
+	report := row(TopicTasks, "отчёт @работа", true, 10*day, now.Add(2*day))
+	milk := row(TopicShopping, "молоко", false, time.Hour, time.Time{})
+	late := row(TopicTasks, "налоги", false, 30*day, now.Add(-day))
+
+	tests := []struct {
+		src  string
+		row  viewRow
+		want bool
+	}{
+		{"topic:tasks", report, true},
+		{"topic:tasks", milk, false},
+		{"@работа", report, true},
+		{"@работа", late, false},
+		{"отчет", report, true},
+		{"flagged", milk, false},
+		{"due:<3d", report, true},
+		{"due:<1d", report, false},
+		{"due:>1d", report, true},
+		{"due:overdue", late, true},
+		{"due:overdue", report, false},
+		{"due:any", milk, false},
+		{"due:none", milk, true},
+		{"older:1w", report, true},
+		{"older:1w", milk, false},
+		{"topic:tasks older:2w due:overdue", late, true},
+	}
+	for _, tt := range tests {
+		q, err := parseQuery(LangRU, tt.src, testTopicOf)
+		if err != nil {
+			t.Fatalf("parseQuery(%q): %v", tt.src, err)
+		}
+		if got := q.match(tt.row, now); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.src, tt.row.Item.Text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Voice notes become items: the OGG is downloaded from the Bot API and sent
+// to STT_BACKEND — "whisper" (OpenAI's transcription API, STT_KEY) or
+// "local" (any server with the same multipart form at STT_URL, such as the
+// whisper.cpp server). The transcript is captured like typed text in the
+// active topic. If transcription is off or fails, the item says "🎤
+// голосовое" and keeps the voice note's file_id, so it can still be played.
+
+const maxVoiceBytes = 25 << 20 // OpenAI's limit for one file
+
+// Transcriber turns speech into text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio []byte, filename string) (string, error)
+}
+
+// httpTranscriber posts audio as an OpenAI-style multipart form.
+type httpTranscriber struct {
+	url      string
+	key      string
+	model    string
+	language string
+	http     *http.Client
+}
+
+// NewTranscriberFromEnv returns nil when STT_BACKEND is unset or "off".
+func NewTranscriberFromEnv() (Transcriber, error) {
+	t := &httpTranscriber{
+		url:      strings.TrimSpace(os.Getenv("STT_URL")),
+		key:      strings.TrimSpace(os.Getenv("STT_KEY")),
+		model:    envOr("STT_MODEL", "whisper-1"),
+		language: strings.TrimSpace(os.Getenv("STT_LANGUAGE")),
+		http:     &http.Client{Timeout: 2 * time.Minute},
+	}
+	switch b := strings.ToLower(strings.TrimSpace(os.Getenv("STT_BACKEND"))); b {
+	case "", "off":
+		return nil, nil
+	case "whisper", "openai":
+		if t.key == "" {
+			return nil, fmt.Errorf("STT_BACKEND=whisper requires STT_KEY")
+		}
+		if t.url == "" {
+			t.url = "https://api.openai.com/v1/audio/transcriptions"
+		}
+	case "local":
+		if t.url == "" {
+			return nil, fmt.Errorf("STT_BACKEND=local requires STT_URL")
+		}
+	default:
+		return nil, fmt.Errorf("unknown STT_BACKEND %q (whisper, local, off)", b)
+	}
+	return t, nil
+}
+
+func (t *httpTranscriber) Transcribe(ctx context.Context, audio []byte, filename string) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fw, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fw.Write(audio); err != nil {
+		return "", err
+	}
+	fields := map[string]string{"model": t.model, "language": t.language, "response_format": "json"}
+	for k, v := range fields {
+		if v != "" {
+			if err := w.WriteField(k, v); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if t.key != "" {
+		req.Header.Set("Authorization", "Bearer "+t.key)
+	}
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("transcribe: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var out struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.Text), nil
+}
+
+// transcribeVoice downloads and transcribes a voice note.
+func (a *App) transcribeVoice(ctx context.Context, v *tgbotapi.Voice) (string, error) {
+	if a.STT == nil {
+		return "", fmt.Errorf("speech-to-text is off")
+	}
+	if v.FileSize > maxVoiceBytes {
+		return "", fmt.Errorf("voice note over %d bytes", maxVoiceBytes)
+	}
+	r, err := a.Media.open(ctx, v.FileID)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	audio, err := io.ReadAll(io.LimitReader(r, maxVoiceBytes))
+	if err != nil {
+		return "", err
+	}
+	text, err := a.STT.Transcribe(ctx, audio, "voice.ogg")
+	if err == nil && text == "" {
+		err = fmt.Errorf("empty transcript")
+	}
+	return text, err
+}
+
+// captureVoice handles a voice note in the active topic.
+func (a *App) captureVoice(ctx context.Context, m *tgbotapi.Message) bool {
+	if m.Voice == nil {
+		return false
+	}
+	chatID := m.Chat.ID
+	st := a.touchState(chatID)
+	text, err := a.transcribeVoice(ctx, m.Voice)
+	if err == nil {
+		if a.voiceReply(ctx, chatID, text) {
+			return true
+		}
+		text = a.scriptCapture(chatID, st.Topic, text)
+		a.captureText(m, a.captureRules(chatID, st.Topic, text), text)
+		return true
+	}
+	if a.STT != nil {
+		log.Printf("voice: chat %d: %v", chatID, err)
+	}
+
+	text = "🎤 " + a.tr(chatID, "voice.note", m.Voice.Duration/60, m.Voice.Duration%60, time.Now().In(a.tz(chatID)).Format("02.01 15:04"))
+	id, res := a.storeCapture(chatID, provenanceOf(m), st.Topic, text)
+	switch res {
+	case captureStored:
+		ref := FileRef{FileID: m.Voice.FileID, UniqueID: m.Voice.FileUniqueID, Kind: "voice", Size: int64(m.Voice.FileSize), Mime: m.Voice.MimeType}
+		if err := a.Media.Attach(ctx, chatID, id, ref); err != nil {
+			log.Printf("voice: attach to %d: %v", id, err)
+		}
+		a.ackCapture(m, st.Topic, id)
+		if a.STT != nil {
+			a.send(chatID, a.tr(chatID, "voice.unrecognized", id))
+		}
+	case captureDuplicate:
+		a.send(chatID, a.tr(chatID, "dup", topicLabel(a.Store.Lang(chatID), st.Topic), id))
+	case captureRejected:
+		a.send(chatID, a.tr(chatID, "filter.rejected"))
+	case captureLocked:
+		a.sendLocked(chatID)
+	default:
+		a.send(chatID, a.tr(chatID, "err.write"))
+	}
+	return true
+}
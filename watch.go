@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Watches are saved searches (the /view query syntax) checked against every
+// newly created item; a match is reported at once to whoever saved it.
+
+const maxWatches = 20
+
+type Watch struct {
+	Query    string `json:"query"`
+	NotifyID int64  `json:"notify_id"` // private chat of the author, or the chat itself
+}
+
+func (s *sqlStore) Watches(chatID int64) ([]Watch, error) {
+	v, ok, err := s.GetKV(chatKey(chatID, "watches"))
+	if err != nil || !ok {
+		return nil, err
+	}
+	var ws []Watch
+	err = json.Unmarshal([]byte(v), &ws)
+	return ws, err
+}
+
+func (s *sqlStore) SetWatches(chatID int64, ws []Watch) error {
+	if len(ws) == 0 {
+		return s.DeleteKV(chatKey(chatID, "watches"))
+	}
+	b, err := json.Marshal(ws)
+	if err != nil {
+		return err
+	}
+	return s.SetKV(chatKey(chatID, "watches"), string(b))
+}
+
+// startWatches checks new items against the chat's watches.
+func (a *App) startWatches() {
+	a.Events.Subscribe(func(ev Event) {
+		ws, err := a.Store.Watches(ev.ChatID)
+		if err != nil {
+			log.Printf("watch: chat %d: %v", ev.ChatID, err)
+			return
+		}
+		if len(ws) == 0 {
+			return
+		}
+		lang := a.Store.Lang(ev.ChatID)
+		it := Item{ID: ev.ItemID, ChatID: ev.ChatID, Topic: ev.Topic, Text: ev.Text, CreatedAt: ev.At}
+		for _, w := range ws {
+			q, err := parseQuery(lang, w.Query, func(name string) (string, bool) { return topicFromName(a.Store, ev.ChatID, name) })
+			if err != nil || !q.match(viewRow{Item: it}, ev.At) {
+				continue
+			}
+			text := fmt.Sprintf("🔔 «%s»\n%s", w.Query, formatSingleItem(lang, it.Topic, it))
+			if w.NotifyID != ev.ChatID {
+				if title := chatTitle(a.Bot, ev.ChatID); title != "" {
+					text += "\n(" + title + ")"
+				}
+			}
+			_ = deliver(a.Bot, a.Store, "watch", tgbotapi.NewMessage(w.NotifyID, text))
+		}
+	}, EventItemCreated)
+}
+
+func chatTitle(bot *tgbotapi.BotAPI, chatID int64) string {
+	chat, err := bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}})
+	if err != nil {
+		return ""
+	}
+	return chat.Title
+}
+
+// handleWatch handles "/watch", "/watch <запрос>" and "/watch del <n>".
+func (a *App) handleWatch(m *tgbotapi.Message) {
+	chatID := m.Chat.ID
+	ws, err := a.Store.Watches(chatID)
+	if err != nil {
+		a.send(chatID, a.tr(chatID, "err.read"))
+		return
+	}
+	lang := a.Store.Lang(chatID)
+	arg := strings.TrimSpace(m.CommandArguments())
+	fields := strings.Fields(arg)
+
+	switch {
+	case len(fields) == 0:
+		var b strings.Builder
+		if len(ws) == 0 {
+			b.WriteString(tr(lang, "watches.none") + "\n")
+		} else {
+			b.WriteString(tr(lang, "watches.header") + "\n")
+			for i, w := range ws {
+				fmt.Fprintf(&b, "%d. %s\n", i+1, w.Query)
+			}
+		}
+		b.WriteString("\n" + tr(lang, "watch.help"))
+		a.send(chatID, b.String())
+		return
+	case fields[0] == "del" && len(fields) == 2:
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 1 || n > len(ws) {
+			a.send(chatID, tr(lang, "watch.notfound"))
+			return
+		}
+		if err := a.Store.SetWatches(chatID, append(ws[:n-1:n-1], ws[n:]...)); err != nil {
+			a.send(chatID, a.tr(chatID, "err.write"))
+			return
+		}
+		a.send(chatID, tr(lang, "watch.deleted"))
+		return
+	}
+
+	if len(ws) >= maxWatches {
+		a.send(chatID, tr(lang, "watch.max", maxWatches))
+		return
+	}
+	if _, err := parseQuery(lang, arg, func(name string) (string, bool) { return a.topicFromButton(chatID, name) }); err != nil {
+		a.send(chatID, tr(lang, "view.err", err))
+		return
+	}
+	notify := chatID
+	if isGroupChat(chatID) && m.From != nil {
+		notify = m.From.ID
+	}
+	if err := a.Store.SetWatches(chatID, append(ws, Watch{Query: arg, NotifyID: notify})); err != nil {
+		a.send(chatID, a.tr(chatID, "err.write"))
+		return
+	}
+	reply := tr(lang, "watch.added", arg)
+	if notify != chatID {
+		reply += " " + tr(lang, "watch.dm")
+	}
+	a.send(chatID, reply)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WeatherClient provides the one-line forecast for the morning digest.
+type WeatherClient interface {
+	TodayForecast(ctx context.Context, lang string, now time.Time) (string, error)
+}
+
+// openMeteoClient talks to the free Open-Meteo API (no key required).
+type openMeteoClient struct {
+	enabled bool
+	geo     GeoPoint
+	baseURL string
+	http    *http.Client
+}
+
+func NewWeatherClientFromEnv() (WeatherClient, error) {
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("WEATHER_DISABLED")), "true") {
+		return &openMeteoClient{enabled: false}, nil
+	}
+	geo, ok := geoFromEnv()
+	if !ok {
+		return &openMeteoClient{enabled: false}, nil
+	}
+	return &openMeteoClient{
+		enabled: true,
+		geo:     geo,
+		baseURL: envOr("OPEN_METEO_URL", "https://api.open-meteo.com/v1/forecast"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type openMeteoDaily struct {
+	Daily struct {
+		WeatherCode []int     `json:"weathercode"`
+		TempMax     []float64 `json:"temperature_2m_max"`
+		TempMin     []float64 `json:"temperature_2m_min"`
+		PrecipProb  []float64 `json:"precipitation_probability_max"`
+	} `json:"daily"`
+}
+
+func (c *openMeteoClient) TodayForecast(ctx context.Context, lang string, now time.Time) (string, error) {
+	if !c.enabled {
+		return "", nil
+	}
+
+	q := url.Values{}
+	q.Set("latitude", strconv.FormatFloat(c.geo.Lat, 'f', 4, 64))
+	q.Set("longitude", strconv.FormatFloat(c.geo.Lon, 'f', 4, 64))
+	q.Set("daily", "weathercode,temperature_2m_max,temperature_2m_min,precipitation_probability_max")
+	q.Set("timezone", now.Location().String())
+	q.Set("start_date", now.Format("2006-01-02"))
+	q.Set("end_date", now.Format("2006-01-02"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("open-meteo: %s", resp.Status)
+	}
+
+	var body openMeteoDaily
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	d := body.Daily
+	if len(d.WeatherCode) == 0 || len(d.TempMax) == 0 || len(d.TempMin) == 0 {
+		return "", fmt.Errorf("open-meteo: empty forecast")
+	}
+
+	line := fmt.Sprintf("%s %s…%s°C", weatherLabel(lang, d.WeatherCode[0]), formatTemp(d.TempMin[0]), formatTemp(d.TempMax[0]))
+	if len(d.PrecipProb) > 0 && d.PrecipProb[0] >= 20 {
+		line += ", " + tr(lang, "weather.precip", d.PrecipProb[0])
+	}
+	return line, nil
+}
+
+func formatTemp(t float64) string {
+	n := int(math.Round(t))
+	if n > 0 {
+		return "+" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// weatherLabel maps WMO weather codes to a short description.
+func weatherLabel(lang string, code int) string {
+	switch {
+	case code == 0:
+		return "☀️ " + tr(lang, "weather.clear")
+	case code <= 2:
+		return "🌤 " + tr(lang, "weather.partly")
+	case code == 3:
+		return "☁️ " + tr(lang, "weather.overcast")
+	case code == 45 || code == 48:
+		return "🌫 " + tr(lang, "weather.fog")
+	case code >= 51 && code <= 57:
+		return "🌦 " + tr(lang, "weather.drizzle")
+	case code >= 61 && code <= 67, code >= 80 && code <= 82:
+		return "🌧 " + tr(lang, "weather.rain")
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return "🌨 " + tr(lang, "weather.snow")
+	case code >= 95:
+		return "⛈ " + tr(lang, "weather.storm")
+	default:
+		return "🌡"
+	}
+}